@@ -24,6 +24,9 @@ const (
 
 	sdpAttributeRid = "rid"
 
+	// sdpSemanticTokenFECFramework groups a media SSRC with its FlexFEC repair flow
+	sdpSemanticTokenFECFramework = "FEC-FR"
+
 	rtpOutboundMTU = 1200
 
 	rtpPayloadTypeBitmask = 0x7F
@@ -0,0 +1,325 @@
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/randutil"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/dtmf"
+	"github.com/pion/webrtc/v3/pkg/rtcerr"
+)
+
+const (
+	dtmfMinDuration     = 40 * time.Millisecond
+	dtmfMaxDuration     = 6000 * time.Millisecond
+	dtmfMinInterToneGap = 30 * time.Millisecond
+
+	// dtmfCommaPause is how long a ',' in the tone buffer delays the next tone
+	dtmfCommaPause = 2 * time.Second
+
+	// dtmfPacketInterval is the time between two packets of an event
+	dtmfPacketInterval = 50 * time.Millisecond
+
+	// dtmfEndPackets is the number of times the final packet of an event is sent
+	dtmfEndPackets = 3
+
+	// dtmfVolume is the power level of the tones, in -dBm0
+	dtmfVolume = 10
+
+	// The duration field of a telephone-event is 16 bits long, longer events are split
+	dtmfMaxSegmentDuration = 0xFFFF
+)
+
+// dtmfPacketAttribute is set on the attributes of the telephone-event packets
+type dtmfPacketAttribute struct{}
+
+// DTMFSender sends DTMF tones as RFC 4733 telephone-events, on the stream of the audio
+// track of an RTPSender. Tones can only be sent once a telephone-event codec with the clock
+// rate of the audio codec has been negotiated.
+type DTMFSender struct {
+	rtpSender *RTPSender
+
+	mu                     sync.Mutex
+	toneBuffer             string
+	duration, interToneGap time.Duration
+	playing                bool
+	onToneChange           func(tone string)
+}
+
+// CanInsertDTMF tells if InsertDTMF can be called
+func (d *DTMFSender) CanInsertDTMF() bool {
+	_, _, ok := d.telephoneEvent()
+	return ok
+}
+
+// ToneBuffer returns the tones that remain to be played
+func (d *DTMFSender) ToneBuffer() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.toneBuffer
+}
+
+// OnToneChange sets an event handler which is called when a tone starts being played, and
+// with an empty tone once all the tones have been played
+func (d *DTMFSender) OnToneChange(f func(tone string)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.onToneChange = f
+}
+
+// InsertDTMF replaces the tone buffer with the given tones, 0-9, A-D, # and *. A ',' delays
+// the next tone by two seconds. Each tone lasts duration, between 40ms and 6s, and is
+// followed by interToneGap, at least 30ms. The browser defaults are 100ms and 70ms.
+func (d *DTMFSender) InsertDTMF(tones string, duration, interToneGap time.Duration) error {
+	if d.rtpSender.hasStopped() {
+		return &rtcerr.InvalidStateError{Err: errRTPSenderStopped}
+	}
+	if !d.CanInsertDTMF() {
+		return &rtcerr.InvalidStateError{Err: errDTMFSenderCannotInsert}
+	}
+
+	tones = strings.ToUpper(tones)
+	for _, tone := range tones {
+		if _, ok := dtmf.CodeFromTone(tone); !ok && tone != ',' {
+			return &rtcerr.SyntaxError{Err: errDTMFSenderInvalidTone}
+		}
+	}
+
+	switch {
+	case duration < dtmfMinDuration:
+		duration = dtmfMinDuration
+	case duration > dtmfMaxDuration:
+		duration = dtmfMaxDuration
+	}
+	if interToneGap < dtmfMinInterToneGap {
+		interToneGap = dtmfMinInterToneGap
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.toneBuffer, d.duration, d.interToneGap = tones, duration, interToneGap
+	if !d.playing && tones != "" {
+		d.playing = true
+		go d.play()
+	}
+
+	return nil
+}
+
+// telephoneEvent returns the negotiated telephone-event codec with the clock rate of the
+// audio codec
+func (d *DTMFSender) telephoneEvent() (PayloadType, uint32, bool) {
+	r := d.rtpSender
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if !r.hasSent() || r.hasStopped() || r.trackEncodings[0].track == nil {
+		return 0, 0, false
+	}
+
+	audioCodecs := r.trackEncodings[0].context.CodecParameters()
+	if len(audioCodecs) == 0 {
+		return 0, 0, false
+	}
+
+	var codecs []RTPCodecParameters
+	if r.rtpTransceiver != nil {
+		codecs = r.rtpTransceiver.getCodecs()
+	} else {
+		codecs = r.api.mediaEngine.getCodecsByKind(RTPCodecTypeAudio)
+	}
+	for _, codec := range codecs {
+		if strings.EqualFold(codec.MimeType, MimeTypeTelephoneEvent) && codec.ClockRate == audioCodecs[0].ClockRate {
+			return codec.PayloadType, codec.ClockRate, true
+		}
+	}
+
+	return 0, 0, false
+}
+
+// play sends the tones of the buffer until it is empty or the RTPSender is stopped
+func (d *DTMFSender) play() {
+	for {
+		d.mu.Lock()
+		onToneChange := d.onToneChange
+		if d.toneBuffer == "" {
+			d.playing = false
+			d.mu.Unlock()
+
+			if onToneChange != nil {
+				onToneChange("")
+			}
+			return
+		}
+
+		tone := rune(d.toneBuffer[0])
+		d.toneBuffer = d.toneBuffer[1:]
+		duration, interToneGap := d.duration, d.interToneGap
+		d.mu.Unlock()
+
+		if onToneChange != nil {
+			onToneChange(string(tone))
+		}
+
+		pause := interToneGap
+		if tone == ',' {
+			pause = dtmfCommaPause
+		} else if code, ok := dtmf.CodeFromTone(tone); ok {
+			d.sendTone(code, duration)
+		}
+
+		if !d.wait(pause) {
+			d.mu.Lock()
+			d.toneBuffer, d.playing = "", false
+			d.mu.Unlock()
+			return
+		}
+	}
+}
+
+// wait returns false if the RTPSender is stopped before the duration elapses
+func (d *DTMFSender) wait(duration time.Duration) bool {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-d.rtpSender.stopCalled:
+		return false
+	}
+}
+
+// sendTone sends the packets of a telephone-event, one every dtmfPacketInterval
+func (d *DTMFSender) sendTone(code uint8, duration time.Duration) {
+	payloadType, clockRate, ok := d.telephoneEvent()
+	if !ok {
+		return
+	}
+
+	encoding := d.rtpSender.trackEncodings[0]
+	units := func(length time.Duration) uint32 {
+		return uint32(int64(length) * int64(clockRate) / int64(time.Second))
+	}
+
+	timestamp := encoding.sequencer.timestamp(clockRate)
+	total := units(duration)
+	var segmentStart uint32
+	marker := true
+	write := func(event dtmf.Event) bool {
+		payload, err := event.Marshal()
+		if err != nil {
+			return false
+		}
+
+		header := &rtp.Header{
+			Version:        2,
+			Marker:         marker,
+			PayloadType:    uint8(payloadType),
+			SequenceNumber: encoding.sequencer.nextSequenceNumber(),
+			Timestamp:      timestamp + segmentStart,
+			SSRC:           uint32(encoding.ssrc),
+		}
+		marker = false
+
+		attributes := interceptor.Attributes{}
+		attributes.Set(dtmfPacketAttribute{}, true)
+		_, err = encoding.writeStream.writeRTP(header, payload, attributes)
+		return err == nil
+	}
+
+	for elapsed := time.Duration(0); ; elapsed += dtmfPacketInterval {
+		played := units(elapsed + dtmfPacketInterval)
+		if played > total {
+			played = total
+		}
+
+		for played-segmentStart > dtmfMaxSegmentDuration {
+			if !write(dtmf.Event{Code: code, Volume: dtmfVolume, Duration: dtmfMaxSegmentDuration}) {
+				return
+			}
+			segmentStart += dtmfMaxSegmentDuration
+		}
+
+		event := dtmf.Event{Code: code, Volume: dtmfVolume, Duration: uint16(played - segmentStart)}
+		if played == total {
+			event.End = true
+			for i := 0; i < dtmfEndPackets; i++ {
+				if !write(event) {
+					return
+				}
+			}
+			return
+		}
+
+		if !write(event) || !d.wait(dtmfPacketInterval) {
+			return
+		}
+	}
+}
+
+// dtmfSequencer shifts the sequence numbers of the packets of a track to make room for the
+// DTMF packets, and tracks its timestamps so DTMF events are aligned with the audio
+type dtmfSequencer struct {
+	mu                   sync.Mutex
+	started              bool
+	sequenceNumberOffset uint16
+	lastSequenceNumber   uint16
+	lastTimestamp        uint32
+	lastPacketTime       time.Time
+}
+
+func (s *dtmfSequencer) onTrackPacket(header *rtp.Header) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	header.SequenceNumber += s.sequenceNumberOffset
+	s.started = true
+	s.lastSequenceNumber = header.SequenceNumber
+	s.lastTimestamp = header.Timestamp
+	s.lastPacketTime = time.Now()
+}
+
+// start picks random sequence number and timestamp if DTMF is sent before the track
+func (s *dtmfSequencer) start() {
+	if s.started {
+		return
+	}
+
+	generator := randutil.NewMathRandomGenerator()
+	s.started = true
+	s.lastSequenceNumber = uint16(generator.Uint32())
+	s.lastTimestamp = generator.Uint32()
+	s.lastPacketTime = time.Now()
+}
+
+func (s *dtmfSequencer) nextSequenceNumber() uint16 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.start()
+	s.sequenceNumberOffset++
+	s.lastSequenceNumber++
+
+	return s.lastSequenceNumber
+}
+
+// timestamp returns the timestamp of the audio at the current time
+func (s *dtmfSequencer) timestamp(clockRate uint32) uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.start()
+	elapsed := time.Since(s.lastPacketTime)
+
+	return s.lastTimestamp + uint32(int64(elapsed)*int64(clockRate)/int64(time.Second))
+}
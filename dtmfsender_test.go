@@ -0,0 +1,170 @@
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/v2/test"
+	"github.com/pion/webrtc/v3/pkg/dtmf"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/rtcerr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDTMFSender(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerer, answerer, err := newPair()
+	assert.NoError(t, err)
+
+	videoTrack, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+
+	videoSender, err := offerer.AddTrack(videoTrack)
+	assert.NoError(t, err)
+	assert.Nil(t, videoSender.DTMF())
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeOpus}, "audio", "pion")
+	assert.NoError(t, err)
+
+	rtpSender, err := offerer.AddTrack(track)
+	assert.NoError(t, err)
+
+	dtmfSender := rtpSender.DTMF()
+	assert.NotNil(t, dtmfSender)
+	assert.False(t, dtmfSender.CanInsertDTMF())
+
+	var invalidStateErr *rtcerr.InvalidStateError
+	assert.True(t, errors.As(dtmfSender.InsertDTMF("1", 100*time.Millisecond, 70*time.Millisecond), &invalidStateErr))
+
+	digits := make(chan dtmf.Digit, 2)
+	answerer.OnTrack(func(trackRemote *TrackRemote, _ *RTPReceiver) {
+		if trackRemote.Kind() != RTPCodecTypeAudio {
+			return
+		}
+
+		trackRemote.OnDTMF(func(digit dtmf.Digit) {
+			digits <- digit
+		})
+		for {
+			packet, _, readErr := trackRemote.ReadRTP()
+			if readErr != nil {
+				return
+			}
+			assert.Equal(t, uint8(111), packet.PayloadType)
+		}
+	})
+
+	assert.NoError(t, signalPair(offerer, answerer))
+
+	toneChanges := make(chan string, 3)
+	dtmfSender.OnToneChange(func(tone string) {
+		toneChanges <- tone
+	})
+
+	var received []dtmf.Digit
+	func() {
+		ticker := time.NewTicker(time.Millisecond * 20)
+		defer ticker.Stop()
+		for {
+			select {
+			case digit := <-digits:
+				if received = append(received, digit); len(received) == 2 {
+					return
+				}
+			case <-ticker.C:
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0xAA}, Duration: 20 * time.Millisecond}))
+
+				if dtmfSender.CanInsertDTMF() && len(toneChanges) == 0 && dtmfSender.ToneBuffer() == "" {
+					var syntaxErr *rtcerr.SyntaxError
+					assert.True(t, errors.As(dtmfSender.InsertDTMF("1E", 100*time.Millisecond, 70*time.Millisecond), &syntaxErr))
+					assert.NoError(t, dtmfSender.InsertDTMF("1#", 100*time.Millisecond, 40*time.Millisecond))
+				}
+			}
+		}
+	}()
+
+	assert.Equal(t, []dtmf.Digit{
+		{Tone: '1', Duration: 100 * time.Millisecond},
+		{Tone: '#', Duration: 100 * time.Millisecond},
+	}, received)
+	assert.Equal(t, "1", <-toneChanges)
+	assert.Equal(t, "#", <-toneChanges)
+	assert.Equal(t, "", <-toneChanges)
+
+	closePairNow(t, offerer, answerer)
+}
+
+// Assert that telephone-events aren't returned by Read when no OnDTMF handler is set
+func TestDTMFSender_NoHandler(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerer, answerer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeOpus}, "audio", "pion")
+	assert.NoError(t, err)
+
+	rtpSender, err := offerer.AddTrack(track)
+	assert.NoError(t, err)
+
+	packets := make(chan uint8, 100)
+	answerer.OnTrack(func(trackRemote *TrackRemote, _ *RTPReceiver) {
+		for {
+			packet, _, readErr := trackRemote.ReadRTP()
+			if readErr != nil {
+				return
+			}
+			assert.Equal(t, MimeTypeOpus, trackRemote.Codec().MimeType)
+			packets <- packet.PayloadType
+		}
+	})
+
+	assert.NoError(t, signalPair(offerer, answerer))
+
+	dtmfSender := rtpSender.DTMF()
+	toneChanges := make(chan string, 2)
+	dtmfSender.OnToneChange(func(tone string) {
+		toneChanges <- tone
+	})
+
+	func() {
+		ticker := time.NewTicker(time.Millisecond * 20)
+		defer ticker.Stop()
+
+		inserted, afterTones := false, 0
+		for afterTones < 5 {
+			select {
+			case payloadType := <-packets:
+				assert.Equal(t, uint8(111), payloadType)
+				if len(toneChanges) == 2 {
+					afterTones++
+				}
+			case <-ticker.C:
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0xAA}, Duration: 20 * time.Millisecond}))
+
+				if !inserted && dtmfSender.CanInsertDTMF() {
+					assert.NoError(t, dtmfSender.InsertDTMF("1", 100*time.Millisecond, 40*time.Millisecond))
+					inserted = true
+				}
+			}
+		}
+	}()
+
+	assert.Equal(t, "1", <-toneChanges)
+	assert.Equal(t, "", <-toneChanges)
+
+	closePairNow(t, offerer, answerer)
+}
@@ -215,6 +215,20 @@ var (
 	errRTPSenderBaseEncodingMismatch = errors.New("Sender cannot add encoding as provided track does not match base track")
 	errRTPSenderRIDCollision         = errors.New("Sender cannot encoding due to RID collision")
 	errRTPSenderNoTrackForRID        = errors.New("Sender does not have track for RID")
+	errRTPSenderTransactionMismatch  = errors.New("Sender parameters were not returned by the latest GetParameters call")
+	errRTPSenderEncodingsChanged     = errors.New("Sender encodings can not be added, removed or reordered by SetParameters")
+	errRTPSenderInvalidScaleDownBy   = errors.New("Sender encoding ScaleResolutionDownBy must not be less than 1")
+	errRTPSenderInvalidMaxFramerate  = errors.New("Sender encoding MaxFramerate must not be negative")
+
+	errDTMFSenderCannotInsert = errors.New("DTMF can't be sent until telephone-event has been negotiated for the audio codec")
+	errDTMFSenderInvalidTone  = errors.New("DTMF tones must be 0-9, A-D, #, * or ,")
+
+	errTrackLocalReplayPlaying         = errors.New("TrackLocalReplay is already playing")
+	errTrackLocalReplaySourceExhausted = errors.New("TrackLocalReplay source is exhausted")
+
+	errTrackLocalStaticRTPREDDistance = errors.New("WithREDDistance can only be used with a TrackLocalStaticSample")
+
+	errTrackRemoteKeyFrameNotNegotiated = errors.New("neither PLI nor FIR has been negotiated for the track")
 
 	errRTPTransceiverCannotChangeMid        = errors.New("errRTPSenderTrackNil")
 	errRTPTransceiverSetSendingInvalidState = errors.New("invalid state change in RTPTransceiver.setSending")
@@ -5,13 +5,10 @@ package main
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"io"
+	"sync"
 	"time"
 
-	"github.com/pion/rtcp"
-	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v3"
 	"github.com/pion/webrtc/v3/examples/internal/signal"
 )
@@ -38,8 +35,8 @@ func main() { // nolint:gocognit
 		}
 	}()
 
-	// Create Track that we send video back to browser on
-	outputTrack, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, "video", "pion")
+	// Create Track that we send video back to browser on, it forwards one of the incoming tracks
+	outputTrack, err := webrtc.NewTrackLocalForwarding(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, "video", "pion")
 	if err != nil {
 		panic(err)
 	}
@@ -72,53 +69,24 @@ func main() { // nolint:gocognit
 		panic(err)
 	}
 
-	// Which track is currently being handled
-	currTrack := 0
-	// The total number of tracks
-	trackCount := 0
-	// The channel of packets with a bit of buffer
-	packets := make(chan *rtp.Packet, 60)
+	// The SSRCs of the incoming tracks, and the one currently being forwarded
+	var (
+		tracksLock sync.Mutex
+		tracks     []webrtc.SSRC
+		currTrack  int
+	)
 
 	// Set a handler for when a new remote track starts
 	peerConnection.OnTrack(func(track *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
 		fmt.Printf("Track has started, of type %d: %s \n", track.PayloadType(), track.Codec().MimeType)
-		trackNum := trackCount
-		trackCount++
-		// The last timestamp so that we can change the packet to only be the delta
-		var lastTimestamp uint32
-
-		// Whether this track is the one currently sending to the channel (on change
-		// of this we send a PLI to have the entire picture updated)
-		var isCurrTrack bool
-		for {
-			// Read RTP packets being sent to Pion
-			rtp, _, readErr := track.ReadRTP()
-			if readErr != nil {
-				panic(readErr)
-			}
-
-			// Change the timestamp to only be the delta
-			oldTimestamp := rtp.Timestamp
-			if lastTimestamp == 0 {
-				rtp.Timestamp = 0
-			} else {
-				rtp.Timestamp -= lastTimestamp
-			}
-			lastTimestamp = oldTimestamp
-
-			// Check if this is the current track
-			if currTrack == trackNum {
-				// If just switched to this track, send PLI to get picture refresh
-				if !isCurrTrack {
-					isCurrTrack = true
-					if writeErr := peerConnection.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: uint32(track.SSRC())}}); writeErr != nil {
-						fmt.Println(writeErr)
-					}
-				}
-				packets <- rtp
-			} else {
-				isCurrTrack = false
-			}
+		tracksLock.Lock()
+		tracks = append(tracks, track.SSRC())
+		tracksLock.Unlock()
+
+		// Forward the packets of the track while it is selected. The outgoing sequence numbers and
+		// timestamps stay continuous, and a PLI is sent to get a key frame when switching to it.
+		if forwardErr := outputTrack.Forward(track, peerConnection.WriteRTCP); forwardErr != nil {
+			fmt.Println(forwardErr)
 		}
 	})
 
@@ -159,29 +127,6 @@ func main() { // nolint:gocognit
 
 	fmt.Println(signal.Encode(*peerConnection.LocalDescription()))
 
-	// Asynchronously take all packets in the channel and write them out to our
-	// track
-	go func() {
-		var currTimestamp uint32
-		for i := uint16(0); ; i++ {
-			packet := <-packets
-			// Timestamp on the packet is really a diff, so add it to current
-			currTimestamp += packet.Timestamp
-			packet.Timestamp = currTimestamp
-			// Keep an increasing sequence number
-			packet.SequenceNumber = i
-			// Write out the packet, ignoring closed pipe if nobody is listening
-			if err := outputTrack.WriteRTP(packet); err != nil {
-				if errors.Is(err, io.ErrClosedPipe) {
-					// The peerConnection has been closed.
-					return
-				}
-
-				panic(err)
-			}
-		}
-	}()
-
 	// Wait for connection, then rotate the track every 5s
 	fmt.Printf("Waiting for connection\n")
 	for {
@@ -191,6 +136,10 @@ func main() { // nolint:gocognit
 		default:
 		}
 
+		tracksLock.Lock()
+		trackCount := len(tracks)
+		tracksLock.Unlock()
+
 		// We haven't gotten any tracks yet
 		if trackCount == 0 {
 			continue
@@ -198,11 +147,11 @@ func main() { // nolint:gocognit
 
 		fmt.Printf("Waiting 5 seconds then changing...\n")
 		time.Sleep(5 * time.Second)
-		if currTrack == trackCount-1 {
-			currTrack = 0
-		} else {
-			currTrack++
-		}
+
+		tracksLock.Lock()
+		currTrack = (currTrack + 1) % len(tracks)
+		outputTrack.SwitchTo(tracks[currTrack])
+		tracksLock.Unlock()
 		fmt.Printf("Switched to track #%v\n", currTrack+1)
 	}
 }
@@ -4,14 +4,24 @@
 package webrtc
 
 import (
+	"fmt"
+	"strings"
+	"sync"
 	"sync/atomic"
 
 	"github.com/pion/interceptor"
-	"github.com/pion/interceptor/pkg/nack"
+	"github.com/pion/interceptor/pkg/cc"
+	"github.com/pion/interceptor/pkg/gcc"
 	"github.com/pion/interceptor/pkg/report"
+	"github.com/pion/interceptor/pkg/stats"
 	"github.com/pion/interceptor/pkg/twcc"
 	"github.com/pion/rtp"
 	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3/pkg/flexfec"
+	"github.com/pion/webrtc/v3/pkg/lipsync"
+	"github.com/pion/webrtc/v3/pkg/nack"
+	"github.com/pion/webrtc/v3/pkg/remb"
+	"github.com/pion/webrtc/v3/pkg/ulpfec"
 )
 
 // RegisterDefaultInterceptors will register some useful interceptors.
@@ -30,9 +40,210 @@ func RegisterDefaultInterceptors(mediaEngine *MediaEngine, interceptorRegistry *
 		return err
 	}
 
+	if err := ConfigureStatsInterceptor(interceptorRegistry); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// statsGetters maps the statsID of a PeerConnection to the stats.Getter
+// of the stats interceptor built for it
+// nolint:gochecknoglobals
+var statsGetters sync.Map
+
+// ConfigureStatsInterceptor will setup everything necessary for generating RTP stream statistics.
+// The collected statistics are exposed by PeerConnection.GetStats as inbound-rtp, outbound-rtp,
+// remote-inbound-rtp and remote-outbound-rtp entries.
+func ConfigureStatsInterceptor(interceptorRegistry *interceptor.Registry) error {
+	statsInterceptor, err := stats.NewInterceptor()
+	if err != nil {
+		return err
+	}
+
+	statsInterceptor.OnNewPeerConnection(func(id string, getter stats.Getter) {
+		statsGetters.Store(id, getter)
+	})
+
+	interceptorRegistry.Add(statsInterceptor)
+	return nil
+}
+
+// lookupStats returns the stats.Getter for the PeerConnection with the given statsID
+func lookupStats(id string) (stats.Getter, bool) {
+	if value, ok := statsGetters.Load(id); ok {
+		if getter, ok := value.(stats.Getter); ok {
+			return getter, true
+		}
+	}
+
+	return nil, false
+}
+
+// cleanupInterceptors removes everything the interceptors registered for the PeerConnection with the given statsID
+func cleanupInterceptors(id string) {
+	cleanupStats(id)
+	cleanupBandwidthEstimator(id)
+	cleanupNackGenerator(id)
+	cleanupLipSynchronizer(id)
+}
+
+// cleanupStats removes the stats.Getter for the PeerConnection with the given statsID
+func cleanupStats(id string) {
+	statsGetters.Delete(id)
+}
+
+// bandwidthEstimator is the part of a congestion control interceptor exposed by the PeerConnection
+type bandwidthEstimator interface {
+	GetTargetBitrate() int
+	OnTargetBitrateChange(f func(bitrate int))
+}
+
+// bandwidthEstimators maps the statsID of a PeerConnection to the combinedBandwidthEstimator
+// of the congestion control interceptors built for it
+// nolint:gochecknoglobals
+var bandwidthEstimators sync.Map
+
+// combinedBandwidthEstimator reports the lowest estimate of the bandwidthEstimators of a
+// PeerConnection, so that GCC and REMB can be configured together. Estimators reporting 0
+// don't have an estimate yet and are ignored.
+type combinedBandwidthEstimator struct {
+	mu                    sync.Mutex
+	estimators            []bandwidthEstimator
+	targetBitrate         int
+	onTargetBitrateChange func(bitrate int)
+}
+
+func (c *combinedBandwidthEstimator) add(estimator bandwidthEstimator) {
+	c.mu.Lock()
+	c.estimators = append(c.estimators, estimator)
+	c.mu.Unlock()
+
+	estimator.OnTargetBitrateChange(func(int) {
+		c.update()
+	})
+}
+
+// GetTargetBitrate returns the lowest estimate, or 0 if there is none yet
+func (c *combinedBandwidthEstimator) GetTargetBitrate() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lowestBitrate()
+}
+
+// OnTargetBitrateChange sets a callback that is called when the lowest estimate changes
+func (c *combinedBandwidthEstimator) OnTargetBitrateChange(f func(bitrate int)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.targetBitrate = c.lowestBitrate()
+	c.onTargetBitrateChange = f
+}
+
+func (c *combinedBandwidthEstimator) update() {
+	c.mu.Lock()
+	bitrate := c.lowestBitrate()
+	if bitrate == c.targetBitrate {
+		c.mu.Unlock()
+		return
+	}
+	c.targetBitrate = bitrate
+	f := c.onTargetBitrateChange
+	c.mu.Unlock()
+
+	if f != nil {
+		f(bitrate)
+	}
+}
+
+func (c *combinedBandwidthEstimator) lowestBitrate() int {
+	lowest := 0
+	for _, estimator := range c.estimators {
+		if bitrate := estimator.GetTargetBitrate(); bitrate != 0 && (lowest == 0 || bitrate < lowest) {
+			lowest = bitrate
+		}
+	}
+
+	return lowest
+}
+
+// addBandwidthEstimator combines estimator with the other ones of the PeerConnection with the given statsID
+func addBandwidthEstimator(id string, estimator bandwidthEstimator) {
+	value, _ := bandwidthEstimators.LoadOrStore(id, &combinedBandwidthEstimator{})
+	value.(*combinedBandwidthEstimator).add(estimator)
+}
+
+// ConfigureCongestionControl will setup everything necessary for estimating the available bandwidth with
+// Google Congestion Control (delay and loss based), from the transport wide feedback sent by the remote peer.
+// The estimate is exposed by PeerConnection.GetTargetBitrate and PeerConnection.OnTargetBitrateChange.
+// Outgoing packets are paced to the estimate. It adds a TWCC header extension to outgoing packets, so it
+// must not be combined with ConfigureTWCCHeaderExtensionSender.
+func ConfigureCongestionControl(mediaEngine *MediaEngine, interceptorRegistry *interceptor.Registry, opts ...gcc.Option) error {
+	congestionController, err := cc.NewInterceptor(func() (cc.BandwidthEstimator, error) {
+		return gcc.NewSendSideBWE(opts...)
+	})
+	if err != nil {
+		return err
+	}
+
+	congestionController.OnNewPeerConnection(func(id string, estimator cc.BandwidthEstimator) {
+		addBandwidthEstimator(id, estimator)
+	})
+
+	// The estimator reads the TWCC sequence numbers, so the header extension
+	// interceptor must be registered after it to run first
+	interceptorRegistry.Add(congestionController)
+	if err := ConfigureTWCCHeaderExtensionSender(mediaEngine, interceptorRegistry); err != nil {
+		return err
+	}
+
+	mediaEngine.RegisterFeedback(RTCPFeedback{Type: TypeRTCPFBTransportCC}, RTPCodecTypeVideo)
+	mediaEngine.RegisterFeedback(RTCPFeedback{Type: TypeRTCPFBTransportCC}, RTPCodecTypeAudio)
+	return nil
+}
+
+// ConfigureREMB will setup everything necessary for bandwidth estimation with REMB (goog-remb), for
+// remote peers that don't support transport wide feedback. Remote video tracks are estimated from the
+// abs-send-time header extension and the estimate is sent back in REMB packets. Local video tracks are
+// stamped with abs-send-time, and the REMB packets received for them are reported by
+// PeerConnection.GetTargetBitrate and PeerConnection.OnTargetBitrateChange once the first one arrives.
+// When combined with ConfigureCongestionControl, the lower of both estimates is reported.
+func ConfigureREMB(mediaEngine *MediaEngine, interceptorRegistry *interceptor.Registry, opts ...remb.Option) error {
+	mediaEngine.RegisterFeedback(RTCPFeedback{Type: TypeRTCPFBGoogREMB}, RTPCodecTypeVideo)
+	if err := mediaEngine.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: sdp.ABSSendTimeURI}, RTPCodecTypeVideo); err != nil {
+		return err
+	}
+
+	i, err := remb.NewInterceptor(opts...)
+	if err != nil {
+		return err
+	}
+
+	i.OnNewPeerConnection(func(id string, estimator *remb.Interceptor) {
+		addBandwidthEstimator(id, estimator)
+	})
+
+	interceptorRegistry.Add(i)
 	return nil
 }
 
+// lookupBandwidthEstimator returns the bandwidthEstimator for the PeerConnection with the given statsID
+func lookupBandwidthEstimator(id string) (bandwidthEstimator, bool) {
+	if value, ok := bandwidthEstimators.Load(id); ok {
+		if estimator, ok := value.(bandwidthEstimator); ok {
+			return estimator, true
+		}
+	}
+
+	return nil, false
+}
+
+// cleanupBandwidthEstimator removes the bandwidthEstimator for the PeerConnection with the given statsID
+func cleanupBandwidthEstimator(id string) {
+	bandwidthEstimators.Delete(id)
+}
+
 // ConfigureRTCPReports will setup everything necessary for generating Sender and Receiver Reports
 func ConfigureRTCPReports(interceptorRegistry *interceptor.Registry) error {
 	reciver, err := report.NewReceiverInterceptor()
@@ -50,14 +261,25 @@ func ConfigureRTCPReports(interceptorRegistry *interceptor.Registry) error {
 	return nil
 }
 
+// nackGenerators maps the statsID of a PeerConnection to the pkg/nack generator built for it
+// nolint:gochecknoglobals
+var nackGenerators sync.Map
+
 // ConfigureNack will setup everything necessary for handling generating/responding to nack messages.
-func ConfigureNack(mediaEngine *MediaEngine, interceptorRegistry *interceptor.Registry) error {
-	generator, err := nack.NewGeneratorInterceptor()
+// NACKs are generated by pkg/nack, which schedules the retries of each missing packet from the round
+// trip time, and counts the packets it requested in the inbound-rtp entries returned by
+// PeerConnection.GetStats.
+func ConfigureNack(mediaEngine *MediaEngine, interceptorRegistry *interceptor.Registry, opts ...nack.GeneratorOption) error {
+	generator, err := nack.NewGeneratorInterceptor(opts...)
 	if err != nil {
 		return err
 	}
 
-	responder, err := nack.NewResponderInterceptor()
+	generator.OnNewPeerConnection(func(id string, g *nack.GeneratorInterceptor) {
+		nackGenerators.Store(id, g)
+	})
+
+	responder, err := newNackResponder()
 	if err != nil {
 		return err
 	}
@@ -69,6 +291,60 @@ func ConfigureNack(mediaEngine *MediaEngine, interceptorRegistry *interceptor.Re
 	return nil
 }
 
+// lookupNackGenerator returns the NACK generator for the PeerConnection with the given statsID
+func lookupNackGenerator(id string) (*nack.GeneratorInterceptor, bool) {
+	if value, ok := nackGenerators.Load(id); ok {
+		if generator, ok := value.(*nack.GeneratorInterceptor); ok {
+			return generator, true
+		}
+	}
+
+	return nil, false
+}
+
+// cleanupNackGenerator removes the NACK generator for the PeerConnection with the given statsID
+func cleanupNackGenerator(id string) {
+	nackGenerators.Delete(id)
+}
+
+// lipSynchronizers maps the statsID of a PeerConnection to the lipsync.Interceptor built for it
+// nolint:gochecknoglobals
+var lipSynchronizers sync.Map
+
+// ConfigureLipSync will setup everything necessary for mapping the RTP timestamps of remote tracks
+// to the NTP clock of their sender. The RTPReceivers read the RTCP of their tracks themselves, so
+// that the Sender Reports are handled even if the application doesn't read it. The capture times
+// are returned by TrackRemote.CaptureTime.
+func ConfigureLipSync(interceptorRegistry *interceptor.Registry) error {
+	i, err := lipsync.NewInterceptor()
+	if err != nil {
+		return err
+	}
+
+	i.OnNewPeerConnection(func(id string, synchronizer *lipsync.Interceptor) {
+		lipSynchronizers.Store(id, synchronizer)
+	})
+
+	interceptorRegistry.Add(i)
+	return nil
+}
+
+// lookupLipSynchronizer returns the lipsync.Interceptor for the PeerConnection with the given statsID
+func lookupLipSynchronizer(id string) (*lipsync.Interceptor, bool) {
+	if value, ok := lipSynchronizers.Load(id); ok {
+		if synchronizer, ok := value.(*lipsync.Interceptor); ok {
+			return synchronizer, true
+		}
+	}
+
+	return nil, false
+}
+
+// cleanupLipSynchronizer removes the lipsync.Interceptor for the PeerConnection with the given statsID
+func cleanupLipSynchronizer(id string) {
+	lipSynchronizers.Delete(id)
+}
+
 // ConfigureTWCCHeaderExtensionSender will setup everything necessary for adding
 // a TWCC header extension to outgoing RTP packets. This will allow the remote peer to generate TWCC reports.
 func ConfigureTWCCHeaderExtensionSender(mediaEngine *MediaEngine, interceptorRegistry *interceptor.Registry) error {
@@ -110,11 +386,115 @@ func ConfigureTWCCSender(mediaEngine *MediaEngine, interceptorRegistry *intercep
 	return nil
 }
 
+// ConfigureFlexFEC will setup everything necessary for protecting video tracks with FlexFEC forward
+// error correction, and for recovering lost packets of remote tracks protected by it. FEC packets are
+// sent on their own SSRC, alongside the media of the track. Simulcast tracks aren't protected.
+func ConfigureFlexFEC(mediaEngine *MediaEngine, interceptorRegistry *interceptor.Registry, opts ...flexfec.Option) error {
+	if err := mediaEngine.RegisterCodec(RTPCodecParameters{
+		RTPCodecCapability: RTPCodecCapability{MimeType: flexfec.MimeType, ClockRate: 90000, SDPFmtpLine: flexfec.SDPFmtpLine},
+		PayloadType:        113,
+	}, RTPCodecTypeVideo); err != nil {
+		return err
+	}
+
+	i, err := flexfec.NewInterceptor(opts...)
+	if err != nil {
+		return err
+	}
+
+	interceptorRegistry.Add(i)
+	return nil
+}
+
+// ConfigureRED will setup everything necessary for sending Opus in RED (RFC 2198) from tracks created
+// WithREDDistance, and for unwrapping the RED packets of remote tracks. It must be called after Opus is
+// registered, like after RegisterDefaultCodecs.
+func ConfigureRED(mediaEngine *MediaEngine) error {
+	var opus *RTPCodecParameters
+	mediaEngine.mu.RLock()
+	for i := range mediaEngine.audioCodecs {
+		if strings.EqualFold(mediaEngine.audioCodecs[i].MimeType, MimeTypeOpus) {
+			opus = &mediaEngine.audioCodecs[i]
+			break
+		}
+	}
+	mediaEngine.mu.RUnlock()
+
+	if opus == nil {
+		return ErrCodecNotFound
+	}
+
+	return mediaEngine.RegisterCodec(RTPCodecParameters{
+		RTPCodecCapability: RTPCodecCapability{
+			MimeType:    MimeTypeRED,
+			ClockRate:   opus.ClockRate,
+			Channels:    opus.Channels,
+			SDPFmtpLine: fmt.Sprintf("%d/%d", opus.PayloadType, opus.PayloadType),
+		},
+		PayloadType: 63,
+	}, RTPCodecTypeAudio)
+}
+
+// ConfigureULPFEC will setup everything necessary for protecting video tracks with ULPFEC forward
+// error correction, and for recovering lost packets of remote tracks protected by it. Media and FEC
+// packets are sent in RED on the SSRC of the track. It must be called after the interceptors that
+// add header extensions or respond to NACKs are registered, like after RegisterDefaultInterceptors.
+func ConfigureULPFEC(mediaEngine *MediaEngine, interceptorRegistry *interceptor.Registry, opts ...ulpfec.Option) error {
+	for _, codec := range []RTPCodecParameters{
+		{
+			RTPCodecCapability: RTPCodecCapability{MimeType: ulpfec.MimeTypeRED, ClockRate: 90000},
+			PayloadType:        114,
+		},
+		{
+			RTPCodecCapability: RTPCodecCapability{MimeType: MimeTypeRTX, ClockRate: 90000, SDPFmtpLine: "apt=114"},
+			PayloadType:        115,
+		},
+		{
+			RTPCodecCapability: RTPCodecCapability{MimeType: ulpfec.MimeType, ClockRate: 90000},
+			PayloadType:        116,
+		},
+	} {
+		if err := mediaEngine.RegisterCodec(codec, RTPCodecTypeVideo); err != nil {
+			return err
+		}
+	}
+
+	i, err := ulpfec.NewInterceptor(opts...)
+	if err != nil {
+		return err
+	}
+
+	interceptorRegistry.Add(i)
+	return nil
+}
+
+// setULPFECStream pairs a stream with the RED and ULPFEC codecs, if both are in codecs
+func setULPFECStream(info *interceptor.StreamInfo, codecs []RTPCodecParameters) {
+	var stream ulpfec.Stream
+	var hasRED, hasULPFEC bool
+	for _, codec := range codecs {
+		switch {
+		case strings.EqualFold(codec.MimeType, ulpfec.MimeTypeRED):
+			stream.REDPayloadType, hasRED = uint8(codec.PayloadType), true
+		case strings.EqualFold(codec.MimeType, ulpfec.MimeType):
+			stream.ULPFECPayloadType, hasULPFEC = uint8(codec.PayloadType), true
+		}
+	}
+
+	if hasRED && hasULPFEC {
+		ulpfec.SetStream(info, stream)
+	}
+}
+
 type interceptorToTrackLocalWriter struct{ interceptor atomic.Value } // interceptor.RTPWriter }
 
 func (i *interceptorToTrackLocalWriter) WriteRTP(header *rtp.Header, payload []byte) (int, error) {
+	return i.writeRTP(header, payload, interceptor.Attributes{})
+}
+
+func (i *interceptorToTrackLocalWriter) writeRTP(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
 	if writer, ok := i.interceptor.Load().(interceptor.RTPWriter); ok && writer != nil {
-		return writer.Write(header, payload, interceptor.Attributes{})
+		return writer.Write(header, payload, attributes)
 	}
 
 	return 0, nil
@@ -6,15 +6,21 @@ package webrtc
 //
 import (
 	"context"
+	"fmt"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/pion/interceptor"
 	mock_interceptor "github.com/pion/interceptor/pkg/mock"
+	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
 	"github.com/pion/transport/v2/test"
 	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/nack"
+	"github.com/pion/webrtc/v3/pkg/remb"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -198,19 +204,21 @@ func Test_Interceptor_BindUnbind(t *testing.T) {
 	if cnt := atomic.LoadUint32(&cntUnbindLocalStream); cnt != 1 {
 		t.Errorf("UnbindLocalStreamFn is expected to be called once, but called %d times", cnt)
 	}
-	if cnt := atomic.LoadUint32(&cntBindRemoteStream); cnt != 1 {
-		t.Errorf("BindRemoteStreamFn is expected to be called once, but called %d times", cnt)
+	// The receiver also binds the RTX repair flow announced by the sender
+	if cnt := atomic.LoadUint32(&cntBindRemoteStream); cnt != 2 {
+		t.Errorf("BindRemoteStreamFn is expected to be called twice, but called %d times", cnt)
 	}
-	if cnt := atomic.LoadUint32(&cntUnbindRemoteStream); cnt != 1 {
-		t.Errorf("UnbindRemoteStreamFn is expected to be called once, but called %d times", cnt)
+	if cnt := atomic.LoadUint32(&cntUnbindRemoteStream); cnt != 2 {
+		t.Errorf("UnbindRemoteStreamFn is expected to be called twice, but called %d times", cnt)
 	}
 
-	// BindRTCPWriter/Reader and Close should be called from both side.
+	// BindRTCPWriter/Reader and Close should be called from both side, plus once for
+	// the RTCP of the RTX repair flow.
 	if cnt := atomic.LoadUint32(&cntBindRTCPWriter); cnt != 2 {
 		t.Errorf("BindRTCPWriterFn is expected to be called twice, but called %d times", cnt)
 	}
-	if cnt := atomic.LoadUint32(&cntBindRTCPReader); cnt != 2 {
-		t.Errorf("BindRTCPReaderFn is expected to be called twice, but called %d times", cnt)
+	if cnt := atomic.LoadUint32(&cntBindRTCPReader); cnt != 3 {
+		t.Errorf("BindRTCPReaderFn is expected to be called three times, but called %d times", cnt)
 	}
 	if cnt := atomic.LoadUint32(&cntClose); cnt != 2 {
 		t.Errorf("CloseFn is expected to be called twice, but called %d times", cnt)
@@ -235,5 +243,636 @@ func Test_InterceptorRegistry_Build(t *testing.T) {
 	assert.NoError(t, err)
 
 	assert.Equal(t, 2, registryBuildCount)
+	assert.NotEqual(t, peerConnectionA.statsID, peerConnectionB.statsID)
 	closePairNow(t, peerConnectionA, peerConnectionB)
 }
+
+// Assert that the interceptors of a PeerConnection that fails to be created are closed and forgotten
+func Test_InterceptorRegistry_Build_Failure(t *testing.T) {
+	var id string
+	closed := false
+
+	m := &MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+	ir := &interceptor.Registry{}
+	assert.NoError(t, RegisterDefaultInterceptors(m, ir))
+	ir.Add(&mock_interceptor.Factory{
+		NewInterceptorFn: func(statsID string) (interceptor.Interceptor, error) {
+			id = statsID
+			return &mock_interceptor.Interceptor{
+				CloseFn: func() error {
+					closed = true
+					return nil
+				},
+			}, nil
+		},
+	})
+
+	_, err := NewAPI(WithMediaEngine(m), WithInterceptorRegistry(ir)).NewPeerConnection(Configuration{
+		ICEServers: []ICEServer{{URLs: []string{"turns:google.de?transport=tcp"}}},
+	})
+	assert.ErrorIs(t, err, ErrNoTurnCredentials)
+
+	assert.NotEmpty(t, id)
+	assert.True(t, closed)
+
+	_, ok := lookupStats(id)
+	assert.False(t, ok)
+	_, ok = lookupNackGenerator(id)
+	assert.False(t, ok)
+}
+
+// Assert that packets lost on the way are recovered from FlexFEC before being read
+func Test_ConfigureFlexFEC(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	createAPI := func(ir *interceptor.Registry) *API {
+		m := &MediaEngine{}
+		assert.NoError(t, m.RegisterDefaultCodecs())
+		assert.NoError(t, ConfigureFlexFEC(m, ir))
+		return NewAPI(WithMediaEngine(m), WithInterceptorRegistry(ir))
+	}
+
+	// Drop some media packets after FEC has been computed
+	senderRegistry := &interceptor.Registry{}
+	senderRegistry.Add(&mock_interceptor.Factory{
+		NewInterceptorFn: func(_ string) (interceptor.Interceptor, error) {
+			return &mock_interceptor.Interceptor{
+				BindLocalStreamFn: func(info *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+					if info.MimeType != MimeTypeVP8 {
+						return writer
+					}
+
+					return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+						if header.SequenceNumber%10 == 3 {
+							return header.MarshalSize() + len(payload), nil
+						}
+						return writer.Write(header, payload, attributes)
+					})
+				},
+			}, nil
+		},
+	})
+
+	offerer, err := createAPI(senderRegistry).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	answerer, err := createAPI(&interceptor.Registry{}).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+
+	rtpSender, err := offerer.AddTrack(track)
+	assert.NoError(t, err)
+
+	parameters := rtpSender.GetParameters()
+	assert.NotEqual(t, SSRC(0), parameters.Encodings[0].FEC.SSRC)
+
+	offer, err := offerer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, offer.SDP, fmt.Sprintf("a=ssrc-group:FEC-FR %d %d", parameters.Encodings[0].SSRC, parameters.Encodings[0].FEC.SSRC))
+
+	recovered, recoveredFn := context.WithCancel(context.Background())
+	answerer.OnTrack(func(trackRemote *TrackRemote, _ *RTPReceiver) {
+		for {
+			packet, _, readErr := trackRemote.ReadRTP()
+			if readErr != nil {
+				return
+			}
+
+			if packet.SequenceNumber%10 == 3 {
+				assert.Equal(t, []byte{0xAA}, packet.Payload[len(packet.Payload)-1:])
+				recoveredFn()
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(offerer, answerer))
+
+	func() {
+		ticker := time.NewTicker(time.Millisecond * 20)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-recovered.Done():
+				return
+			case <-ticker.C:
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0xAA}, Duration: time.Second}))
+			}
+		}
+	}()
+
+	closePairNow(t, offerer, answerer)
+}
+
+// Assert that media packets lost on the way are recovered from ULPFEC, and that the FEC
+// packets leave no gaps in the sequence numbers of the remote track
+func Test_ConfigureULPFEC(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	createAPI := func(ir *interceptor.Registry) *API {
+		m := &MediaEngine{}
+		assert.NoError(t, m.RegisterDefaultCodecs())
+		assert.NoError(t, ConfigureULPFEC(m, ir))
+		return NewAPI(WithMediaEngine(m), WithInterceptorRegistry(ir))
+	}
+
+	// Drop some media packets after FEC has been computed, the dropping interceptor is
+	// registered first so ULPFEC writes through it
+	senderRegistry := &interceptor.Registry{}
+	senderRegistry.Add(&mock_interceptor.Factory{
+		NewInterceptorFn: func(_ string) (interceptor.Interceptor, error) {
+			return &mock_interceptor.Interceptor{
+				BindLocalStreamFn: func(info *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+					return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+						if header.SequenceNumber%10 == 3 && len(payload) != 0 && payload[0] != 116 {
+							return header.MarshalSize() + len(payload), nil
+						}
+						return writer.Write(header, payload, attributes)
+					})
+				},
+			}, nil
+		},
+	})
+
+	offerer, err := createAPI(senderRegistry).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	answerer, err := createAPI(&interceptor.Registry{}).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+
+	_, err = offerer.AddTrack(track)
+	assert.NoError(t, err)
+
+	offer, err := offerer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, offer.SDP, "a=rtpmap:114 red/90000")
+	assert.Contains(t, offer.SDP, "a=rtpmap:116 ulpfec/90000")
+
+	recovered, recoveredFn := context.WithCancel(context.Background())
+	answerer.OnTrack(func(trackRemote *TrackRemote, _ *RTPReceiver) {
+		assert.Equal(t, MimeTypeVP8, trackRemote.Codec().MimeType)
+
+		var first uint16
+		received := map[uint16]bool{}
+		for {
+			packet, _, readErr := trackRemote.ReadRTP()
+			if readErr != nil {
+				return
+			}
+
+			assert.Equal(t, []byte{0xAA}, packet.Payload[len(packet.Payload)-1:])
+			if len(received) == 0 {
+				first = packet.SequenceNumber
+			}
+			received[packet.SequenceNumber-first] = true
+
+			// Without recovery and renumbering, the track would have gaps before 30
+			complete := true
+			for i := uint16(0); i < 30; i++ {
+				complete = complete && received[i]
+			}
+			if complete {
+				recoveredFn()
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(offerer, answerer))
+
+	func() {
+		ticker := time.NewTicker(time.Millisecond * 20)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-recovered.Done():
+				return
+			case <-ticker.C:
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0xAA}, Duration: time.Second}))
+			}
+		}
+	}()
+
+	closePairNow(t, offerer, answerer)
+}
+
+// Assert that RED is only offered once configured, and that it carries the registered Opus
+func Test_ConfigureRED(t *testing.T) {
+	assert.ErrorIs(t, ConfigureRED(&MediaEngine{}), ErrCodecNotFound)
+
+	m := &MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+
+	pc, err := NewAPI(WithMediaEngine(m)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pc.AddTransceiverFromKind(RTPCodecTypeAudio)
+	assert.NoError(t, err)
+
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NotContains(t, offer.SDP, "red/48000")
+	assert.NoError(t, pc.Close())
+
+	assert.NoError(t, ConfigureRED(m))
+
+	pc, err = NewAPI(WithMediaEngine(m)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pc.AddTransceiverFromKind(RTPCodecTypeAudio)
+	assert.NoError(t, err)
+
+	offer, err = pc.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, offer.SDP, "a=rtpmap:63 red/48000/2")
+	assert.Contains(t, offer.SDP, "a=fmtp:63 111/111")
+	assert.NoError(t, pc.Close())
+}
+
+func Test_ConfigureCongestionControl(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	m := &MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+	ir := &interceptor.Registry{}
+	assert.NoError(t, ConfigureCongestionControl(m, ir))
+
+	offerer, err := NewAPI(WithMediaEngine(m), WithInterceptorRegistry(ir)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	assert.Greater(t, offerer.GetTargetBitrate(), 0)
+
+	// The answerer sends transport wide feedback with the default interceptors
+	answerer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, answerer.GetTargetBitrate())
+
+	answerer.OnTrack(func(trackRemote *TrackRemote, _ *RTPReceiver) {
+		for {
+			if _, _, readErr := trackRemote.ReadRTP(); readErr != nil {
+				return
+			}
+		}
+	})
+
+	bitrateChanged, bitrateChangedFn := context.WithCancel(context.Background())
+	offerer.OnTargetBitrateChange(func(int) {
+		bitrateChangedFn()
+	})
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+
+	rtpSender, err := offerer.AddTrack(track)
+	assert.NoError(t, err)
+
+	// Feedback is only processed while RTCP is read
+	go func() {
+		for {
+			if _, _, readErr := rtpSender.ReadRTCP(); readErr != nil {
+				return
+			}
+		}
+	}()
+
+	offer, err := offerer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(offer.SDP, "a=rtcp-fb:96 transport-cc"))
+
+	assert.NoError(t, signalPair(offerer, answerer))
+
+	func() {
+		ticker := time.NewTicker(time.Millisecond * 20)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-bitrateChanged.Done():
+				return
+			case <-ticker.C:
+				assert.NoError(t, track.WriteSample(media.Sample{Data: make([]byte, 1000), Duration: time.Second}))
+			}
+		}
+	}()
+
+	closePairNow(t, offerer, answerer)
+	_, ok := lookupBandwidthEstimator(offerer.statsID)
+	assert.False(t, ok)
+}
+
+func Test_ConfigureREMB(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	createPeerConnection := func(opts ...remb.Option) *PeerConnection {
+		m := &MediaEngine{}
+		assert.NoError(t, m.RegisterDefaultCodecs())
+		ir := &interceptor.Registry{}
+		assert.NoError(t, ConfigureREMB(m, ir, opts...))
+
+		pc, err := NewAPI(WithMediaEngine(m), WithInterceptorRegistry(ir)).NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+		return pc
+	}
+
+	offerer := createPeerConnection()
+	answerer := createPeerConnection(remb.SendInterval(time.Millisecond * 100))
+
+	answerer.OnTrack(func(trackRemote *TrackRemote, _ *RTPReceiver) {
+		for {
+			if _, _, readErr := trackRemote.ReadRTP(); readErr != nil {
+				return
+			}
+		}
+	})
+
+	bitrateChanged, bitrateChangedFn := context.WithCancel(context.Background())
+	offerer.OnTargetBitrateChange(func(int) {
+		bitrateChangedFn()
+	})
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+
+	rtpSender, err := offerer.AddTrack(track)
+	assert.NoError(t, err)
+
+	// REMB is only processed while RTCP is read
+	go func() {
+		for {
+			if _, _, readErr := rtpSender.ReadRTCP(); readErr != nil {
+				return
+			}
+		}
+	}()
+
+	offer, err := offerer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(offer.SDP, "a=rtcp-fb:96 goog-remb"))
+	assert.Contains(t, offer.SDP, sdp.ABSSendTimeURI)
+
+	assert.NoError(t, signalPair(offerer, answerer))
+
+	func() {
+		ticker := time.NewTicker(time.Millisecond * 20)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-bitrateChanged.Done():
+				return
+			case <-ticker.C:
+				assert.NoError(t, track.WriteSample(media.Sample{Data: make([]byte, 1000), Duration: time.Second}))
+			}
+		}
+	}()
+
+	assert.NotZero(t, offerer.GetTargetBitrate())
+
+	closePairNow(t, offerer, answerer)
+}
+
+type fakeBandwidthEstimator struct {
+	bitrate  int
+	onChange func(bitrate int)
+}
+
+func (f *fakeBandwidthEstimator) GetTargetBitrate() int {
+	return f.bitrate
+}
+
+func (f *fakeBandwidthEstimator) OnTargetBitrateChange(onChange func(bitrate int)) {
+	f.onChange = onChange
+}
+
+func (f *fakeBandwidthEstimator) setTargetBitrate(bitrate int) {
+	f.bitrate = bitrate
+	f.onChange(bitrate)
+}
+
+// Assert that the lowest estimate of GCC and REMB is reported, once they have one
+func Test_CombinedBandwidthEstimator(t *testing.T) {
+	gccEstimator := &fakeBandwidthEstimator{bitrate: 10000}
+	rembEstimator := &fakeBandwidthEstimator{}
+
+	addBandwidthEstimator("test", gccEstimator)
+	addBandwidthEstimator("test", rembEstimator)
+	defer cleanupBandwidthEstimator("test")
+
+	estimator, ok := lookupBandwidthEstimator("test")
+	assert.True(t, ok)
+	assert.Equal(t, 10000, estimator.GetTargetBitrate())
+
+	var changes []int
+	estimator.OnTargetBitrateChange(func(bitrate int) {
+		changes = append(changes, bitrate)
+	})
+
+	gccEstimator.setTargetBitrate(500000)
+	rembEstimator.setTargetBitrate(300000)
+	gccEstimator.setTargetBitrate(400000)
+	rembEstimator.setTargetBitrate(600000)
+	assert.Equal(t, []int{500000, 300000, 400000}, changes)
+	assert.Equal(t, 400000, estimator.GetTargetBitrate())
+}
+
+func Test_ConfigureNack(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	createAPI := func(ir *interceptor.Registry) *API {
+		m := &MediaEngine{}
+		assert.NoError(t, m.RegisterDefaultCodecs())
+		assert.NoError(t, ConfigureNack(m, ir, nack.GeneratorInterval(10*time.Millisecond), nack.GeneratorInitialRTT(time.Second)))
+		assert.NoError(t, ConfigureStatsInterceptor(ir))
+		return NewAPI(WithMediaEngine(m), WithInterceptorRegistry(ir))
+	}
+
+	// Drop the first transmission of a packet, after the responder stored it
+	var dropped uint32
+	senderRegistry := &interceptor.Registry{}
+	senderRegistry.Add(&mock_interceptor.Factory{
+		NewInterceptorFn: func(_ string) (interceptor.Interceptor, error) {
+			return &mock_interceptor.Interceptor{
+				BindLocalStreamFn: func(info *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+					return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+						if header.SequenceNumber%10 == 3 && atomic.CompareAndSwapUint32(&dropped, 0, 1) {
+							return header.MarshalSize() + len(payload), nil
+						}
+						return writer.Write(header, payload, attributes)
+					})
+				},
+			}, nil
+		},
+	})
+
+	offerer, err := createAPI(senderRegistry).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	answerer, err := createAPI(&interceptor.Registry{}).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+
+	rtpSender, err := offerer.AddTrack(track)
+	assert.NoError(t, err)
+
+	// NACKs are only handled by the responder while RTCP is being read
+	go func() {
+		for {
+			if _, _, readErr := rtpSender.ReadRTCP(); readErr != nil {
+				return
+			}
+		}
+	}()
+
+	answerer.OnTrack(func(trackRemote *TrackRemote, _ *RTPReceiver) {
+		for {
+			if _, _, readErr := trackRemote.ReadRTP(); readErr != nil {
+				return
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(offerer, answerer))
+
+	ssrc := rtpSender.GetParameters().Encodings[0].SSRC
+	func() {
+		ticker := time.NewTicker(time.Millisecond * 20)
+		defer ticker.Stop()
+		for range ticker.C {
+			assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0xAA}, Duration: time.Second}))
+
+			inbound, ok := answerer.GetStats()[fmt.Sprintf("inbound-rtp-%d", ssrc)].(InboundRTPStreamStats)
+			if ok && inbound.PacketsRecovered == 1 {
+				assert.Equal(t, uint32(1), inbound.PacketsNACKed)
+				assert.Equal(t, uint32(0), inbound.PacketsUnrecovered)
+				return
+			}
+		}
+	}()
+
+	// The answerer only receives media, its round trip time is measured from the DLRR reports
+	// answering its RRTR reports
+	generator, ok := lookupNackGenerator(answerer.statsID)
+	assert.True(t, ok)
+	assert.Eventually(t, func() bool {
+		return generator.RTT() < time.Second
+	}, 5*time.Second, 10*time.Millisecond)
+
+	closePairNow(t, offerer, answerer)
+}
+
+// Assert that the generator of pkg/nack is used without options, so the NACK counters of the
+// inbound-rtp stats are filled by RegisterDefaultInterceptors
+func Test_ConfigureNack_Default(t *testing.T) {
+	m := &MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+	ir := &interceptor.Registry{}
+	assert.NoError(t, RegisterDefaultInterceptors(m, ir))
+
+	pc, err := NewAPI(WithMediaEngine(m), WithInterceptorRegistry(ir)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, ok := lookupNackGenerator(pc.statsID)
+	assert.True(t, ok)
+	assert.NoError(t, pc.Close())
+
+	_, ok = lookupNackGenerator(pc.statsID)
+	assert.False(t, ok)
+}
+
+// Assert that the capture times of a remote track are known from the Sender Reports, even if
+// the application doesn't read the RTCP of the RTPReceiver
+func Test_ConfigureLipSync(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	m := &MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+	ir := &interceptor.Registry{}
+	assert.NoError(t, ConfigureLipSync(ir))
+
+	answerer, err := NewAPI(WithMediaEngine(m), WithInterceptorRegistry(ir)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+
+	rtpSender, err := offerer.AddTrack(track)
+	assert.NoError(t, err)
+
+	go func() {
+		for {
+			if _, _, readErr := rtpSender.ReadRTCP(); readErr != nil {
+				return
+			}
+		}
+	}()
+
+	captureTimeKnown, captureTimeKnownFn := context.WithCancel(context.Background())
+	answerer.OnTrack(func(trackRemote *TrackRemote, _ *RTPReceiver) {
+		for {
+			pkt, _, readErr := trackRemote.ReadRTP()
+			if readErr != nil {
+				return
+			}
+
+			if captureTime, ok := trackRemote.CaptureTime(pkt.Timestamp); ok {
+				assert.WithinDuration(t, time.Now(), captureTime, 5*time.Second)
+				captureTimeKnownFn()
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(offerer, answerer))
+
+	func() {
+		ticker := time.NewTicker(time.Millisecond * 20)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-captureTimeKnown.Done():
+				return
+			case <-ticker.C:
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0xAA}, Duration: 20 * time.Millisecond}))
+			}
+		}
+	}()
+
+	// The Sender Reports are still returned to the application
+	pkts, _, err := answerer.GetReceivers()[0].ReadRTCP()
+	assert.NoError(t, err)
+	_, ok := pkts[0].(*rtcp.SenderReport)
+	assert.True(t, ok)
+
+	closePairNow(t, offerer, answerer)
+	_, ok = lookupLipSynchronizer(answerer.statsID)
+	assert.False(t, ok)
+}
@@ -0,0 +1,323 @@
+// Package ebml implements the EBML encoding used by the Matroska and WebM containers
+package ebml
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// Element IDs of the EBML header and of the Matroska elements used by WebM
+// https://www.matroska.org/technical/elements.html
+const (
+	IDEBML               uint32 = 0x1A45DFA3
+	IDEBMLVersion        uint32 = 0x4286
+	IDEBMLReadVersion    uint32 = 0x42F7
+	IDEBMLMaxIDLength    uint32 = 0x42F2
+	IDEBMLMaxSizeLength  uint32 = 0x42F3
+	IDDocType            uint32 = 0x4282
+	IDDocTypeVersion     uint32 = 0x4287
+	IDDocTypeReadVersion uint32 = 0x4285
+	IDVoid               uint32 = 0xEC
+
+	IDSegment      uint32 = 0x18538067
+	IDSeekHead     uint32 = 0x114D9B74
+	IDSeek         uint32 = 0x4DBB
+	IDSeekID       uint32 = 0x53AB
+	IDSeekPosition uint32 = 0x53AC
+
+	IDInfo          uint32 = 0x1549A966
+	IDTimecodeScale uint32 = 0x2AD7B1
+	IDDuration      uint32 = 0x4489
+	IDMuxingApp     uint32 = 0x4D80
+	IDWritingApp    uint32 = 0x5741
+
+	IDTracks            uint32 = 0x1654AE6B
+	IDTrackEntry        uint32 = 0xAE
+	IDTrackNumber       uint32 = 0xD7
+	IDTrackUID          uint32 = 0x73C5
+	IDTrackType         uint32 = 0x83
+	IDCodecID           uint32 = 0x86
+	IDCodecPrivate      uint32 = 0x63A2
+	IDCodecDelay        uint32 = 0x56AA
+	IDSeekPreRoll       uint32 = 0x56BB
+	IDVideo             uint32 = 0xE0
+	IDPixelWidth        uint32 = 0xB0
+	IDPixelHeight       uint32 = 0xBA
+	IDAudio             uint32 = 0xE1
+	IDSamplingFrequency uint32 = 0xB5
+	IDChannels          uint32 = 0x9F
+
+	IDCluster        uint32 = 0x1F43B675
+	IDTimecode       uint32 = 0xE7
+	IDSimpleBlock    uint32 = 0xA3
+	IDBlockGroup     uint32 = 0xA0
+	IDBlock          uint32 = 0xA1
+	IDBlockDuration  uint32 = 0x9B
+	IDReferenceBlock uint32 = 0xFB
+
+	IDCues               uint32 = 0x1C53BB6B
+	IDCuePoint           uint32 = 0xBB
+	IDCueTime            uint32 = 0xB3
+	IDCueTrackPositions  uint32 = 0xB7
+	IDCueTrack           uint32 = 0xF7
+	IDCueClusterPosition uint32 = 0xF1
+)
+
+const (
+	// UnknownSize is the size of elements written before their size is known
+	UnknownSize = uint64(1<<56 - 1)
+
+	// MaxVintLength is the length of the longest variable size integer
+	MaxVintLength = 8
+)
+
+var (
+	errInvalidVint  = errors.New("invalid EBML variable size integer")
+	errInvalidID    = errors.New("invalid EBML element ID")
+	errInvalidFloat = errors.New("EBML float must be 0, 4 or 8 bytes")
+)
+
+// Element is an element whose content has been read
+type Element struct {
+	ID   uint32
+	Data []byte
+}
+
+// AppendID appends an element ID, IDs keep their length marker
+func AppendID(b []byte, id uint32) []byte {
+	switch {
+	case id > 0xFFFFFF:
+		return append(b, byte(id>>24), byte(id>>16), byte(id>>8), byte(id))
+	case id > 0xFFFF:
+		return append(b, byte(id>>16), byte(id>>8), byte(id))
+	case id > 0xFF:
+		return append(b, byte(id>>8), byte(id))
+	default:
+		return append(b, byte(id))
+	}
+}
+
+// AppendSize appends a size with the shortest variable size integer
+func AppendSize(b []byte, size uint64) []byte {
+	length := 1
+	for length < MaxVintLength && size >= 1<<(7*uint(length))-1 {
+		length++
+	}
+
+	return AppendSizeWithLength(b, size, length)
+}
+
+// AppendSizeWithLength appends a size with a variable size integer of the given length,
+// so it can be overwritten later
+func AppendSizeWithLength(b []byte, size uint64, length int) []byte {
+	size |= 1 << (7 * uint(length))
+	for i := length - 1; i >= 0; i-- {
+		b = append(b, byte(size>>(8*uint(i))))
+	}
+
+	return b
+}
+
+// Master returns an element containing the given children
+func Master(id uint32, children ...[]byte) []byte {
+	size := 0
+	for _, c := range children {
+		size += len(c)
+	}
+
+	b := AppendSize(AppendID(make([]byte, 0, 12+size), id), uint64(size))
+	for _, c := range children {
+		b = append(b, c...)
+	}
+
+	return b
+}
+
+// Binary returns an element with binary content
+func Binary(id uint32, data []byte) []byte {
+	return Master(id, data)
+}
+
+// String returns an element with string content
+func String(id uint32, s string) []byte {
+	return Master(id, []byte(s))
+}
+
+// Uint returns an unsigned integer element, with the shortest encoding of the value
+func Uint(id uint32, v uint64) []byte {
+	length := 1
+	for length < 8 && v >= 1<<(8*uint(length)) {
+		length++
+	}
+
+	return UintWithLength(id, v, length)
+}
+
+// UintWithLength returns an unsigned integer element of the given length
+func UintWithLength(id uint32, v uint64, length int) []byte {
+	data := make([]byte, length)
+	for i := range data {
+		data[i] = byte(v >> (8 * uint(length-1-i)))
+	}
+
+	return Master(id, data)
+}
+
+// Float returns a 8 bytes float element
+func Float(id uint32, v float64) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, math.Float64bits(v))
+
+	return Master(id, data)
+}
+
+// Void returns a void element of the given total size, at least 2 bytes
+func Void(size int) []byte {
+	// The size field grows to two bytes for the largest voids
+	length := 1
+	if size-2 >= 0x7F {
+		length = 2
+	}
+
+	b := AppendSizeWithLength([]byte{byte(IDVoid)}, uint64(size-1-length), length)
+	return append(b, make([]byte, size-1-length)...)
+}
+
+// ReadVint reads a variable size integer from data, and returns its value and length.
+// The value of a size with all its bits set is UnknownSize.
+func ReadVint(data []byte) (uint64, int, error) {
+	if len(data) == 0 || data[0] == 0 {
+		return 0, 0, errInvalidVint
+	}
+
+	length := 1
+	for data[0]&(0x80>>uint(length-1)) == 0 {
+		length++
+	}
+	if len(data) < length {
+		return 0, 0, errInvalidVint
+	}
+
+	value := uint64(data[0] & (0xFF >> uint(length)))
+	allOnes := value == uint64(0xFF>>uint(length))
+	for _, b := range data[1:length] {
+		value = value<<8 | uint64(b)
+		allOnes = allOnes && b == 0xFF
+	}
+	if allOnes {
+		return UnknownSize, length, nil
+	}
+
+	return value, length, nil
+}
+
+// ReadElementHeader reads the ID and the size of the next element of the stream
+func ReadElementHeader(r io.Reader) (id uint32, size uint64, err error) {
+	buf := make([]byte, MaxVintLength)
+
+	if _, err = io.ReadFull(r, buf[:1]); err != nil {
+		return 0, 0, err
+	}
+	idLength := 1
+	for idLength <= 4 && buf[0]&(0x80>>uint(idLength-1)) == 0 {
+		idLength++
+	}
+	if idLength > 4 {
+		return 0, 0, errInvalidID
+	}
+	if _, err = io.ReadFull(r, buf[1:idLength]); err != nil {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	for _, b := range buf[:idLength] {
+		id = id<<8 | uint32(b)
+	}
+
+	if _, err = io.ReadFull(r, buf[:1]); err != nil {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	if buf[0] == 0 {
+		return 0, 0, errInvalidVint
+	}
+	sizeLength := 1
+	for buf[0]&(0x80>>uint(sizeLength-1)) == 0 {
+		sizeLength++
+	}
+	if _, err = io.ReadFull(r, buf[1:sizeLength]); err != nil {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+
+	size, _, err = ReadVint(buf[:sizeLength])
+	return id, size, err
+}
+
+// Children splits the content of a master element into its children
+func Children(data []byte) ([]Element, error) {
+	var children []Element
+	for len(data) > 0 {
+		id, idLength, err := readID(data)
+		if err != nil {
+			return nil, err
+		}
+		size, sizeLength, err := ReadVint(data[idLength:])
+		if err != nil {
+			return nil, err
+		}
+
+		start := uint64(idLength + sizeLength)
+		if size > uint64(len(data))-start {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		children = append(children, Element{ID: id, Data: data[start : start+size]})
+		data = data[start+size:]
+	}
+
+	return children, nil
+}
+
+func readID(data []byte) (uint32, int, error) {
+	if len(data) == 0 || data[0] == 0 {
+		return 0, 0, errInvalidID
+	}
+
+	length := 1
+	for data[0]&(0x80>>uint(length-1)) == 0 {
+		length++
+	}
+	if length > 4 || len(data) < length {
+		return 0, 0, errInvalidID
+	}
+
+	id := uint32(0)
+	for _, b := range data[:length] {
+		id = id<<8 | uint32(b)
+	}
+
+	return id, length, nil
+}
+
+// ParseUint returns the value of an unsigned integer element
+func ParseUint(data []byte) uint64 {
+	v := uint64(0)
+	for _, b := range data {
+		v = v<<8 | uint64(b)
+	}
+
+	return v
+}
+
+// ParseFloat returns the value of a float element
+func ParseFloat(data []byte) (float64, error) {
+	switch len(data) {
+	case 0:
+		return 0, nil
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data))), nil
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data)), nil
+	default:
+		return 0, errInvalidFloat
+	}
+}
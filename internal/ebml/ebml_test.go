@@ -0,0 +1,71 @@
+package ebml
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppendSize(t *testing.T) {
+	for _, test := range []struct {
+		size     uint64
+		expected []byte
+	}{
+		{0, []byte{0x80}},
+		{126, []byte{0xFE}},
+		{127, []byte{0x40, 0x7F}},
+		{0x3FFE, []byte{0x7F, 0xFE}},
+		{0x3FFF, []byte{0x20, 0x3F, 0xFF}},
+	} {
+		encoded := AppendSize(nil, test.size)
+		assert.Equal(t, test.expected, encoded)
+
+		size, length, err := ReadVint(encoded)
+		assert.NoError(t, err)
+		assert.Equal(t, test.size, size)
+		assert.Equal(t, len(encoded), length)
+	}
+
+	size, _, err := ReadVint([]byte{0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF})
+	assert.NoError(t, err)
+	assert.Equal(t, UnknownSize, size)
+
+	_, _, err = ReadVint([]byte{0x00})
+	assert.Equal(t, errInvalidVint, err)
+	_, _, err = ReadVint([]byte{0x40})
+	assert.Equal(t, errInvalidVint, err)
+}
+
+func TestElements(t *testing.T) {
+	data := Master(IDTrackEntry,
+		Uint(IDTrackNumber, 1),
+		String(IDCodecID, "V_VP8"),
+		Float(IDSamplingFrequency, 48000),
+		Void(130),
+	)
+	assert.Equal(t, []byte{0xAE, 0x40, 0x96, 0xD7, 0x81, 0x01, 0x86, 0x85}, data[:8])
+
+	id, size, err := ReadElementHeader(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, IDTrackEntry, id)
+	assert.Equal(t, uint64(len(data)-3), size)
+
+	children, err := Children(data[3:])
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(children))
+	assert.Equal(t, uint64(1), ParseUint(children[0].Data))
+	assert.Equal(t, "V_VP8", string(children[1].Data))
+	frequency, err := ParseFloat(children[2].Data)
+	assert.NoError(t, err)
+	assert.Equal(t, 48000.0, frequency)
+	assert.Equal(t, IDVoid, children[3].ID)
+	assert.Equal(t, 127, len(children[3].Data))
+
+	_, err = Children(data[3 : len(data)-1])
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	_, _, err = ReadElementHeader(bytes.NewReader(data[:2]))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
@@ -0,0 +1,50 @@
+// Package fectest implements the test helpers shared by the FEC packages
+package fectest
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+// ProtectedSSRC is the SSRC of the media packets returned by MediaPacket
+const ProtectedSSRC = 1234
+
+// MediaPacket returns a media packet of the protected stream, with a timestamp
+// and a marker derived from its sequence number
+func MediaPacket(sequenceNumber uint16, payload []byte) *rtp.Packet {
+	return &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         sequenceNumber%2 == 0,
+			PayloadType:    96,
+			SequenceNumber: sequenceNumber,
+			Timestamp:      uint32(sequenceNumber) * 3000,
+			SSRC:           ProtectedSSRC,
+			CSRC:           []uint32{},
+		},
+		Payload: payload,
+	}
+}
+
+// HeaderRoundTrip asserts that the FEC header protecting each list of offsets is
+// parsed back to the same value. marshal returns the header and its marshaled
+// form, unmarshal parses it with the header type of the FEC package.
+func HeaderRoundTrip(
+	t *testing.T,
+	offsets [][]uint16,
+	marshal func(offsets []uint16) (header interface{}, b []byte),
+	unmarshal func(b []byte) (header interface{}, n int, err error),
+) {
+	t.Helper()
+
+	for _, o := range offsets {
+		header, b := marshal(o)
+
+		parsed, n, err := unmarshal(b)
+		assert.NoError(t, err)
+		assert.Equal(t, len(b), n)
+		assert.Equal(t, header, parsed)
+	}
+}
@@ -40,6 +40,10 @@ func Parse(mimetype, line string) FMTP {
 		f = &h264FMTP{
 			parameters: parameters,
 		}
+	case strings.EqualFold(mimetype, "video/h265"):
+		f = &h265FMTP{
+			parameters: parameters,
+		}
 	default:
 		f = &genericFMTP{
 			mimeType:   mimetype,
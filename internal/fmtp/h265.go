@@ -0,0 +1,58 @@
+package fmtp
+
+// Values of the H.265 parameters when they are absent
+// https://www.rfc-editor.org/rfc/rfc7798#section-7.1
+var h265Defaults = map[string]string{ // nolint:gochecknoglobals
+	"profile-space": "0",
+	"profile-id":    "1",
+	"tier-flag":     "0",
+	"tx-mode":       "SRST",
+}
+
+type h265FMTP struct {
+	parameters map[string]string
+}
+
+func (h *h265FMTP) MimeType() string {
+	return "video/h265"
+}
+
+func (h *h265FMTP) parameterOrDefault(key string) string {
+	if v, ok := h.parameters[key]; ok {
+		return v
+	}
+	return h265Defaults[key]
+}
+
+// Match returns true if h and b are compatible fmtp descriptions
+// Based on RFC7798 Section 7.2.2:
+//
+//	The parameters identifying a media format configuration for HEVC
+//	are profile-space, tier-flag, profile-id, profile-compatibility-
+//	indicator, interop-constraints, and level-id.  These media
+//	configuration parameters (except level-id) MUST be used
+//	symmetrically when the answerer does not include recv-sub-layer-id
+//	in the answer for the media format (payload type) or the included
+//	recv-sub-layer-id is equal to sps_max_sub_layers_minus1 [...]
+//
+// The level-id may be downgraded by the answerer, so it is not compared.
+// The transmission mode must be the same on both sides.
+func (h *h265FMTP) Match(b FMTP) bool {
+	c, ok := b.(*h265FMTP)
+	if !ok {
+		return false
+	}
+
+	for key := range h265Defaults {
+		if h.parameterOrDefault(key) != c.parameterOrDefault(key) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (h *h265FMTP) Parameter(key string) (string, bool) {
+	v, ok := h.parameters[key]
+	return v, ok
+}
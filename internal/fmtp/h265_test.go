@@ -0,0 +1,102 @@
+package fmtp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestH265FMTPParse(t *testing.T) {
+	f := Parse("video/H265", "level-id=93;profile-id=1;tier-flag=0;tx-mode=SRST")
+
+	expected := &h265FMTP{
+		parameters: map[string]string{
+			"level-id":   "93",
+			"profile-id": "1",
+			"tier-flag":  "0",
+			"tx-mode":    "SRST",
+		},
+	}
+	if !reflect.DeepEqual(expected, f) {
+		t.Errorf("Expected Fmtp params: %v, got: %v", expected, f)
+	}
+
+	if f.MimeType() != "video/h265" {
+		t.Errorf("Expected MimeType of video/h265, got: %s", f.MimeType())
+	}
+}
+
+func TestH265FMTPCompare(t *testing.T) {
+	consistString := map[bool]string{true: "consist", false: "inconsist"}
+
+	testCases := map[string]struct {
+		a, b    string
+		consist bool
+	}{
+		"Equal": {
+			a:       "level-id=93;profile-id=1;tier-flag=0;tx-mode=SRST",
+			b:       "level-id=93;profile-id=1;tier-flag=0;tx-mode=SRST",
+			consist: true,
+		},
+		"DifferentLevel": {
+			a:       "level-id=93;profile-id=1;tier-flag=0;tx-mode=SRST",
+			b:       "level-id=120;profile-id=1;tier-flag=0;tx-mode=SRST",
+			consist: true,
+		},
+		"Defaults": {
+			a:       "level-id=93;profile-id=1;tier-flag=0;tx-mode=SRST",
+			b:       "level-id=93",
+			consist: true,
+		},
+		"Inconsistent_Profile": {
+			a:       "level-id=93;profile-id=1",
+			b:       "level-id=93;profile-id=2",
+			consist: false,
+		},
+		"Inconsistent_DefaultProfile": {
+			a:       "level-id=93",
+			b:       "level-id=93;profile-id=2",
+			consist: false,
+		},
+		"Inconsistent_Tier": {
+			a:       "profile-id=1;tier-flag=0",
+			b:       "profile-id=1;tier-flag=1",
+			consist: false,
+		},
+		"Inconsistent_ProfileSpace": {
+			a:       "profile-id=1",
+			b:       "profile-id=1;profile-space=1",
+			consist: false,
+		},
+		"Inconsistent_TxMode": {
+			a:       "profile-id=1;tx-mode=SRST",
+			b:       "profile-id=1;tx-mode=MRST",
+			consist: false,
+		},
+	}
+	for name, testCase := range testCases {
+		testCase := testCase
+		check := func(t *testing.T, a, b string) {
+			aa := Parse("video/h265", a)
+			bb := Parse("video/h265", b)
+			c := aa.Match(bb)
+			if c != testCase.consist {
+				t.Errorf(
+					"'%s' and '%s' are expected to be %s, but treated as %s",
+					a, b, consistString[testCase.consist], consistString[c],
+				)
+			}
+
+			// test reverse case here
+			c = bb.Match(aa)
+			if c != testCase.consist {
+				t.Errorf(
+					"'%s' and '%s' are expected to be %s, but treated as %s",
+					a, b, consistString[testCase.consist], consistString[c],
+				)
+			}
+		}
+		t.Run(name, func(t *testing.T) {
+			check(t, testCase.a, testCase.b)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+// Package ntp converts between time.Time and the NTP timestamps of RTCP packets
+package ntp
+
+import (
+	"time"
+)
+
+// EpochOffset is the number of seconds between the NTP epoch, 1900, and the Unix epoch, 1970
+const EpochOffset = 2208988800
+
+// ToTime converts a 64 bits NTP timestamp to a time.Time
+func ToTime(ntp uint64) time.Time {
+	seconds := int64(ntp>>32) - EpochOffset
+	nanoseconds := int64((ntp & 0xFFFFFFFF) * uint64(time.Second) >> 32)
+	return time.Unix(seconds, nanoseconds)
+}
+
+// FromTime converts a time.Time to a 64 bits NTP timestamp
+func FromTime(t time.Time) uint64 {
+	seconds := uint64(t.Unix() + EpochOffset)
+	fraction := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+
+	return seconds<<32 | fraction
+}
+
+// Compact returns the middle 32 bits of the NTP timestamp of t, as used by the LSR and
+// LRR fields of RTCP reports
+func Compact(t time.Time) uint32 {
+	return uint32(FromTime(t) >> 16)
+}
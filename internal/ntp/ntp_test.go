@@ -0,0 +1,22 @@
+package ntp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToTime(t *testing.T) {
+	assert.Equal(t, time.Unix(0, 0), ToTime(EpochOffset<<32))
+	assert.Equal(t, time.Unix(1600000000, 500000000), ToTime((1600000000+EpochOffset)<<32|0x80000000))
+}
+
+func TestFromTime(t *testing.T) {
+	assert.Equal(t, uint64(1600000000+EpochOffset)<<32|0x80000000, FromTime(time.Unix(1600000000, 500000000)))
+}
+
+func TestCompact(t *testing.T) {
+	ntp := uint64(1600000000+EpochOffset)<<32 | 0x80000000
+	assert.Equal(t, uint32(ntp>>16), Compact(time.Unix(1600000000, 500000000)))
+}
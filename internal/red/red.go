@@ -0,0 +1,161 @@
+// Package red implements the RTP payload for redundant audio data (RFC 2198)
+package red
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+)
+
+const (
+	blockHeaderSize        = 4
+	primaryBlockHeaderSize = 1
+
+	maxTimestampOffset = 1<<14 - 1
+	maxBlockLength     = 1<<10 - 1
+)
+
+var errShortPacket = errors.New("red: packet is not large enough")
+
+// Block is a single encoding carried in a RED payload
+type Block struct {
+	PayloadType uint8
+	// TimestampOffset is subtracted from the timestamp of the RTP packet to get the one of the block.
+	// It is always 0 for the primary encoding.
+	TimestampOffset uint16
+	Payload         []byte
+}
+
+// Marshal builds a RED payload from the redundant blocks, oldest first, followed by the primary block
+//
+//	 0                   1                   2                   3
+//	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|F|   block PT  |  timestamp offset         |   block length    |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+func Marshal(redundant []Block, primary Block) []byte {
+	size := primaryBlockHeaderSize + len(primary.Payload)
+	for _, block := range redundant {
+		size += blockHeaderSize + len(block.Payload)
+	}
+
+	out := make([]byte, size)
+	offset := 0
+	for _, block := range redundant {
+		binary.BigEndian.PutUint32(out[offset:],
+			1<<31|uint32(block.PayloadType&0x7F)<<24|uint32(block.TimestampOffset&maxTimestampOffset)<<10|uint32(len(block.Payload)&maxBlockLength),
+		)
+		offset += blockHeaderSize
+	}
+	out[offset] = primary.PayloadType & 0x7F
+	offset += primaryBlockHeaderSize
+
+	for _, block := range redundant {
+		offset += copy(out[offset:], block.Payload)
+	}
+	copy(out[offset:], primary.Payload)
+
+	return out
+}
+
+// Unmarshal splits a RED payload into its blocks. The primary encoding is the last one.
+// Payloads of the returned blocks reference the given buffer.
+func Unmarshal(payload []byte) ([]Block, error) {
+	blocks := []Block{}
+	lengths := []int{}
+
+	offset := 0
+	for {
+		if offset >= len(payload) {
+			return nil, errShortPacket
+		}
+
+		if payload[offset]&0x80 == 0 {
+			blocks = append(blocks, Block{PayloadType: payload[offset] & 0x7F})
+			offset += primaryBlockHeaderSize
+			break
+		}
+
+		if offset+blockHeaderSize > len(payload) {
+			return nil, errShortPacket
+		}
+
+		header := binary.BigEndian.Uint32(payload[offset:])
+		blocks = append(blocks, Block{
+			PayloadType:     uint8(header>>24) & 0x7F,
+			TimestampOffset: uint16(header>>10) & maxTimestampOffset,
+		})
+		lengths = append(lengths, int(header&maxBlockLength))
+		offset += blockHeaderSize
+	}
+
+	for i, length := range lengths {
+		if offset+length > len(payload) {
+			return nil, errShortPacket
+		}
+
+		blocks[i].Payload = payload[offset : offset+length]
+		offset += length
+	}
+	blocks[len(blocks)-1].Payload = payload[offset:]
+
+	return blocks, nil
+}
+
+type historyEntry struct {
+	timestamp uint32
+	payload   []byte
+}
+
+// Encoder wraps payloads in RED, adding up to distance previous payloads as redundant encodings
+type Encoder struct {
+	mu       sync.Mutex
+	distance int
+	history  []historyEntry
+}
+
+// NewEncoder creates an Encoder carrying distance redundant encodings in each payload
+func NewEncoder(distance int) *Encoder {
+	return &Encoder{distance: distance}
+}
+
+// Encode returns the RED payload for the given primary payload. The previous payloads
+// are added as redundant encodings as long as they can be described by the RED header:
+// the chain stops at the first one that can't, so that the redundant encodings are always
+// the ones of the packets directly preceding this one.
+// payloadType is the payload type of the primary and redundant encodings.
+func (e *Encoder) Encode(payloadType uint8, timestamp uint32, payload []byte) []byte {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	first := len(e.history)
+	for first > 0 {
+		entry := e.history[first-1]
+		if timestamp-entry.timestamp > maxTimestampOffset || len(entry.payload) > maxBlockLength {
+			break
+		}
+		first--
+	}
+
+	redundant := make([]Block, 0, len(e.history)-first)
+	for _, entry := range e.history[first:] {
+		redundant = append(redundant, Block{PayloadType: payloadType, TimestampOffset: uint16(timestamp - entry.timestamp), Payload: entry.payload})
+	}
+
+	return Marshal(redundant, Block{PayloadType: payloadType, Payload: payload})
+}
+
+// Push adds a payload to the history used for redundant encodings of the next payloads
+func (e *Encoder) Push(timestamp uint32, payload []byte) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.distance == 0 {
+		return
+	}
+
+	if len(e.history) == e.distance {
+		e.history = e.history[1:]
+	}
+	e.history = append(e.history, historyEntry{timestamp: timestamp, payload: append([]byte{}, payload...)})
+}
@@ -0,0 +1,71 @@
+package red
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalUnmarshal(t *testing.T) {
+	redundant := []Block{
+		{PayloadType: 111, TimestampOffset: 1920, Payload: []byte{0x01, 0x02}},
+		{PayloadType: 111, TimestampOffset: 960, Payload: []byte{0x03}},
+	}
+	primary := Block{PayloadType: 111, Payload: []byte{0x04, 0x05, 0x06}}
+
+	payload := Marshal(redundant, primary)
+	assert.Equal(t, []byte{
+		0xEF, 0x1E, 0x00, 0x02,
+		0xEF, 0x0F, 0x00, 0x01,
+		0x6F,
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06,
+	}, payload)
+
+	blocks, err := Unmarshal(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, append(redundant, primary), blocks)
+
+	for _, invalid := range [][]byte{{}, {0xEF, 0x1E}, {0xEF, 0x1E, 0x00, 0x02, 0x6F, 0x01}} {
+		_, err = Unmarshal(invalid)
+		assert.Equal(t, errShortPacket, err)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	e := NewEncoder(2)
+
+	for i := uint32(0); i < 4; i++ {
+		payload := []byte{byte(i)}
+		blocks, err := Unmarshal(e.Encode(111, i*960, payload))
+		assert.NoError(t, err)
+
+		expected := []Block{}
+		for j := uint32(2); j > 0; j-- {
+			if i >= j {
+				expected = append(expected, Block{PayloadType: 111, TimestampOffset: uint16(j * 960), Payload: []byte{byte(i - j)}})
+			}
+		}
+		expected = append(expected, Block{PayloadType: 111, Payload: payload})
+		assert.Equal(t, expected, blocks)
+
+		e.Push(i*960, payload)
+	}
+
+	// Encodings too old to be described are skipped
+	blocks, err := Unmarshal(e.Encode(111, 1<<15, []byte{0xFF}))
+	assert.NoError(t, err)
+	assert.Len(t, blocks, 1)
+
+	// The chain stops at the first encoding that can't be described, even if older ones could
+	e = NewEncoder(3)
+	e.Push(0, []byte{0x00})
+	e.Push(960, make([]byte, maxBlockLength+1))
+	e.Push(1920, []byte{0x02})
+
+	blocks, err = Unmarshal(e.Encode(111, 2880, []byte{0x03}))
+	assert.NoError(t, err)
+	assert.Equal(t, []Block{
+		{PayloadType: 111, TimestampOffset: 960, Payload: []byte{0x02}},
+		{PayloadType: 111, Payload: []byte{0x03}},
+	}, blocks)
+}
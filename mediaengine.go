@@ -14,6 +14,8 @@ import (
 	"github.com/pion/rtp/codecs"
 	"github.com/pion/sdp/v3"
 	"github.com/pion/webrtc/v3/internal/fmtp"
+	"github.com/pion/webrtc/v3/pkg/rtpcodecs"
+	"github.com/pion/webrtc/v3/pkg/svc"
 )
 
 const (
@@ -44,6 +46,15 @@ const (
 	// MimeTypePCMA PCMA MIME type
 	// Note: Matching should be case insensitive.
 	MimeTypePCMA = "audio/PCMA"
+	// MimeTypeRTX RTX MIME type
+	// Note: Matching should be case insensitive.
+	MimeTypeRTX = "video/rtx"
+	// MimeTypeRED RED (RFC 2198) MIME type
+	// Note: Matching should be case insensitive.
+	MimeTypeRED = "audio/red"
+	// MimeTypeTelephoneEvent telephone-event (RFC 4733) MIME type, used to send DTMF tones
+	// Note: Matching should be case insensitive.
+	MimeTypeTelephoneEvent = "audio/telephone-event"
 )
 
 type mediaEngineHeaderExtension struct {
@@ -91,6 +102,14 @@ func (m *MediaEngine) RegisterDefaultCodecs() error {
 			RTPCodecCapability: RTPCodecCapability{MimeTypePCMA, 8000, 0, "", nil},
 			PayloadType:        8,
 		},
+		{
+			RTPCodecCapability: RTPCodecCapability{MimeTypeTelephoneEvent, 48000, 0, "0-16", nil},
+			PayloadType:        110,
+		},
+		{
+			RTPCodecCapability: RTPCodecCapability{MimeTypeTelephoneEvent, 8000, 0, "0-16", nil},
+			PayloadType:        105,
+		},
 	} {
 		if err := m.RegisterCodec(codec, RTPCodecTypeAudio); err != nil {
 			return err
@@ -104,7 +123,7 @@ func (m *MediaEngine) RegisterDefaultCodecs() error {
 			PayloadType:        96,
 		},
 		{
-			RTPCodecCapability: RTPCodecCapability{"video/rtx", 90000, 0, "apt=96", nil},
+			RTPCodecCapability: RTPCodecCapability{MimeTypeRTX, 90000, 0, "apt=96", nil},
 			PayloadType:        97,
 		},
 
@@ -113,7 +132,7 @@ func (m *MediaEngine) RegisterDefaultCodecs() error {
 			PayloadType:        98,
 		},
 		{
-			RTPCodecCapability: RTPCodecCapability{"video/rtx", 90000, 0, "apt=98", nil},
+			RTPCodecCapability: RTPCodecCapability{MimeTypeRTX, 90000, 0, "apt=98", nil},
 			PayloadType:        99,
 		},
 
@@ -122,7 +141,7 @@ func (m *MediaEngine) RegisterDefaultCodecs() error {
 			PayloadType:        100,
 		},
 		{
-			RTPCodecCapability: RTPCodecCapability{"video/rtx", 90000, 0, "apt=100", nil},
+			RTPCodecCapability: RTPCodecCapability{MimeTypeRTX, 90000, 0, "apt=100", nil},
 			PayloadType:        101,
 		},
 
@@ -131,7 +150,7 @@ func (m *MediaEngine) RegisterDefaultCodecs() error {
 			PayloadType:        102,
 		},
 		{
-			RTPCodecCapability: RTPCodecCapability{"video/rtx", 90000, 0, "apt=102", nil},
+			RTPCodecCapability: RTPCodecCapability{MimeTypeRTX, 90000, 0, "apt=102", nil},
 			PayloadType:        121,
 		},
 
@@ -140,7 +159,7 @@ func (m *MediaEngine) RegisterDefaultCodecs() error {
 			PayloadType:        127,
 		},
 		{
-			RTPCodecCapability: RTPCodecCapability{"video/rtx", 90000, 0, "apt=127", nil},
+			RTPCodecCapability: RTPCodecCapability{MimeTypeRTX, 90000, 0, "apt=127", nil},
 			PayloadType:        120,
 		},
 
@@ -149,7 +168,7 @@ func (m *MediaEngine) RegisterDefaultCodecs() error {
 			PayloadType:        125,
 		},
 		{
-			RTPCodecCapability: RTPCodecCapability{"video/rtx", 90000, 0, "apt=125", nil},
+			RTPCodecCapability: RTPCodecCapability{MimeTypeRTX, 90000, 0, "apt=125", nil},
 			PayloadType:        107,
 		},
 
@@ -158,7 +177,7 @@ func (m *MediaEngine) RegisterDefaultCodecs() error {
 			PayloadType:        108,
 		},
 		{
-			RTPCodecCapability: RTPCodecCapability{"video/rtx", 90000, 0, "apt=108", nil},
+			RTPCodecCapability: RTPCodecCapability{MimeTypeRTX, 90000, 0, "apt=108", nil},
 			PayloadType:        109,
 		},
 
@@ -167,7 +186,7 @@ func (m *MediaEngine) RegisterDefaultCodecs() error {
 			PayloadType:        127,
 		},
 		{
-			RTPCodecCapability: RTPCodecCapability{"video/rtx", 90000, 0, "apt=127", nil},
+			RTPCodecCapability: RTPCodecCapability{MimeTypeRTX, 90000, 0, "apt=127", nil},
 			PayloadType:        120,
 		},
 
@@ -176,10 +195,19 @@ func (m *MediaEngine) RegisterDefaultCodecs() error {
 			PayloadType:        123,
 		},
 		{
-			RTPCodecCapability: RTPCodecCapability{"video/rtx", 90000, 0, "apt=123", nil},
+			RTPCodecCapability: RTPCodecCapability{MimeTypeRTX, 90000, 0, "apt=123", nil},
 			PayloadType:        118,
 		},
 
+		{
+			RTPCodecCapability: RTPCodecCapability{MimeTypeH265, 90000, 0, "level-id=93;profile-id=1;tier-flag=0;tx-mode=SRST", videoRTCPFeedback},
+			PayloadType:        126,
+		},
+		{
+			RTPCodecCapability: RTPCodecCapability{MimeTypeRTX, 90000, 0, "apt=126", nil},
+			PayloadType:        119,
+		},
+
 		{
 			RTPCodecCapability: RTPCodecCapability{"video/ulpfec", 90000, 0, "", nil},
 			PayloadType:        116,
@@ -266,6 +294,13 @@ func (m *MediaEngine) RegisterHeaderExtension(extension RTPHeaderExtensionCapabi
 	return nil
 }
 
+// RegisterDependencyDescriptor adds the AV1 Dependency Descriptor header extension for video. It
+// carries the spatial and temporal layers of AV1 frames that svc.LayerFilter selects, the negotiated
+// ID is in the header extensions of the RTPReceiver parameters.
+func (m *MediaEngine) RegisterDependencyDescriptor() error {
+	return m.RegisterHeaderExtension(RTPHeaderExtensionCapability{URI: svc.DependencyDescriptorURI}, RTPCodecTypeVideo)
+}
+
 // RegisterFeedback adds feedback mechanism to already registered codecs.
 func (m *MediaEngine) RegisterFeedback(feedback RTCPFeedback, typ RTPCodecType) {
 	m.mu.Lock()
@@ -274,17 +309,31 @@ func (m *MediaEngine) RegisterFeedback(feedback RTCPFeedback, typ RTPCodecType)
 	switch typ {
 	case RTPCodecTypeVideo:
 		for i, v := range m.videoCodecs {
-			v.RTCPFeedback = append(v.RTCPFeedback, feedback)
+			if !hasRTCPFeedback(v.RTCPFeedback, feedback) {
+				v.RTCPFeedback = append(v.RTCPFeedback, feedback)
+			}
 			m.videoCodecs[i] = v
 		}
 	case RTPCodecTypeAudio:
 		for i, v := range m.audioCodecs {
-			v.RTCPFeedback = append(v.RTCPFeedback, feedback)
+			if !hasRTCPFeedback(v.RTCPFeedback, feedback) {
+				v.RTCPFeedback = append(v.RTCPFeedback, feedback)
+			}
 			m.audioCodecs[i] = v
 		}
 	}
 }
 
+func hasRTCPFeedback(haystack []RTCPFeedback, needle RTCPFeedback) bool {
+	for _, f := range haystack {
+		if f == needle {
+			return true
+		}
+	}
+
+	return false
+}
+
 // getHeaderExtensionID returns the negotiated ID for a header extension.
 // If the Header Extension isn't enabled ok will be false
 func (m *MediaEngine) getHeaderExtensionID(extension RTPHeaderExtensionCapability) (val int, audioNegotiated, videoNegotiated bool) {
@@ -385,6 +434,26 @@ func (m *MediaEngine) collectStats(collector *statsReportCollector) {
 	statsLoop(m.audioCodecs)
 }
 
+// getCodecStatsID returns the ID of the CodecStats describing the registered codec
+// that matches the given codec. An empty string is returned if there is no match
+func (m *MediaEngine) getCodecStatsID(codec RTPCodecParameters, typ RTPCodecType) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	codecs := m.videoCodecs
+	if typ == RTPCodecTypeAudio {
+		codecs = m.audioCodecs
+	}
+
+	for _, c := range codecs {
+		if c.PayloadType == codec.PayloadType && strings.EqualFold(c.MimeType, codec.MimeType) {
+			return c.statsID
+		}
+	}
+
+	return ""
+}
+
 // Look up a codec and enable if it exists
 func (m *MediaEngine) matchRemoteCodec(remoteCodec RTPCodecParameters, typ RTPCodecType, exactMatches, partialMatches []RTPCodecParameters) (codecMatchType, error) {
 	codecs := m.videoCodecs
@@ -627,10 +696,55 @@ func (m *MediaEngine) getRTPParametersByPayloadType(payloadType PayloadType) (RT
 	}, nil
 }
 
+// findRTXPayloadType returns the PayloadType of the RTX codec in haystack that
+// repairs the codec with PayloadType needle, or 0 if there is none
+func findRTXPayloadType(needle PayloadType, haystack []RTPCodecParameters) PayloadType {
+	apt := strconv.FormatUint(uint64(needle), 10)
+	for _, c := range haystack {
+		if !strings.EqualFold(c.MimeType, MimeTypeRTX) {
+			continue
+		}
+
+		if value, ok := fmtp.Parse(c.MimeType, c.SDPFmtpLine).Parameter("apt"); ok && value == apt {
+			return c.PayloadType
+		}
+	}
+
+	return PayloadType(0)
+}
+
+// findREDCodec returns the RED codec in haystack that carries redundant
+// encodings of the codec with PayloadType needle
+func findREDCodec(needle PayloadType, haystack []RTPCodecParameters) (RTPCodecParameters, bool) {
+	pt := strconv.FormatUint(uint64(needle), 10)
+	for _, c := range haystack {
+		if !strings.EqualFold(c.MimeType, MimeTypeRED) {
+			continue
+		}
+
+		// The fmtp line lists the payload type of each encoding, like `111/111`
+		matches := true
+		for _, encoding := range strings.Split(c.SDPFmtpLine, "/") {
+			if strings.TrimSpace(encoding) != pt {
+				matches = false
+				break
+			}
+		}
+
+		if matches {
+			return c, true
+		}
+	}
+
+	return RTPCodecParameters{}, false
+}
+
 func payloaderForCodec(codec RTPCodecCapability) (rtp.Payloader, error) {
 	switch strings.ToLower(codec.MimeType) {
 	case strings.ToLower(MimeTypeH264):
 		return &codecs.H264Payloader{}, nil
+	case strings.ToLower(MimeTypeH265):
+		return &rtpcodecs.H265Payloader{}, nil
 	case strings.ToLower(MimeTypeOpus):
 		return &codecs.OpusPayloader{}, nil
 	case strings.ToLower(MimeTypeVP8):
@@ -11,6 +11,7 @@ import (
 
 	"github.com/pion/sdp/v3"
 	"github.com/pion/transport/v2/test"
+	"github.com/pion/webrtc/v3/pkg/svc"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -43,6 +44,7 @@ func TestVideoCase(t *testing.T) {
 	assert.True(t, regexp.MustCompile(`(?m)^a=rtpmap:\d+ H264/90000`).MatchString(offer.SDP))
 	assert.True(t, regexp.MustCompile(`(?m)^a=rtpmap:\d+ VP8/90000`).MatchString(offer.SDP))
 	assert.True(t, regexp.MustCompile(`(?m)^a=rtpmap:\d+ VP9/90000`).MatchString(offer.SDP))
+	assert.True(t, regexp.MustCompile(`(?m)^a=rtpmap:\d+ H265/90000`).MatchString(offer.SDP))
 	assert.NoError(t, pc.Close())
 }
 
@@ -89,6 +91,38 @@ a=fmtp:111 minptime=10; useinbandfec=1
 		assert.Equal(t, opusCodec.MimeType, MimeTypeOpus)
 	})
 
+	t.Run("Enable H265", func(t *testing.T) {
+		const h265Offer = `v=0
+o=- 4596489990601351948 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=video 60323 UDP/TLS/RTP/SAVPF 49 50
+a=rtpmap:49 H265/90000
+a=fmtp:49 level-id=120;profile-id=1;tier-flag=0;tx-mode=SRST
+a=rtpmap:50 H265/90000
+a=fmtp:50 level-id=120;profile-id=2;tier-flag=0;tx-mode=SRST
+`
+
+		m := MediaEngine{}
+		assert.NoError(t, m.RegisterDefaultCodecs())
+		assert.NoError(t, m.updateFromRemoteDescription(mustParse(h265Offer)))
+
+		assert.True(t, m.negotiatedVideo)
+		assert.False(t, m.negotiatedAudio)
+
+		h265Codec, _, err := m.getCodecByPayload(49)
+		assert.NoError(t, err)
+		assert.Equal(t, h265Codec.MimeType, MimeTypeH265)
+
+		// Main 10 is not registered
+		_, _, err = m.getCodecByPayload(50)
+		assert.Error(t, err)
+
+		payloader, err := payloaderForCodec(h265Codec.RTPCodecCapability)
+		assert.NoError(t, err)
+		assert.NotNil(t, payloader)
+	})
+
 	t.Run("Change Payload Type", func(t *testing.T) {
 		const opusSamePayload = `v=0
 o=- 4596489990601351948 2 IN IP4 127.0.0.1
@@ -452,6 +486,22 @@ func TestMediaEngineDoubleRegister(t *testing.T) {
 	assert.Equal(t, len(m.audioCodecs), 1)
 }
 
+func TestMediaEngineDoubleRegisterFeedback(t *testing.T) {
+	m := MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+
+	m.RegisterFeedback(RTCPFeedback{Type: TypeRTCPFBTransportCC}, RTPCodecTypeVideo)
+	m.RegisterFeedback(RTCPFeedback{Type: TypeRTCPFBTransportCC}, RTPCodecTypeVideo)
+
+	count := 0
+	for _, feedback := range m.videoCodecs[0].RTCPFeedback {
+		if feedback.Type == TypeRTCPFBTransportCC {
+			count++
+		}
+	}
+	assert.Equal(t, 1, count)
+}
+
 // The cloned MediaEngine instance should be able to update negotiated header extensions.
 func TestUpdateHeaderExtenstionToClonedMediaEngine(t *testing.T) {
 	src := MediaEngine{}
@@ -634,3 +684,16 @@ a=fmtp:127 level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42001
 		})
 	}
 }
+
+func TestMediaEngineDependencyDescriptor(t *testing.T) {
+	m := &MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+	assert.NoError(t, m.RegisterDependencyDescriptor())
+
+	video := m.getRTPParametersByKind(RTPCodecTypeVideo, []RTPTransceiverDirection{RTPTransceiverDirectionSendonly})
+	assert.Equal(t, 1, len(video.HeaderExtensions))
+	assert.Equal(t, svc.DependencyDescriptorURI, video.HeaderExtensions[0].URI)
+
+	audio := m.getRTPParametersByKind(RTPCodecTypeAudio, []RTPTransceiverDirection{RTPTransceiverDirectionSendonly})
+	assert.Equal(t, 0, len(audio.HeaderExtensions))
+}
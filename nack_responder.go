@@ -0,0 +1,84 @@
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"github.com/pion/interceptor"
+	interceptornack "github.com/pion/interceptor/pkg/nack"
+	"github.com/pion/rtp"
+)
+
+type retransmissionAttributeKey struct{}
+
+type forwardedAttributeKey struct{}
+
+// isRetransmission tells if a packet was written by a nackResponder in response to a NACK,
+// from the attributes it was written with
+func isRetransmission(attributes interceptor.Attributes) bool {
+	if attributes == nil {
+		return false
+	}
+
+	retransmission, _ := attributes.Get(retransmissionAttributeKey{}).(bool)
+	return retransmission
+}
+
+// nackResponderFactory is a interceptor.Factory for a nackResponder
+type nackResponderFactory struct {
+	factory *interceptornack.ResponderInterceptorFactory
+}
+
+func newNackResponder(opts ...interceptornack.ResponderOption) (*nackResponderFactory, error) {
+	factory, err := interceptornack.NewResponderInterceptor(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &nackResponderFactory{factory: factory}, nil
+}
+
+// NewInterceptor constructs a new nackResponder
+func (f *nackResponderFactory) NewInterceptor(id string) (interceptor.Interceptor, error) {
+	i, err := f.factory.NewInterceptor(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &nackResponder{Interceptor: i}, nil
+}
+
+// nackResponder resends the packets requested in NACKs, like the responder of pion/interceptor,
+// and marks the packets it resends so the RTPSender can send them on the RTX repair flow
+type nackResponder struct {
+	interceptor.Interceptor
+}
+
+// BindLocalStream lets you modify any outgoing RTP packets. It is called once for per LocalStream. The returned method
+// will be called once per rtp packet.
+func (r *nackResponder) BindLocalStream(info *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+	// Packets forwarded by the responder are marked before entering it, the others
+	// were written by the responder itself
+	responderWriter := r.Interceptor.BindLocalStream(info, interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+		if attributes == nil {
+			attributes = interceptor.Attributes{}
+		}
+
+		if attributes.Get(forwardedAttributeKey{}) != nil {
+			delete(attributes, forwardedAttributeKey{})
+		} else {
+			attributes.Set(retransmissionAttributeKey{}, true)
+		}
+
+		return writer.Write(header, payload, attributes)
+	}))
+
+	return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+		if attributes == nil {
+			attributes = interceptor.Attributes{}
+		}
+		attributes.Set(forwardedAttributeKey{}, true)
+
+		return responderWriter.Write(header, payload, attributes)
+	})
+}
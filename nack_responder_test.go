@@ -0,0 +1,59 @@
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NackResponder_MarksRetransmissions(t *testing.T) {
+	f, err := newNackResponder()
+	assert.NoError(t, err)
+
+	i, err := f.NewInterceptor("")
+	assert.NoError(t, err)
+
+	type written struct {
+		sequenceNumber uint16
+		retransmission bool
+	}
+	packets := make(chan written, 10)
+	writer := i.BindLocalStream(&interceptor.StreamInfo{SSRC: 1, RTCPFeedback: []interceptor.RTCPFeedback{{Type: "nack"}}}, interceptor.RTPWriterFunc(func(header *rtp.Header, _ []byte, attributes interceptor.Attributes) (int, error) {
+		packets <- written{header.SequenceNumber, isRetransmission(attributes)}
+		return 0, nil
+	}))
+
+	for _, sequenceNumber := range []uint16{10, 11, 12} {
+		_, err = writer.Write(&rtp.Header{SSRC: 1, SequenceNumber: sequenceNumber}, nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, written{sequenceNumber, false}, <-packets)
+	}
+
+	raw, err := rtcp.Marshal([]rtcp.Packet{&rtcp.TransportLayerNack{
+		MediaSSRC: 1,
+		Nacks:     rtcp.NackPairsFromSequenceNumbers([]uint16{11}),
+	}})
+	assert.NoError(t, err)
+
+	reader := i.BindRTCPReader(interceptor.RTCPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		return copy(b, raw), a, nil
+	}))
+	_, _, err = reader.Read(make([]byte, 1500), nil)
+	assert.NoError(t, err)
+
+	select {
+	case packet := <-packets:
+		assert.Equal(t, written{11, true}, packet)
+	case <-time.After(time.Second):
+		assert.Fail(t, "the NACKed packet wasn't resent")
+	}
+
+	assert.NoError(t, i.Close())
+}
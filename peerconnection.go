@@ -54,6 +54,7 @@ type PeerConnection struct {
 
 	isClosed               *atomicBool
 	isNegotiationNeeded    *atomicBool
+	isICERestartRequested  *atomicBool
 	negotiationNeededState negotiationNeededState
 
 	lastOffer  string
@@ -67,6 +68,10 @@ type PeerConnection struct {
 
 	rtpTransceivers []*RTPTransceiver
 
+	// remoteOfferTransceivers are the transceivers created by the pending remote offer, they
+	// are removed if it is rolled back
+	remoteOfferTransceivers []*RTPTransceiver
+
 	onSignalingStateChangeHandler     func(SignalingState)
 	onICEConnectionStateChangeHandler atomic.Value // func(ICEConnectionState)
 	onConnectionStateChangeHandler    atomic.Value // func(PeerConnectionState)
@@ -107,13 +112,17 @@ func NewPeerConnection(configuration Configuration) (*PeerConnection, error) {
 	return api.NewPeerConnection(configuration)
 }
 
+// peerConnectionCount makes the statsIDs of PeerConnections created at the same time unique
+// nolint:gochecknoglobals
+var peerConnectionCount uint64
+
 // NewPeerConnection creates a new PeerConnection with the provided configuration against the received API object
-func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection, error) {
+func (api *API) NewPeerConnection(configuration Configuration) (_ *PeerConnection, err error) {
 	// https://w3c.github.io/webrtc-pc/#constructor (Step #2)
 	// Some variables defined explicitly despite their implicit zero values to
 	// allow better readability to understand what is happening.
 	pc := &PeerConnection{
-		statsID: fmt.Sprintf("PeerConnection-%d", time.Now().UnixNano()),
+		statsID: fmt.Sprintf("PeerConnection-%d-%d", time.Now().UnixNano(), atomic.AddUint64(&peerConnectionCount, 1)),
 		configuration: Configuration{
 			ICEServers:           []ICEServer{},
 			ICETransportPolicy:   ICETransportPolicyAll,
@@ -125,6 +134,7 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 		ops:                    newOperations(),
 		isClosed:               &atomicBool{},
 		isNegotiationNeeded:    &atomicBool{},
+		isICERestartRequested:  &atomicBool{},
 		negotiationNeededState: negotiationNeededStateEmpty,
 		lastOffer:              "",
 		lastAnswer:             "",
@@ -137,10 +147,18 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 	pc.iceConnectionState.Store(ICEConnectionStateNew)
 	pc.connectionState.Store(PeerConnectionStateNew)
 
-	i, err := api.interceptorRegistry.Build("")
+	// The interceptors built before a failure may have been registered already
+	i, err := api.interceptorRegistry.Build(pc.statsID)
 	if err != nil {
+		cleanupInterceptors(pc.statsID)
 		return nil, err
 	}
+	defer func() {
+		if err != nil {
+			_ = i.Close()
+			cleanupInterceptors(pc.statsID)
+		}
+	}()
 
 	pc.api = &API{
 		settingEngine: api.settingEngine,
@@ -304,8 +322,12 @@ func (pc *PeerConnection) onNegotiationNeeded() {
 }
 
 func (pc *PeerConnection) negotiationNeededOp() {
-	// Don't run NegotiatedNeeded checks if OnNegotiationNeeded is not set
+	// Don't run NegotiatedNeeded checks if OnNegotiationNeeded is not set,
+	// but allow them to run once it is
 	if handler, ok := pc.onNegotiationNeededHandler.Load().(func()); !ok || handler == nil {
+		pc.mu.Lock()
+		pc.negotiationNeededState = negotiationNeededStateEmpty
+		pc.mu.Unlock()
 		return
 	}
 
@@ -365,7 +387,7 @@ func (pc *PeerConnection) checkNegotiationNeeded() bool { //nolint:gocognit
 	localDesc := pc.currentLocalDescription
 	remoteDesc := pc.currentRemoteDescription
 
-	if localDesc == nil {
+	if localDesc == nil || pc.isICERestartRequested.get() {
 		return true
 	}
 
@@ -443,6 +465,27 @@ func (pc *PeerConnection) OnICEGatheringStateChange(f func(ICEGathererState)) {
 	pc.iceGatherer.OnStateChange(f)
 }
 
+// OnTargetBitrateChange sets an event handler which is invoked when the congestion
+// controller updates its estimate of the available bandwidth, in bits per second.
+// The handler is never called unless ConfigureCongestionControl or ConfigureREMB has been
+// used, when both are the lower estimate is reported.
+func (pc *PeerConnection) OnTargetBitrateChange(f func(bitrate int)) {
+	if estimator, ok := lookupBandwidthEstimator(pc.statsID); ok {
+		estimator.OnTargetBitrateChange(f)
+	}
+}
+
+// GetTargetBitrate returns the current estimate of the available bandwidth in bits per second.
+// It returns 0 unless ConfigureCongestionControl or ConfigureREMB has been used, or while
+// there is no estimate yet.
+func (pc *PeerConnection) GetTargetBitrate() int {
+	if estimator, ok := lookupBandwidthEstimator(pc.statsID); ok {
+		return estimator.GetTargetBitrate()
+	}
+
+	return 0
+}
+
 // OnTrack sets an event handler which is called when remote track
 // arrives from a remote peer.
 func (pc *PeerConnection) OnTrack(f func(*TrackRemote, *RTPReceiver)) {
@@ -597,6 +640,21 @@ func (pc *PeerConnection) hasLocalDescriptionChanged(desc *SessionDescription) b
 	return false
 }
 
+// RestartICE requests an ICE restart. Negotiation is marked as needed, and the next offer
+// carries new ICE credentials, as if it was created with OfferOptions.ICERestart.
+// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-restartice
+func (pc *PeerConnection) RestartICE() {
+	if pc.isClosed.get() {
+		return
+	}
+
+	pc.isICERestartRequested.set(true)
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.onNegotiationNeeded()
+}
+
 // CreateOffer starts the PeerConnection and generates the localDescription
 // https://w3c.github.io/webrtc-pc/#dom-rtcpeerconnection-createoffer
 func (pc *PeerConnection) CreateOffer(options *OfferOptions) (SessionDescription, error) { //nolint:gocognit
@@ -608,10 +666,11 @@ func (pc *PeerConnection) CreateOffer(options *OfferOptions) (SessionDescription
 		return SessionDescription{}, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 
-	if options != nil && options.ICERestart {
+	if options != nil && options.ICERestart || pc.isICERestartRequested.get() {
 		if err := pc.iceTransport.restart(); err != nil {
 			return SessionDescription{}, err
 		}
+		pc.isICERestartRequested.set(false)
 	}
 
 	var (
@@ -964,6 +1023,15 @@ func (pc *PeerConnection) SetLocalDescription(desc SessionDescription) error {
 
 	haveLocalDescription := pc.currentLocalDescription != nil
 
+	if desc.Type == SDPTypeRollback {
+		if err := pc.setDescription(&desc, stateChangeOpSetLocal); err != nil {
+			return err
+		}
+
+		pc.releaseUnnegotiatedMids()
+		return nil
+	}
+
 	// JSEP 5.4
 	if desc.SDP == "" {
 		switch desc.Type {
@@ -1007,6 +1075,73 @@ func (pc *PeerConnection) SetLocalDescription(desc SessionDescription) error {
 	return nil
 }
 
+// SetLocalDescriptionImplicit creates an offer or an answer depending on the signaling
+// state, and sets it as the local description. It is the equivalent of calling
+// setLocalDescription without a description in the browser.
+// https://www.w3.org/TR/webrtc/#dom-peerconnection-setlocaldescription
+func (pc *PeerConnection) SetLocalDescriptionImplicit() error {
+	var (
+		desc SessionDescription
+		err  error
+	)
+	switch pc.SignalingState() {
+	case SignalingStateHaveRemoteOffer, SignalingStateHaveLocalPranswer:
+		desc, err = pc.CreateAnswer(nil)
+	default:
+		desc, err = pc.CreateOffer(nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	return pc.SetLocalDescription(desc)
+}
+
+// releaseUnnegotiatedMids clears the mids assigned by an offer that was rolled back,
+// so the transceivers can be matched by the next remote offer
+func (pc *PeerConnection) releaseUnnegotiatedMids() {
+	pc.mu.RLock()
+	current := pc.currentLocalDescription
+	pc.mu.RUnlock()
+
+	negotiated := map[string]struct{}{}
+	if current != nil && current.parsed != nil {
+		for _, media := range current.parsed.MediaDescriptions {
+			negotiated[getMidValue(media)] = struct{}{}
+		}
+	}
+
+	for _, t := range pc.GetTransceivers() {
+		if _, ok := negotiated[t.Mid()]; !ok {
+			t.mid.Store("")
+		}
+	}
+}
+
+// removeRemoteOfferTransceivers stops and removes the transceivers created by a remote offer
+// that was rolled back, unless a track was added to them meanwhile
+func (pc *PeerConnection) removeRemoteOfferTransceivers() {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	for _, t := range pc.remoteOfferTransceivers {
+		if t.Sender() != nil {
+			continue
+		}
+
+		if err := t.Stop(); err != nil {
+			pc.log.Warnf("Failed to stop transceiver: %s", err)
+		}
+		for i := range pc.rtpTransceivers {
+			if pc.rtpTransceivers[i] == t {
+				pc.rtpTransceivers = append(pc.rtpTransceivers[:i], pc.rtpTransceivers[i+1:]...)
+				break
+			}
+		}
+	}
+	pc.remoteOfferTransceivers = nil
+}
+
 // LocalDescription returns PendingLocalDescription if it is not null and
 // otherwise it returns CurrentLocalDescription. This property is used to
 // determine if SetLocalDescription has already been called.
@@ -1027,6 +1162,16 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 
 	isRenegotation := pc.currentRemoteDescription != nil
 
+	if desc.Type == SDPTypeRollback {
+		if err := pc.setDescription(&desc, stateChangeOpSetRemote); err != nil {
+			return err
+		}
+
+		pc.removeRemoteOfferTransceivers()
+		pc.releaseUnnegotiatedMids()
+		return nil
+	}
+
 	if _, err := desc.Unmarshal(); err != nil {
 		return err
 	}
@@ -1047,6 +1192,10 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 
 	weOffer := desc.Type == SDPTypeAnswer
 
+	pc.mu.Lock()
+	pc.remoteOfferTransceivers = nil
+	pc.mu.Unlock()
+
 	if !weOffer && !detectedPlanB {
 		for _, media := range pc.RemoteDescription().parsed.MediaDescriptions {
 			midValue := getMidValue(media)
@@ -1090,6 +1239,9 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 				t = newRTPTransceiver(receiver, nil, localDirection, kind, pc.api)
 				pc.mu.Lock()
 				pc.addRTPTransceiver(t)
+				if desc.Type == SDPTypeOffer {
+					pc.remoteOfferTransceivers = append(pc.remoteOfferTransceivers, t)
+				}
 				pc.mu.Unlock()
 
 				// if transceiver is create by remote sdp, set prefer codec same as remote peer
@@ -1217,6 +1369,10 @@ func (pc *PeerConnection) startReceiver(incoming trackDetails, receiver *RTPRece
 		return
 	}
 
+	if synchronizer, ok := lookupLipSynchronizer(pc.statsID); ok {
+		receiver.startReadingRTCP(synchronizer)
+	}
+
 	for _, t := range receiver.Tracks() {
 		if t.SSRC() == 0 || t.RID() != "" {
 			return
@@ -1440,7 +1596,7 @@ func (pc *PeerConnection) startRTPReceivers(remoteDesc *SessionDescription, curr
 func (pc *PeerConnection) startRTPSenders(currentTransceivers []*RTPTransceiver) error {
 	for _, transceiver := range currentTransceivers {
 		if sender := transceiver.Sender(); sender != nil && sender.isNegotiated() && !sender.hasSent() {
-			err := sender.Send(sender.GetParameters())
+			err := sender.Send(sender.currentParameters())
 			if err != nil {
 				return err
 			}
@@ -1521,6 +1677,9 @@ func (pc *PeerConnection) handleIncomingSSRC(rtpStream io.Reader, ssrc SSRC) err
 		if track.repairSsrc != nil && ssrc == *track.repairSsrc {
 			return nil
 		}
+		if track.fecSsrc != nil && ssrc == *track.fecSsrc {
+			return nil
+		}
 		for _, trackSsrc := range track.ssrcs {
 			if ssrc == trackSsrc {
 				return nil
@@ -1564,6 +1723,9 @@ func (pc *PeerConnection) handleIncomingSSRC(rtpStream io.Reader, ssrc SSRC) err
 	}
 
 	streamInfo := createStreamInfo("", ssrc, params.Codecs[0].PayloadType, params.Codecs[0].RTPCodecCapability, params.HeaderExtensions)
+	if _, typ, codecErr := pc.api.mediaEngine.getCodecByPayload(payloadType); codecErr == nil && typ == RTPCodecTypeVideo {
+		setULPFECStream(streamInfo, pc.api.mediaEngine.getCodecsByKind(RTPCodecTypeVideo))
+	}
 	readStream, interceptor, rtcpReadStream, rtcpInterceptor, err := pc.dtlsTransport.streamsForSSRC(ssrc, *streamInfo)
 	if err != nil {
 		return err
@@ -1599,6 +1761,9 @@ func (pc *PeerConnection) handleIncomingSSRC(rtpStream io.Reader, ssrc SSRC) err
 			if err != nil {
 				return err
 			}
+			if synchronizer, ok := lookupLipSynchronizer(pc.statsID); ok {
+				receiver.startReadingRTCP(synchronizer)
+			}
 			pc.onTrack(track, receiver)
 			return nil
 		}
@@ -1672,8 +1837,30 @@ func (pc *PeerConnection) undeclaredRTCPMediaProcessor() {
 			pc.log.Warnf("Failed to accept RTCP %v", err)
 			return
 		}
-		pc.log.Warnf("Incoming unhandled RTCP ssrc(%d), OnTrack will not be fired", ssrc)
 		unhandledStreams = append(unhandledStreams, stream)
+
+		// The DLRR reports answering the RRTR reports of the NACK generator are addressed to
+		// its SSRC, they are read through the interceptors to reach it
+		if generator, ok := lookupNackGenerator(pc.statsID); ok && ssrc == generator.SenderSSRC() {
+			go pc.readUndeclaredRTCP(stream)
+			continue
+		}
+		pc.log.Warnf("Incoming unhandled RTCP ssrc(%d), OnTrack will not be fired", ssrc)
+	}
+}
+
+// readUndeclaredRTCP reads the RTCP of an undeclared SSRC through the interceptors until the stream is closed
+func (pc *PeerConnection) readUndeclaredRTCP(stream *srtp.ReadStreamSRTCP) {
+	reader := pc.api.interceptor.BindRTCPReader(interceptor.RTCPReaderFunc(func(in []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		n, err := stream.Read(in)
+		return n, a, err
+	}))
+
+	b := make([]byte, pc.api.settingEngine.getReceiveMTU())
+	for {
+		if _, _, err := reader.Read(b, nil); err != nil {
+			return
+		}
 	}
 }
 
@@ -2034,6 +2221,7 @@ func (pc *PeerConnection) Close() error {
 	closeErrs := make([]error, 4)
 
 	closeErrs = append(closeErrs, pc.api.interceptor.Close())
+	cleanupInterceptors(pc.statsID)
 
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #4)
 	pc.mu.Lock()
@@ -2213,6 +2401,18 @@ func (pc *PeerConnection) GetStats() StatsReport {
 			continue
 		}
 	}
+
+	if getter, ok := lookupStats(pc.statsID); ok {
+		nackGenerator, _ := lookupNackGenerator(pc.statsID)
+		for _, t := range pc.rtpTransceivers {
+			if sender := t.Sender(); sender != nil {
+				sender.collectStats(statsCollector, getter)
+			}
+			if receiver := t.Receiver(); receiver != nil {
+				receiver.collectStats(statsCollector, getter, nackGenerator)
+			}
+		}
+	}
 	pc.mu.Unlock()
 
 	pc.api.mediaEngine.collectStats(statsCollector)
@@ -909,6 +909,79 @@ func TestICERestart(t *testing.T) {
 	closePairNow(t, offerPC, answerPC)
 }
 
+// Assert that RestartICE triggers a negotiation with new credentials, and that
+// SetLocalDescriptionImplicit creates the offer and the answer
+func TestPeerConnection_RestartICE(t *testing.T) {
+	extractUfrag := func(desc *SessionDescription) string {
+		for _, line := range strings.Split(desc.SDP, "\r\n") {
+			if strings.HasPrefix(line, "a=ice-ufrag:") {
+				return line
+			}
+		}
+		return ""
+	}
+
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerPC, answerPC, err := newPair()
+	assert.NoError(t, err)
+
+	var connectedWaitGroup sync.WaitGroup
+	connectedWaitGroup.Add(2)
+	for _, pc := range []*PeerConnection{offerPC, answerPC} {
+		pc.OnICEConnectionStateChange(func(state ICEConnectionState) {
+			if state == ICEConnectionStateConnected {
+				connectedWaitGroup.Done()
+			}
+		})
+	}
+
+	assert.NoError(t, signalPair(offerPC, answerPC))
+	connectedWaitGroup.Wait()
+
+	firstOfferUfrag := extractUfrag(offerPC.LocalDescription())
+	firstAnswerUfrag := extractUfrag(answerPC.LocalDescription())
+
+	offerPC.OnICECandidate(func(c *ICECandidate) {
+		if c != nil {
+			assert.NoError(t, answerPC.AddICECandidate(c.ToJSON()))
+		}
+	})
+	answerPC.OnICECandidate(func(c *ICECandidate) {
+		if c != nil {
+			assert.NoError(t, offerPC.AddICECandidate(c.ToJSON()))
+		}
+	})
+
+	negotiationNeeded := make(chan struct{}, 1)
+	offerPC.OnNegotiationNeeded(func() {
+		negotiationNeeded <- struct{}{}
+	})
+
+	// Re-signal with implicit descriptions, block until ICEConnectionStateConnected
+	connectedWaitGroup.Add(2)
+	offerPC.RestartICE()
+	<-negotiationNeeded
+
+	assert.NoError(t, offerPC.SetLocalDescriptionImplicit())
+	assert.Equal(t, SDPTypeOffer, offerPC.LocalDescription().Type)
+	assert.NoError(t, answerPC.SetRemoteDescription(*offerPC.LocalDescription()))
+
+	assert.NoError(t, answerPC.SetLocalDescriptionImplicit())
+	assert.Equal(t, SDPTypeAnswer, answerPC.LocalDescription().Type)
+	assert.NoError(t, offerPC.SetRemoteDescription(*answerPC.LocalDescription()))
+
+	connectedWaitGroup.Wait()
+
+	assert.NotEqual(t, firstOfferUfrag, extractUfrag(offerPC.LocalDescription()))
+	assert.NotEqual(t, firstAnswerUfrag, extractUfrag(answerPC.LocalDescription()))
+	closePairNow(t, offerPC, answerPC)
+}
+
 // Assert error handling when an Agent is restart
 func TestICERestart_Error_Handling(t *testing.T) {
 	iceStates := make(chan ICEConnectionState, 100)
@@ -875,6 +875,38 @@ func TestNegotiationCreateDataChannel(t *testing.T) {
 	assert.NoError(t, pc.Close())
 }
 
+// Assert that OnNegotiationNeeded fires for changes made after it is set, even if the
+// negotiation was needed before, as NewPerfectNegotiator sets it on a PeerConnection in use
+func TestNegotiationNeededLateHandler(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pc.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	// Wait for the negotiation needed check of the first transceiver to run without handler
+	ran := make(chan struct{})
+	pc.ops.Enqueue(func() { close(ran) })
+	<-ran
+
+	negotiationNeeded := make(chan struct{})
+	pc.OnNegotiationNeeded(func() {
+		close(negotiationNeeded)
+	})
+
+	_, err = pc.AddTransceiverFromKind(RTPCodecTypeAudio)
+	assert.NoError(t, err)
+
+	<-negotiationNeeded
+	assert.NoError(t, pc.Close())
+}
+
 func TestNegotiationNeededRemoveTrack(t *testing.T) {
 	var wg sync.WaitGroup
 	wg.Add(1)
@@ -1285,3 +1317,140 @@ func TestPeerConnection_Regegotiation_AnswerAddsTrack(t *testing.T) {
 
 	closePairNow(t, pcOffer, pcAnswer)
 }
+
+func TestPeerConnection_PerfectNegotiation(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcPolite, pcImpolite, err := newPair()
+	assert.NoError(t, err)
+
+	type message struct {
+		description *SessionDescription
+		candidate   *ICECandidateInit
+	}
+	toPolite, toImpolite := make(chan message, 100), make(chan message, 100)
+
+	polite := NewPerfectNegotiator(pcPolite, true, func(d SessionDescription) {
+		toImpolite <- message{description: &d}
+	})
+	impolite := NewPerfectNegotiator(pcImpolite, false, func(d SessionDescription) {
+		toPolite <- message{description: &d}
+	})
+
+	forwardCandidates := func(pc *PeerConnection, messages chan message) {
+		pc.OnICECandidate(func(c *ICECandidate) {
+			if c != nil {
+				candidate := c.ToJSON()
+				messages <- message{candidate: &candidate}
+			}
+		})
+	}
+	forwardCandidates(pcPolite, toImpolite)
+	forwardCandidates(pcImpolite, toPolite)
+
+	onTrack := func(pc *PeerConnection) context.Context {
+		trackReceived, trackReceivedFn := context.WithCancel(context.Background())
+		pc.OnTrack(func(track *TrackRemote, r *RTPReceiver) {
+			trackReceivedFn()
+			for {
+				if _, _, readErr := track.ReadRTP(); errors.Is(readErr, io.EOF) {
+					return
+				}
+			}
+		})
+		return trackReceived
+	}
+	politeTrackReceived, impoliteTrackReceived := onTrack(pcPolite), onTrack(pcImpolite)
+
+	// Both peers add a track at the same time, and their offers collide
+	politeTrack, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "polite")
+	assert.NoError(t, err)
+	_, err = pcPolite.AddTrack(politeTrack)
+	assert.NoError(t, err)
+
+	impoliteTrack, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "impolite")
+	assert.NoError(t, err)
+	_, err = pcImpolite.AddTrack(impoliteTrack)
+	assert.NoError(t, err)
+
+	for pcPolite.SignalingState() != SignalingStateHaveLocalOffer || pcImpolite.SignalingState() != SignalingStateHaveLocalOffer {
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	handle := func(n *PerfectNegotiator, messages chan message) {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			case m := <-messages:
+				if m.description != nil {
+					assert.NoError(t, n.HandleDescription(*m.description))
+				} else {
+					assert.NoError(t, n.HandleICECandidate(*m.candidate))
+				}
+			}
+		}
+	}
+	wg.Add(2)
+	go handle(polite, toPolite)
+	go handle(impolite, toImpolite)
+
+	go sendVideoUntilDone(done, t, []*TrackLocalStaticSample{politeTrack, impoliteTrack})
+
+	<-politeTrackReceived.Done()
+	<-impoliteTrackReceived.Done()
+
+	close(done)
+	wg.Wait()
+
+	assert.Equal(t, SignalingStateStable, pcPolite.SignalingState())
+	assert.Equal(t, SignalingStateStable, pcImpolite.SignalingState())
+
+	closePairNow(t, pcPolite, pcImpolite)
+}
+
+// Assert that rolling back a remote offer removes the transceivers it created, and releases
+// the mids it assigned to the existing ones
+func TestPeerConnection_RemoteRollback(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	for _, kind := range []RTPCodecType{RTPCodecTypeAudio, RTPCodecTypeVideo} {
+		_, err = pcOffer.AddTransceiverFromKind(kind)
+		assert.NoError(t, err)
+	}
+	audioTransceiver, err := pcAnswer.AddTransceiverFromKind(RTPCodecTypeAudio)
+	assert.NoError(t, err)
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+	assert.Equal(t, 2, len(pcAnswer.GetTransceivers()))
+	assert.Equal(t, "0", audioTransceiver.Mid())
+
+	assert.NoError(t, pcAnswer.SetRemoteDescription(SessionDescription{Type: SDPTypeRollback}))
+	assert.Equal(t, SignalingStateStable, pcAnswer.SignalingState())
+	assert.Equal(t, []*RTPTransceiver{audioTransceiver}, pcAnswer.GetTransceivers())
+	assert.Equal(t, "", audioTransceiver.Mid())
+
+	// The next offer only has the m-line of the remaining transceiver
+	answerOffer, err := pcAnswer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(answerOffer.SDP, "m="))
+	assert.Equal(t, 1, strings.Count(answerOffer.SDP, "m=audio"))
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
@@ -0,0 +1,114 @@
+//go:build !js
+// +build !js
+
+package webrtc
+
+// PerfectNegotiator implements the perfect negotiation pattern on top of a PeerConnection.
+// Both peers can add tracks, transceivers or data channels at any time; when their offers
+// collide the polite peer rolls back its own offer and answers the remote one, while the
+// impolite peer ignores the remote offer.
+// https://www.w3.org/TR/webrtc/#perfect-negotiation-example
+type PerfectNegotiator struct {
+	pc     *PeerConnection
+	polite bool
+	signal func(SessionDescription)
+
+	// Only accessed from the operations queue of the PeerConnection
+	ignoreOffer       bool
+	pendingCandidates []ICECandidateInit
+}
+
+// NewPerfectNegotiator creates a PerfectNegotiator for the PeerConnection. Exactly one of the two
+// peers must be polite. signal is called with every description that must be sent to the remote
+// peer; it is called from the operations queue of the PeerConnection, so it must not wait for
+// the PeerConnection. The PerfectNegotiator takes over the OnNegotiationNeeded handler.
+func NewPerfectNegotiator(pc *PeerConnection, polite bool, signal func(SessionDescription)) *PerfectNegotiator {
+	n := &PerfectNegotiator{
+		pc:     pc,
+		polite: polite,
+		signal: signal,
+	}
+	pc.OnNegotiationNeeded(n.onNegotiationNeeded)
+
+	return n
+}
+
+// onNegotiationNeeded is called from the operations queue
+func (n *PerfectNegotiator) onNegotiationNeeded() {
+	if err := n.pc.SetLocalDescriptionImplicit(); err != nil {
+		n.pc.log.Warnf("Failed to create offer: %v", err)
+		return
+	}
+
+	n.signal(*n.pc.LocalDescription())
+}
+
+// HandleDescription applies a description received from the remote peer, and answers it
+// when it is an offer. An offer colliding with a local one is ignored by the impolite peer.
+func (n *PerfectNegotiator) HandleDescription(desc SessionDescription) error {
+	return n.run(func() error {
+		offerCollision := desc.Type == SDPTypeOffer && n.pc.SignalingState() != SignalingStateStable
+
+		n.ignoreOffer = !n.polite && offerCollision
+		if n.ignoreOffer {
+			return nil
+		}
+
+		if offerCollision {
+			if err := n.pc.SetLocalDescription(SessionDescription{Type: SDPTypeRollback}); err != nil {
+				return err
+			}
+		}
+
+		if err := n.pc.SetRemoteDescription(desc); err != nil {
+			return err
+		}
+
+		for _, candidate := range n.pendingCandidates {
+			if err := n.pc.AddICECandidate(candidate); err != nil {
+				return err
+			}
+		}
+		n.pendingCandidates = nil
+
+		if desc.Type != SDPTypeOffer {
+			return nil
+		}
+
+		if err := n.pc.SetLocalDescriptionImplicit(); err != nil {
+			return err
+		}
+
+		n.signal(*n.pc.LocalDescription())
+		return nil
+	})
+}
+
+// HandleICECandidate adds a candidate received from the remote peer. Candidates received
+// before the first remote description are added once it is set, and failures caused by
+// an ignored offer are not reported.
+func (n *PerfectNegotiator) HandleICECandidate(candidate ICECandidateInit) error {
+	return n.run(func() error {
+		if n.pc.RemoteDescription() == nil {
+			n.pendingCandidates = append(n.pendingCandidates, candidate)
+			return nil
+		}
+
+		if err := n.pc.AddICECandidate(candidate); err != nil && !n.ignoreOffer {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// run executes f on the operations queue, so it is serialized with the negotiation
+// needed handler, and waits for its result
+func (n *PerfectNegotiator) run(f func() error) error {
+	errChan := make(chan error, 1)
+	n.pc.ops.Enqueue(func() {
+		errChan <- f()
+	})
+
+	return <-errChan
+}
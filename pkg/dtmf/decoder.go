@@ -0,0 +1,76 @@
+package dtmf
+
+import (
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// Digit is a DTMF tone received from the remote peer
+type Digit struct {
+	Tone     rune
+	Duration time.Duration
+}
+
+// Decoder turns the telephone-event packets of a stream into digits. Each digit is emitted
+// once, when its event ends. The end of events whose final packets were lost is detected
+// when the next event starts.
+type Decoder struct {
+	clockRate uint32
+
+	hasEvent  bool
+	ended     bool
+	timestamp uint32
+	last      Event
+}
+
+// NewDecoder returns a Decoder for telephone-events of the given clock rate
+func NewDecoder(clockRate uint32) *Decoder {
+	return &Decoder{clockRate: clockRate}
+}
+
+// Decode parses a telephone-event packet, and returns the digits that ended with it. Events
+// that aren't DTMF tones are ignored.
+func (d *Decoder) Decode(p *rtp.Packet) ([]Digit, error) {
+	event := Event{}
+	if err := event.Unmarshal(p.Payload); err != nil {
+		return nil, err
+	}
+
+	var digits []Digit
+	if !d.hasEvent || p.Timestamp != d.timestamp {
+		// Packets of an earlier event are late retransmissions
+		if d.hasEvent && p.Timestamp-d.timestamp >= 1<<31 {
+			return nil, nil
+		}
+
+		if d.hasEvent && !d.ended {
+			digits = d.appendDigit(digits)
+		}
+		d.hasEvent, d.ended, d.timestamp = true, false, p.Timestamp
+	} else if d.ended {
+		return nil, nil
+	}
+
+	d.last = event
+	if event.End {
+		d.ended = true
+		digits = d.appendDigit(digits)
+	}
+
+	return digits, nil
+}
+
+func (d *Decoder) appendDigit(digits []Digit) []Digit {
+	tone, ok := ToneFromCode(d.last.Code)
+	if !ok {
+		return digits
+	}
+
+	var duration time.Duration
+	if d.clockRate != 0 {
+		duration = time.Duration(d.last.Duration) * time.Second / time.Duration(d.clockRate)
+	}
+
+	return append(digits, Digit{Tone: tone, Duration: duration})
+}
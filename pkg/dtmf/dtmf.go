@@ -0,0 +1,83 @@
+// Package dtmf implements the RFC 4733 telephone-event RTP payload, used to send DTMF tones
+// alongside the audio of a stream
+package dtmf
+
+import (
+	"errors"
+	"strings"
+)
+
+// EventLen is the size of a telephone-event payload
+const EventLen = 4
+
+// MaxVolume is the lowest power level of a tone, in -dBm0
+const MaxVolume = 63
+
+// tones maps the event codes 0 to 15 to their DTMF tone
+const tones = "0123456789*#ABCD"
+
+var (
+	errShortPayload  = errors.New("dtmf: telephone-event payload is too short")
+	errInvalidVolume = errors.New("dtmf: volume must not be greater than 63")
+)
+
+// Event is a telephone-event payload. Every packet of an event has the timestamp of its
+// start, and the duration of the event so far in units of the clock rate.
+type Event struct {
+	Code     uint8
+	End      bool
+	Volume   uint8
+	Duration uint16
+}
+
+// Marshal returns the payload of the event
+func (e Event) Marshal() ([]byte, error) {
+	if e.Volume > MaxVolume {
+		return nil, errInvalidVolume
+	}
+
+	b := make([]byte, EventLen)
+	b[0] = e.Code
+	b[1] = e.Volume
+	if e.End {
+		b[1] |= 0x80
+	}
+	b[2] = byte(e.Duration >> 8)
+	b[3] = byte(e.Duration)
+
+	return b, nil
+}
+
+// Unmarshal parses the payload of an event
+func (e *Event) Unmarshal(b []byte) error {
+	if len(b) < EventLen {
+		return errShortPayload
+	}
+
+	e.Code = b[0]
+	e.End = b[1]&0x80 != 0
+	e.Volume = b[1] & MaxVolume
+	e.Duration = uint16(b[2])<<8 | uint16(b[3])
+
+	return nil
+}
+
+// CodeFromTone returns the event code of a DTMF tone, 0-9, *, # or A-D
+func CodeFromTone(tone rune) (uint8, bool) {
+	i := strings.IndexRune(tones, tone)
+	if i < 0 {
+		return 0, false
+	}
+
+	return uint8(i), true
+}
+
+// ToneFromCode returns the DTMF tone of an event code, ok is false for the events that
+// aren't DTMF tones
+func ToneFromCode(code uint8) (tone rune, ok bool) {
+	if int(code) >= len(tones) {
+		return 0, false
+	}
+
+	return rune(tones[code]), true
+}
@@ -0,0 +1,71 @@
+package dtmf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventMarshal(t *testing.T) {
+	raw, err := Event{Code: 11, End: true, Volume: 10, Duration: 800}.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x0B, 0x8A, 0x03, 0x20}, raw)
+
+	event := Event{}
+	assert.NoError(t, event.Unmarshal(raw))
+	assert.Equal(t, Event{Code: 11, End: true, Volume: 10, Duration: 800}, event)
+
+	_, err = Event{Volume: 64}.Marshal()
+	assert.ErrorIs(t, err, errInvalidVolume)
+	assert.ErrorIs(t, event.Unmarshal(raw[:3]), errShortPayload)
+}
+
+func TestTones(t *testing.T) {
+	for code, tone := range "0123456789*#ABCD" {
+		c, ok := CodeFromTone(tone)
+		assert.True(t, ok)
+		assert.Equal(t, uint8(code), c)
+
+		r, ok := ToneFromCode(c)
+		assert.True(t, ok)
+		assert.Equal(t, tone, r)
+	}
+
+	_, ok := CodeFromTone('E')
+	assert.False(t, ok)
+	_, ok = ToneFromCode(16)
+	assert.False(t, ok)
+}
+
+func TestDecoder(t *testing.T) {
+	packet := func(timestamp uint32, event Event) *rtp.Packet {
+		payload, err := event.Marshal()
+		assert.NoError(t, err)
+		return &rtp.Packet{Header: rtp.Header{Timestamp: timestamp}, Payload: payload}
+	}
+
+	d := NewDecoder(8000)
+	decode := func(p *rtp.Packet) []Digit {
+		digits, err := d.Decode(p)
+		assert.NoError(t, err)
+		return digits
+	}
+
+	assert.Empty(t, decode(packet(1000, Event{Code: 1, Duration: 400})))
+	assert.Equal(t, []Digit{{Tone: '1', Duration: 100 * time.Millisecond}}, decode(packet(1000, Event{Code: 1, End: true, Duration: 800})))
+	// Retransmissions of the end packet
+	assert.Empty(t, decode(packet(1000, Event{Code: 1, End: true, Duration: 800})))
+
+	// The end of an event is detected when the next one starts
+	assert.Empty(t, decode(packet(3000, Event{Code: 11, Duration: 400})))
+	assert.Equal(t, []Digit{{Tone: '#', Duration: 50 * time.Millisecond}}, decode(packet(5000, Event{Code: 16, Duration: 400})))
+	assert.Empty(t, decode(packet(5000, Event{Code: 16, End: true, Duration: 800})))
+
+	// Late packets of an earlier event
+	assert.Empty(t, decode(packet(3000, Event{Code: 11, End: true, Duration: 800})))
+
+	_, err := d.Decode(&rtp.Packet{Payload: []byte{0x00}})
+	assert.ErrorIs(t, err, errShortPayload)
+}
@@ -0,0 +1,190 @@
+package flexfec
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	// mediaHistorySize is how many media packets are kept to recover from
+	mediaHistorySize = 512
+
+	// maxPendingFECPackets is how many FEC packets are kept while waiting for media packets
+	maxPendingFECPackets = 64
+)
+
+type fecPacket struct {
+	fecHeader
+	body []byte
+}
+
+// decoder recovers lost media packets of a stream from the FEC packets protecting it
+type decoder struct {
+	mu sync.Mutex
+
+	protectedSSRC uint32
+
+	media      map[uint16]protectedPacket
+	mediaOrder []uint16
+	fecPackets []*fecPacket
+
+	// recovered holds packets that have been restored but not read yet
+	recovered []*rtp.Packet
+}
+
+func newDecoder(protectedSSRC uint32) *decoder {
+	return &decoder{
+		protectedSSRC: protectedSSRC,
+		media:         map[uint16]protectedPacket{},
+	}
+}
+
+// pushMedia stores a received media packet. false is returned if the packet has already
+// been received or recovered, in which case it should be dropped.
+func (d *decoder) pushMedia(packet *rtp.Packet) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.media[packet.SequenceNumber]; ok {
+		return false
+	}
+
+	d.storeMedia(newProtectedPacket(&packet.Header, packet.Payload))
+	d.recover()
+	return true
+}
+
+// pushFEC stores a FEC packet and tries to recover the media packets it protects
+func (d *decoder) pushFEC(packet *rtp.Packet) error {
+	fec := &fecPacket{}
+	n, err := fec.unmarshal(packet.Payload)
+	if err != nil {
+		return err
+	}
+	fec.body = append([]byte{}, packet.Payload[n:]...)
+
+	if fec.protectedSSRC != d.protectedSSRC {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.fecPackets) == maxPendingFECPackets {
+		d.fecPackets = d.fecPackets[1:]
+	}
+	d.fecPackets = append(d.fecPackets, fec)
+	d.recover()
+	return nil
+}
+
+// popRecovered returns the oldest recovered packet that hasn't been read yet
+func (d *decoder) popRecovered() *rtp.Packet {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.recovered) == 0 {
+		return nil
+	}
+
+	packet := d.recovered[0]
+	d.recovered = d.recovered[1:]
+	return packet
+}
+
+func (d *decoder) storeMedia(packet protectedPacket) {
+	if len(d.mediaOrder) == mediaHistorySize {
+		delete(d.media, d.mediaOrder[0])
+		d.mediaOrder = d.mediaOrder[1:]
+	}
+
+	d.media[packet.sequenceNumber] = packet
+	d.mediaOrder = append(d.mediaOrder, packet.sequenceNumber)
+}
+
+// recover repeatedly applies the pending FEC packets until no more media packets can be restored.
+// FEC packets are dropped once all the packets they protect are known.
+func (d *decoder) recover() {
+	for recovered := true; recovered; {
+		recovered = false
+
+		pending := d.fecPackets[:0]
+		for _, fec := range d.fecPackets {
+			missing := 0
+			var missingSequenceNumber uint16
+			for _, offset := range fec.offsets {
+				if _, ok := d.media[fec.snBase+offset]; !ok {
+					missing++
+					missingSequenceNumber = fec.snBase + offset
+				}
+			}
+
+			switch missing {
+			case 0:
+				continue
+			case 1:
+				if packet, ok := d.recoverPacket(fec, missingSequenceNumber); ok {
+					d.recovered = append(d.recovered, packet)
+					recovered = true
+				}
+				continue
+			}
+
+			pending = append(pending, fec)
+		}
+		d.fecPackets = pending
+	}
+}
+
+func (d *decoder) recoverPacket(fec *fecPacket, sequenceNumber uint16) (*rtp.Packet, bool) {
+	firstByte := fec.firstByte
+	secondByte := fec.secondByte
+	length := fec.lengthRecovery
+	timestamp := fec.tsRecovery
+	body := append([]byte{}, fec.body...)
+
+	for _, offset := range fec.offsets {
+		media, ok := d.media[fec.snBase+offset]
+		if !ok {
+			continue
+		}
+
+		firstByte ^= media.csrcCount
+		secondByte ^= media.secondByte()
+		length ^= uint16(len(media.body))
+		timestamp ^= media.timestamp
+		if len(media.body) > len(body) {
+			return nil, false
+		}
+		for i := range media.body {
+			body[i] ^= media.body[i]
+		}
+	}
+
+	csrcCount := int(firstByte & 0x0F)
+	if int(length) > len(body) || int(length) < 4*csrcCount {
+		return nil, false
+	}
+	body = body[:length]
+
+	packet := &rtp.Packet{
+		Header: rtp.Header{
+			Version:        rtpVersion,
+			Marker:         secondByte&0x80 != 0,
+			PayloadType:    secondByte & 0x7F,
+			SequenceNumber: sequenceNumber,
+			Timestamp:      timestamp,
+			SSRC:           d.protectedSSRC,
+			CSRC:           make([]uint32, csrcCount),
+		},
+		Payload: body[4*csrcCount:],
+	}
+	for i := range packet.CSRC {
+		packet.CSRC[i] = binary.BigEndian.Uint32(body[4*i:])
+	}
+
+	d.storeMedia(newProtectedPacket(&packet.Header, packet.Payload))
+	return packet, true
+}
@@ -0,0 +1,102 @@
+package flexfec
+
+import (
+	"sync"
+
+	"github.com/pion/rtp"
+)
+
+// encoder generates FEC packets for a media stream. Every numMediaPackets media packets
+// numFECPackets FEC packets are generated, each protecting an interleaved subset of the
+// group so that a burst of losses can still be recovered.
+type encoder struct {
+	mu sync.Mutex
+
+	fecStream       FECStream
+	numMediaPackets int
+	numFECPackets   int
+	sequencer       rtp.Sequencer
+
+	packets []protectedPacket
+}
+
+func newEncoder(fecStream FECStream, numMediaPackets, numFECPackets int) *encoder {
+	return &encoder{
+		fecStream:       fecStream,
+		numMediaPackets: numMediaPackets,
+		numFECPackets:   numFECPackets,
+		sequencer:       rtp.NewRandomSequencer(),
+		packets:         make([]protectedPacket, 0, numMediaPackets),
+	}
+}
+
+// encode adds a media packet to the current group and returns the FEC packets once the group is complete
+func (e *encoder) encode(header *rtp.Header, payload []byte, payloadType uint8) []*rtp.Packet {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// A FEC packet can't describe sequence numbers that are too far apart, start a new group
+	if len(e.packets) != 0 && header.SequenceNumber-e.packets[0].sequenceNumber >= maxProtectedPackets {
+		e.packets = e.packets[:0]
+	}
+
+	e.packets = append(e.packets, newProtectedPacket(header, payload))
+	if len(e.packets) < e.numMediaPackets {
+		return nil
+	}
+
+	fecPackets := make([]*rtp.Packet, 0, e.numFECPackets)
+	for i := 0; i < e.numFECPackets; i++ {
+		group := []protectedPacket{}
+		for j := i; j < len(e.packets); j += e.numFECPackets {
+			group = append(group, e.packets[j])
+		}
+
+		fecPackets = append(fecPackets, e.fecPacket(group, payloadType))
+	}
+
+	e.packets = e.packets[:0]
+	return fecPackets
+}
+
+func (e *encoder) fecPacket(group []protectedPacket, payloadType uint8) *rtp.Packet {
+	h := fecHeader{
+		protectedSSRC: e.fecStream.ProtectedSSRC,
+		snBase:        group[0].sequenceNumber,
+		offsets:       make([]uint16, 0, len(group)),
+	}
+
+	bodyLength := 0
+	for i := range group {
+		if len(group[i].body) > bodyLength {
+			bodyLength = len(group[i].body)
+		}
+	}
+	body := make([]byte, bodyLength)
+
+	for i := range group {
+		h.firstByte ^= group[i].csrcCount
+		h.secondByte ^= group[i].secondByte()
+		h.lengthRecovery ^= uint16(len(group[i].body))
+		h.tsRecovery ^= group[i].timestamp
+		h.offsets = append(h.offsets, group[i].sequenceNumber-h.snBase)
+
+		for j := range group[i].body {
+			body[j] ^= group[i].body[j]
+		}
+	}
+
+	payload := make([]byte, h.marshalSize()+len(body))
+	copy(payload[h.marshalTo(payload):], body)
+
+	return &rtp.Packet{
+		Header: rtp.Header{
+			Version:        rtpVersion,
+			PayloadType:    payloadType,
+			SequenceNumber: e.sequencer.NextSequenceNumber(),
+			Timestamp:      group[len(group)-1].timestamp,
+			SSRC:           e.fecStream.SSRC,
+		},
+		Payload: payload,
+	}
+}
@@ -0,0 +1,54 @@
+// Package flexfec implements FlexFEC (draft-ietf-payload-flexible-fec-scheme-03) forward error
+// correction for RTP streams. FEC packets are sent on their own SSRC and allow a receiver to recover
+// lost media packets without waiting for a retransmission.
+package flexfec
+
+import (
+	"github.com/pion/interceptor"
+)
+
+const (
+	// MimeType is the MIME type of the FlexFEC repair flow
+	MimeType = "video/flexfec-03"
+
+	// SDPFmtpLine is the fmtp line of the FlexFEC repair flow, the repair window is in microseconds
+	SDPFmtpLine = "repair-window=10000000"
+
+	// headerSize is the size of the FlexFEC header for a single protected SSRC and a 15 bit mask
+	headerSize = 20
+
+	// maxProtectedPackets is the largest span of sequence numbers a single FEC packet can describe
+	maxProtectedPackets = 109
+
+	rtpFixedHeaderSize = 12
+	rtpVersion         = 2
+)
+
+// FECStream describes a FlexFEC repair flow and the media stream it protects. It must be
+// set on the interceptor.StreamInfo of both streams so the interceptor can pair them.
+type FECStream struct {
+	// ProtectedSSRC is the SSRC of the media stream
+	ProtectedSSRC uint32
+	// SSRC is the SSRC of the repair flow
+	SSRC uint32
+}
+
+type fecStreamAttributeKey struct{}
+
+// SetFECStream stores a FECStream in the Attributes of a StreamInfo
+func SetFECStream(info *interceptor.StreamInfo, stream FECStream) {
+	if info.Attributes == nil {
+		info.Attributes = interceptor.Attributes{}
+	}
+
+	info.Attributes.Set(fecStreamAttributeKey{}, stream)
+}
+
+func getFECStream(info *interceptor.StreamInfo) (FECStream, bool) {
+	if info == nil || info.Attributes == nil {
+		return FECStream{}, false
+	}
+
+	stream, ok := info.Attributes.Get(fecStreamAttributeKey{}).(FECStream)
+	return stream, ok
+}
@@ -0,0 +1,175 @@
+package flexfec
+
+import (
+	"testing"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/internal/fectest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFECHeader_RoundTrip(t *testing.T) {
+	fectest.HeaderRoundTrip(t, [][]uint16{
+		{0, 1, 2, 14},
+		{0, 15, 45},
+		{0, 46, 108},
+	}, func(offsets []uint16) (interface{}, []byte) {
+		h := fecHeader{
+			firstByte:      0x02,
+			secondByte:     0xE0,
+			lengthRecovery: 1200,
+			tsRecovery:     0xDEADBEEF,
+			protectedSSRC:  1234,
+			snBase:         65530,
+			offsets:        offsets,
+		}
+
+		b := make([]byte, h.marshalSize())
+		assert.Equal(t, len(b), h.marshalTo(b))
+
+		return h, b
+	}, func(b []byte) (interface{}, int, error) {
+		h := fecHeader{}
+		n, err := h.unmarshal(b)
+
+		return h, n, err
+	})
+
+	_, err := (&fecHeader{}).unmarshal(make([]byte, headerSize-1))
+	assert.Equal(t, errPacketTooShort, err)
+}
+
+func TestDecoder_Recover(t *testing.T) {
+	for _, lost := range []uint16{65534, 65535, 0, 1} {
+		e := newEncoder(FECStream{ProtectedSSRC: fectest.ProtectedSSRC, SSRC: 5678}, 5, 1)
+		d := newDecoder(fectest.ProtectedSSRC)
+
+		var fecPackets []*rtp.Packet
+		var media []*rtp.Packet
+		for i := 0; i < 5; i++ {
+			packet := fectest.MediaPacket(uint16(65533+i), make([]byte, 10+i))
+			for j := range packet.Payload {
+				packet.Payload[j] = byte(i * j)
+			}
+			if i == 2 {
+				packet.CSRC = []uint32{42, 43}
+			}
+
+			media = append(media, packet)
+			fecPackets = append(fecPackets, e.encode(&packet.Header, packet.Payload, 118)...)
+		}
+		assert.Len(t, fecPackets, 1)
+		assert.Equal(t, uint32(5678), fecPackets[0].SSRC)
+		assert.Equal(t, uint8(118), fecPackets[0].PayloadType)
+
+		var expected *rtp.Packet
+		for _, packet := range media {
+			if packet.SequenceNumber == lost {
+				expected = packet
+				continue
+			}
+			assert.True(t, d.pushMedia(packet))
+		}
+		assert.Nil(t, d.popRecovered())
+
+		assert.NoError(t, d.pushFEC(fecPackets[0]))
+		recovered := d.popRecovered()
+		if assert.NotNil(t, recovered) {
+			assert.Equal(t, expected, recovered)
+		}
+
+		// A late copy of the recovered packet is dropped
+		assert.False(t, d.pushMedia(expected))
+	}
+}
+
+func TestDecoder_InterleavedBurst(t *testing.T) {
+	e := newEncoder(FECStream{ProtectedSSRC: fectest.ProtectedSSRC, SSRC: 5678}, 6, 2)
+	d := newDecoder(fectest.ProtectedSSRC)
+
+	var fecPackets []*rtp.Packet
+	for i := uint16(0); i < 6; i++ {
+		packet := fectest.MediaPacket(i, []byte{byte(i), 0xAA, 0xBB})
+		fecPackets = append(fecPackets, e.encode(&packet.Header, packet.Payload, 118)...)
+
+		// Lose two consecutive packets
+		if i != 2 && i != 3 {
+			assert.True(t, d.pushMedia(packet))
+		}
+	}
+	assert.Len(t, fecPackets, 2)
+
+	for _, fecPacket := range fecPackets {
+		assert.NoError(t, d.pushFEC(fecPacket))
+	}
+
+	recovered := map[uint16][]byte{}
+	for packet := d.popRecovered(); packet != nil; packet = d.popRecovered() {
+		recovered[packet.SequenceNumber] = packet.Payload
+	}
+	assert.Equal(t, map[uint16][]byte{2: {2, 0xAA, 0xBB}, 3: {3, 0xAA, 0xBB}}, recovered)
+}
+
+func TestInterceptor(t *testing.T) {
+	factory, err := NewInterceptor(NumMediaPackets(2))
+	assert.NoError(t, err)
+
+	i, err := factory.NewInterceptor("")
+	assert.NoError(t, err)
+
+	fecStream := FECStream{ProtectedSSRC: fectest.ProtectedSSRC, SSRC: 5678}
+	mediaInfo := &interceptor.StreamInfo{SSRC: fecStream.ProtectedSSRC}
+	fecInfo := &interceptor.StreamInfo{SSRC: fecStream.SSRC, PayloadType: 118}
+	SetFECStream(mediaInfo, fecStream)
+	SetFECStream(fecInfo, fecStream)
+
+	// Sender, FEC packets are written on the repair flow
+	var written []*rtp.Packet
+	record := interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, _ interceptor.Attributes) (int, error) {
+		written = append(written, &rtp.Packet{Header: header.Clone(), Payload: append([]byte{}, payload...)})
+		return header.MarshalSize() + len(payload), nil
+	})
+	i.BindLocalStream(fecInfo, record)
+	writer := i.BindLocalStream(mediaInfo, record)
+
+	for seq := uint16(0); seq < 2; seq++ {
+		packet := fectest.MediaPacket(seq, []byte{byte(seq), 0xFF})
+		_, err = writer.Write(&packet.Header, packet.Payload, nil)
+		assert.NoError(t, err)
+	}
+	assert.Len(t, written, 3)
+	assert.Equal(t, fecStream.SSRC, written[2].SSRC)
+
+	// Receiver, the first media packet is lost and returned before the next one read
+	queue := make(chan *rtp.Packet, 3)
+	reader := func(in []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		n, err := (<-queue).MarshalTo(in)
+		return n, a, err
+	}
+	fecReader := i.BindRemoteStream(fecInfo, interceptor.RTPReaderFunc(reader))
+	mediaReader := i.BindRemoteStream(mediaInfo, interceptor.RTPReaderFunc(reader))
+
+	queue <- written[1]
+	queue <- written[2]
+	queue <- fectest.MediaPacket(2, []byte{2, 0xFF})
+
+	b := make([]byte, 1500)
+	readSequenceNumber := func(r interceptor.RTPReader) uint16 {
+		n, _, readErr := r.Read(b, nil)
+		assert.NoError(t, readErr)
+
+		packet := &rtp.Packet{}
+		assert.NoError(t, packet.Unmarshal(b[:n]))
+		return packet.SequenceNumber
+	}
+
+	assert.Equal(t, uint16(1), readSequenceNumber(mediaReader))
+	readSequenceNumber(fecReader)
+	assert.Equal(t, uint16(0), readSequenceNumber(mediaReader))
+	assert.Equal(t, uint16(2), readSequenceNumber(mediaReader))
+
+	i.UnbindRemoteStream(mediaInfo)
+	i.UnbindLocalStream(fecInfo)
+	assert.NoError(t, i.Close())
+}
@@ -0,0 +1,199 @@
+package flexfec
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/pion/rtp"
+)
+
+var (
+	errPacketTooShort       = errors.New("flexfec: packet is too short")
+	errUnsupportedFECScheme = errors.New("flexfec: retransmission and flexible mask FEC packets are not supported")
+	errUnsupportedSSRCCount = errors.New("flexfec: only FEC packets protecting a single SSRC are supported")
+)
+
+// protectedPacket is the part of a media packet covered by FEC. Header extensions and padding
+// are excluded, they are rewritten by interceptors after FEC has been computed.
+type protectedPacket struct {
+	sequenceNumber uint16
+	marker         bool
+	payloadType    uint8
+	timestamp      uint32
+	csrcCount      uint8
+	// body is the CSRC list followed by the payload
+	body []byte
+}
+
+func newProtectedPacket(header *rtp.Header, payload []byte) protectedPacket {
+	body := make([]byte, 4*len(header.CSRC)+len(payload))
+	for i, csrc := range header.CSRC {
+		binary.BigEndian.PutUint32(body[4*i:], csrc)
+	}
+	copy(body[4*len(header.CSRC):], payload)
+
+	return protectedPacket{
+		sequenceNumber: header.SequenceNumber,
+		marker:         header.Marker,
+		payloadType:    header.PayloadType,
+		timestamp:      header.Timestamp,
+		csrcCount:      uint8(len(header.CSRC)),
+		body:           body,
+	}
+}
+
+func (p *protectedPacket) secondByte() uint8 {
+	b := p.payloadType & 0x7F
+	if p.marker {
+		b |= 0x80
+	}
+	return b
+}
+
+// fecHeader is the FlexFEC header of a FEC packet with fixed masks (R=0, F=0)
+//
+//	 0                   1                   2                   3
+//	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|R|F|P|X|  CC   |M| PT recovery |        length recovery        |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                          TS recovery                          |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|   SSRCCount   |                    reserved                   |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                             SSRC_i                            |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|           SN base_i           |k|          Mask [0-14]        |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|k|                   Mask [15-45] (optional)                   |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|k|                                                             |
+//	+-+                   Mask [46-108] (optional)                  |
+//	|                                                               |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+type fecHeader struct {
+	firstByte      uint8
+	secondByte     uint8
+	lengthRecovery uint16
+	tsRecovery     uint32
+	protectedSSRC  uint32
+	snBase         uint16
+	// offsets are the protected sequence numbers relative to snBase
+	offsets []uint16
+}
+
+func (h *fecHeader) marshalSize() int {
+	maxOffset := uint16(0)
+	for _, offset := range h.offsets {
+		if offset > maxOffset {
+			maxOffset = offset
+		}
+	}
+
+	switch {
+	case maxOffset < 15:
+		return headerSize
+	case maxOffset < 46:
+		return headerSize + 4
+	default:
+		return headerSize + 12
+	}
+}
+
+func (h *fecHeader) marshalTo(b []byte) int {
+	size := h.marshalSize()
+
+	b[0] = h.firstByte & 0x3F
+	b[1] = h.secondByte
+	binary.BigEndian.PutUint16(b[2:], h.lengthRecovery)
+	binary.BigEndian.PutUint32(b[4:], h.tsRecovery)
+	b[8] = 1
+	b[9], b[10], b[11] = 0, 0, 0
+	binary.BigEndian.PutUint32(b[12:], h.protectedSSRC)
+	binary.BigEndian.PutUint16(b[16:], h.snBase)
+
+	var mask0 uint16
+	var mask1 uint32
+	var mask2 uint64
+	for _, offset := range h.offsets {
+		switch {
+		case offset < 15:
+			mask0 |= 1 << (14 - offset)
+		case offset < 46:
+			mask1 |= 1 << (30 - (offset - 15))
+		default:
+			mask2 |= 1 << (62 - (offset - 46))
+		}
+	}
+
+	switch size {
+	case headerSize:
+		binary.BigEndian.PutUint16(b[18:], 0x8000|mask0)
+	case headerSize + 4:
+		binary.BigEndian.PutUint16(b[18:], mask0)
+		binary.BigEndian.PutUint32(b[20:], 0x80000000|mask1)
+	default:
+		binary.BigEndian.PutUint16(b[18:], mask0)
+		binary.BigEndian.PutUint32(b[20:], mask1)
+		binary.BigEndian.PutUint64(b[24:], 0x8000000000000000|mask2)
+	}
+
+	return size
+}
+
+// unmarshal parses the FlexFEC header and returns its size
+func (h *fecHeader) unmarshal(b []byte) (int, error) {
+	if len(b) < headerSize {
+		return 0, errPacketTooShort
+	}
+
+	if b[0]&0xC0 != 0 {
+		return 0, errUnsupportedFECScheme
+	}
+	if b[8] != 1 {
+		return 0, errUnsupportedSSRCCount
+	}
+
+	h.firstByte = b[0]
+	h.secondByte = b[1]
+	h.lengthRecovery = binary.BigEndian.Uint16(b[2:])
+	h.tsRecovery = binary.BigEndian.Uint32(b[4:])
+	h.protectedSSRC = binary.BigEndian.Uint32(b[12:])
+	h.snBase = binary.BigEndian.Uint16(b[16:])
+	h.offsets = h.offsets[:0]
+
+	mask0 := binary.BigEndian.Uint16(b[18:])
+	for i := uint16(0); i < 15; i++ {
+		if mask0&(1<<(14-i)) != 0 {
+			h.offsets = append(h.offsets, i)
+		}
+	}
+	if mask0&0x8000 != 0 {
+		return headerSize, nil
+	}
+
+	if len(b) < headerSize+4 {
+		return 0, errPacketTooShort
+	}
+	mask1 := binary.BigEndian.Uint32(b[20:])
+	for i := uint16(0); i < 31; i++ {
+		if mask1&(1<<(30-i)) != 0 {
+			h.offsets = append(h.offsets, 15+i)
+		}
+	}
+	if mask1&0x80000000 != 0 {
+		return headerSize + 4, nil
+	}
+
+	if len(b) < headerSize+12 {
+		return 0, errPacketTooShort
+	}
+	mask2 := binary.BigEndian.Uint64(b[24:])
+	for i := uint16(0); i < 63; i++ {
+		if mask2&(1<<(62-i)) != 0 {
+			h.offsets = append(h.offsets, 46+i)
+		}
+	}
+
+	return headerSize + 12, nil
+}
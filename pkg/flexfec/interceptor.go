@@ -0,0 +1,185 @@
+package flexfec
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/logging"
+	"github.com/pion/rtp"
+)
+
+var errInvalidProtection = errors.New("flexfec: invalid number of media or FEC packets")
+
+// InterceptorFactory is a interceptor.Factory for a Interceptor
+type InterceptorFactory struct {
+	opts []Option
+}
+
+// NewInterceptor constructs a new InterceptorFactory
+func NewInterceptor(opts ...Option) (*InterceptorFactory, error) {
+	return &InterceptorFactory{opts: opts}, nil
+}
+
+// NewInterceptor constructs a new Interceptor
+func (f *InterceptorFactory) NewInterceptor(id string) (interceptor.Interceptor, error) {
+	i := &Interceptor{
+		numMediaPackets: 5,
+		numFECPackets:   1,
+		log:             logging.NewDefaultLoggerFactory().NewLogger("flexfec"),
+		fecWriters:      map[uint32]*fecWriter{},
+		decoders:        map[uint32]*decoder{},
+	}
+
+	for _, opt := range f.opts {
+		if err := opt(i); err != nil {
+			return nil, err
+		}
+	}
+
+	if i.numMediaPackets < 1 || i.numFECPackets < 1 || i.numFECPackets > i.numMediaPackets ||
+		i.numMediaPackets > maxProtectedPackets {
+		return nil, errInvalidProtection
+	}
+
+	return i, nil
+}
+
+// Interceptor sends FEC packets on the repair flow of local streams, and recovers lost
+// packets of remote streams before they are read. Streams are only processed when their
+// StreamInfo carries a FECStream, see SetFECStream.
+type Interceptor struct {
+	interceptor.NoOp
+	numMediaPackets int
+	numFECPackets   int
+	log             logging.LeveledLogger
+
+	mu         sync.Mutex
+	fecWriters map[uint32]*fecWriter // keyed by protected SSRC
+	decoders   map[uint32]*decoder   // keyed by protected SSRC
+}
+
+type fecWriter struct {
+	writer      interceptor.RTPWriter
+	payloadType uint8
+}
+
+// BindLocalStream lets you modify any outgoing RTP packets. It is called once for per LocalStream. The returned method
+// will be called once per rtp packet.
+func (i *Interceptor) BindLocalStream(info *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+	fecStream, ok := getFECStream(info)
+	if !ok {
+		return writer
+	}
+
+	if info.SSRC == fecStream.SSRC {
+		i.mu.Lock()
+		i.fecWriters[fecStream.ProtectedSSRC] = &fecWriter{writer: writer, payloadType: info.PayloadType}
+		i.mu.Unlock()
+		return writer
+	}
+
+	encoder := newEncoder(fecStream, i.numMediaPackets, i.numFECPackets)
+	return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+		i.mu.Lock()
+		fecWriter := i.fecWriters[fecStream.ProtectedSSRC]
+		i.mu.Unlock()
+
+		// Interceptors further down may modify the header, so FEC is computed before writing
+		var fecPackets []*rtp.Packet
+		if fecWriter != nil {
+			fecPackets = encoder.encode(header, payload, fecWriter.payloadType)
+		}
+
+		n, err := writer.Write(header, payload, attributes)
+		if err != nil {
+			return n, err
+		}
+
+		for _, fecPacket := range fecPackets {
+			if _, fecErr := fecWriter.writer.Write(&fecPacket.Header, fecPacket.Payload, interceptor.Attributes{}); fecErr != nil {
+				i.log.Warnf("failed sending FEC packet: %v", fecErr)
+			}
+		}
+
+		return n, nil
+	})
+}
+
+// UnbindLocalStream is called when the Stream is removed. It can be used to clean up any data related to that track.
+func (i *Interceptor) UnbindLocalStream(info *interceptor.StreamInfo) {
+	if fecStream, ok := getFECStream(info); ok && info.SSRC == fecStream.SSRC {
+		i.mu.Lock()
+		delete(i.fecWriters, fecStream.ProtectedSSRC)
+		i.mu.Unlock()
+	}
+}
+
+// BindRemoteStream lets you modify any incoming RTP packets. It is called once for per RemoteStream. The returned method
+// will be called once per rtp packet.
+func (i *Interceptor) BindRemoteStream(info *interceptor.StreamInfo, reader interceptor.RTPReader) interceptor.RTPReader {
+	fecStream, ok := getFECStream(info)
+	if !ok {
+		return reader
+	}
+
+	i.mu.Lock()
+	d, ok := i.decoders[fecStream.ProtectedSSRC]
+	if !ok {
+		d = newDecoder(fecStream.ProtectedSSRC)
+		i.decoders[fecStream.ProtectedSSRC] = d
+	}
+	i.mu.Unlock()
+
+	if info.SSRC == fecStream.SSRC {
+		return interceptor.RTPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+			n, attr, err := reader.Read(b, a)
+			if err != nil {
+				return n, attr, err
+			}
+
+			packet := &rtp.Packet{}
+			if err = packet.Unmarshal(b[:n]); err != nil {
+				return n, attr, nil
+			}
+			if err = d.pushFEC(packet); err != nil {
+				i.log.Debugf("dropping FEC packet: %v", err)
+			}
+
+			return n, attr, nil
+		})
+	}
+
+	return interceptor.RTPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		for {
+			if recovered := d.popRecovered(); recovered != nil {
+				n, err := recovered.MarshalTo(b)
+				return n, a, err
+			}
+
+			n, attr, err := reader.Read(b, a)
+			if err != nil {
+				return n, attr, err
+			}
+
+			packet := &rtp.Packet{}
+			if err = packet.Unmarshal(append([]byte{}, b[:n]...)); err != nil {
+				return n, attr, nil
+			}
+
+			// Packets that have already been recovered are dropped
+			if d.pushMedia(packet) {
+				return n, attr, nil
+			}
+		}
+	})
+}
+
+// UnbindRemoteStream is called when the Stream is removed. It can be used to clean up any data related to that track.
+func (i *Interceptor) UnbindRemoteStream(info *interceptor.StreamInfo) {
+	if fecStream, ok := getFECStream(info); ok && info.SSRC == fecStream.ProtectedSSRC {
+		i.mu.Lock()
+		delete(i.decoders, fecStream.ProtectedSSRC)
+		i.mu.Unlock()
+	}
+}
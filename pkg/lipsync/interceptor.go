@@ -0,0 +1,77 @@
+package lipsync
+
+import (
+	"github.com/pion/interceptor"
+)
+
+// NewPeerConnectionCallback is called with the Interceptor built for each PeerConnection
+type NewPeerConnectionCallback func(id string, i *Interceptor)
+
+// InterceptorFactory is a interceptor.Factory for a Interceptor
+type InterceptorFactory struct {
+	addPeerConnection NewPeerConnectionCallback
+}
+
+// NewInterceptor constructs a new InterceptorFactory
+func NewInterceptor() (*InterceptorFactory, error) {
+	return &InterceptorFactory{}, nil
+}
+
+// OnNewPeerConnection sets a callback that is called when a new Interceptor is created
+func (f *InterceptorFactory) OnNewPeerConnection(cb NewPeerConnectionCallback) {
+	f.addPeerConnection = cb
+}
+
+// NewInterceptor constructs a new Interceptor
+func (f *InterceptorFactory) NewInterceptor(id string) (interceptor.Interceptor, error) {
+	i := &Interceptor{Synchronizer: NewSynchronizer()}
+
+	if f.addPeerConnection != nil {
+		f.addPeerConnection(id, i)
+	}
+
+	return i, nil
+}
+
+// Interceptor is a Synchronizer fed with the clock rates of the remote streams
+// of a PeerConnection, and with the Sender Reports as the RTCP is read.
+type Interceptor struct {
+	interceptor.NoOp
+	*Synchronizer
+}
+
+// BindRTCPReader lets you modify any incoming RTCP packets. It is called once per sender/receiver, however this might
+// change in the future. The returned method will be called once per packet batch.
+func (i *Interceptor) BindRTCPReader(reader interceptor.RTCPReader) interceptor.RTCPReader {
+	return interceptor.RTCPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		n, attr, err := reader.Read(b, a)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if attr == nil {
+			attr = make(interceptor.Attributes)
+		}
+		pkts, err := attr.GetRTCPPackets(b[:n])
+		if err != nil {
+			return 0, nil, err
+		}
+
+		i.HandleRTCP(pkts)
+
+		return n, attr, nil
+	})
+}
+
+// BindRemoteStream lets you modify any incoming RTP packets. It is called once for per RemoteStream. The returned method
+// will be called once per rtp packet.
+func (i *Interceptor) BindRemoteStream(info *interceptor.StreamInfo, reader interceptor.RTPReader) interceptor.RTPReader {
+	i.AddStream(info.SSRC, info.ClockRate)
+
+	return reader
+}
+
+// UnbindRemoteStream is called when the Stream is removed. It can be used to clean up any data related to that track.
+func (i *Interceptor) UnbindRemoteStream(info *interceptor.StreamInfo) {
+	i.RemoveStream(info.SSRC)
+}
@@ -0,0 +1,90 @@
+package lipsync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3/internal/ntp"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSynchronizer(t *testing.T) {
+	s := NewSynchronizer()
+	reference := time.Unix(1600000000, 0)
+	ntpTime := uint64(1600000000+ntp.EpochOffset) << 32
+
+	// Nothing is known before the clock rate and a Sender Report
+	_, ok := s.CaptureTime(1, 0)
+	assert.False(t, ok)
+	s.AddStream(1, 90000)
+	_, ok = s.CaptureTime(1, 0)
+	assert.False(t, ok)
+
+	// Audio and video sampled at the same time have the same capture time
+	s.AddStream(2, 48000)
+	s.HandleRTCP([]rtcp.Packet{
+		&rtcp.SenderReport{SSRC: 1, NTPTime: ntpTime, RTPTime: 4294967000},
+		&rtcp.ReceiverReport{SSRC: 3},
+		&rtcp.SenderReport{SSRC: 2, NTPTime: ntpTime, RTPTime: 1000},
+	})
+
+	// Timestamps wrap around
+	captureTime, ok := s.CaptureTime(1, 8704)
+	assert.True(t, ok)
+	assert.Equal(t, reference.Add(100*time.Millisecond), captureTime)
+
+	captureTime, ok = s.CaptureTime(1, 89704)
+	assert.True(t, ok)
+	assert.Equal(t, reference.Add(time.Second), captureTime)
+
+	captureTime, ok = s.CaptureTime(2, 1000-960)
+	assert.True(t, ok)
+	assert.Equal(t, reference.Add(-20*time.Millisecond), captureTime)
+
+	sample := &media.Sample{PacketTimestamp: 1000 + 4800}
+	assert.True(t, s.SetCaptureTime(2, sample))
+	assert.Equal(t, reference.Add(100*time.Millisecond), sample.CaptureTime)
+
+	s.RemoveStream(2)
+	assert.False(t, s.SetCaptureTime(2, &media.Sample{}))
+}
+
+func TestInterceptor(t *testing.T) {
+	factory, err := NewInterceptor()
+	assert.NoError(t, err)
+
+	var built *Interceptor
+	factory.OnNewPeerConnection(func(_ string, i *Interceptor) {
+		built = i
+	})
+
+	i, err := factory.NewInterceptor("")
+	assert.NoError(t, err)
+	assert.Equal(t, i, built)
+
+	info := &interceptor.StreamInfo{SSRC: 1234, ClockRate: 90000}
+	i.BindRemoteStream(info, interceptor.RTPReaderFunc(func(in []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		return 0, a, nil
+	}))
+
+	sr, err := (&rtcp.SenderReport{SSRC: 1234, NTPTime: uint64(1600000000+ntp.EpochOffset) << 32, RTPTime: 90000}).Marshal()
+	assert.NoError(t, err)
+	rtcpReader := i.BindRTCPReader(interceptor.RTCPReaderFunc(func(in []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		return copy(in, sr), a, nil
+	}))
+	_, _, err = rtcpReader.Read(make([]byte, 1500), nil)
+	assert.NoError(t, err)
+
+	captureTime, ok := built.CaptureTime(1234, 180000)
+	assert.True(t, ok)
+	assert.Equal(t, time.Unix(1600000001, 0), captureTime)
+
+	i.UnbindRemoteStream(info)
+	_, ok = built.CaptureTime(1234, 180000)
+	assert.False(t, ok)
+
+	assert.NoError(t, i.Close())
+}
@@ -0,0 +1,111 @@
+// Package lipsync maps the RTP timestamps of remote streams onto the NTP clock
+// of their sender, using the RTCP Sender Reports. Streams sent by the same
+// peer, like the audio and video tracks of a StreamID, share this clock.
+package lipsync
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3/internal/ntp"
+	"github.com/pion/webrtc/v3/pkg/media"
+)
+
+type stream struct {
+	clockRate uint32
+
+	hasSenderReport bool
+	ntpTime         time.Time
+	rtpTime         uint32
+}
+
+// Synchronizer keeps the last Sender Report of each stream, and converts their
+// RTP timestamps to capture times
+type Synchronizer struct {
+	mu      sync.Mutex
+	streams map[uint32]*stream
+}
+
+// NewSynchronizer constructs a new Synchronizer
+func NewSynchronizer() *Synchronizer {
+	return &Synchronizer{streams: map[uint32]*stream{}}
+}
+
+// NTPToTime converts a 64 bits NTP timestamp to a time.Time
+func NTPToTime(timestamp uint64) time.Time {
+	return ntp.ToTime(timestamp)
+}
+
+func (s *Synchronizer) getStream(ssrc uint32) *stream {
+	st, ok := s.streams[ssrc]
+	if !ok {
+		st = &stream{}
+		s.streams[ssrc] = st
+	}
+
+	return st
+}
+
+// AddStream sets the clock rate of the RTP timestamps of a stream
+func (s *Synchronizer) AddStream(ssrc, clockRate uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.getStream(ssrc).clockRate = clockRate
+}
+
+// RemoveStream forgets a stream
+func (s *Synchronizer) RemoveStream(ssrc uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.streams, ssrc)
+}
+
+// HandleRTCP handles the Sender Reports among RTCP packets, as returned by
+// RTPReceiver.ReadRTCP
+func (s *Synchronizer) HandleRTCP(pkts []rtcp.Packet) {
+	for _, pkt := range pkts {
+		if sr, ok := pkt.(*rtcp.SenderReport); ok {
+			s.HandleSenderReport(sr)
+		}
+	}
+}
+
+// HandleSenderReport updates the mapping of the RTP timestamps of the stream
+// of the Sender Report
+func (s *Synchronizer) HandleSenderReport(sr *rtcp.SenderReport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.getStream(sr.SSRC)
+	st.hasSenderReport, st.ntpTime, st.rtpTime = true, NTPToTime(sr.NTPTime), sr.RTPTime
+}
+
+// CaptureTime returns the capture time of a RTP timestamp, on the NTP clock of
+// the sender. It is false until the stream has a clock rate and a Sender Report.
+func (s *Synchronizer) CaptureTime(ssrc, rtpTimestamp uint32) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.streams[ssrc]
+	if !ok || !st.hasSenderReport || st.clockRate == 0 {
+		return time.Time{}, false
+	}
+
+	// The timestamp may be before or after the one of the report
+	ticks := int64(int32(rtpTimestamp - st.rtpTime))
+	return st.ntpTime.Add(time.Duration(ticks) * time.Second / time.Duration(st.clockRate)), true
+}
+
+// SetCaptureTime sets the CaptureTime of a sample of the stream from its
+// PacketTimestamp, it returns false if the capture time is not known yet
+func (s *Synchronizer) SetCaptureTime(ssrc uint32, sample *media.Sample) bool {
+	captureTime, ok := s.CaptureTime(ssrc, sample.PacketTimestamp)
+	if ok {
+		sample.CaptureTime = captureTime
+	}
+
+	return ok
+}
@@ -0,0 +1,113 @@
+package h265reader
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func CreateReader(h265 []byte, require *require.Assertions) *H265Reader {
+	reader, err := NewReader(bytes.NewReader(h265))
+
+	require.Nil(err)
+	require.NotNil(reader)
+
+	return reader
+}
+
+func TestDataDoesNotStartWithH265Header(t *testing.T) {
+	require := require.New(t)
+
+	testFunction := func(input []byte, expectedErr error) {
+		reader := CreateReader(input, require)
+		nal, err := reader.NextNAL()
+		require.ErrorIs(err, expectedErr)
+		require.Nil(nal)
+	}
+
+	testFunction([]byte{2}, io.EOF)
+	testFunction([]byte{0, 2}, io.EOF)
+	testFunction([]byte{0, 0, 2}, io.EOF)
+	testFunction([]byte{0, 0, 2, 0}, errDataIsNotH265Stream)
+	testFunction([]byte{0, 0, 0, 2}, errDataIsNotH265Stream)
+}
+
+func TestParseHeader(t *testing.T) {
+	require := require.New(t)
+	h265Bytes := []byte{0x0, 0x0, 0x1, 0x40, 0x01, 0x0c}
+
+	reader := CreateReader(h265Bytes, require)
+
+	nal, err := reader.NextNAL()
+	require.Nil(err)
+
+	require.Equal(3, len(nal.Data))
+	require.False(nal.ForbiddenZeroBit)
+	require.Equal(NalUnitTypeVPS, nal.UnitType)
+	require.Equal(uint8(0), nal.LayerID)
+	require.Equal(uint8(1), nal.TemporalIDPlus1)
+	require.False(nal.UnitType.IsIRAP())
+}
+
+func TestEOF(t *testing.T) {
+	require := require.New(t)
+
+	testFunction := func(input []byte) {
+		reader := CreateReader(input, require)
+
+		nal, err := reader.NextNAL()
+		require.Equal(io.EOF, err)
+		require.Nil(nal)
+	}
+
+	testFunction([]byte{0, 0, 0, 1})
+	testFunction([]byte{0, 0, 1})
+	testFunction([]byte{})
+}
+
+func TestSEI(t *testing.T) {
+	require := require.New(t)
+	h265Bytes := []byte{
+		0x0, 0x0, 0x0, 0x1, 0x4e, 0x01, 0xAB,
+		0x0, 0x0, 0x0, 0x1, 0x26, 0x01, 0xAB,
+	}
+
+	reader := CreateReader(h265Bytes, require)
+
+	nal, err := reader.NextNAL()
+	require.Nil(err)
+	require.Equal(NalUnitTypePrefixSEI, nal.UnitType)
+
+	nal, err = reader.NextNAL()
+	require.Nil(err)
+	require.Equal(NalUnitTypeIdrWRadl, nal.UnitType)
+	require.True(nal.UnitType.IsIRAP())
+	require.Equal([]byte{0x26, 0x01, 0xAB}, nal.Data)
+
+	nal, err = reader.NextNAL()
+	require.Equal(io.EOF, err)
+	require.Nil(nal)
+}
+
+func TestIssue1734_NextNal(t *testing.T) {
+	tt := [...][]byte{
+		[]byte("\x00\x00\x010\x00\x00\x01\x00\x00\x01"),
+		[]byte("\x00\x00\x00\x01\x00\x00\x01"),
+	}
+
+	for _, cur := range tt {
+		r, err := NewReader(bytes.NewReader(cur))
+		require.NoError(t, err)
+
+		// Just make sure it doesn't crash
+		for {
+			nal, err := r.NextNAL()
+
+			if err != nil || nal == nil {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package h265reader
+
+import "strconv"
+
+// NalUnitType is the type of a NAL
+type NalUnitType uint8
+
+// Enums for NalUnitTypes
+// https://www.itu.int/rec/T-REC-H.265 Table 7-1
+const (
+	NalUnitTypeTrailN      NalUnitType = 0  // Coded slice of a non-TSA, non-STSA trailing picture, non-reference
+	NalUnitTypeTrailR      NalUnitType = 1  // Coded slice of a non-TSA, non-STSA trailing picture, reference
+	NalUnitTypeTsaN        NalUnitType = 2  // Coded slice of a TSA picture, non-reference
+	NalUnitTypeTsaR        NalUnitType = 3  // Coded slice of a TSA picture, reference
+	NalUnitTypeStsaN       NalUnitType = 4  // Coded slice of a STSA picture, non-reference
+	NalUnitTypeStsaR       NalUnitType = 5  // Coded slice of a STSA picture, reference
+	NalUnitTypeRadlN       NalUnitType = 6  // Coded slice of a RADL picture, non-reference
+	NalUnitTypeRadlR       NalUnitType = 7  // Coded slice of a RADL picture, reference
+	NalUnitTypeRaslN       NalUnitType = 8  // Coded slice of a RASL picture, non-reference
+	NalUnitTypeRaslR       NalUnitType = 9  // Coded slice of a RASL picture, reference
+	NalUnitTypeBlaWLp      NalUnitType = 16 // Coded slice of a BLA picture with leading pictures
+	NalUnitTypeBlaWRadl    NalUnitType = 17 // Coded slice of a BLA picture with RADL pictures
+	NalUnitTypeBlaNLp      NalUnitType = 18 // Coded slice of a BLA picture without leading pictures
+	NalUnitTypeIdrWRadl    NalUnitType = 19 // Coded slice of an IDR picture with RADL pictures
+	NalUnitTypeIdrNLp      NalUnitType = 20 // Coded slice of an IDR picture without leading pictures
+	NalUnitTypeCraNut      NalUnitType = 21 // Coded slice of a CRA picture
+	NalUnitTypeVPS         NalUnitType = 32 // Video parameter set
+	NalUnitTypeSPS         NalUnitType = 33 // Sequence parameter set
+	NalUnitTypePPS         NalUnitType = 34 // Picture parameter set
+	NalUnitTypeAUD         NalUnitType = 35 // Access unit delimiter
+	NalUnitTypeEndOfSeq    NalUnitType = 36 // End of sequence
+	NalUnitTypeEndOfStream NalUnitType = 37 // End of bitstream
+	NalUnitTypeFiller      NalUnitType = 38 // Filler data
+	NalUnitTypePrefixSEI   NalUnitType = 39 // Supplemental enhancement information (SEI), prefix
+	NalUnitTypeSuffixSEI   NalUnitType = 40 // Supplemental enhancement information (SEI), suffix
+	// 10..15                               // Reserved non-IRAP
+	// 22..23                               // Reserved IRAP
+	// 24..31                               // Reserved non-IRAP
+	// 41..47                               // Reserved
+	// 48..63                               // Unspecified
+)
+
+// IsIRAP returns true for the types of the intra random access point pictures,
+// that can be decoded without any previous picture
+func (n NalUnitType) IsIRAP() bool {
+	return n >= NalUnitTypeBlaWLp && n <= 23
+}
+
+func (n *NalUnitType) String() string {
+	var str string
+	switch *n {
+	case NalUnitTypeTrailN:
+		str = "TrailN"
+	case NalUnitTypeTrailR:
+		str = "TrailR"
+	case NalUnitTypeTsaN:
+		str = "TsaN"
+	case NalUnitTypeTsaR:
+		str = "TsaR"
+	case NalUnitTypeStsaN:
+		str = "StsaN"
+	case NalUnitTypeStsaR:
+		str = "StsaR"
+	case NalUnitTypeRadlN:
+		str = "RadlN"
+	case NalUnitTypeRadlR:
+		str = "RadlR"
+	case NalUnitTypeRaslN:
+		str = "RaslN"
+	case NalUnitTypeRaslR:
+		str = "RaslR"
+	case NalUnitTypeBlaWLp:
+		str = "BlaWLp"
+	case NalUnitTypeBlaWRadl:
+		str = "BlaWRadl"
+	case NalUnitTypeBlaNLp:
+		str = "BlaNLp"
+	case NalUnitTypeIdrWRadl:
+		str = "IdrWRadl"
+	case NalUnitTypeIdrNLp:
+		str = "IdrNLp"
+	case NalUnitTypeCraNut:
+		str = "CraNut"
+	case NalUnitTypeVPS:
+		str = "VPS"
+	case NalUnitTypeSPS:
+		str = "SPS"
+	case NalUnitTypePPS:
+		str = "PPS"
+	case NalUnitTypeAUD:
+		str = "AUD"
+	case NalUnitTypeEndOfSeq:
+		str = "EndOfSeq"
+	case NalUnitTypeEndOfStream:
+		str = "EndOfStream"
+	case NalUnitTypeFiller:
+		str = "Filler"
+	case NalUnitTypePrefixSEI:
+		str = "PrefixSEI"
+	case NalUnitTypeSuffixSEI:
+		str = "SuffixSEI"
+	default:
+		str = "Unknown"
+	}
+	str = str + "(" + strconv.FormatInt(int64(*n), 10) + ")"
+	return str
+}
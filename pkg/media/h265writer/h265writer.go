@@ -0,0 +1,79 @@
+// Package h265writer implements H265 media container writer
+package h265writer
+
+import (
+	"io"
+	"os"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/rtpcodecs"
+)
+
+type (
+	// H265Writer is used to take RTP packets, parse them and
+	// write the data to an io.Writer as an Annex-B byte stream.
+	// Single NAL unit packets, aggregation packets (48) and
+	// fragmentation units (49) are supported, PACI packets are not.
+	// https://www.rfc-editor.org/rfc/rfc7798#section-4.4
+	H265Writer struct {
+		writer       io.Writer
+		hasKeyFrame  bool
+		depacketizer *rtpcodecs.H265Depacketizer
+	}
+)
+
+// New builds a new H265 writer
+func New(filename string) (*H265Writer, error) {
+	f, err := os.Create(filename) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWith(f), nil
+}
+
+// NewWith initializes a new H265 writer with an io.Writer output
+func NewWith(w io.Writer) *H265Writer {
+	return &H265Writer{
+		writer: w,
+	}
+}
+
+// WriteRTP adds a new packet and writes the appropriate headers for it
+func (h *H265Writer) WriteRTP(packet *rtp.Packet) error {
+	if len(packet.Payload) == 0 {
+		return nil
+	}
+
+	if !h.hasKeyFrame {
+		if h.hasKeyFrame = rtpcodecs.H265IsKeyFrame(packet.Payload); !h.hasKeyFrame {
+			// key frame not defined yet. discarding packet
+			return nil
+		}
+	}
+
+	if h.depacketizer == nil {
+		h.depacketizer = &rtpcodecs.H265Depacketizer{}
+	}
+
+	data, err := h.depacketizer.Unmarshal(packet.Payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.writer.Write(data)
+
+	return err
+}
+
+// Close closes the underlying writer
+func (h *H265Writer) Close() error {
+	h.depacketizer = nil
+	if h.writer != nil {
+		if closer, ok := h.writer.(io.Closer); ok {
+			return closer.Close()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package h265writer
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+type writerCloser struct {
+	bytes.Buffer
+}
+
+var errClose = errors.New("close error")
+
+func (w *writerCloser) Close() error {
+	return errClose
+}
+
+func TestNewWith(t *testing.T) {
+	writer := &writerCloser{}
+	h265Writer := NewWith(writer)
+	assert.NotNil(t, h265Writer.Close())
+}
+
+func TestWriteRTP(t *testing.T) {
+	tests := []struct {
+		name        string
+		payload     []byte
+		hasKeyFrame bool
+		wantBytes   []byte
+		wantErr     error
+		reuseWriter bool
+	}{
+		{
+			"When given an empty payload; it should return nil",
+			[]byte{},
+			false,
+			[]byte{},
+			nil,
+			false,
+		},
+		{
+			"When no keyframe is defined; it should discard the packet",
+			[]byte{0x02, 0x01, 0x90},
+			false,
+			[]byte{},
+			nil,
+			false,
+		},
+		{
+			"When a VPS is given; it should start writing",
+			[]byte{0x40, 0x01, 0x0c},
+			false,
+			[]byte{0x00, 0x00, 0x00, 0x01, 0x40, 0x01, 0x0c},
+			nil,
+			false,
+		},
+		{
+			"When a valid Single NAL Unit packet is given; it should unpack it without error",
+			[]byte{0x02, 0x01, 0x90},
+			true,
+			[]byte{0x00, 0x00, 0x00, 0x01, 0x02, 0x01, 0x90},
+			nil,
+			false,
+		},
+		{
+			"When a valid aggregation packet is given; it should unpack it without error",
+			[]byte{0x60, 0x01, 0x00, 0x03, 0x40, 0x01, 0x0c, 0x00, 0x02, 0x42, 0x01},
+			true,
+			[]byte{0x00, 0x00, 0x00, 0x01, 0x40, 0x01, 0x0c, 0x00, 0x00, 0x00, 0x01, 0x42, 0x01},
+			nil,
+			false,
+		},
+		{
+			"When a valid FU start packet is given; it should unpack it without error",
+			[]byte{0x62, 0x01, 0x93, 0x90, 0x90},
+			true,
+			[]byte{},
+			nil,
+			true,
+		},
+		{
+			"When a valid FU end packet is given; it should unpack it without error",
+			[]byte{0x62, 0x01, 0x53, 0x90, 0x90},
+			true,
+			[]byte{0x00, 0x00, 0x00, 0x01, 0x26, 0x01, 0x90, 0x90, 0x90, 0x90},
+			nil,
+			false,
+		},
+	}
+
+	var reuseWriter *bytes.Buffer
+	var reuseH265Writer *H265Writer
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &bytes.Buffer{}
+			h265Writer := &H265Writer{
+				hasKeyFrame: tt.hasKeyFrame,
+				writer:      writer,
+			}
+			if reuseWriter != nil {
+				writer = reuseWriter
+			}
+			if reuseH265Writer != nil {
+				h265Writer = reuseH265Writer
+			}
+
+			assert.Equal(t, tt.wantErr, h265Writer.WriteRTP(&rtp.Packet{
+				Payload: tt.payload,
+			}))
+			assert.True(t, bytes.Equal(tt.wantBytes, writer.Bytes()))
+
+			if !tt.reuseWriter {
+				assert.Nil(t, h265Writer.Close())
+				reuseWriter = nil
+				reuseH265Writer = nil
+			} else {
+				reuseWriter = writer
+				reuseH265Writer = h265Writer
+			}
+		})
+	}
+}
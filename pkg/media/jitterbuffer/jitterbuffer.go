@@ -0,0 +1,315 @@
+// Package jitterbuffer provides a jitter buffer that schedules the playout of
+// media frames from RTP packets, with a delay that adapts to the network jitter.
+package jitterbuffer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/media"
+)
+
+const (
+	defaultMinDelay = 20 * time.Millisecond
+	defaultMaxDelay = time.Second
+
+	// The target delay is a multiple of the interarrival jitter
+	jitterDelayFactor = 4
+
+	// The target delay shrinks by 1/delayDecay of the excess at each frame
+	delayDecay = 64
+)
+
+// Stats are the statistics of a JitterBuffer
+type Stats struct {
+	// Jitter is the RFC 3550 interarrival jitter
+	Jitter time.Duration
+	// TargetDelay is the current playout delay
+	TargetDelay time.Duration
+	// BufferedFrames is the number of frames waiting for their playout time
+	BufferedFrames int
+
+	PacketsReceived  uint64
+	DuplicatePackets uint64
+	// PacketsLost is the number of packets missing when frames were played
+	PacketsLost uint64
+	// LatePackets is the number of packets dropped because their frame was
+	// already played
+	LatePackets uint64
+	// Underruns is the number of packets that arrived after their playout
+	// time, the target delay is increased for each of them
+	Underruns uint64
+	// Overruns is the number of frames dropped because the buffer held more
+	// than the maximum delay
+	Overruns uint64
+	// FramesPlayed is the number of frames returned by Pop
+	FramesPlayed uint64
+}
+
+type frame struct {
+	timestamp int64
+	sequences []int64
+	packets   []*rtp.Packet
+}
+
+// JitterBuffer reorders RTP packets into frames, and releases them at their
+// playout time. A frame is made of the packets with the same RTP timestamp.
+type JitterBuffer struct {
+	mu sync.Mutex
+
+	depacketizer rtp.Depacketizer
+	clockRate    uint32
+
+	minDelay, maxDelay time.Duration
+	targetDelay        time.Duration
+
+	// Unwrapped sequence number and timestamp of the last packet received
+	started                     bool
+	lastSequence, lastTimestamp int64
+	lastArrival                 time.Time
+	jitter                      float64
+
+	// Last sequence number and timestamp played, or dropped
+	hasPlayed                   bool
+	playedSequence, playedFrame int64
+	lastDuration                time.Duration
+
+	// base is the wall clock time of the timestamp 0, for the fastest packet
+	// received
+	base time.Time
+
+	// frames are sorted by timestamp
+	frames []*frame
+
+	stats Stats
+}
+
+// New constructs a new JitterBuffer, the depacketizer extracts the media of the
+// RTP packets, and the clock rate is the one of the RTP timestamps
+func New(depacketizer rtp.Depacketizer, clockRate uint32, opts ...Option) *JitterBuffer {
+	j := &JitterBuffer{
+		depacketizer: depacketizer,
+		clockRate:    clockRate,
+		minDelay:     defaultMinDelay,
+		maxDelay:     defaultMaxDelay,
+	}
+	for _, o := range opts {
+		o(j)
+	}
+	if j.maxDelay < j.minDelay {
+		j.maxDelay = j.minDelay
+	}
+	j.targetDelay = j.minDelay
+
+	return j
+}
+
+func (j *JitterBuffer) toDuration(ticks int64) time.Duration {
+	return time.Duration(ticks) * time.Second / time.Duration(j.clockRate)
+}
+
+func (j *JitterBuffer) playoutTime(timestamp int64) time.Time {
+	return j.base.Add(j.toDuration(timestamp) + j.targetDelay)
+}
+
+func (j *JitterBuffer) growDelay(delay time.Duration) {
+	j.targetDelay += delay
+	if j.targetDelay > j.maxDelay {
+		j.targetDelay = j.maxDelay
+	}
+}
+
+// Push adds a RTP packet received at the arrival time
+func (j *JitterBuffer) Push(packet *rtp.Packet, arrival time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.stats.PacketsReceived++
+
+	// Sequence numbers and timestamps are unwrapped relatively to the last packet
+	sequence, timestamp := int64(packet.SequenceNumber), int64(packet.Timestamp)
+	if j.started {
+		sequence = j.lastSequence + int64(int16(packet.SequenceNumber-uint16(j.lastSequence)))
+		timestamp = j.lastTimestamp + int64(int32(packet.Timestamp-uint32(j.lastTimestamp)))
+	}
+
+	// Interarrival jitter of RFC 3550 section 6.4.1, in timestamp units
+	if j.started {
+		d := arrival.Sub(j.lastArrival).Seconds()*float64(j.clockRate) - float64(timestamp-j.lastTimestamp)
+		if d < 0 {
+			d = -d
+		}
+		j.jitter += (d - j.jitter) / 16
+	}
+	j.started, j.lastSequence, j.lastTimestamp, j.lastArrival = true, sequence, timestamp, arrival
+
+	if base := arrival.Add(-j.toDuration(timestamp)); j.base.IsZero() || base.Before(j.base) {
+		j.base = base
+	}
+
+	if playout := j.playoutTime(timestamp); arrival.After(playout) {
+		j.stats.Underruns++
+		j.growDelay(arrival.Sub(playout))
+	}
+
+	if j.hasPlayed && (timestamp <= j.playedFrame || sequence <= j.playedSequence) {
+		j.stats.LatePackets++
+		return
+	}
+
+	j.insert(packet, sequence, timestamp)
+
+	// The oldest frames are dropped when the buffer holds more than the maximum delay
+	for len(j.frames) > 1 && j.toDuration(j.frames[len(j.frames)-1].timestamp-j.frames[0].timestamp) > j.maxDelay {
+		dropped := j.frames[0]
+		j.frames = j.frames[1:]
+		j.stats.Overruns++
+		j.hasPlayed = true
+		j.playedFrame, j.playedSequence = dropped.timestamp, dropped.sequences[len(dropped.sequences)-1]
+	}
+}
+
+func (j *JitterBuffer) insert(packet *rtp.Packet, sequence, timestamp int64) {
+	i := len(j.frames)
+	for i > 0 && j.frames[i-1].timestamp >= timestamp {
+		i--
+	}
+
+	if i == len(j.frames) || j.frames[i].timestamp != timestamp {
+		j.frames = append(j.frames, nil)
+		copy(j.frames[i+1:], j.frames[i:])
+		j.frames[i] = &frame{timestamp: timestamp}
+	}
+	f := j.frames[i]
+
+	k := len(f.sequences)
+	for k > 0 && f.sequences[k-1] >= sequence {
+		if f.sequences[k-1] == sequence {
+			j.stats.DuplicatePackets++
+			return
+		}
+		k--
+	}
+	f.sequences = append(f.sequences, 0)
+	copy(f.sequences[k+1:], f.sequences[k:])
+	f.sequences[k] = sequence
+	f.packets = append(f.packets, nil)
+	copy(f.packets[k+1:], f.packets[k:])
+	f.packets[k] = packet
+}
+
+// NextPlayout returns the playout time of the next frame, if there is one
+func (j *JitterBuffer) NextPlayout() (time.Time, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if len(j.frames) == 0 {
+		return time.Time{}, false
+	}
+
+	return j.playoutTime(j.frames[0].timestamp), true
+}
+
+// Pop returns the next frame if its playout time is reached, or nil. The
+// Timestamp of the sample is its playout time, and PrevDroppedPackets is the
+// number of packets missing since the previous frame.
+func (j *JitterBuffer) Pop(now time.Time) *media.Sample {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if len(j.frames) == 0 {
+		return nil
+	}
+	f := j.frames[0]
+	playout := j.playoutTime(f.timestamp)
+	if now.Before(playout) {
+		return nil
+	}
+	j.frames = j.frames[1:]
+
+	// Packets missing before and in the frame are lost
+	lost := int64(0)
+	previous := f.sequences[0] - 1
+	if j.hasPlayed {
+		previous = j.playedSequence
+	}
+	for _, sequence := range f.sequences {
+		lost += sequence - previous - 1
+		previous = sequence
+	}
+	j.stats.PacketsLost += uint64(lost)
+	if lost > 0xFFFF {
+		lost = 0xFFFF
+	}
+	j.hasPlayed, j.playedFrame, j.playedSequence = true, f.timestamp, previous
+
+	var data []byte
+	for _, packet := range f.packets {
+		payload, err := j.depacketizer.Unmarshal(packet.Payload)
+		if err != nil {
+			continue
+		}
+		data = append(data, payload...)
+	}
+
+	if len(j.frames) > 0 {
+		j.lastDuration = j.toDuration(j.frames[0].timestamp - f.timestamp)
+	}
+
+	// The target delay follows the jitter, it grows at once and shrinks slowly
+	desired := jitterDelayFactor * time.Duration(j.jitter*float64(time.Second)/float64(j.clockRate))
+	switch {
+	case desired < j.minDelay:
+		desired = j.minDelay
+	case desired > j.maxDelay:
+		desired = j.maxDelay
+	}
+	if desired > j.targetDelay {
+		j.targetDelay = desired
+	} else {
+		j.targetDelay -= (j.targetDelay - desired) / delayDecay
+	}
+
+	j.stats.FramesPlayed++
+
+	return &media.Sample{
+		Data:               data,
+		Timestamp:          playout,
+		Duration:           j.lastDuration,
+		PacketTimestamp:    uint32(f.timestamp),
+		PrevDroppedPackets: uint16(lost),
+	}
+}
+
+// Stats returns the statistics of the JitterBuffer
+func (j *JitterBuffer) Stats() Stats {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	stats := j.stats
+	stats.Jitter = time.Duration(j.jitter * float64(time.Second) / float64(j.clockRate))
+	stats.TargetDelay = j.targetDelay
+	stats.BufferedFrames = len(j.frames)
+
+	return stats
+}
+
+// An Option configures a JitterBuffer.
+type Option func(j *JitterBuffer)
+
+// WithMinDelay sets the minimum playout delay, and the initial one. The
+// default is 20ms.
+func WithMinDelay(delay time.Duration) Option {
+	return func(j *JitterBuffer) {
+		j.minDelay = delay
+	}
+}
+
+// WithMaxDelay sets the maximum playout delay, frames are dropped when the
+// buffer holds more. The default is 1s.
+func WithMaxDelay(delay time.Duration) Option {
+	return func(j *JitterBuffer) {
+		j.maxDelay = delay
+	}
+}
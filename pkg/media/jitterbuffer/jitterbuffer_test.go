@@ -0,0 +1,178 @@
+package jitterbuffer
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/stretchr/testify/assert"
+)
+
+const ms = time.Millisecond
+
+func opusPacket(sequenceNumber uint16) *rtp.Packet {
+	return &rtp.Packet{
+		Header:  rtp.Header{SequenceNumber: sequenceNumber, Timestamp: 1000 + uint32(sequenceNumber)*960},
+		Payload: []byte{0xFC, byte(sequenceNumber)},
+	}
+}
+
+func TestJitterBuffer_Playout(t *testing.T) {
+	start := time.Unix(1000, 0)
+	j := New(&codecs.OpusPacket{}, 48000)
+
+	for i := uint16(0); i < 5; i++ {
+		j.Push(opusPacket(i), start.Add(time.Duration(i)*20*ms))
+	}
+
+	// Frames are played with the minimum delay of 20ms
+	assert.Nil(t, j.Pop(start.Add(19*ms)))
+	for i := uint16(0); i < 4; i++ {
+		now := start.Add(time.Duration(i)*20*ms + 20*ms)
+		sample := j.Pop(now)
+		if !assert.NotNil(t, sample) {
+			return
+		}
+		assert.Equal(t, []byte{0xFC, byte(i)}, sample.Data)
+		assert.Equal(t, now, sample.Timestamp)
+		assert.Equal(t, 20*ms, sample.Duration)
+		assert.Equal(t, 1000+uint32(i)*960, sample.PacketTimestamp)
+		assert.Nil(t, j.Pop(now))
+	}
+
+	stats := j.Stats()
+	assert.Equal(t, time.Duration(0), stats.Jitter)
+	assert.Equal(t, 20*ms, stats.TargetDelay)
+	assert.Equal(t, 1, stats.BufferedFrames)
+	assert.Equal(t, uint64(5), stats.PacketsReceived)
+	assert.Equal(t, uint64(4), stats.FramesPlayed)
+}
+
+func TestJitterBuffer_ReorderAndLoss(t *testing.T) {
+	start := time.Unix(1000, 0)
+	j := New(&codecs.OpusPacket{}, 48000, WithMinDelay(100*ms))
+
+	for _, i := range []uint16{0, 2, 1, 1, 4, 5} {
+		// Sequence numbers wrap around
+		packet := opusPacket(i)
+		packet.SequenceNumber += 65534
+		j.Push(packet, start.Add(time.Duration(i)*20*ms))
+	}
+
+	var sequenceNumbers []byte
+	var dropped []uint16
+	for sample := j.Pop(start.Add(time.Second)); sample != nil; sample = j.Pop(start.Add(time.Second)) {
+		sequenceNumbers = append(sequenceNumbers, sample.Data[1])
+		dropped = append(dropped, sample.PrevDroppedPackets)
+	}
+	assert.Equal(t, []byte{0, 1, 2, 4, 5}, sequenceNumbers)
+	assert.Equal(t, []uint16{0, 0, 0, 1, 0}, dropped)
+
+	stats := j.Stats()
+	assert.Equal(t, uint64(1), stats.DuplicatePackets)
+	assert.Equal(t, uint64(1), stats.PacketsLost)
+
+	// The lost packet arrives after its frame was played
+	late := opusPacket(3)
+	late.SequenceNumber = 1
+	j.Push(late, start.Add(time.Second))
+	stats = j.Stats()
+	assert.Equal(t, uint64(1), stats.LatePackets)
+	assert.Equal(t, 0, stats.BufferedFrames)
+}
+
+func TestJitterBuffer_AdaptiveDelay(t *testing.T) {
+	start := time.Unix(1000, 0)
+	j := New(&codecs.OpusPacket{}, 48000, WithMaxDelay(200*ms))
+
+	// A packet arriving 30ms after its playout time increases the delay
+	j.Push(opusPacket(0), start)
+	j.Push(opusPacket(1), start.Add(70*ms))
+	stats := j.Stats()
+	assert.Equal(t, uint64(1), stats.Underruns)
+	assert.Equal(t, 50*ms, stats.TargetDelay)
+
+	// Arrivals alternating 10ms early and late make a jitter of 20ms
+	j = New(&codecs.OpusPacket{}, 48000, WithMaxDelay(200*ms))
+	for i := uint16(0); i < 200; i++ {
+		offset := 10 * ms
+		if i%2 == 0 {
+			offset = -10 * ms
+		}
+		now := start.Add(time.Duration(i)*20*ms + offset)
+		j.Push(opusPacket(i), now)
+		for sample := j.Pop(now); sample != nil; sample = j.Pop(now) {
+		}
+	}
+	stats = j.Stats()
+	assert.InDelta(t, float64(20*ms), float64(stats.Jitter), float64(ms))
+	assert.InDelta(t, float64(80*ms), float64(stats.TargetDelay), float64(ms))
+
+	// Without jitter the delay shrinks back to the minimum
+	for i := uint16(200); i < 1000; i++ {
+		now := start.Add(time.Duration(i) * 20 * ms)
+		j.Push(opusPacket(i), now)
+		for sample := j.Pop(now); sample != nil; sample = j.Pop(now) {
+		}
+	}
+	stats = j.Stats()
+	assert.Less(t, int64(stats.Jitter), int64(ms))
+	assert.InDelta(t, float64(20*ms), float64(stats.TargetDelay), float64(ms))
+}
+
+func TestJitterBuffer_Overrun(t *testing.T) {
+	start := time.Unix(1000, 0)
+	j := New(&codecs.OpusPacket{}, 48000, WithMaxDelay(100*ms))
+
+	for i := uint16(0); i < 10; i++ {
+		j.Push(opusPacket(i), start.Add(time.Duration(i)*20*ms))
+	}
+	stats := j.Stats()
+	assert.Equal(t, uint64(4), stats.Overruns)
+	assert.Equal(t, 6, stats.BufferedFrames)
+
+	// Dropped frames are not lost packets
+	sample := j.Pop(start.Add(time.Second))
+	assert.Equal(t, []byte{0xFC, 0x04}, sample.Data)
+	assert.Equal(t, uint16(0), sample.PrevDroppedPackets)
+}
+
+type testRTPReader struct {
+	packets chan *rtp.Packet
+}
+
+func (r *testRTPReader) ReadRTP() (*rtp.Packet, interceptor.Attributes, error) {
+	packet, ok := <-r.packets
+	if !ok {
+		return nil, nil, io.EOF
+	}
+	return packet, nil, nil
+}
+
+func TestReader(t *testing.T) {
+	source := &testRTPReader{packets: make(chan *rtp.Packet)}
+	reader := NewReader(source, &codecs.OpusPacket{}, 48000)
+
+	go func() {
+		for i := uint16(0); i < 5; i++ {
+			source.packets <- opusPacket(i)
+			time.Sleep(20 * ms)
+		}
+		close(source.packets)
+	}()
+
+	start := time.Now()
+	for i := uint16(0); i < 5; i++ {
+		sample, err := reader.ReadSample()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0xFC, byte(i)}, sample.Data)
+		assert.False(t, time.Now().Before(sample.Timestamp))
+	}
+	assert.True(t, time.Since(start) >= 80*ms)
+
+	_, err := reader.ReadSample()
+	assert.Equal(t, io.EOF, err)
+}
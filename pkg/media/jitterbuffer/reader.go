@@ -0,0 +1,85 @@
+package jitterbuffer
+
+import (
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/media"
+)
+
+// RTPReader is the source of the packets of a Reader, it is implemented by
+// webrtc.TrackRemote
+type RTPReader interface {
+	ReadRTP() (*rtp.Packet, interceptor.Attributes, error)
+}
+
+// Reader reads the packets of a RTPReader into a JitterBuffer, and returns
+// the frames at their playout time
+type Reader struct {
+	*JitterBuffer
+
+	notify chan struct{}
+	done   chan struct{}
+	err    error
+}
+
+// NewReader starts reading the packets of the RTPReader, until it returns an
+// error
+func NewReader(reader RTPReader, depacketizer rtp.Depacketizer, clockRate uint32, opts ...Option) *Reader {
+	r := &Reader{
+		JitterBuffer: New(depacketizer, clockRate, opts...),
+		notify:       make(chan struct{}, 1),
+		done:         make(chan struct{}),
+	}
+
+	go func() {
+		defer close(r.done)
+		for {
+			packet, _, err := reader.ReadRTP()
+			if err != nil {
+				r.err = err
+				return
+			}
+
+			r.Push(packet, time.Now())
+			select {
+			case r.notify <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	return r
+}
+
+// ReadSample blocks until the playout time of the next frame. Once the
+// RTPReader failed and the buffered frames are played, it returns its error.
+func (r *Reader) ReadSample() (*media.Sample, error) {
+	for {
+		now := time.Now()
+		if sample := r.Pop(now); sample != nil {
+			return sample, nil
+		}
+
+		next, ok := r.NextPlayout()
+		if !ok {
+			select {
+			case <-r.notify:
+			case <-r.done:
+				if _, ok = r.NextPlayout(); !ok {
+					return nil, r.err
+				}
+			}
+			continue
+		}
+
+		// A new packet may be played earlier than the next frame
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-timer.C:
+		case <-r.notify:
+			timer.Stop()
+		}
+	}
+}
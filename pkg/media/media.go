@@ -14,6 +14,15 @@ type Sample struct {
 	Duration           time.Duration
 	PacketTimestamp    uint32
 	PrevDroppedPackets uint16
+
+	// CaptureTime is the capture time of the sample on the NTP clock of its
+	// sender, set from RTCP Sender Reports by the lipsync package or by the
+	// samplebuilder WithCaptureTime. It is ignored when sending samples
+	CaptureTime time.Time
+
+	// IsKeyFrame is set by the samplebuilder when its depacketizer can detect
+	// key frames, it is ignored when sending samples
+	IsKeyFrame bool
 }
 
 // Writer defines an interface to handle
@@ -0,0 +1,429 @@
+package mp4writer
+
+import (
+	"github.com/pion/rtp/pkg/obu"
+)
+
+const (
+	av1OBUTypeMask            = byte(0b01111000)
+	av1OBUExtensionFlagMask   = byte(0b00000100)
+	av1OBUHasSizeFieldBitMask = byte(0b00000010)
+
+	av1OBUTypeBitshift = 3
+
+	av1OBUTypeSequenceHeader    = 1
+	av1OBUTypeTemporalDelimiter = 2
+)
+
+// av1Track keeps the last sequence header for the av1C box
+type av1Track struct {
+	sequenceHeaderOBU []byte
+	sequenceHeader    *av1SequenceHeader
+}
+
+// convert removes the temporal delimiters from a temporal unit made of OBUs with size fields
+func (a *av1Track) convert(temporalUnit []byte) (sample []byte, keyFrame bool) {
+	for data := temporalUnit; len(data) > 0; {
+		header := data[0]
+		if header&av1OBUHasSizeFieldBitMask == 0 {
+			// Not in the low overhead bitstream format, written as is
+			return temporalUnit, keyFrame
+		}
+
+		headerSize := uint(1)
+		if header&av1OBUExtensionFlagMask != 0 {
+			headerSize = 2
+		}
+		if uint(len(data)) < headerSize {
+			return temporalUnit, keyFrame
+		}
+		size, n, err := obu.ReadLeb128(data[headerSize:])
+		if err != nil || uint(len(data))-headerSize-n < size {
+			return temporalUnit, keyFrame
+		}
+
+		unit := data[:headerSize+n+size]
+		data = data[headerSize+n+size:]
+
+		switch (header & av1OBUTypeMask) >> av1OBUTypeBitshift {
+		case av1OBUTypeTemporalDelimiter:
+			continue
+		case av1OBUTypeSequenceHeader:
+			if sequenceHeader, err := parseAV1SequenceHeader(unit[headerSize+n:]); err == nil {
+				a.sequenceHeaderOBU = append([]byte{}, unit...)
+				a.sequenceHeader = sequenceHeader
+				keyFrame = true
+			}
+		}
+
+		sample = append(sample, unit...)
+	}
+
+	return sample, keyFrame
+}
+
+// sampleEntry returns the av01 sample entry, once a sequence header has been received
+// https://aomediacodec.github.io/av1-isobmff/#av1codecconfigurationbox-section
+func (a *av1Track) sampleEntry() ([]byte, error) {
+	if a.sequenceHeader == nil {
+		return nil, errMissingCodecConfig
+	}
+
+	s := a.sequenceHeader
+	av1C := []byte{
+		0x81, // marker and version
+		s.profile<<5 | s.levelIdx0,
+		s.tier0<<7 | s.highBitdepth<<6 | s.twelveBit<<5 | s.monochrome<<4 |
+			s.subsamplingX<<3 | s.subsamplingY<<2 | s.chromaSamplePosition,
+		0, // no initial_presentation_delay
+	}
+	av1C = append(av1C, a.sequenceHeaderOBU...)
+
+	return visualSampleEntry("av01", s.width, s.height, box("av1C", av1C)), nil
+}
+
+type av1SequenceHeader struct {
+	profile   byte
+	levelIdx0 byte
+	tier0     byte
+
+	width, height uint16
+
+	highBitdepth         byte
+	twelveBit            byte
+	monochrome           byte
+	subsamplingX         byte
+	subsamplingY         byte
+	chromaSamplePosition byte
+}
+
+// parseAV1SequenceHeader parses the payload of a sequence header OBU
+// https://aomediacodec.github.io/av1-spec/#sequence-header-obu-syntax
+func parseAV1SequenceHeader(payload []byte) (*av1SequenceHeader, error) { //nolint:gocognit
+	s := &av1SequenceHeader{}
+	r := &bitReader{data: payload}
+
+	profile, err := r.readBits(3)
+	if err != nil {
+		return nil, err
+	}
+	s.profile = byte(profile)
+
+	// still_picture
+	if err = r.skipBits(1); err != nil {
+		return nil, err
+	}
+	reducedStillPictureHeader, err := r.readFlag()
+	if err != nil {
+		return nil, err
+	}
+
+	if reducedStillPictureHeader {
+		level, err := r.readBits(5)
+		if err != nil {
+			return nil, err
+		}
+		s.levelIdx0 = byte(level)
+	} else if err = parseAV1OperatingPoints(r, s); err != nil {
+		return nil, err
+	}
+
+	frameWidthBitsMinus1, err := r.readBits(4)
+	if err != nil {
+		return nil, err
+	}
+	frameHeightBitsMinus1, err := r.readBits(4)
+	if err != nil {
+		return nil, err
+	}
+	maxFrameWidthMinus1, err := r.readBits(int(frameWidthBitsMinus1) + 1)
+	if err != nil {
+		return nil, err
+	}
+	maxFrameHeightMinus1, err := r.readBits(int(frameHeightBitsMinus1) + 1)
+	if err != nil {
+		return nil, err
+	}
+	s.width, s.height = uint16(maxFrameWidthMinus1+1), uint16(maxFrameHeightMinus1+1)
+
+	if !reducedStillPictureHeader {
+		frameIDNumbersPresent, err := r.readFlag()
+		if err != nil {
+			return nil, err
+		}
+		if frameIDNumbersPresent {
+			// delta_frame_id_length_minus_2, additional_frame_id_length_minus_1
+			if err = r.skipBits(4, 3); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// use_128x128_superblock, enable_filter_intra, enable_intra_edge_filter
+	if err = r.skipBits(1, 1, 1); err != nil {
+		return nil, err
+	}
+
+	if !reducedStillPictureHeader {
+		if err = skipAV1CodingTools(r); err != nil {
+			return nil, err
+		}
+	}
+
+	// enable_superres, enable_cdef, enable_restoration
+	if err = r.skipBits(1, 1, 1); err != nil {
+		return nil, err
+	}
+
+	if err = parseAV1ColorConfig(r, s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func parseAV1OperatingPoints(r *bitReader, s *av1SequenceHeader) error { //nolint:gocognit
+	timingInfoPresent, err := r.readFlag()
+	if err != nil {
+		return err
+	}
+
+	decoderModelInfoPresent := false
+	bufferDelayLength := 0
+	if timingInfoPresent {
+		// num_units_in_display_tick, time_scale
+		if err = r.skipBits(32, 32); err != nil {
+			return err
+		}
+		equalPictureInterval, err := r.readFlag()
+		if err != nil {
+			return err
+		}
+		if equalPictureInterval {
+			if _, err = r.readUVLC(); err != nil {
+				return err
+			}
+		}
+
+		if decoderModelInfoPresent, err = r.readFlag(); err != nil {
+			return err
+		}
+		if decoderModelInfoPresent {
+			bufferDelayLengthMinus1, err := r.readBits(5)
+			if err != nil {
+				return err
+			}
+			bufferDelayLength = int(bufferDelayLengthMinus1) + 1
+
+			// num_units_in_decoding_tick, buffer_removal_time_length_minus_1,
+			// frame_presentation_time_length_minus_1
+			if err = r.skipBits(32, 5, 5); err != nil {
+				return err
+			}
+		}
+	}
+
+	initialDisplayDelayPresent, err := r.readFlag()
+	if err != nil {
+		return err
+	}
+	operatingPointsCntMinus1, err := r.readBits(5)
+	if err != nil {
+		return err
+	}
+
+	for i := uint64(0); i <= operatingPointsCntMinus1; i++ {
+		// operating_point_idc
+		if err = r.skipBits(12); err != nil {
+			return err
+		}
+		level, err := r.readBits(5)
+		if err != nil {
+			return err
+		}
+		tier := uint64(0)
+		if level > 7 {
+			if tier, err = r.readBits(1); err != nil {
+				return err
+			}
+		}
+		if i == 0 {
+			s.levelIdx0, s.tier0 = byte(level), byte(tier)
+		}
+
+		if decoderModelInfoPresent {
+			decoderModelPresent, err := r.readFlag()
+			if err != nil {
+				return err
+			}
+			if decoderModelPresent {
+				// decoder_buffer_delay, encoder_buffer_delay, low_delay_mode_flag
+				if err = r.skipBits(bufferDelayLength, bufferDelayLength, 1); err != nil {
+					return err
+				}
+			}
+		}
+
+		if initialDisplayDelayPresent {
+			present, err := r.readFlag()
+			if err != nil {
+				return err
+			}
+			if present {
+				// initial_display_delay_minus_1
+				if err = r.skipBits(4); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func skipAV1CodingTools(r *bitReader) error {
+	// enable_interintra_compound, enable_masked_compound, enable_warped_motion, enable_dual_filter
+	if err := r.skipBits(1, 1, 1, 1); err != nil {
+		return err
+	}
+	enableOrderHint, err := r.readFlag()
+	if err != nil {
+		return err
+	}
+	if enableOrderHint {
+		// enable_jnt_comp, enable_ref_frame_mvs
+		if err = r.skipBits(1, 1); err != nil {
+			return err
+		}
+	}
+
+	seqChooseScreenContentTools, err := r.readFlag()
+	if err != nil {
+		return err
+	}
+	seqForceScreenContentTools := true
+	if !seqChooseScreenContentTools {
+		if seqForceScreenContentTools, err = r.readFlag(); err != nil {
+			return err
+		}
+	}
+	if seqForceScreenContentTools {
+		seqChooseIntegerMV, err := r.readFlag()
+		if err != nil {
+			return err
+		}
+		if !seqChooseIntegerMV {
+			// seq_force_integer_mv
+			if err = r.skipBits(1); err != nil {
+				return err
+			}
+		}
+	}
+
+	if enableOrderHint {
+		// order_hint_bits_minus_1
+		return r.skipBits(3)
+	}
+
+	return nil
+}
+
+func parseAV1ColorConfig(r *bitReader, s *av1SequenceHeader) error { //nolint:gocognit
+	const (
+		colorPrimariesBT709         = 1
+		transferCharacteristicsSRGB = 13
+		matrixCoefficientsIdentity  = 0
+		unspecified                 = 2
+	)
+
+	highBitdepth, err := r.readBits(1)
+	if err != nil {
+		return err
+	}
+	s.highBitdepth = byte(highBitdepth)
+	if s.profile == 2 && s.highBitdepth == 1 {
+		twelveBit, err := r.readBits(1)
+		if err != nil {
+			return err
+		}
+		s.twelveBit = byte(twelveBit)
+	}
+
+	if s.profile != 1 {
+		monochrome, err := r.readBits(1)
+		if err != nil {
+			return err
+		}
+		s.monochrome = byte(monochrome)
+	}
+
+	colorDescriptionPresent, err := r.readFlag()
+	if err != nil {
+		return err
+	}
+	colorPrimaries, transferCharacteristics, matrixCoefficients := uint64(unspecified), uint64(unspecified), uint64(unspecified)
+	if colorDescriptionPresent {
+		if colorPrimaries, err = r.readBits(8); err != nil {
+			return err
+		}
+		if transferCharacteristics, err = r.readBits(8); err != nil {
+			return err
+		}
+		if matrixCoefficients, err = r.readBits(8); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case s.monochrome == 1:
+		s.subsamplingX, s.subsamplingY = 1, 1
+		return nil
+	case colorPrimaries == colorPrimariesBT709 && transferCharacteristics == transferCharacteristicsSRGB &&
+		matrixCoefficients == matrixCoefficientsIdentity:
+		return nil
+	}
+
+	// color_range
+	if err = r.skipBits(1); err != nil {
+		return err
+	}
+
+	switch {
+	case s.profile == 0:
+		s.subsamplingX, s.subsamplingY = 1, 1
+	case s.profile == 1:
+	case s.twelveBit == 1:
+		subsamplingX, err := r.readBits(1)
+		if err != nil {
+			return err
+		}
+		s.subsamplingX = byte(subsamplingX)
+		if subsamplingX == 1 {
+			subsamplingY, err := r.readBits(1)
+			if err != nil {
+				return err
+			}
+			s.subsamplingY = byte(subsamplingY)
+		}
+	default:
+		s.subsamplingX = 1
+	}
+
+	if s.subsamplingX == 1 && s.subsamplingY == 1 {
+		chromaSamplePosition, err := r.readBits(2)
+		if err != nil {
+			return err
+		}
+		s.chromaSamplePosition = byte(chromaSamplePosition)
+	}
+
+	return nil
+}
+
+func (a *av1Track) size() (width, height uint16) {
+	if a.sequenceHeader == nil {
+		return 0, 0
+	}
+
+	return a.sequenceHeader.width, a.sequenceHeader.height
+}
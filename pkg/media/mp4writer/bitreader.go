@@ -0,0 +1,100 @@
+package mp4writer
+
+// bitReader reads the bit fields of codec headers, MSB first
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bitReader) readBits(n int) (uint64, error) {
+	var v uint64
+	for i := 0; i < n; i++ {
+		if r.pos >= len(r.data)*8 {
+			return 0, errShortHeader
+		}
+		bit := (r.data[r.pos/8] >> (7 - uint(r.pos%8))) & 1
+		v = v<<1 | uint64(bit)
+		r.pos++
+	}
+
+	return v, nil
+}
+
+func (r *bitReader) readFlag() (bool, error) {
+	v, err := r.readBits(1)
+	return v == 1, err
+}
+
+// readUE reads an unsigned Exp-Golomb value
+func (r *bitReader) readUE() (uint64, error) {
+	leadingZeros := 0
+	for {
+		bit, err := r.readBits(1)
+		if err != nil {
+			return 0, err
+		}
+		if bit == 1 {
+			break
+		}
+		leadingZeros++
+		if leadingZeros > 32 {
+			return 0, errInvalidHeader
+		}
+	}
+
+	v, err := r.readBits(leadingZeros)
+	if err != nil {
+		return 0, err
+	}
+
+	return (1 << uint(leadingZeros)) - 1 + v, nil
+}
+
+// readSE reads a signed Exp-Golomb value
+func (r *bitReader) readSE() (int64, error) {
+	v, err := r.readUE()
+	if err != nil {
+		return 0, err
+	}
+	if v%2 == 1 {
+		return int64((v + 1) / 2), nil
+	}
+
+	return -int64(v / 2), nil
+}
+
+// readUVLC reads an AV1 variable length unsigned value
+func (r *bitReader) readUVLC() (uint64, error) {
+	leadingZeros := 0
+	for {
+		done, err := r.readFlag()
+		if err != nil {
+			return 0, err
+		}
+		if done {
+			break
+		}
+		leadingZeros++
+	}
+	if leadingZeros >= 32 {
+		return (1 << 32) - 1, nil
+	}
+
+	v, err := r.readBits(leadingZeros)
+	if err != nil {
+		return 0, err
+	}
+
+	return v + (1 << uint(leadingZeros)) - 1, nil
+}
+
+// skipBits skips the fields of the given sizes
+func (r *bitReader) skipBits(sizes ...int) error {
+	for _, n := range sizes {
+		if _, err := r.readBits(n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
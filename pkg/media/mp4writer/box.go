@@ -0,0 +1,228 @@
+package mp4writer
+
+import (
+	"encoding/binary"
+)
+
+const (
+	movieTimescale = 1000
+
+	// sample_flags of the track fragment runs, ISO/IEC 14496-12 8.8.3.1
+	sampleFlagsSync    = 0x02000000 // sample_depends_on I frame
+	sampleFlagsNonSync = 0x01010000 // sample_depends_on others, sample_is_non_sync_sample
+
+	trunDataOffsetPresent        = 0x000001
+	trunSampleDurationPresent    = 0x000100
+	trunSampleSizePresent        = 0x000200
+	trunSampleFlagsPresent       = 0x000400
+	tfhdDefaultBaseIsMoof        = 0x020000
+	trackEnabledInMovieInPreview = 0x000007
+)
+
+// box returns an ISO BMFF box of the given type wrapping the content
+func box(typ string, content ...[]byte) []byte {
+	size := 8
+	for _, c := range content {
+		size += len(c)
+	}
+
+	b := make([]byte, 8, size)
+	binary.BigEndian.PutUint32(b, uint32(size))
+	copy(b[4:], typ)
+	for _, c := range content {
+		b = append(b, c...)
+	}
+
+	return b
+}
+
+// fullBox returns a box with a version and flags header
+func fullBox(typ string, version byte, flags uint32, content ...[]byte) []byte {
+	header := []byte{version, byte(flags >> 16), byte(flags >> 8), byte(flags)}
+	return box(typ, append([][]byte{header}, content...)...)
+}
+
+func u16(v uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, v)
+	return b
+}
+
+func u32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+func u64(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+// unityMatrix is the identity transformation of mvhd and tkhd
+func unityMatrix() []byte {
+	m := make([]byte, 0, 36)
+	for _, v := range []uint32{0x00010000, 0, 0, 0, 0x00010000, 0, 0, 0, 0x40000000} {
+		m = append(m, u32(v)...)
+	}
+
+	return m
+}
+
+// visualSampleEntry returns the sample entry of a video track, ISO/IEC 14496-12 12.1.3
+func visualSampleEntry(fourcc string, width, height uint16, config []byte) []byte {
+	compressorName := make([]byte, 32)
+	return box(fourcc,
+		make([]byte, 6), u16(1), // reserved, data_reference_index
+		make([]byte, 16), // pre_defined and reserved
+		u16(width), u16(height),
+		u32(0x00480000), u32(0x00480000), // 72 dpi
+		u32(0), u16(1), // reserved, frame_count
+		compressorName,
+		u16(0x0018), u16(0xFFFF), // depth, pre_defined
+		config,
+	)
+}
+
+// opusSampleEntry returns the Opus sample entry with its dOps box
+// https://opus-codec.org/docs/opus_in_isobmff.html
+func opusSampleEntry(channels uint16) []byte {
+	dOps := []byte{0, byte(channels)} // Version, OutputChannelCount
+	dOps = append(dOps, u16(0)...)    // PreSkip
+	dOps = append(dOps, u32(48000)...)
+	dOps = append(dOps, 0, 0, 0) // OutputGain, ChannelMappingFamily
+
+	return box("Opus",
+		make([]byte, 6), u16(1), // reserved, data_reference_index
+		make([]byte, 8),        // reserved
+		u16(channels), u16(16), // channelcount, samplesize
+		u32(0),         // pre_defined, reserved
+		u32(48000<<16), // samplerate
+		box("dOps", dOps),
+	)
+}
+
+// initSegment returns the ftyp and moov boxes describing the tracks
+func initSegment(tracks []*track) ([]byte, error) {
+	ftyp := box("ftyp", []byte("iso5"), u32(512), []byte("iso5iso6mp41"))
+
+	mvhd := fullBox("mvhd", 0, 0,
+		u32(0), u32(0), // creation_time, modification_time
+		u32(movieTimescale), u32(0), // timescale, duration
+		u32(0x00010000), u16(0x0100), make([]byte, 10), // rate, volume, reserved
+		unityMatrix(),
+		make([]byte, 24), // pre_defined
+		u32(uint32(len(tracks)+1)),
+	)
+
+	moov := [][]byte{mvhd}
+	var trex [][]byte
+	for _, t := range tracks {
+		trak, err := t.trak()
+		if err != nil {
+			return nil, err
+		}
+		moov = append(moov, trak)
+		trex = append(trex, fullBox("trex", 0, 0,
+			u32(t.id), u32(1), // track_ID, default_sample_description_index
+			u32(0), u32(0), u32(0), // default duration, size and flags
+		))
+	}
+	moov = append(moov, box("mvex", trex...))
+
+	return append(ftyp, box("moov", moov...)...), nil
+}
+
+func (t *track) trak() ([]byte, error) {
+	sampleEntry, err := t.codec.sampleEntry()
+	if err != nil {
+		return nil, err
+	}
+
+	var width, height uint16
+	volume := uint16(0x0100)
+	handler, mediaHeader := "soun", fullBox("smhd", 0, 0, u16(0), u16(0))
+	if t.isVideo {
+		volume = 0
+		handler, mediaHeader = "vide", fullBox("vmhd", 0, 1, make([]byte, 8))
+		width, height = t.codec.size()
+	}
+
+	tkhd := fullBox("tkhd", 0, trackEnabledInMovieInPreview,
+		u32(0), u32(0), // creation_time, modification_time
+		u32(t.id), u32(0), u32(0), // track_ID, reserved, duration
+		make([]byte, 8), // reserved
+		u16(0), u16(0),  // layer, alternate_group
+		u16(volume), u16(0), // volume, reserved
+		unityMatrix(),
+		u32(uint32(width)<<16), u32(uint32(height)<<16),
+	)
+
+	mdhd := fullBox("mdhd", 0, 0,
+		u32(0), u32(0), // creation_time, modification_time
+		u32(t.clockRate), u32(0), // timescale, duration
+		u16(0x55C4), u16(0), // language und, pre_defined
+	)
+	hdlr := fullBox("hdlr", 0, 0,
+		u32(0), []byte(handler), make([]byte, 12), // pre_defined, handler_type, reserved
+		[]byte("pion\x00"),
+	)
+
+	dinf := box("dinf", fullBox("dref", 0, 0, u32(1), fullBox("url ", 0, 1)))
+	stbl := box("stbl",
+		fullBox("stsd", 0, 0, u32(1), sampleEntry),
+		fullBox("stts", 0, 0, u32(0)),
+		fullBox("stsc", 0, 0, u32(0)),
+		fullBox("stsz", 0, 0, u32(0), u32(0)),
+		fullBox("stco", 0, 0, u32(0)),
+	)
+
+	return box("trak", tkhd, box("mdia", mdhd, hdlr, box("minf", mediaHeader, dinf, stbl))), nil
+}
+
+// fragment returns the moof and mdat boxes of the pending samples of the tracks
+func fragment(sequenceNumber uint32, tracks []*track) []byte {
+	const (
+		trunHeaderSize = 8 + 4 + 4 + 4 // box header, version and flags, sample_count, data_offset
+		trunEntrySize  = 4 + 4 + 4
+	)
+
+	// The data offsets are relative to the start of the moof box, so its size is computed first
+	moofSize := 8 + 16 // moof header, mfhd
+	for _, t := range tracks {
+		if len(t.samples) == 0 {
+			continue
+		}
+		moofSize += 8 + 16 + 20 + trunHeaderSize + trunEntrySize*len(t.samples) // traf header, tfhd, tfdt, trun
+	}
+
+	moof := [][]byte{fullBox("mfhd", 0, 0, u32(sequenceNumber))}
+	var mdat [][]byte
+	dataOffset := moofSize + 8
+	for _, t := range tracks {
+		if len(t.samples) == 0 {
+			continue
+		}
+
+		entries := make([][]byte, 0, 3*len(t.samples)+2)
+		entries = append(entries, u32(uint32(len(t.samples))), u32(uint32(dataOffset)))
+		for _, s := range t.samples {
+			flags := uint32(sampleFlagsSync)
+			if t.isVideo && !s.keyFrame {
+				flags = sampleFlagsNonSync
+			}
+			entries = append(entries, u32(s.duration), u32(uint32(len(s.data))), u32(flags))
+			mdat = append(mdat, s.data)
+			dataOffset += len(s.data)
+		}
+
+		moof = append(moof, box("traf",
+			fullBox("tfhd", 0, tfhdDefaultBaseIsMoof, u32(t.id)),
+			fullBox("tfdt", 1, 0, u64(t.samples[0].decodeTime)),
+			fullBox("trun", 0, trunDataOffsetPresent|trunSampleDurationPresent|trunSampleSizePresent|trunSampleFlagsPresent, entries...),
+		))
+	}
+
+	return append(box("moof", moof...), box("mdat", mdat...)...)
+}
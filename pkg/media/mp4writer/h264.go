@@ -0,0 +1,336 @@
+package mp4writer
+
+import (
+	"encoding/binary"
+)
+
+const (
+	h264NaluTypeMask = 0x1F
+
+	h264NaluTypeIDR = 5
+	h264NaluTypeSPS = 7
+	h264NaluTypePPS = 8
+	h264NaluTypeAUD = 9
+)
+
+// splitAnnexB returns the NAL units of an Annex-B byte stream, without start codes
+func splitAnnexB(data []byte) [][]byte {
+	var nalus [][]byte
+
+	start, zeros := -1, 0
+	for i, b := range data {
+		switch {
+		case b == 0:
+			zeros++
+			continue
+		case b == 1 && zeros >= 2:
+			if start >= 0 {
+				nalus = append(nalus, data[start:i-zeros])
+			}
+			start = i + 1
+		}
+		zeros = 0
+	}
+	if start >= 0 && start < len(data) {
+		nalus = append(nalus, data[start:])
+	}
+
+	return nalus
+}
+
+// h264Track converts Annex-B access units to length prefixed samples, and keeps
+// the last parameter sets for the avcC box
+type h264Track struct {
+	sps, pps []byte
+}
+
+func (h *h264Track) convert(accessUnit []byte) (sample []byte, keyFrame bool) {
+	for _, nalu := range splitAnnexB(accessUnit) {
+		if len(nalu) == 0 {
+			continue
+		}
+
+		switch nalu[0] & h264NaluTypeMask {
+		case h264NaluTypeAUD:
+			continue
+		case h264NaluTypeSPS:
+			h.sps = append([]byte{}, nalu...)
+		case h264NaluTypePPS:
+			h.pps = append([]byte{}, nalu...)
+		case h264NaluTypeIDR:
+			keyFrame = true
+		}
+
+		sample = append(sample, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(sample[len(sample)-4:], uint32(len(nalu)))
+		sample = append(sample, nalu...)
+	}
+
+	return sample, keyFrame
+}
+
+// sampleEntry returns the avc1 sample entry, once a SPS and a PPS have been received
+func (h *h264Track) sampleEntry() ([]byte, error) {
+	if h.sps == nil || h.pps == nil {
+		return nil, errMissingCodecConfig
+	}
+
+	sps, err := parseH264SPS(h.sps)
+	if err != nil {
+		return nil, err
+	}
+
+	avcC := []byte{
+		1,        // configurationVersion
+		h.sps[1], // AVCProfileIndication
+		h.sps[2], // profile_compatibility
+		h.sps[3], // AVCLevelIndication
+		0xFC | 3, // lengthSizeMinusOne
+		0xE0 | 1, // numOfSequenceParameterSets
+		byte(len(h.sps) >> 8), byte(len(h.sps)),
+	}
+	avcC = append(avcC, h.sps...)
+	avcC = append(avcC, 1, byte(len(h.pps)>>8), byte(len(h.pps)))
+	avcC = append(avcC, h.pps...)
+	if sps.highProfile {
+		avcC = append(avcC,
+			0xFC|byte(sps.chromaFormatIDC),
+			0xF8|byte(sps.bitDepthLumaMinus8),
+			0xF8|byte(sps.bitDepthChromaMinus8),
+			0, // numOfSequenceParameterSetExt
+		)
+	}
+
+	return visualSampleEntry("avc1", sps.width, sps.height, box("avcC", avcC)), nil
+}
+
+type h264SPS struct {
+	width, height uint16
+
+	highProfile          bool
+	chromaFormatIDC      uint64
+	bitDepthLumaMinus8   uint64
+	bitDepthChromaMinus8 uint64
+}
+
+// removeEmulationPrevention returns the RBSP of a NAL unit
+func removeEmulationPrevention(nalu []byte) []byte {
+	rbsp := make([]byte, 0, len(nalu))
+	zeros := 0
+	for _, b := range nalu {
+		if zeros >= 2 && b == 3 {
+			zeros = 0
+			continue
+		}
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		rbsp = append(rbsp, b)
+	}
+
+	return rbsp
+}
+
+// parseH264SPS reads the picture size and the chroma format of a SPS
+// https://www.itu.int/rec/T-REC-H.264 7.3.2.1.1
+func parseH264SPS(nalu []byte) (*h264SPS, error) { //nolint:gocognit
+	if len(nalu) < 4 {
+		return nil, errShortHeader
+	}
+
+	sps := &h264SPS{chromaFormatIDC: 1}
+	r := &bitReader{data: removeEmulationPrevention(nalu[1:])}
+
+	profileIDC, err := r.readBits(8)
+	if err != nil {
+		return nil, err
+	}
+	// constraint flags and level_idc
+	if err = r.skipBits(8, 8); err != nil {
+		return nil, err
+	}
+	// seq_parameter_set_id
+	if _, err = r.readUE(); err != nil {
+		return nil, err
+	}
+
+	switch profileIDC {
+	case 100, 110, 122, 244, 44, 83, 86, 118, 128, 138, 139, 134, 135:
+		sps.highProfile = true
+		if sps.chromaFormatIDC, err = r.readUE(); err != nil {
+			return nil, err
+		}
+		if sps.chromaFormatIDC == 3 {
+			// separate_colour_plane_flag
+			if err = r.skipBits(1); err != nil {
+				return nil, err
+			}
+		}
+		if sps.bitDepthLumaMinus8, err = r.readUE(); err != nil {
+			return nil, err
+		}
+		if sps.bitDepthChromaMinus8, err = r.readUE(); err != nil {
+			return nil, err
+		}
+		// qpprime_y_zero_transform_bypass_flag
+		if err = r.skipBits(1); err != nil {
+			return nil, err
+		}
+		if err = skipH264ScalingMatrix(r, sps.chromaFormatIDC); err != nil {
+			return nil, err
+		}
+	}
+
+	// log2_max_frame_num_minus4
+	if _, err = r.readUE(); err != nil {
+		return nil, err
+	}
+	picOrderCntType, err := r.readUE()
+	if err != nil {
+		return nil, err
+	}
+	switch picOrderCntType {
+	case 0:
+		// log2_max_pic_order_cnt_lsb_minus4
+		if _, err = r.readUE(); err != nil {
+			return nil, err
+		}
+	case 1:
+		// delta_pic_order_always_zero_flag, offset_for_non_ref_pic, offset_for_top_to_bottom_field
+		if err = r.skipBits(1); err != nil {
+			return nil, err
+		}
+		if _, err = r.readSE(); err != nil {
+			return nil, err
+		}
+		if _, err = r.readSE(); err != nil {
+			return nil, err
+		}
+		numRefFramesInPicOrderCntCycle, err := r.readUE()
+		if err != nil {
+			return nil, err
+		}
+		for i := uint64(0); i < numRefFramesInPicOrderCntCycle; i++ {
+			if _, err = r.readSE(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// max_num_ref_frames
+	if _, err = r.readUE(); err != nil {
+		return nil, err
+	}
+	// gaps_in_frame_num_value_allowed_flag
+	if err = r.skipBits(1); err != nil {
+		return nil, err
+	}
+	picWidthInMbsMinus1, err := r.readUE()
+	if err != nil {
+		return nil, err
+	}
+	picHeightInMapUnitsMinus1, err := r.readUE()
+	if err != nil {
+		return nil, err
+	}
+	frameMbsOnly, err := r.readFlag()
+	if err != nil {
+		return nil, err
+	}
+	if !frameMbsOnly {
+		// mb_adaptive_frame_field_flag
+		if err = r.skipBits(1); err != nil {
+			return nil, err
+		}
+	}
+	// direct_8x8_inference_flag
+	if err = r.skipBits(1); err != nil {
+		return nil, err
+	}
+
+	frameHeightFactor := uint64(2)
+	if frameMbsOnly {
+		frameHeightFactor = 1
+	}
+	width := (picWidthInMbsMinus1 + 1) * 16
+	height := frameHeightFactor * (picHeightInMapUnitsMinus1 + 1) * 16
+
+	frameCropping, err := r.readFlag()
+	if err != nil {
+		return nil, err
+	}
+	if frameCropping {
+		var offsets [4]uint64
+		for i := range offsets {
+			if offsets[i], err = r.readUE(); err != nil {
+				return nil, err
+			}
+		}
+
+		cropUnitX, cropUnitY := uint64(1), frameHeightFactor
+		if sps.chromaFormatIDC == 1 || sps.chromaFormatIDC == 2 {
+			cropUnitX = 2
+		}
+		if sps.chromaFormatIDC == 1 {
+			cropUnitY *= 2
+		}
+
+		width -= cropUnitX * (offsets[0] + offsets[1])
+		height -= cropUnitY * (offsets[2] + offsets[3])
+	}
+
+	sps.width, sps.height = uint16(width), uint16(height)
+
+	return sps, nil
+}
+
+func skipH264ScalingMatrix(r *bitReader, chromaFormatIDC uint64) error {
+	present, err := r.readFlag()
+	if err != nil || !present {
+		return err
+	}
+
+	count := 8
+	if chromaFormatIDC == 3 {
+		count = 12
+	}
+	for i := 0; i < count; i++ {
+		listPresent, err := r.readFlag()
+		if err != nil {
+			return err
+		}
+		if !listPresent {
+			continue
+		}
+
+		size := 16
+		if i >= 6 {
+			size = 64
+		}
+		lastScale, nextScale := int64(8), int64(8)
+		for j := 0; j < size; j++ {
+			if nextScale != 0 {
+				delta, err := r.readSE()
+				if err != nil {
+					return err
+				}
+				nextScale = (lastScale + delta + 256) % 256
+			}
+			if nextScale != 0 {
+				lastScale = nextScale
+			}
+		}
+	}
+
+	return nil
+}
+
+func (h *h264Track) size() (width, height uint16) {
+	if sps, err := parseH264SPS(h.sps); err == nil {
+		return sps.width, sps.height
+	}
+
+	return 0, 0
+}
@@ -0,0 +1,355 @@
+// Package mp4writer implements a fragmented MP4 (fMP4/CMAF) media container writer
+package mp4writer
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/media/samplebuilder"
+	"github.com/pion/webrtc/v3/pkg/rtpcodecs"
+)
+
+var (
+	errFileNotOpened      = errors.New("file not opened")
+	errInvalidNilPacket   = errors.New("invalid nil packet")
+	errCodecAlreadySet    = errors.New("codec is already set")
+	errNoSuchCodec        = errors.New("no codec for this MimeType")
+	errNoTracks           = errors.New("no audio or video codec configured")
+	errNoVideoTrack       = errors.New("no video codec configured")
+	errNoAudioTrack       = errors.New("no audio codec configured")
+	errAmbiguousTrack     = errors.New("WriteRTP requires a single track, use WriteVideoRTP or WriteAudioRTP")
+	errShortHeader        = errors.New("codec header is too short")
+	errInvalidHeader      = errors.New("invalid codec header")
+	errMissingCodecConfig = errors.New("codec configuration has not been received yet")
+)
+
+const (
+	mimeTypeH264 = "video/H264"
+	mimeTypeVP9  = "video/VP9"
+	mimeTypeAV1  = "video/AV1"
+	mimeTypeOpus = "audio/opus"
+
+	videoClockRate = 90000
+	opusClockRate  = 48000
+	opusChannels   = 2
+
+	videoMaxLate = 512
+	audioMaxLate = 50
+
+	defaultFragmentDuration = time.Second
+)
+
+// trackCodec converts the depacketized samples of a codec to MP4 samples
+type trackCodec interface {
+	convert(data []byte) (sample []byte, keyFrame bool)
+	sampleEntry() ([]byte, error)
+	size() (width, height uint16)
+}
+
+type opusTrack struct{}
+
+func (opusTrack) convert(data []byte) ([]byte, bool) { return data, true }
+func (opusTrack) sampleEntry() ([]byte, error)       { return opusSampleEntry(opusChannels), nil }
+func (opusTrack) size() (uint16, uint16)             { return 0, 0 }
+
+type sample struct {
+	data       []byte
+	keyFrame   bool
+	decodeTime uint64
+	duration   uint32
+}
+
+type track struct {
+	id        uint32
+	isVideo   bool
+	clockRate uint32
+	codec     trackCodec
+	builder   *samplebuilder.SampleBuilder
+
+	// The last sample is held back until the next one gives its duration
+	last          *sample
+	lastTimestamp uint32
+	lastDuration  uint32
+
+	// Samples of the fragment being built
+	samples          []*sample
+	fragmentDuration uint64
+}
+
+// MP4Writer is used to take RTP packets of an audio and a video track, and
+// write them to a fragmented MP4 file
+type MP4Writer struct {
+	ioWriter io.Writer
+
+	videoTrack, audioTrack *track
+	tracks                 []*track
+
+	fragmentDuration time.Duration
+	initialized      bool
+	sequenceNumber   uint32
+}
+
+// New builds a new MP4 writer
+func New(fileName string, opts ...Option) (*MP4Writer, error) {
+	f, err := os.Create(fileName) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+	writer, err := NewWith(f, opts...)
+	if err != nil {
+		return nil, err
+	}
+	writer.ioWriter = f
+	return writer, nil
+}
+
+// NewWith initialize a new MP4 writer with an io.Writer output
+func NewWith(out io.Writer, opts ...Option) (*MP4Writer, error) {
+	if out == nil {
+		return nil, errFileNotOpened
+	}
+
+	writer := &MP4Writer{
+		ioWriter:         out,
+		fragmentDuration: defaultFragmentDuration,
+	}
+
+	for _, o := range opts {
+		if err := o(writer); err != nil {
+			return nil, err
+		}
+	}
+
+	// The video track comes first, fragments are cut on its key frames
+	for _, t := range []*track{writer.videoTrack, writer.audioTrack} {
+		if t != nil {
+			t.id = uint32(len(writer.tracks) + 1)
+			writer.tracks = append(writer.tracks, t)
+		}
+	}
+	if len(writer.tracks) == 0 {
+		return nil, errNoTracks
+	}
+
+	return writer, nil
+}
+
+// WriteRTP adds a new packet to the only track of the writer
+func (m *MP4Writer) WriteRTP(packet *rtp.Packet) error {
+	if len(m.tracks) != 1 {
+		return errAmbiguousTrack
+	}
+
+	return m.writeRTP(m.tracks[0], packet)
+}
+
+// WriteVideoRTP adds a new packet to the video track
+func (m *MP4Writer) WriteVideoRTP(packet *rtp.Packet) error {
+	if m.videoTrack == nil {
+		return errNoVideoTrack
+	}
+
+	return m.writeRTP(m.videoTrack, packet)
+}
+
+// WriteAudioRTP adds a new packet to the audio track
+func (m *MP4Writer) WriteAudioRTP(packet *rtp.Packet) error {
+	if m.audioTrack == nil {
+		return errNoAudioTrack
+	}
+
+	return m.writeRTP(m.audioTrack, packet)
+}
+
+func (m *MP4Writer) writeRTP(t *track, packet *rtp.Packet) error {
+	if m.ioWriter == nil {
+		return errFileNotOpened
+	} else if packet == nil {
+		return errInvalidNilPacket
+	}
+
+	t.builder.Push(packet)
+	for s := t.builder.Pop(); s != nil; s = t.builder.Pop() {
+		if err := m.writeSample(t, s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *MP4Writer) writeSample(t *track, s *media.Sample) error {
+	data, keyFrame := t.codec.convert(s.Data)
+	if len(data) == 0 {
+		return nil
+	}
+
+	if !m.initialized {
+		// Everything is dropped until the video starts with a key frame, which
+		// carries the codec configuration
+		if m.videoTrack != nil && (t != m.videoTrack || !keyFrame) {
+			return nil
+		}
+
+		init, err := initSegment(m.tracks)
+		if errors.Is(err, errMissingCodecConfig) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if _, err := m.ioWriter.Write(init); err != nil {
+			return err
+		}
+		m.initialized = true
+	}
+
+	next := &sample{data: data, keyFrame: keyFrame}
+	if t.last != nil {
+		t.lastDuration = s.PacketTimestamp - t.lastTimestamp
+		t.last.duration = t.lastDuration
+		next.decodeTime = t.last.decodeTime + uint64(t.lastDuration)
+
+		t.samples = append(t.samples, t.last)
+		t.fragmentDuration += uint64(t.lastDuration)
+	}
+	t.last, t.lastTimestamp = next, s.PacketTimestamp
+
+	if m.shouldFlush(t, keyFrame) {
+		return m.flush()
+	}
+
+	return nil
+}
+
+// shouldFlush is true when a video key frame is about to start a new fragment,
+// or when the first track exceeds the fragment duration
+func (m *MP4Writer) shouldFlush(t *track, keyFrame bool) bool {
+	if t != m.tracks[0] || len(t.samples) == 0 {
+		return false
+	}
+	if t.isVideo && keyFrame {
+		return true
+	}
+
+	return t.fragmentDuration >= uint64(m.fragmentDuration.Seconds()*float64(t.clockRate))
+}
+
+// flush writes the samples of all tracks as a fragment
+func (m *MP4Writer) flush() error {
+	hasSamples := false
+	for _, t := range m.tracks {
+		hasSamples = hasSamples || len(t.samples) != 0
+	}
+	if !hasSamples {
+		return nil
+	}
+
+	m.sequenceNumber++
+	if _, err := m.ioWriter.Write(fragment(m.sequenceNumber, m.tracks)); err != nil {
+		return err
+	}
+
+	for _, t := range m.tracks {
+		t.samples, t.fragmentDuration = nil, 0
+	}
+
+	return nil
+}
+
+// Close writes the pending samples and stops the recording
+func (m *MP4Writer) Close() error {
+	if m.ioWriter == nil {
+		// Returns no error as it may be convenient to call
+		// Close() multiple times
+		return nil
+	}
+
+	defer func() {
+		m.ioWriter = nil
+	}()
+
+	for _, t := range m.tracks {
+		if t.last != nil {
+			// The duration of the last sample is unknown, the previous one is reused
+			t.last.duration = t.lastDuration
+			t.samples = append(t.samples, t.last)
+			t.last = nil
+		}
+	}
+	if err := m.flush(); err != nil {
+		return err
+	}
+
+	if closer, ok := m.ioWriter.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// An Option configures a MP4Writer.
+type Option func(m *MP4Writer) error
+
+// WithVideoCodec adds a H264, VP9 or AV1 video track to the MP4Writer
+func WithVideoCodec(mimeType string) Option {
+	return func(m *MP4Writer) error {
+		if m.videoTrack != nil {
+			return errCodecAlreadySet
+		}
+
+		var codec trackCodec
+		var depacketizer rtp.Depacketizer
+		switch {
+		case strings.EqualFold(mimeType, mimeTypeH264):
+			codec, depacketizer = &h264Track{}, &codecs.H264Packet{}
+		case strings.EqualFold(mimeType, mimeTypeVP9):
+			codec, depacketizer = &vp9Track{}, &codecs.VP9Packet{}
+		case strings.EqualFold(mimeType, mimeTypeAV1):
+			codec, depacketizer = &av1Track{}, &rtpcodecs.AV1Depacketizer{}
+		default:
+			return errNoSuchCodec
+		}
+
+		m.videoTrack = &track{
+			isVideo:   true,
+			clockRate: videoClockRate,
+			codec:     codec,
+			builder:   samplebuilder.New(videoMaxLate, depacketizer, videoClockRate),
+		}
+		return nil
+	}
+}
+
+// WithAudioCodec adds an Opus audio track to the MP4Writer
+func WithAudioCodec(mimeType string) Option {
+	return func(m *MP4Writer) error {
+		if m.audioTrack != nil {
+			return errCodecAlreadySet
+		}
+		if !strings.EqualFold(mimeType, mimeTypeOpus) {
+			return errNoSuchCodec
+		}
+
+		m.audioTrack = &track{
+			clockRate: opusClockRate,
+			codec:     opusTrack{},
+			builder:   samplebuilder.New(audioMaxLate, &codecs.OpusPacket{}, opusClockRate),
+		}
+		return nil
+	}
+}
+
+// WithFragmentDuration sets the duration of the fragments of audio only files,
+// or the maximum duration between two video key frames before a fragment is written
+func WithFragmentDuration(duration time.Duration) Option {
+	return func(m *MP4Writer) error {
+		m.fragmentDuration = duration
+		return nil
+	}
+}
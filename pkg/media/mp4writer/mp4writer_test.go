@@ -0,0 +1,219 @@
+package mp4writer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testH264SPS = []byte{
+		0x67, 0x42, 0xc0, 0x1f, 0xda, 0x01, 0x40, 0x16, 0xec, 0x04, 0x40, 0x00,
+		0x00, 0x03, 0x00, 0x40, 0x00, 0x00, 0x0f, 0x03, 0xc6, 0x0c, 0xa8,
+	}
+	testH264PPS = []byte{0x68, 0xce, 0x3c, 0x80}
+
+	// Profile 0 key frame, 640x480
+	testVP9KeyFrame = []byte{0x82, 0x49, 0x83, 0x42, 0x00, 0x27, 0xF0, 0x1D, 0xF0, 0xAA}
+
+	// Main profile, level 4.0, 640x480, 4:2:0
+	testAV1SequenceHeaderOBU = []byte{
+		0x0A, 0x0B, 0x00, 0x00, 0x00, 0x42, 0x62, 0x7F, 0xEF, 0x9F, 0xFF, 0x30, 0x08,
+	}
+)
+
+func TestParseH264SPS(t *testing.T) {
+	sps, err := parseH264SPS(testH264SPS)
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(1280), sps.width)
+	assert.Equal(t, uint16(720), sps.height)
+	assert.False(t, sps.highProfile)
+
+	_, err = parseH264SPS(testH264SPS[:3])
+	assert.Equal(t, errShortHeader, err)
+}
+
+func TestVP9Track(t *testing.T) {
+	v := &vp9Track{}
+	_, err := v.sampleEntry()
+	assert.Equal(t, errMissingCodecConfig, err)
+
+	_, keyFrame := v.convert([]byte{0x86, 0x00, 0x40})
+	assert.False(t, keyFrame)
+
+	_, keyFrame = v.convert(testVP9KeyFrame)
+	assert.True(t, keyFrame)
+	assert.Equal(t, &vp9KeyFrameHeader{
+		bitDepth:     8,
+		subsamplingX: true,
+		subsamplingY: true,
+		width:        640,
+		height:       480,
+	}, v.header)
+
+	entry, err := v.sampleEntry()
+	assert.NoError(t, err)
+	assert.True(t, bytes.Contains(entry, []byte{'v', 'p', 'c', 'C', 1, 0, 0, 0, 0, 30, 0x82, 2, 2, 2, 0, 0}))
+}
+
+func TestAV1Track(t *testing.T) {
+	a := &av1Track{}
+	_, err := a.sampleEntry()
+	assert.Equal(t, errMissingCodecConfig, err)
+
+	frame := []byte{0x32, 0x01, 0xFF}
+	temporalUnit := append(append([]byte{0x12, 0x00}, testAV1SequenceHeaderOBU...), frame...)
+
+	sample, keyFrame := a.convert(temporalUnit)
+	assert.True(t, keyFrame)
+	assert.Equal(t, append(append([]byte{}, testAV1SequenceHeaderOBU...), frame...), sample)
+	assert.Equal(t, uint16(640), a.sequenceHeader.width)
+	assert.Equal(t, uint16(480), a.sequenceHeader.height)
+
+	sample, keyFrame = a.convert(frame)
+	assert.False(t, keyFrame)
+	assert.Equal(t, frame, sample)
+
+	entry, err := a.sampleEntry()
+	assert.NoError(t, err)
+	av1C := append([]byte{'a', 'v', '1', 'C', 0x81, 0x08, 0x0C, 0x00}, testAV1SequenceHeaderOBU...)
+	assert.True(t, bytes.Contains(entry, av1C))
+}
+
+// topLevelBoxes returns the types and the contents of the boxes of a file
+func topLevelBoxes(t *testing.T, data []byte) (types []string, contents [][]byte) {
+	for len(data) > 0 {
+		if !assert.GreaterOrEqual(t, len(data), 8) {
+			return
+		}
+		size := int(binary.BigEndian.Uint32(data))
+		if !assert.LessOrEqual(t, size, len(data)) {
+			return
+		}
+		types = append(types, string(data[4:8]))
+		contents = append(contents, data[8:size])
+		data = data[size:]
+	}
+
+	return types, contents
+}
+
+// trunSampleCounts returns the sample count of each track run of a moof box
+func trunSampleCounts(moof []byte) (counts []uint32) {
+	for {
+		i := bytes.Index(moof, []byte("trun"))
+		if i < 0 {
+			return counts
+		}
+		counts = append(counts, binary.BigEndian.Uint32(moof[i+8:]))
+		moof = moof[i+4:]
+	}
+}
+
+func TestMP4Writer_H264Opus(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer, err := NewWith(buffer, WithVideoCodec("video/h264"), WithAudioCodec(mimeTypeOpus))
+	assert.NoError(t, err)
+
+	assert.Equal(t, errAmbiguousTrack, writer.WriteRTP(&rtp.Packet{}))
+
+	videoSequenceNumber, audioSequenceNumber := uint16(0), uint16(0)
+	writeVideo := func(timestamp uint32, marker bool, payload []byte) {
+		assert.NoError(t, writer.WriteVideoRTP(&rtp.Packet{
+			Header:  rtp.Header{SequenceNumber: videoSequenceNumber, Timestamp: timestamp, Marker: marker},
+			Payload: payload,
+		}))
+		videoSequenceNumber++
+	}
+	writeAudio := func(timestamp uint32) {
+		assert.NoError(t, writer.WriteAudioRTP(&rtp.Packet{
+			Header:  rtp.Header{SequenceNumber: audioSequenceNumber, Timestamp: timestamp},
+			Payload: []byte{0xFC, 0x01, 0x02},
+		}))
+		audioSequenceNumber++
+	}
+
+	// Audio is dropped until the first video key frame
+	writeAudio(0)
+	writeAudio(960)
+
+	for i := uint32(0); i < 10; i++ {
+		timestamp := i * 3000
+		if i%5 == 0 {
+			writeVideo(timestamp, false, testH264SPS)
+			writeVideo(timestamp, false, testH264PPS)
+			writeVideo(timestamp, true, []byte{0x65, 0x88, 0x84})
+		} else {
+			writeVideo(timestamp, true, []byte{0x41, 0x9a, 0x02})
+		}
+		writeAudio(1920 + i*1600)
+		writeAudio(1920 + i*1600 + 800)
+	}
+	writeVideo(30000, true, []byte{0x41, 0x9a, 0x02})
+
+	assert.NoError(t, writer.Close())
+	assert.NoError(t, writer.Close())
+	assert.Equal(t, errFileNotOpened, writer.WriteVideoRTP(&rtp.Packet{}))
+
+	types, contents := topLevelBoxes(t, buffer.Bytes())
+	assert.Equal(t, []string{"ftyp", "moov", "moof", "mdat", "moof", "mdat"}, types)
+
+	moov := contents[1]
+	assert.True(t, bytes.Contains(moov, []byte("avcC")))
+	assert.True(t, bytes.Contains(moov, append([]byte("dOps"), 0, 2, 0, 0, 0, 0, 0xBB, 0x80)))
+
+	// The first fragment ends before the second key frame
+	assert.Equal(t, []uint32{5, 9}, trunSampleCounts(contents[2]))
+	assert.Equal(t, []uint32{5, 9}, trunSampleCounts(contents[4]))
+
+	// The first video sample is the key frame in AVCC format
+	expectedKeyFrame := []byte{0, 0, 0, byte(len(testH264SPS))}
+	expectedKeyFrame = append(expectedKeyFrame, testH264SPS...)
+	expectedKeyFrame = append(expectedKeyFrame, 0, 0, 0, byte(len(testH264PPS)))
+	expectedKeyFrame = append(expectedKeyFrame, testH264PPS...)
+	expectedKeyFrame = append(expectedKeyFrame, 0, 0, 0, 3, 0x65, 0x88, 0x84)
+	assert.True(t, bytes.HasPrefix(contents[3], expectedKeyFrame))
+}
+
+func TestMP4Writer_Errors(t *testing.T) {
+	_, err := NewWith(nil, WithAudioCodec(mimeTypeOpus))
+	assert.Equal(t, errFileNotOpened, err)
+
+	_, err = NewWith(&bytes.Buffer{})
+	assert.Equal(t, errNoTracks, err)
+
+	_, err = NewWith(&bytes.Buffer{}, WithVideoCodec("video/VP8"))
+	assert.Equal(t, errNoSuchCodec, err)
+
+	_, err = NewWith(&bytes.Buffer{}, WithAudioCodec(mimeTypeOpus), WithAudioCodec(mimeTypeOpus))
+	assert.Equal(t, errCodecAlreadySet, err)
+
+	writer, err := NewWith(&bytes.Buffer{}, WithAudioCodec(mimeTypeOpus))
+	assert.NoError(t, err)
+	assert.Equal(t, errNoVideoTrack, writer.WriteVideoRTP(&rtp.Packet{}))
+	assert.Equal(t, errInvalidNilPacket, writer.WriteRTP(nil))
+}
+
+func TestMP4Writer_AudioOnlyFragments(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer, err := NewWith(buffer, WithAudioCodec(mimeTypeOpus))
+	assert.NoError(t, err)
+
+	// 2.5 seconds of 20ms packets
+	for i := uint32(0); i < 125; i++ {
+		assert.NoError(t, writer.WriteRTP(&rtp.Packet{
+			Header:  rtp.Header{SequenceNumber: uint16(i), Timestamp: i * 960},
+			Payload: []byte{0xFC},
+		}))
+	}
+	assert.NoError(t, writer.Close())
+
+	types, contents := topLevelBoxes(t, buffer.Bytes())
+	assert.Equal(t, []string{"ftyp", "moov", "moof", "mdat", "moof", "mdat", "moof", "mdat"}, types)
+	assert.Equal(t, []uint32{50}, trunSampleCounts(contents[2]))
+	assert.Equal(t, []uint32{50}, trunSampleCounts(contents[4]))
+	assert.Equal(t, []uint32{24}, trunSampleCounts(contents[6]))
+}
@@ -0,0 +1,199 @@
+package mp4writer
+
+// vp9Track keeps the configuration of the last VP9 key frame for the vpcC box
+type vp9Track struct {
+	header *vp9KeyFrameHeader
+}
+
+func (v *vp9Track) convert(frame []byte) (sample []byte, keyFrame bool) {
+	header, err := parseVP9KeyFrameHeader(frame)
+	if err != nil || header == nil {
+		return frame, false
+	}
+
+	v.header = header
+	return frame, true
+}
+
+// sampleEntry returns the vp09 sample entry, once a key frame has been received
+// https://www.webmproject.org/vp9/mp4/
+func (v *vp9Track) sampleEntry() ([]byte, error) {
+	if v.header == nil {
+		return nil, errMissingCodecConfig
+	}
+
+	// 4:2:0 colocated with luma, 4:2:2 and 4:4:4
+	chromaSubsampling := byte(1)
+	switch {
+	case !v.header.subsamplingX:
+		chromaSubsampling = 3
+	case !v.header.subsamplingY:
+		chromaSubsampling = 2
+	}
+
+	vpcC := []byte{
+		v.header.profile,
+		vp9Level(uint64(v.header.width) * uint64(v.header.height)),
+		v.header.bitDepth<<4 | chromaSubsampling<<1 | v.header.colorRange,
+		2,    // colourPrimaries, unspecified
+		2,    // transferCharacteristics, unspecified
+		2,    // matrixCoefficients, unspecified
+		0, 0, // codecInitializationDataSize
+	}
+
+	return visualSampleEntry("vp09", v.header.width, v.header.height, fullBox("vpcC", 1, 0, vpcC)), nil
+}
+
+// vp9Level returns the lowest level allowing the picture size
+func vp9Level(lumaPictureSize uint64) byte {
+	for _, level := range []struct {
+		maxLumaPictureSize uint64
+		level              byte
+	}{
+		{36864, 10},
+		{73728, 11},
+		{122880, 20},
+		{245760, 21},
+		{552960, 30},
+		{983040, 31},
+		{2228224, 40},
+		{8912896, 50},
+	} {
+		if lumaPictureSize <= level.maxLumaPictureSize {
+			return level.level
+		}
+	}
+
+	return 60
+}
+
+type vp9KeyFrameHeader struct {
+	profile       byte
+	bitDepth      byte
+	colorRange    byte
+	subsamplingX  bool
+	subsamplingY  bool
+	width, height uint16
+}
+
+// parseVP9KeyFrameHeader parses the uncompressed header of a frame, and returns
+// nil when the frame is not a key frame
+// https://storage.googleapis.com/downloads.webmproject.org/docs/vp9/vp9-bitstream-specification-v0.6-20160331-draft.pdf 6.2
+func parseVP9KeyFrameHeader(frame []byte) (*vp9KeyFrameHeader, error) { //nolint:gocognit
+	const (
+		frameMarker   = 2
+		syncCode      = 0x498342
+		colorSpaceRGB = 7
+	)
+
+	r := &bitReader{data: frame}
+	marker, err := r.readBits(2)
+	if err != nil {
+		return nil, err
+	}
+	if marker != frameMarker {
+		return nil, errInvalidHeader
+	}
+
+	profileLow, err := r.readBits(1)
+	if err != nil {
+		return nil, err
+	}
+	profileHigh, err := r.readBits(1)
+	if err != nil {
+		return nil, err
+	}
+	header := &vp9KeyFrameHeader{profile: byte(profileHigh<<1 | profileLow), bitDepth: 8}
+	if header.profile == 3 {
+		// reserved_zero
+		if err = r.skipBits(1); err != nil {
+			return nil, err
+		}
+	}
+
+	showExistingFrame, err := r.readFlag()
+	if err != nil || showExistingFrame {
+		return nil, err
+	}
+	frameType, err := r.readBits(1)
+	if err != nil || frameType != 0 {
+		return nil, err
+	}
+	// show_frame, error_resilient_mode
+	if err = r.skipBits(1, 1); err != nil {
+		return nil, err
+	}
+
+	code, err := r.readBits(24)
+	if err != nil {
+		return nil, err
+	}
+	if code != syncCode {
+		return nil, errInvalidHeader
+	}
+
+	if header.profile >= 2 {
+		tenOrTwelveBit, err := r.readFlag()
+		if err != nil {
+			return nil, err
+		}
+		header.bitDepth = 10
+		if tenOrTwelveBit {
+			header.bitDepth = 12
+		}
+	}
+
+	colorSpace, err := r.readBits(3)
+	if err != nil {
+		return nil, err
+	}
+	if colorSpace != colorSpaceRGB {
+		colorRange, err := r.readBits(1)
+		if err != nil {
+			return nil, err
+		}
+		header.colorRange = byte(colorRange)
+
+		header.subsamplingX, header.subsamplingY = true, true
+		if header.profile == 1 || header.profile == 3 {
+			if header.subsamplingX, err = r.readFlag(); err != nil {
+				return nil, err
+			}
+			if header.subsamplingY, err = r.readFlag(); err != nil {
+				return nil, err
+			}
+			// reserved_zero
+			if err = r.skipBits(1); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		header.colorRange = 1
+		if header.profile == 1 || header.profile == 3 {
+			// reserved_zero
+			if err = r.skipBits(1); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	widthMinus1, err := r.readBits(16)
+	if err != nil {
+		return nil, err
+	}
+	heightMinus1, err := r.readBits(16)
+	if err != nil {
+		return nil, err
+	}
+	header.width, header.height = uint16(widthMinus1+1), uint16(heightMinus1+1)
+
+	return header, nil
+}
+
+func (v *vp9Track) size() (width, height uint16) {
+	if v.header == nil {
+		return 0, 0
+	}
+
+	return v.header.width, v.header.height
+}
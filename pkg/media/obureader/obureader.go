@@ -0,0 +1,318 @@
+// Package obureader implements an AV1 OBU stream reader, for both the low overhead
+// bitstream format and the length delimited Annex-B format
+package obureader
+
+import (
+	"errors"
+	"io"
+)
+
+const (
+	obuForbiddenBitMask    = byte(0b10000000)
+	obuTypeMask            = byte(0b01111000)
+	obuExtensionFlagMask   = byte(0b00000100)
+	obuHasSizeFieldBitMask = byte(0b00000010)
+
+	obuTypeBitshift = 3
+
+	leb128MaxSize = 8
+
+	// Temporal units and OBUs read in memory are limited
+	maxTemporalUnitSize = 64 * 1024 * 1024
+)
+
+// OBUType is the type of an OBU
+// https://aomediacodec.github.io/av1-spec/#obu-header-semantics
+type OBUType uint8
+
+// Enums for OBUTypes
+const (
+	OBUTypeSequenceHeader       OBUType = 1
+	OBUTypeTemporalDelimiter    OBUType = 2
+	OBUTypeFrameHeader          OBUType = 3
+	OBUTypeTileGroup            OBUType = 4
+	OBUTypeMetadata             OBUType = 5
+	OBUTypeFrame                OBUType = 6
+	OBUTypeRedundantFrameHeader OBUType = 7
+	OBUTypeTileList             OBUType = 8
+	OBUTypePadding              OBUType = 15
+)
+
+// Format is the bitstream format of an AV1 stream
+type Format int
+
+const (
+	// FormatLowOverhead is the low overhead bitstream format of section 5 of the AV1
+	// specification, used by .obu files. Every OBU has a size field.
+	FormatLowOverhead Format = iota
+	// FormatAnnexB is the length delimited bitstream format of annex B of the AV1
+	// specification, where temporal units, frames and OBUs are prefixed by their size.
+	FormatAnnexB
+)
+
+var (
+	errNilStream            = errors.New("stream is nil")
+	errInvalidFormat        = errors.New("invalid AV1 bitstream format")
+	errForbiddenBitSet      = errors.New("OBU forbidden bit is set")
+	errMissingOBUSize       = errors.New("OBU has no size field in the low overhead bitstream format")
+	errInvalidLEB128        = errors.New("leb128 value is longer than 8 bytes")
+	errIncompleteOBU        = errors.New("incomplete OBU")
+	errIncompleteAnnexBTU   = errors.New("incomplete Annex-B temporal unit")
+	errInvalidAnnexBLength  = errors.New("length exceeds its enclosing Annex-B unit")
+	errTemporalUnitTooLarge = errors.New("temporal unit is too large")
+)
+
+// TemporalUnit contains the OBUs of all the frames with the same presentation time
+type TemporalUnit struct {
+	// Data contains the OBUs of the temporal unit in the low overhead bitstream
+	// format, without the temporal delimiter, as expected by the AV1 payloader
+	Data []byte
+	// IsKeyFrame is true when the temporal unit contains a sequence header
+	IsKeyFrame bool
+}
+
+// OBUReader reads AV1 temporal units from a stream
+type OBUReader struct {
+	stream io.Reader
+	format Format
+
+	// In the low overhead format, the temporal delimiter starting the next
+	// temporal unit has been read while looking for the end of the previous one
+	pending *TemporalUnit
+}
+
+// NewWith returns a new OBU reader for a stream in the given format
+func NewWith(in io.Reader, format Format) (*OBUReader, error) {
+	if in == nil {
+		return nil, errNilStream
+	}
+	if format != FormatLowOverhead && format != FormatAnnexB {
+		return nil, errInvalidFormat
+	}
+
+	return &OBUReader{stream: in, format: format}, nil
+}
+
+// NextTemporalUnit reads the next temporal unit of the stream.
+// io.EOF is returned once the stream is exhausted.
+func (r *OBUReader) NextTemporalUnit() (*TemporalUnit, error) {
+	if r.format == FormatAnnexB {
+		return r.nextAnnexBTemporalUnit()
+	}
+
+	return r.nextLowOverheadTemporalUnit()
+}
+
+func (r *OBUReader) nextLowOverheadTemporalUnit() (*TemporalUnit, error) {
+	tu := r.pending
+	r.pending = nil
+	if tu == nil {
+		tu = &TemporalUnit{}
+	}
+
+	for {
+		obu, err := r.readLowOverheadOBU()
+		switch {
+		case errors.Is(err, io.EOF):
+			if len(tu.Data) == 0 {
+				return nil, io.EOF
+			}
+			return tu, nil
+		case err != nil:
+			return nil, err
+		}
+
+		if obuType(obu[0]) == OBUTypeTemporalDelimiter {
+			if len(tu.Data) == 0 {
+				continue
+			}
+			r.pending = &TemporalUnit{}
+			return tu, nil
+		}
+
+		if len(tu.Data)+len(obu) > maxTemporalUnitSize {
+			return nil, errTemporalUnitTooLarge
+		}
+		tu.append(obu)
+	}
+}
+
+// readLowOverheadOBU reads a whole OBU, header and size field included
+func (r *OBUReader) readLowOverheadOBU() ([]byte, error) {
+	header := make([]byte, 1, 2)
+	if _, err := io.ReadFull(r.stream, header); err != nil {
+		return nil, err
+	}
+	if header[0]&obuForbiddenBitMask != 0 {
+		return nil, errForbiddenBitSet
+	}
+	if header[0]&obuHasSizeFieldBitMask == 0 {
+		return nil, errMissingOBUSize
+	}
+	if header[0]&obuExtensionFlagMask != 0 {
+		header = header[:2]
+		if _, err := io.ReadFull(r.stream, header[1:]); err != nil {
+			return nil, errIncompleteOBU
+		}
+	}
+
+	size, sizeField, err := readLEB128(r.stream)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxTemporalUnitSize {
+		return nil, errTemporalUnitTooLarge
+	}
+
+	obu := make([]byte, len(header)+len(sizeField)+int(size))
+	copy(obu, header)
+	copy(obu[len(header):], sizeField)
+	if _, err := io.ReadFull(r.stream, obu[len(header)+len(sizeField):]); err != nil {
+		return nil, errIncompleteOBU
+	}
+
+	return obu, nil
+}
+
+func (r *OBUReader) nextAnnexBTemporalUnit() (*TemporalUnit, error) {
+	tuSize, _, err := readLEB128(r.stream)
+	if err != nil {
+		return nil, err
+	}
+	if tuSize > maxTemporalUnitSize {
+		return nil, errTemporalUnitTooLarge
+	}
+
+	data := make([]byte, tuSize)
+	if _, err := io.ReadFull(r.stream, data); err != nil {
+		return nil, errIncompleteAnnexBTU
+	}
+
+	tu := &TemporalUnit{}
+	for len(data) > 0 {
+		frame, rest, err := splitAnnexBUnit(data)
+		if err != nil {
+			return nil, err
+		}
+		data = rest
+
+		for len(frame) > 0 {
+			var obu []byte
+			if obu, frame, err = splitAnnexBUnit(frame); err != nil {
+				return nil, err
+			}
+			if len(obu) == 0 {
+				continue
+			}
+			if obu[0]&obuForbiddenBitMask != 0 {
+				return nil, errForbiddenBitSet
+			}
+			if obuType(obu[0]) == OBUTypeTemporalDelimiter {
+				continue
+			}
+
+			if obu, err = withSizeField(obu); err != nil {
+				return nil, err
+			}
+			tu.append(obu)
+		}
+	}
+
+	return tu, nil
+}
+
+func (tu *TemporalUnit) append(obu []byte) {
+	if obuType(obu[0]) == OBUTypeSequenceHeader {
+		tu.IsKeyFrame = true
+	}
+	tu.Data = append(tu.Data, obu...)
+}
+
+func obuType(header byte) OBUType {
+	return OBUType((header & obuTypeMask) >> obuTypeBitshift)
+}
+
+// splitAnnexBUnit returns the first length prefixed unit of data, and the data following it
+func splitAnnexBUnit(data []byte) ([]byte, []byte, error) {
+	size, n, err := parseLEB128(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if uint64(len(data)-n) < size {
+		return nil, nil, errInvalidAnnexBLength
+	}
+
+	end := n + int(size)
+	return data[n:end], data[end:], nil
+}
+
+// withSizeField returns the OBU with a size field, Annex-B OBUs usually have none
+func withSizeField(obu []byte) ([]byte, error) {
+	if obu[0]&obuHasSizeFieldBitMask != 0 {
+		return obu, nil
+	}
+
+	headerSize := 1
+	if obu[0]&obuExtensionFlagMask != 0 {
+		headerSize = 2
+	}
+	if len(obu) < headerSize {
+		return nil, errIncompleteOBU
+	}
+
+	out := make([]byte, 0, len(obu)+leb128MaxSize)
+	out = append(out, obu[0]|obuHasSizeFieldBitMask)
+	out = append(out, obu[1:headerSize]...)
+	out = appendLEB128(out, uint64(len(obu)-headerSize))
+
+	return append(out, obu[headerSize:]...), nil
+}
+
+// readLEB128 reads a leb128 value from the stream, and returns it with its encoding
+func readLEB128(stream io.Reader) (uint64, []byte, error) {
+	encoded := make([]byte, 0, leb128MaxSize)
+	b := make([]byte, 1)
+	for i := 0; i < leb128MaxSize; i++ {
+		if _, err := io.ReadFull(stream, b); err != nil {
+			if i != 0 {
+				return 0, nil, errIncompleteOBU
+			}
+			return 0, nil, err
+		}
+
+		encoded = append(encoded, b[0])
+		if b[0]&0x80 == 0 {
+			value, _, err := parseLEB128(encoded)
+			return value, encoded, err
+		}
+	}
+
+	return 0, nil, errInvalidLEB128
+}
+
+// parseLEB128 returns the leb128 value at the start of data and the length of its encoding
+func parseLEB128(data []byte) (uint64, int, error) {
+	var value uint64
+	for i := 0; i < leb128MaxSize; i++ {
+		if i >= len(data) {
+			return 0, 0, errInvalidAnnexBLength
+		}
+
+		value |= uint64(data[i]&0x7F) << (7 * i)
+		if data[i]&0x80 == 0 {
+			return value, i + 1, nil
+		}
+	}
+
+	return 0, 0, errInvalidLEB128
+}
+
+func appendLEB128(b []byte, value uint64) []byte {
+	for {
+		if value < 0x80 {
+			return append(b, byte(value))
+		}
+		b = append(b, byte(value&0x7F)|0x80)
+		value >>= 7
+	}
+}
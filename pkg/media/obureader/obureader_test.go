@@ -0,0 +1,111 @@
+package obureader
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWith(t *testing.T) {
+	_, err := NewWith(nil, FormatLowOverhead)
+	assert.ErrorIs(t, err, errNilStream)
+
+	_, err = NewWith(&bytes.Buffer{}, Format(5))
+	assert.ErrorIs(t, err, errInvalidFormat)
+}
+
+func TestLowOverhead(t *testing.T) {
+	stream := []byte{
+		0x12, 0x00, // Temporal delimiter
+		0x0A, 0x02, 0xAA, 0xBB, // Sequence header
+		0x32, 0x01, 0x01, // Frame
+		0x12, 0x00, // Temporal delimiter
+		0x36, 0x28, 0x01, 0x02, // Frame with extension
+	}
+
+	reader, err := NewWith(bytes.NewReader(stream), FormatLowOverhead)
+	assert.NoError(t, err)
+
+	tu, err := reader.NextTemporalUnit()
+	assert.NoError(t, err)
+	assert.True(t, tu.IsKeyFrame)
+	assert.Equal(t, []byte{0x0A, 0x02, 0xAA, 0xBB, 0x32, 0x01, 0x01}, tu.Data)
+
+	tu, err = reader.NextTemporalUnit()
+	assert.NoError(t, err)
+	assert.False(t, tu.IsKeyFrame)
+	assert.Equal(t, []byte{0x36, 0x28, 0x01, 0x02}, tu.Data)
+
+	_, err = reader.NextTemporalUnit()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestLowOverhead_Errors(t *testing.T) {
+	for _, test := range []struct {
+		stream []byte
+		err    error
+	}{
+		{[]byte{0x92, 0x00}, errForbiddenBitSet},
+		{[]byte{0x10}, errMissingOBUSize},
+		{[]byte{0x32, 0x05, 0x01}, errIncompleteOBU},
+		{[]byte{0x32, 0x80}, errIncompleteOBU},
+		{[]byte{0x32, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80}, errInvalidLEB128},
+		{[]byte{0x32, 0xFF, 0xFF, 0xFF, 0xFF, 0x0F}, errTemporalUnitTooLarge},
+	} {
+		reader, err := NewWith(bytes.NewReader(test.stream), FormatLowOverhead)
+		assert.NoError(t, err)
+
+		_, err = reader.NextTemporalUnit()
+		assert.ErrorIs(t, err, test.err)
+	}
+}
+
+func TestAnnexB(t *testing.T) {
+	stream := []byte{
+		0x0A,       // Temporal unit size
+		0x09,       // Frame unit size
+		0x01, 0x10, // Temporal delimiter
+		0x03, 0x08, 0xAA, 0xBB, // Sequence header
+		0x02, 0x30, 0x01, // Frame
+
+		0x05,                   // Temporal unit size
+		0x04,                   // Frame unit size
+		0x03, 0x34, 0x28, 0x02, // Frame with extension
+	}
+
+	reader, err := NewWith(bytes.NewReader(stream), FormatAnnexB)
+	assert.NoError(t, err)
+
+	tu, err := reader.NextTemporalUnit()
+	assert.NoError(t, err)
+	assert.True(t, tu.IsKeyFrame)
+	assert.Equal(t, []byte{0x0A, 0x02, 0xAA, 0xBB, 0x32, 0x01, 0x01}, tu.Data)
+
+	tu, err = reader.NextTemporalUnit()
+	assert.NoError(t, err)
+	assert.False(t, tu.IsKeyFrame)
+	assert.Equal(t, []byte{0x36, 0x28, 0x01, 0x02}, tu.Data)
+
+	_, err = reader.NextTemporalUnit()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestAnnexB_Errors(t *testing.T) {
+	for _, test := range []struct {
+		stream []byte
+		err    error
+	}{
+		{[]byte{0x05, 0x01}, errIncompleteAnnexBTU},
+		{[]byte{0x02, 0x05, 0x00}, errInvalidAnnexBLength},
+		{[]byte{0x03, 0x02, 0x01, 0x90}, errForbiddenBitSet},
+		{[]byte{0xFF, 0xFF, 0xFF, 0xFF, 0x0F}, errTemporalUnitTooLarge},
+	} {
+		reader, err := NewWith(bytes.NewReader(test.stream), FormatAnnexB)
+		assert.NoError(t, err)
+
+		_, err = reader.NextTemporalUnit()
+		assert.ErrorIs(t, err, test.err)
+	}
+}
@@ -0,0 +1,113 @@
+// Package obuwriter implements an AV1 OBU stream writer, in the low overhead bitstream
+// format of .obu files
+package obuwriter
+
+import (
+	"io"
+	"os"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/rtpcodecs"
+)
+
+// temporalDelimiter is the OBU starting every temporal unit, with a size field
+var temporalDelimiter = []byte{0x12, 0x00} //nolint:gochecknoglobals
+
+type (
+	// OBUWriter is used to take AV1 RTP packets and write the temporal units they carry
+	// to an io.Writer in the low overhead bitstream format, which obureader reads back.
+	// Every temporal unit is preceded by a temporal delimiter and every OBU has a size field.
+	// Temporal units are discarded until the first key frame, and incomplete ones are dropped.
+	OBUWriter struct {
+		writer       io.Writer
+		hasKeyFrame  bool
+		depacketizer *rtpcodecs.AV1Depacketizer
+
+		temporalUnit   []byte
+		started        bool
+		timestamp      uint32
+		sequenceNumber uint16
+		incomplete     bool
+	}
+)
+
+// New builds a new OBU writer
+func New(filename string) (*OBUWriter, error) {
+	f, err := os.Create(filename) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWith(f), nil
+}
+
+// NewWith initializes a new OBU writer with an io.Writer output
+func NewWith(w io.Writer) *OBUWriter {
+	return &OBUWriter{
+		writer:       w,
+		depacketizer: &rtpcodecs.AV1Depacketizer{},
+	}
+}
+
+// WriteRTP adds a new packet, the temporal unit it belongs to is written once its last packet,
+// with the marker bit, is received
+func (o *OBUWriter) WriteRTP(packet *rtp.Packet) error {
+	if len(packet.Payload) == 0 {
+		return nil
+	}
+
+	// A temporal unit starts with a new timestamp, the previous one is dropped if its last
+	// packet was lost. Packets lost within a temporal unit make it incomplete.
+	switch {
+	case !o.started || packet.Timestamp != o.timestamp:
+		o.temporalUnit = o.temporalUnit[:0]
+		o.incomplete = !o.depacketizer.IsPartitionHead(packet.Payload)
+		o.timestamp, o.started = packet.Timestamp, true
+	case packet.SequenceNumber != o.sequenceNumber+1:
+		o.incomplete = true
+	}
+	o.sequenceNumber = packet.SequenceNumber
+
+	obus, err := o.depacketizer.Unmarshal(packet.Payload)
+	if err != nil {
+		o.incomplete = true
+		return err
+	}
+	o.temporalUnit = append(o.temporalUnit, obus...)
+
+	if !packet.Marker {
+		return nil
+	}
+
+	temporalUnit, incomplete := o.temporalUnit, o.incomplete
+	o.temporalUnit, o.incomplete = o.temporalUnit[:0], true
+	if incomplete || len(temporalUnit) == 0 {
+		return nil
+	}
+
+	if !o.hasKeyFrame {
+		if o.hasKeyFrame = o.depacketizer.IsKeyFrame(temporalUnit); !o.hasKeyFrame {
+			// key frame not defined yet. discarding temporal unit
+			return nil
+		}
+	}
+
+	if _, err = o.writer.Write(temporalDelimiter); err != nil {
+		return err
+	}
+	_, err = o.writer.Write(temporalUnit)
+
+	return err
+}
+
+// Close closes the underlying writer
+func (o *OBUWriter) Close() error {
+	o.temporalUnit = nil
+	if o.writer != nil {
+		if closer, ok := o.writer.(io.Closer); ok {
+			return closer.Close()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package obuwriter
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/media/obureader"
+	"github.com/stretchr/testify/assert"
+)
+
+type writerCloser struct {
+	bytes.Buffer
+}
+
+var errClose = errors.New("close error")
+
+func (w *writerCloser) Close() error {
+	return errClose
+}
+
+func TestNewWith(t *testing.T) {
+	writer := &writerCloser{}
+	obuWriter := NewWith(writer)
+	assert.NotNil(t, obuWriter.Close())
+}
+
+func TestWriteRTP(t *testing.T) {
+	packets := []*rtp.Packet{
+		// A frame before the first key frame is discarded
+		{Header: rtp.Header{SequenceNumber: 1, Timestamp: 1000, Marker: true}, Payload: []byte{0x10, 0x30, 0x01}},
+		// A sequence header and a frame, the last OBU element has no length
+		{Header: rtp.Header{SequenceNumber: 2, Timestamp: 2000, Marker: true}, Payload: []byte{0x28, 0x03, 0x08, 0xAA, 0xBB, 0x30, 0x02}},
+		// A frame fragmented across two packets
+		{Header: rtp.Header{SequenceNumber: 3, Timestamp: 3000}, Payload: []byte{0x50, 0x30, 0x03, 0x04}},
+		{Header: rtp.Header{SequenceNumber: 4, Timestamp: 3000, Marker: true}, Payload: []byte{0x90, 0x05}},
+		// A temporal unit that lost its first packet is dropped
+		{Header: rtp.Header{SequenceNumber: 6, Timestamp: 4000, Marker: true}, Payload: []byte{0x90, 0x07}},
+		// As is one that lost a packet in the middle
+		{Header: rtp.Header{SequenceNumber: 7, Timestamp: 5000}, Payload: []byte{0x10, 0x30, 0x08}},
+		{Header: rtp.Header{SequenceNumber: 9, Timestamp: 5000, Marker: true}, Payload: []byte{0x10, 0x30, 0x09}},
+		{Header: rtp.Header{SequenceNumber: 10, Timestamp: 6000, Marker: true}, Payload: []byte{0x10, 0x30, 0x06}},
+	}
+
+	buffer := &bytes.Buffer{}
+	writer := NewWith(buffer)
+	for _, packet := range packets {
+		assert.NoError(t, writer.WriteRTP(packet))
+	}
+	assert.NoError(t, writer.Close())
+
+	assert.Equal(t, []byte{
+		0x12, 0x00, 0x0A, 0x02, 0xAA, 0xBB, 0x32, 0x01, 0x02,
+		0x12, 0x00, 0x32, 0x03, 0x03, 0x04, 0x05,
+		0x12, 0x00, 0x32, 0x01, 0x06,
+	}, buffer.Bytes())
+
+	// The stream is read back by obureader
+	reader, err := obureader.NewWith(bytes.NewReader(buffer.Bytes()), obureader.FormatLowOverhead)
+	assert.NoError(t, err)
+
+	tu, err := reader.NextTemporalUnit()
+	assert.NoError(t, err)
+	assert.True(t, tu.IsKeyFrame)
+	assert.Equal(t, []byte{0x0A, 0x02, 0xAA, 0xBB, 0x32, 0x01, 0x02}, tu.Data)
+
+	for _, expected := range [][]byte{{0x32, 0x03, 0x03, 0x04, 0x05}, {0x32, 0x01, 0x06}} {
+		tu, err = reader.NextTemporalUnit()
+		assert.NoError(t, err)
+		assert.False(t, tu.IsKeyFrame)
+		assert.Equal(t, expected, tu.Data)
+	}
+
+	_, err = reader.NextTemporalUnit()
+	assert.Equal(t, io.EOF, err)
+}
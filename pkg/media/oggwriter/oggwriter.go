@@ -10,6 +10,7 @@ import (
 	"github.com/pion/randutil"
 	"github.com/pion/rtp"
 	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v3/pkg/media/opus"
 )
 
 const (
@@ -37,7 +38,8 @@ type OggWriter struct {
 	pageIndex               uint32
 	checksumTable           *[256]uint32
 	previousGranulePosition uint64
-	previousTimestamp       uint32
+	previousPayload         []byte
+	nextTimestamp           uint32
 	lastPayloadSize         int
 }
 
@@ -67,11 +69,6 @@ func NewWith(out io.Writer, sampleRate uint32, channelCount uint16) (*OggWriter,
 		channelCount:  channelCount,
 		serial:        randutil.NewMathRandomGenerator().Uint32(),
 		checksumTable: generateChecksumTable(),
-
-		// Timestamp and Granule MUST start from 1
-		// Only headers can have 0 values
-		previousTimestamp:       1,
-		previousGranulePosition: 1,
 	}
 	if err := writer.writeHeaders(); err != nil {
 		return nil, err
@@ -183,13 +180,40 @@ func (i *OggWriter) WriteRTP(packet *rtp.Packet) error {
 	}
 
 	payload := opusPacket.Payload[0:]
+	samples, err := opus.PacketSamples(payload)
+	if err != nil {
+		return err
+	}
 
-	// Should be equivalent to sampleRate * duration
-	if i.previousTimestamp != 1 {
-		increment := packet.Timestamp - i.previousTimestamp
-		i.previousGranulePosition += uint64(increment)
+	// The granule position counts the decoded samples, the gaps left by lost
+	// packets and DTX are filled with empty frames that decoders conceal.
+	// Discontinuities longer than opus.MaxConcealmentGap aren't filled.
+	if i.previousPayload != nil {
+		gap := int32(packet.Timestamp - i.nextTimestamp)
+		if gap < 0 {
+			// Late or duplicated packet
+			return nil
+		}
+		for _, concealment := range opus.ConcealmentPackets(i.previousPayload, uint32(gap)) {
+			if err := i.writePacket(concealment); err != nil {
+				return err
+			}
+		}
+	}
+	i.previousPayload = append([]byte{}, payload...)
+	i.nextTimestamp = packet.Timestamp + uint32(samples)
+
+	return i.writePacket(payload)
+}
+
+// writePacket writes an Opus packet in its own page, the granule position of
+// the page is the one of the end of the packet
+func (i *OggWriter) writePacket(payload []byte) error {
+	samples, err := opus.PacketSamples(payload)
+	if err != nil {
+		return err
 	}
-	i.previousTimestamp = packet.Timestamp
+	i.previousGranulePosition += uint64(samples)
 
 	data := i.createPage(payload, pageHeaderTypeContinuationOfStream, i.previousGranulePosition, i.pageIndex)
 	i.pageIndex++
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/media/oggreader"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -130,3 +131,39 @@ func TestOggWriter_EmptyPayload(t *testing.T) {
 
 	assert.NoError(t, writer.WriteRTP(&rtp.Packet{Payload: []byte{}}))
 }
+
+func TestOggWriter_GranulePosition(t *testing.T) {
+	buffer := &bytes.Buffer{}
+
+	writer, err := NewWith(buffer, 48000, 2)
+	assert.NoError(t, err)
+
+	// 20ms CELT packets, the third one is lost, the fifth one is late, and the
+	// timestamps jump before the last one
+	for _, timestamp := range []uint32{1000, 1960, 3880, 4840, 3880, 5800, 5800 + 1<<30} {
+		assert.NoError(t, writer.WriteRTP(&rtp.Packet{
+			Header:  rtp.Header{Timestamp: timestamp},
+			Payload: []byte{0xFC, 0x01, 0x02},
+		}))
+	}
+	assert.NoError(t, writer.Close())
+
+	reader, _, err := oggreader.NewWith(buffer)
+	assert.NoError(t, err)
+
+	var granulePositions []uint64
+	var payloads [][]byte
+	for {
+		payload, header, err := reader.ParseNextPage()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		granulePositions = append(granulePositions, header.GranulePosition)
+		payloads = append(payloads, payload)
+	}
+
+	// The header page and the packets, with an empty frame for the lost one
+	assert.Equal(t, []uint64{0, 960, 1920, 2880, 3840, 4800, 5760, 6720}, granulePositions)
+	assert.Equal(t, []byte{0xFC}, payloads[3])
+}
@@ -0,0 +1,133 @@
+// Package opus provides Opus specific handling of media samples: packet
+// durations, discontinuous transmission and concealment of lost packets.
+package opus
+
+import (
+	"errors"
+	"time"
+)
+
+// SampleRate is the rate of the RTP timestamps and of the Ogg granule positions
+// of Opus, whatever the sampling rate of the encoder
+const SampleRate = 48000
+
+// Packets of the discontinuous transmission (DTX) are at most this long, they
+// only carry comfort noise parameters
+const maxDTXPacketSize = 2
+
+// 120ms is the longest duration of a packet, and 2.5ms the shortest of a frame
+const (
+	maxPacketSamples = 5760
+	minFrameSamples  = 120
+)
+
+// MaxConcealmentGap is the longest gap, 5 seconds, that is filled with
+// concealment packets. A longer jump of the timestamps is a discontinuity, like
+// a switch of the source, and isn't filled.
+const MaxConcealmentGap = 5 * SampleRate
+
+var (
+	errEmptyPacket   = errors.New("empty Opus packet")
+	errInvalidPacket = errors.New("invalid Opus packet")
+)
+
+// frameSamples returns the number of samples at 48kHz of each frame of a
+// packet, from the configuration of its TOC byte
+func frameSamples(toc byte) int {
+	config := toc >> 3
+	switch {
+	case config < 12: // SILK, 10, 20, 40 or 60ms
+		return []int{480, 960, 1920, 2880}[config&0x03]
+	case config < 16: // Hybrid, 10 or 20ms
+		return []int{480, 960}[config&0x01]
+	default: // CELT, 2.5, 5, 10 or 20ms
+		return []int{120, 240, 480, 960}[config&0x03]
+	}
+}
+
+// PacketSamples returns the number of samples at 48kHz of an Opus packet, from
+// its TOC byte and its frame count
+func PacketSamples(packet []byte) (int, error) {
+	if len(packet) == 0 {
+		return 0, errEmptyPacket
+	}
+
+	frames := 1
+	switch packet[0] & 0x03 {
+	case 1, 2:
+		frames = 2
+	case 3:
+		if len(packet) < 2 {
+			return 0, errInvalidPacket
+		}
+		frames = int(packet[1] & 0x3F)
+	}
+
+	samples := frames * frameSamples(packet[0])
+	if samples == 0 || samples > maxPacketSamples {
+		return 0, errInvalidPacket
+	}
+
+	return samples, nil
+}
+
+// PacketDuration returns the duration of an Opus packet
+func PacketDuration(packet []byte) (time.Duration, error) {
+	samples, err := PacketSamples(packet)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(samples) * time.Second / SampleRate, nil
+}
+
+// IsDTX returns true if the packet is a comfort noise frame of the
+// discontinuous transmission, senders stop sending packets after them until
+// the speech resumes
+func IsDTX(packet []byte) bool {
+	return len(packet) > 0 && len(packet) <= maxDTXPacketSize
+}
+
+// ConcealmentPacket returns a packet with a single empty frame, with the
+// configuration of the TOC byte. Decoders conceal empty frames as lost, or as
+// comfort noise after a DTX frame.
+func ConcealmentPacket(toc byte) []byte {
+	return []byte{toc &^ 0x03}
+}
+
+// ConcealmentPackets returns the packets of empty frames that fill a gap of
+// the given number of samples after the previous packet. They have the frame
+// duration of the previous packet, the last one may be shorter. A remainder
+// shorter than 2.5ms is not filled, nor are gaps longer than MaxConcealmentGap.
+func ConcealmentPackets(previous []byte, gap uint32) [][]byte {
+	if gap > MaxConcealmentGap {
+		return nil
+	}
+	if _, err := PacketSamples(previous); err != nil {
+		return nil
+	}
+
+	var packets [][]byte
+	for gap >= minFrameSamples {
+		samples, toc := uint32(frameSamples(previous[0])), previous[0]
+		if gap < samples {
+			samples, toc = shorterFrame(gap, toc)
+		}
+
+		packets = append(packets, ConcealmentPacket(toc))
+		gap -= samples
+	}
+
+	return packets
+}
+
+// shorterFrame returns the longest CELT frame that fits in the gap, and its
+// TOC byte
+func shorterFrame(gap uint32, toc byte) (uint32, byte) {
+	config, samples := byte(19), uint32(960)
+	for samples > gap && config > 16 {
+		config, samples = config-1, samples/2
+	}
+
+	return samples, config<<3 | toc&0x04
+}
@@ -0,0 +1,144 @@
+package opus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPacketSamples(t *testing.T) {
+	for _, test := range []struct {
+		packet  []byte
+		samples int
+		err     error
+	}{
+		{[]byte{}, 0, errEmptyPacket},
+		{[]byte{0x08}, 960, nil},                  // SILK 20ms
+		{[]byte{0x18, 0x00}, 2880, nil},           // SILK 60ms
+		{[]byte{0x19, 0x00}, 5760, nil},           // 2 SILK 60ms frames
+		{[]byte{0x68}, 960, nil},                  // Hybrid 20ms
+		{[]byte{0x80}, 120, nil},                  // CELT 2.5ms
+		{[]byte{0xFC}, 960, nil},                  // CELT 20ms stereo
+		{[]byte{0xEB, 0x03}, 720, nil},            // 3 CELT 5ms frames
+		{[]byte{0xFB}, 0, errInvalidPacket},       // Frame count missing
+		{[]byte{0x1B, 0x03}, 0, errInvalidPacket}, // 180ms
+		{[]byte{0xFB, 0x00}, 0, errInvalidPacket}, // No frames
+	} {
+		samples, err := PacketSamples(test.packet)
+		assert.Equal(t, test.err, err)
+		assert.Equal(t, test.samples, samples)
+	}
+
+	duration, err := PacketDuration([]byte{0xFC, 0x01})
+	assert.NoError(t, err)
+	assert.Equal(t, 20*time.Millisecond, duration)
+
+	assert.True(t, IsDTX([]byte{0xFC}))
+	assert.False(t, IsDTX([]byte{0xFC, 0x01, 0x02}))
+}
+
+func TestConcealmentPackets(t *testing.T) {
+	assert.Equal(t, [][]byte{{0xFC}, {0xFC}}, ConcealmentPackets([]byte{0xFC, 0x01}, 1920))
+
+	// The remainder is filled with shorter frames, down to 2.5ms
+	assert.Equal(t, [][]byte{{0x08}, {0x90}, {0x80}}, ConcealmentPackets([]byte{0x09, 0x01, 0x02}, 960+480+120+60))
+
+	assert.Nil(t, ConcealmentPackets([]byte{}, 960))
+
+	// Discontinuities aren't filled
+	assert.Nil(t, ConcealmentPackets([]byte{0xFC, 0x01}, MaxConcealmentGap+960))
+}
+
+func opusPacket(sequenceNumber uint16, timestamp uint32, payload ...byte) *rtp.Packet {
+	return &rtp.Packet{
+		Header:  rtp.Header{SequenceNumber: sequenceNumber, Timestamp: timestamp},
+		Payload: payload,
+	}
+}
+
+func TestSampleBuilder(t *testing.T) {
+	type concealment struct {
+		timestamp      uint32
+		previous, next []byte
+	}
+	var concealments []concealment
+
+	s := New(3, WithConcealmentHandler(func(timestamp uint32, duration time.Duration, previous, next []byte) []byte {
+		assert.Equal(t, 20*time.Millisecond, duration)
+		concealments = append(concealments, concealment{timestamp, previous, next})
+		if timestamp == 2920 {
+			return []byte{0xFC, 0xEC}
+		}
+		return nil
+	}))
+
+	for _, p := range []*rtp.Packet{
+		opusPacket(0, 1000, 0xFC, 0x00, 0x00),
+		opusPacket(1, 1960, 0xFC, 0x01, 0x01),
+		// Packets 2 and 3 are lost
+		opusPacket(4, 4840, 0xFC, 0x04, 0x04),
+		// DTX frame, the sender stops for 100ms
+		opusPacket(5, 5800, 0xFC),
+		opusPacket(6, 11560, 0xFC, 0x06, 0x06),
+		opusPacket(7, 12520, 0xFC, 0x07, 0x07),
+	} {
+		s.Push(p)
+	}
+
+	type expectedSample struct {
+		data               []byte
+		timestamp          uint32
+		isDTX, isConcealed bool
+		prevDroppedPackets uint16
+	}
+	var samples []expectedSample
+	for sample := s.Pop(); sample != nil; sample = s.Pop() {
+		assert.Equal(t, 20*time.Millisecond, sample.Duration)
+		samples = append(samples, expectedSample{
+			sample.Data, sample.PacketTimestamp, sample.IsDTX, sample.IsConcealed, sample.PrevDroppedPackets,
+		})
+	}
+
+	assert.Equal(t, []expectedSample{
+		{[]byte{0xFC, 0x00, 0x00}, 1000, false, false, 0},
+		{[]byte{0xFC, 0x01, 0x01}, 1960, false, false, 0},
+		{[]byte{0xFC, 0xEC}, 2920, false, true, 0},
+		{[]byte{0xFC}, 3880, false, true, 0},
+		{[]byte{0xFC, 0x04, 0x04}, 4840, false, false, 2},
+		{[]byte{0xFC}, 5800, true, false, 0},
+		{[]byte{0xFC}, 6760, true, true, 0},
+		{[]byte{0xFC}, 7720, true, true, 0},
+		{[]byte{0xFC}, 8680, true, true, 0},
+		{[]byte{0xFC}, 9640, true, true, 0},
+		{[]byte{0xFC}, 10600, true, true, 0},
+		{[]byte{0xFC, 0x06, 0x06}, 11560, false, false, 0},
+	}, samples)
+
+	// The handler is only called for the lost packets
+	assert.Equal(t, []concealment{
+		{2920, []byte{0xFC, 0x01, 0x01}, []byte{0xFC, 0x04, 0x04}},
+		{3880, []byte{0xFC, 0x01, 0x01}, []byte{0xFC, 0x04, 0x04}},
+	}, concealments)
+}
+
+// Assert that a jump of the timestamps isn't filled with samples
+func TestSampleBuilder_Discontinuity(t *testing.T) {
+	s := New(3)
+	for _, p := range []*rtp.Packet{
+		opusPacket(0, 1000, 0xFC, 0x00, 0x00),
+		opusPacket(1, 1000+1<<30, 0xFC, 0x01, 0x01),
+		opusPacket(2, 1960+1<<30, 0xFC, 0x02, 0x02),
+		opusPacket(3, 2920+1<<30, 0xFC, 0x03, 0x03),
+	} {
+		s.Push(p)
+	}
+
+	var timestamps []uint32
+	for sample := s.Pop(); sample != nil; sample = s.Pop() {
+		assert.False(t, sample.IsConcealed)
+		timestamps = append(timestamps, sample.PacketTimestamp)
+	}
+	assert.Equal(t, []uint32{1000, 1000 + 1<<30, 1960 + 1<<30}, timestamps)
+}
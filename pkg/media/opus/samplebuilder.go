@@ -0,0 +1,149 @@
+package opus
+
+import (
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/media/samplebuilder"
+)
+
+// Sample is a media.Sample of an Opus stream
+type Sample struct {
+	media.Sample
+
+	// IsDTX is set for the comfort noise frames of the discontinuous
+	// transmission, and for the samples synthesized after them
+	IsDTX bool
+	// IsConcealed is set for the samples synthesized to fill a gap
+	IsConcealed bool
+}
+
+// ConcealmentHandler returns the Opus packet of a sample synthesized in a gap
+// at the given timestamp. The packets before and after the gap are given, the
+// next one may carry the forward error correction of the lost packet. If it
+// returns nil, the sample has an empty frame that decoders conceal.
+type ConcealmentHandler func(timestamp uint32, duration time.Duration, previous, next []byte) []byte
+
+// SampleBuilder builds Opus samples from RTP packets, like the SampleBuilder of
+// package samplebuilder. The gaps left by lost packets and by the
+// discontinuous transmission are filled with samples with the right
+// timestamps, so that the timestamps of the samples follow their durations.
+// Gaps longer than MaxConcealmentGap are discontinuities and aren't filled.
+type SampleBuilder struct {
+	builder            *samplebuilder.SampleBuilder
+	concealmentHandler ConcealmentHandler
+
+	hasPrevious   bool
+	previous      []byte
+	previousDTX   bool
+	nextTimestamp uint32
+
+	pending []*Sample
+}
+
+// New constructs a new SampleBuilder, maxLate is the number of packets to
+// wait for a missing one as in samplebuilder.New
+func New(maxLate uint16, opts ...Option) *SampleBuilder {
+	s := &SampleBuilder{
+		builder: samplebuilder.New(maxLate, &codecs.OpusPacket{}, SampleRate),
+	}
+	for _, o := range opts {
+		o(s)
+	}
+
+	return s
+}
+
+// Push adds an RTP Packet to the buffer of the SampleBuilder
+func (s *SampleBuilder) Push(p *rtp.Packet) {
+	s.builder.Push(p)
+}
+
+// Pop returns the next sample, or nil if there is none yet. Samples
+// synthesized for a gap are returned before the sample that ends it.
+func (s *SampleBuilder) Pop() *Sample {
+	for len(s.pending) == 0 {
+		sample := s.builder.Pop()
+		if sample == nil {
+			return nil
+		}
+		s.addSample(sample)
+	}
+
+	sample := s.pending[0]
+	s.pending = s.pending[1:]
+	return sample
+}
+
+func (s *SampleBuilder) addSample(sample *media.Sample) {
+	samples, err := PacketSamples(sample.Data)
+	if err != nil {
+		return
+	}
+
+	if s.hasPrevious {
+		gap := int32(sample.PacketTimestamp - s.nextTimestamp)
+		if gap < 0 {
+			// Overlaps a synthesized sample
+			return
+		}
+		s.fillGap(uint32(gap), sample)
+	}
+
+	isDTX := IsDTX(sample.Data)
+	s.pending = append(s.pending, &Sample{
+		Sample: media.Sample{
+			Data:               sample.Data,
+			Duration:           time.Duration(samples) * time.Second / SampleRate,
+			PacketTimestamp:    sample.PacketTimestamp,
+			PrevDroppedPackets: sample.PrevDroppedPackets,
+		},
+		IsDTX: isDTX,
+	})
+
+	s.hasPrevious, s.previous, s.previousDTX = true, sample.Data, isDTX
+	s.nextTimestamp = sample.PacketTimestamp + uint32(samples)
+}
+
+// fillGap adds samples until the next one
+func (s *SampleBuilder) fillGap(gap uint32, next *media.Sample) {
+	// After a DTX frame, the gap is only silence unless packets were dropped
+	isDTX := s.previousDTX && next.PrevDroppedPackets == 0
+
+	for _, packet := range ConcealmentPackets(s.previous, gap) {
+		samples, _ := PacketSamples(packet)
+		timestamp := s.nextTimestamp
+		duration := time.Duration(samples) * time.Second / SampleRate
+
+		data := packet
+		if s.concealmentHandler != nil && !isDTX {
+			if concealed := s.concealmentHandler(timestamp, duration, s.previous, next.Data); concealed != nil {
+				data = concealed
+			}
+		}
+
+		s.pending = append(s.pending, &Sample{
+			Sample: media.Sample{
+				Data:            data,
+				Duration:        duration,
+				PacketTimestamp: timestamp,
+			},
+			IsDTX:       isDTX,
+			IsConcealed: true,
+		})
+		s.nextTimestamp += uint32(samples)
+	}
+}
+
+// An Option configures a SampleBuilder.
+type Option func(s *SampleBuilder)
+
+// WithConcealmentHandler sets the handler called for the samples synthesized
+// in the place of lost packets
+func WithConcealmentHandler(h ConcealmentHandler) Option {
+	return func(s *SampleBuilder) {
+		s.concealmentHandler = h
+	}
+}
@@ -0,0 +1,253 @@
+// Package pcap implements a reader of the RTP and RTCP packets of pcap and
+// pcapng captures, and a pcapng writer for RTP and RTCP streams
+package pcap
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+// Link types of the captured packets
+// https://www.tcpdump.org/linktypes.html
+const (
+	linkTypeNull      = 0
+	linkTypeEthernet  = 1
+	linkTypeRaw       = 101
+	linkTypeLinuxSLL  = 113
+	linkTypeIPv4      = 228
+	linkTypeIPv6      = 229
+	linkTypeLinuxSLL2 = 276
+)
+
+const (
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86DD
+	etherTypeVLAN = 0x8100
+
+	ipv4HeaderLen = 20
+	ipv6HeaderLen = 40
+	udpHeaderLen  = 8
+
+	protocolUDP = 17
+
+	rtpHeaderLen  = 12
+	rtcpHeaderLen = 8
+)
+
+var (
+	errUnknownFormat = errors.New("not a pcap or pcapng capture")
+	errMalformed     = errors.New("malformed capture")
+	errNotUDP        = errors.New("not an UDP packet")
+)
+
+// Packet is an RTP or RTCP packet of a capture, with the UDP addresses it was
+// sent from and to
+type Packet struct {
+	// Timestamp is the capture time of the packet
+	Timestamp time.Time
+	// Source and Destination are the addresses of the UDP datagram
+	Source, Destination *net.UDPAddr
+	// IsRTCP is true if the payload is RTCP, false if the payload is RTP
+	IsRTCP bool
+	// Payload is the binary RTP or RTCP packet
+	Payload []byte
+}
+
+// RTP unmarshals the payload of a RTP packet
+func (p Packet) RTP() (*rtp.Packet, error) {
+	packet := &rtp.Packet{}
+	if err := packet.Unmarshal(p.Payload); err != nil {
+		return nil, err
+	}
+
+	return packet, nil
+}
+
+// RTCP unmarshals the payload of a compound RTCP packet
+func (p Packet) RTCP() ([]rtcp.Packet, error) {
+	return rtcp.Unmarshal(p.Payload)
+}
+
+// ssrcs returns the SSRC of a RTP packet, or the sender and media SSRCs of a
+// RTCP packet
+func (p Packet) ssrcs() []uint32 {
+	if !p.IsRTCP {
+		return []uint32{binary.BigEndian.Uint32(p.Payload[8:12])}
+	}
+
+	ssrcs := []uint32{binary.BigEndian.Uint32(p.Payload[4:8])}
+	if packets, err := rtcp.Unmarshal(p.Payload); err == nil {
+		for _, packet := range packets {
+			ssrcs = append(ssrcs, packet.DestinationSSRC()...)
+		}
+	}
+
+	return ssrcs
+}
+
+// isRTPOrRTCP classifies a UDP payload as RFC 5761 does, other protocols like
+// STUN and DTLS are rejected
+func isRTPOrRTCP(payload []byte) (ok, isRTCP bool) {
+	if len(payload) < rtcpHeaderLen || payload[0]>>6 != 2 {
+		return false, false
+	}
+	if payload[1] >= 192 && payload[1] <= 223 {
+		return true, true
+	}
+
+	return len(payload) >= rtpHeaderLen, false
+}
+
+// decodeLinkLayer returns the IP packet of a captured frame
+func decodeLinkLayer(linkType uint16, data []byte) ([]byte, error) {
+	switch linkType {
+	case linkTypeNull:
+		if len(data) < 4 {
+			return nil, errMalformed
+		}
+		return data[4:], nil
+	case linkTypeEthernet:
+		if len(data) < 14 {
+			return nil, errMalformed
+		}
+		etherType, data := binary.BigEndian.Uint16(data[12:14]), data[14:]
+		for etherType == etherTypeVLAN {
+			if len(data) < 4 {
+				return nil, errMalformed
+			}
+			etherType, data = binary.BigEndian.Uint16(data[2:4]), data[4:]
+		}
+		if etherType != etherTypeIPv4 && etherType != etherTypeIPv6 {
+			return nil, errNotUDP
+		}
+		return data, nil
+	case linkTypeRaw, linkTypeIPv4, linkTypeIPv6:
+		return data, nil
+	case linkTypeLinuxSLL:
+		if len(data) < 16 {
+			return nil, errMalformed
+		}
+		return data[16:], nil
+	case linkTypeLinuxSLL2:
+		if len(data) < 20 {
+			return nil, errMalformed
+		}
+		return data[20:], nil
+	default:
+		return nil, errNotUDP
+	}
+}
+
+// decodeUDP returns the addresses and the payload of the UDP datagram of an IP
+// packet. Fragmented datagrams are not reassembled.
+func decodeUDP(data []byte) (source, destination *net.UDPAddr, payload []byte, err error) {
+	if len(data) == 0 {
+		return nil, nil, nil, errMalformed
+	}
+
+	var sourceIP, destinationIP net.IP
+	switch data[0] >> 4 {
+	case 4:
+		headerLen := int(data[0]&0x0F) * 4
+		if len(data) < ipv4HeaderLen || len(data) < headerLen {
+			return nil, nil, nil, errMalformed
+		}
+		if data[9] != protocolUDP || binary.BigEndian.Uint16(data[6:8])&0x3FFF != 0 {
+			return nil, nil, nil, errNotUDP
+		}
+		if totalLen := int(binary.BigEndian.Uint16(data[2:4])); totalLen >= headerLen && totalLen < len(data) {
+			data = data[:totalLen]
+		}
+		sourceIP, destinationIP = net.IP(data[12:16]), net.IP(data[16:20])
+		data = data[headerLen:]
+	case 6:
+		if len(data) < ipv6HeaderLen {
+			return nil, nil, nil, errMalformed
+		}
+		if data[6] != protocolUDP {
+			return nil, nil, nil, errNotUDP
+		}
+		if payloadLen := int(binary.BigEndian.Uint16(data[4:6])); ipv6HeaderLen+payloadLen < len(data) {
+			data = data[:ipv6HeaderLen+payloadLen]
+		}
+		sourceIP, destinationIP = net.IP(data[8:24]), net.IP(data[24:40])
+		data = data[ipv6HeaderLen:]
+	default:
+		return nil, nil, nil, errNotUDP
+	}
+
+	if len(data) < udpHeaderLen {
+		return nil, nil, nil, errMalformed
+	}
+	if udpLen := int(binary.BigEndian.Uint16(data[4:6])); udpLen >= udpHeaderLen && udpLen < len(data) {
+		data = data[:udpLen]
+	}
+
+	source = &net.UDPAddr{IP: append(net.IP{}, sourceIP...), Port: int(binary.BigEndian.Uint16(data[0:2]))}
+	destination = &net.UDPAddr{IP: append(net.IP{}, destinationIP...), Port: int(binary.BigEndian.Uint16(data[2:4]))}
+	return source, destination, data[udpHeaderLen:], nil
+}
+
+// checksum computes the Internet checksum of RFC 1071
+func checksum(data []byte) uint16 {
+	var sum uint32
+	for ; len(data) >= 2; data = data[2:] {
+		sum += uint32(data[0])<<8 | uint32(data[1])
+	}
+	if len(data) == 1 {
+		sum += uint32(data[0]) << 8
+	}
+	for sum > 0xFFFF {
+		sum = sum>>16 + sum&0xFFFF
+	}
+
+	return ^uint16(sum)
+}
+
+// encodeUDP returns an IPv4 or IPv6 packet of a UDP datagram
+func encodeUDP(source, destination *net.UDPAddr, payload []byte) []byte {
+	udp := make([]byte, udpHeaderLen, udpHeaderLen+len(payload))
+	binary.BigEndian.PutUint16(udp[0:2], uint16(source.Port))
+	binary.BigEndian.PutUint16(udp[2:4], uint16(destination.Port))
+	binary.BigEndian.PutUint16(udp[4:6], uint16(udpHeaderLen+len(payload)))
+	udp = append(udp, payload...)
+
+	// The checksum covers a pseudo header with the addresses
+	var header []byte
+	sourceIP, destinationIP := source.IP.To4(), destination.IP.To4()
+	if sourceIP != nil && destinationIP != nil {
+		header = make([]byte, ipv4HeaderLen)
+		header[0] = 0x45
+		binary.BigEndian.PutUint16(header[2:4], uint16(ipv4HeaderLen+len(udp)))
+		header[6] = 0x40 // Don't fragment
+		header[8] = 64   // TTL
+		header[9] = protocolUDP
+		copy(header[12:16], sourceIP)
+		copy(header[16:20], destinationIP)
+		binary.BigEndian.PutUint16(header[10:12], checksum(header))
+	} else {
+		sourceIP, destinationIP = source.IP.To16(), destination.IP.To16()
+		header = make([]byte, ipv6HeaderLen)
+		header[0] = 0x60
+		binary.BigEndian.PutUint16(header[4:6], uint16(len(udp)))
+		header[6] = protocolUDP
+		header[7] = 64 // Hop limit
+		copy(header[8:24], sourceIP)
+		copy(header[24:40], destinationIP)
+	}
+
+	pseudoHeader := append(append([]byte{}, sourceIP...), destinationIP...)
+	pseudoHeader = append(pseudoHeader, 0, protocolUDP, udp[4], udp[5])
+	sum := checksum(append(pseudoHeader, udp...))
+	if sum == 0 {
+		sum = 0xFFFF
+	}
+	binary.BigEndian.PutUint16(udp[6:8], sum)
+
+	return append(header, udp...)
+}
@@ -0,0 +1,350 @@
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	pcapMagicMicroseconds = 0xA1B2C3D4
+	pcapMagicNanoseconds  = 0xA1B23C4D
+	pcapHeaderLen         = 24
+	pcapRecordHeaderLen   = 16
+
+	pcapngByteOrderMagic = 0x1A2B3C4D
+	pcapngBlockHeaderLen = 8
+
+	blockTypeSectionHeader        = 0x0A0D0D0A
+	blockTypeInterfaceDescription = 0x00000001
+	blockTypePacket               = 0x00000002
+	blockTypeSimplePacket         = 0x00000003
+	blockTypeEnhancedPacket       = 0x00000006
+
+	optionEndOfOptions     = 0
+	optionInterfaceTSResol = 9
+
+	// Larger blocks are rejected instead of being allocated
+	maxBlockLen = 16 * 1024 * 1024
+)
+
+type captureInterface struct {
+	linkType uint16
+	// units of the timestamps per second
+	resolution uint64
+}
+
+// Reader reads the RTP and RTCP packets of a pcap or pcapng capture
+type Reader struct {
+	readerMu sync.Mutex
+	reader   io.Reader
+
+	isPCAPNG   bool
+	byteOrder  binary.ByteOrder
+	interfaces []captureInterface
+
+	hasSSRC bool
+	ssrc    uint32
+	port    int
+}
+
+// NewReader opens a new Reader, the format of the capture is detected from its
+// first bytes
+func NewReader(r io.Reader, opts ...ReaderOption) (*Reader, error) {
+	reader := &Reader{reader: bufio.NewReader(r)}
+	for _, o := range opts {
+		o(reader)
+	}
+
+	header := make([]byte, pcapHeaderLen)
+	if _, err := io.ReadFull(reader.reader, header[:pcapngBlockHeaderLen]); err != nil {
+		return nil, errUnknownFormat
+	}
+
+	if binary.BigEndian.Uint32(header) == blockTypeSectionHeader {
+		reader.isPCAPNG = true
+		if err := reader.readSectionHeader(header[:pcapngBlockHeaderLen]); err != nil {
+			return nil, err
+		}
+		return reader, nil
+	}
+
+	if _, err := io.ReadFull(reader.reader, header[pcapngBlockHeaderLen:]); err != nil {
+		return nil, errUnknownFormat
+	}
+
+	resolution := uint64(time.Second / time.Microsecond)
+	switch {
+	case binary.LittleEndian.Uint32(header) == pcapMagicMicroseconds:
+		reader.byteOrder = binary.LittleEndian
+	case binary.BigEndian.Uint32(header) == pcapMagicMicroseconds:
+		reader.byteOrder = binary.BigEndian
+	case binary.LittleEndian.Uint32(header) == pcapMagicNanoseconds:
+		reader.byteOrder, resolution = binary.LittleEndian, uint64(time.Second)
+	case binary.BigEndian.Uint32(header) == pcapMagicNanoseconds:
+		reader.byteOrder, resolution = binary.BigEndian, uint64(time.Second)
+	default:
+		return nil, errUnknownFormat
+	}
+
+	// Some writers set the FCS bits of pcap-ng in the link type field
+	reader.interfaces = []captureInterface{{
+		linkType:   uint16(reader.byteOrder.Uint32(header[20:24])),
+		resolution: resolution,
+	}}
+
+	return reader, nil
+}
+
+// Next returns the next RTP or RTCP packet of the capture that matches the
+// filters, or io.EOF at the end of the capture
+func (r *Reader) Next() (Packet, error) {
+	r.readerMu.Lock()
+	defer r.readerMu.Unlock()
+
+	for {
+		iface, timestamp, data, err := r.readFrame()
+		if err != nil {
+			return Packet{}, err
+		}
+
+		ip, err := decodeLinkLayer(iface.linkType, data)
+		if err != nil {
+			if errors.Is(err, errNotUDP) || errors.Is(err, errMalformed) {
+				continue
+			}
+			return Packet{}, err
+		}
+		source, destination, payload, err := decodeUDP(ip)
+		if err != nil {
+			continue
+		}
+
+		ok, isRTCP := isRTPOrRTCP(payload)
+		if !ok {
+			continue
+		}
+
+		packet := Packet{
+			Timestamp:   toTime(timestamp, iface.resolution),
+			Source:      source,
+			Destination: destination,
+			IsRTCP:      isRTCP,
+			Payload:     payload,
+		}
+		if r.matches(packet) {
+			return packet, nil
+		}
+	}
+}
+
+func (r *Reader) matches(packet Packet) bool {
+	if r.port != 0 && packet.Source.Port != r.port && packet.Destination.Port != r.port {
+		return false
+	}
+
+	if r.hasSSRC {
+		for _, ssrc := range packet.ssrcs() {
+			if ssrc == r.ssrc {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+func toTime(timestamp, resolution uint64) time.Time {
+	seconds := timestamp / resolution
+	return time.Unix(int64(seconds), int64((timestamp-seconds*resolution)*uint64(time.Second)/resolution))
+}
+
+// readFrame returns the next captured frame, and the interface it was captured on
+func (r *Reader) readFrame() (iface captureInterface, timestamp uint64, data []byte, err error) {
+	if !r.isPCAPNG {
+		header := make([]byte, pcapRecordHeaderLen)
+		if _, err = io.ReadFull(r.reader, header); err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				err = errMalformed
+			}
+			return
+		}
+
+		iface = r.interfaces[0]
+		timestamp = uint64(r.byteOrder.Uint32(header[0:4]))*iface.resolution + uint64(r.byteOrder.Uint32(header[4:8]))
+		capturedLen := r.byteOrder.Uint32(header[8:12])
+		if capturedLen > maxBlockLen {
+			err = errMalformed
+			return
+		}
+		data = make([]byte, capturedLen)
+		if _, err = io.ReadFull(r.reader, data); err != nil {
+			err = errMalformed
+		}
+		return
+	}
+
+	for {
+		var blockType uint32
+		var body []byte
+		if blockType, body, err = r.readBlock(); err != nil {
+			return
+		}
+
+		switch blockType {
+		case blockTypeInterfaceDescription:
+			if len(body) < 8 {
+				err = errMalformed
+				return
+			}
+			r.interfaces = append(r.interfaces, captureInterface{
+				linkType:   r.byteOrder.Uint16(body[0:2]),
+				resolution: r.interfaceResolution(body[8:]),
+			})
+		case blockTypeEnhancedPacket, blockTypePacket:
+			if len(body) < 20 {
+				err = errMalformed
+				return
+			}
+			interfaceID := r.byteOrder.Uint32(body[0:4])
+			if blockType == blockTypePacket {
+				interfaceID = uint32(r.byteOrder.Uint16(body[0:2]))
+			}
+			capturedLen := r.byteOrder.Uint32(body[12:16])
+			if int(interfaceID) >= len(r.interfaces) || uint64(capturedLen) > uint64(len(body)-20) {
+				err = errMalformed
+				return
+			}
+			iface = r.interfaces[interfaceID]
+			timestamp = uint64(r.byteOrder.Uint32(body[4:8]))<<32 | uint64(r.byteOrder.Uint32(body[8:12]))
+			data = body[20 : 20+capturedLen]
+			return
+		case blockTypeSimplePacket:
+			// Simple packets have no timestamp
+			if len(body) < 4 || len(r.interfaces) == 0 {
+				err = errMalformed
+				return
+			}
+			iface = r.interfaces[0]
+			data = body[4:]
+			if originalLen := r.byteOrder.Uint32(body[0:4]); uint64(originalLen) < uint64(len(data)) {
+				data = data[:originalLen]
+			}
+			return
+		case blockTypeSectionHeader:
+			if err = r.readSectionHeader(body); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readBlock returns the type and the body of the next pcapng block
+func (r *Reader) readBlock() (uint32, []byte, error) {
+	header := make([]byte, pcapngBlockHeaderLen)
+	if _, err := io.ReadFull(r.reader, header); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, nil, errMalformed
+		}
+		return 0, nil, err
+	}
+
+	blockType := binary.BigEndian.Uint32(header[0:4])
+	if blockType == blockTypeSectionHeader {
+		// The byte order may change, it is read again with the section header
+		return blockType, header, nil
+	}
+
+	blockType = r.byteOrder.Uint32(header[0:4])
+	body, err := r.readBlockBody(r.byteOrder.Uint32(header[4:8]))
+	return blockType, body, err
+}
+
+func (r *Reader) readBlockBody(blockLen uint32) ([]byte, error) {
+	// The body is followed by the block length again
+	if blockLen < pcapngBlockHeaderLen+4 || blockLen%4 != 0 || blockLen > maxBlockLen {
+		return nil, errMalformed
+	}
+	body := make([]byte, blockLen-pcapngBlockHeaderLen)
+	if _, err := io.ReadFull(r.reader, body); err != nil {
+		return nil, errMalformed
+	}
+
+	return body[:len(body)-4], nil
+}
+
+// readSectionHeader reads a section header block after its first 8 bytes, a
+// new section resets the interfaces
+func (r *Reader) readSectionHeader(header []byte) error {
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(r.reader, magic); err != nil {
+		return errMalformed
+	}
+
+	switch {
+	case binary.BigEndian.Uint32(magic) == pcapngByteOrderMagic:
+		r.byteOrder = binary.BigEndian
+	case binary.LittleEndian.Uint32(magic) == pcapngByteOrderMagic:
+		r.byteOrder = binary.LittleEndian
+	default:
+		return errUnknownFormat
+	}
+	r.interfaces = nil
+
+	blockLen := r.byteOrder.Uint32(header[4:8])
+	if blockLen < pcapngBlockHeaderLen+4 {
+		return errMalformed
+	}
+	_, err := r.readBlockBody(blockLen - 4)
+	return err
+}
+
+// interfaceResolution returns the timestamps resolution from the options of an
+// interface description block, microseconds by default
+func (r *Reader) interfaceResolution(options []byte) uint64 {
+	resolution := uint64(time.Second / time.Microsecond)
+	for len(options) >= 4 {
+		code, length := r.byteOrder.Uint16(options[0:2]), int(r.byteOrder.Uint16(options[2:4]))
+		if code == optionEndOfOptions || len(options) < 4+length {
+			break
+		}
+
+		if code == optionInterfaceTSResol && length == 1 {
+			value := options[4]
+			if value&0x80 != 0 {
+				resolution = 1 << (value & 0x3F)
+			} else {
+				resolution = 1
+				for i := byte(0); i < value && i < 19; i++ {
+					resolution *= 10
+				}
+			}
+		}
+
+		options = options[4+(length+3)/4*4:]
+	}
+
+	return resolution
+}
+
+// A ReaderOption configures a Reader.
+type ReaderOption func(r *Reader)
+
+// WithSSRC only reads the RTP packets of the SSRC, and the RTCP packets that
+// are sent by it or refer to it
+func WithSSRC(ssrc uint32) ReaderOption {
+	return func(r *Reader) {
+		r.hasSSRC, r.ssrc = true, ssrc
+	}
+}
+
+// WithPort only reads the packets sent from or to the UDP port
+func WithPort(port int) ReaderOption {
+	return func(r *Reader) {
+		r.port = port
+	}
+}
@@ -0,0 +1,133 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+// classicCapture returns a pcap capture of Ethernet frames, in big endian with
+// microsecond timestamps
+func classicCapture(frames ...[]byte) []byte {
+	header := make([]byte, pcapHeaderLen)
+	binary.BigEndian.PutUint32(header[0:4], pcapMagicMicroseconds)
+	binary.BigEndian.PutUint16(header[4:6], 2)
+	binary.BigEndian.PutUint16(header[6:8], 4)
+	binary.BigEndian.PutUint32(header[16:20], 65535)
+	binary.BigEndian.PutUint32(header[20:24], linkTypeEthernet)
+
+	capture := header
+	for i, frame := range frames {
+		record := make([]byte, pcapRecordHeaderLen)
+		binary.BigEndian.PutUint32(record[0:4], 1600000000)
+		binary.BigEndian.PutUint32(record[4:8], uint32(i*1000))
+		binary.BigEndian.PutUint32(record[8:12], uint32(len(frame)))
+		binary.BigEndian.PutUint32(record[12:16], uint32(len(frame)))
+		capture = append(append(capture, record...), frame...)
+	}
+
+	return capture
+}
+
+// ethernetFrame returns a VLAN tagged Ethernet frame of an UDP datagram
+func ethernetFrame(source, destination *net.UDPAddr, payload []byte) []byte {
+	frame := make([]byte, 18)
+	binary.BigEndian.PutUint16(frame[12:14], etherTypeVLAN)
+	binary.BigEndian.PutUint16(frame[16:18], etherTypeIPv4)
+
+	return append(frame, encodeUDP(source, destination, payload)...)
+}
+
+func TestReader_Classic(t *testing.T) {
+	alice := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 1), Port: 4000}
+	bob := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 2), Port: 6000}
+	carol := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 3), Port: 8000}
+
+	rtpPayload, err := (&rtp.Packet{
+		Header:  rtp.Header{Version: 2, PayloadType: 96, SequenceNumber: 1, SSRC: 0x1234},
+		Payload: []byte{0x01, 0x02},
+	}).Marshal()
+	assert.NoError(t, err)
+	otherPayload, err := (&rtp.Packet{
+		Header:  rtp.Header{Version: 2, PayloadType: 96, SequenceNumber: 1, SSRC: 0x5678},
+		Payload: []byte{0x03},
+	}).Marshal()
+	assert.NoError(t, err)
+	rtcpPayload, err := rtcp.Marshal([]rtcp.Packet{&rtcp.PictureLossIndication{SenderSSRC: 1, MediaSSRC: 0x1234}})
+	assert.NoError(t, err)
+	stunPayload := []byte{0x00, 0x01, 0x00, 0x00, 0x21, 0x12, 0xA4, 0x42, 0x00, 0x00, 0x00, 0x00}
+
+	capture := classicCapture(
+		ethernetFrame(alice, bob, stunPayload),
+		ethernetFrame(alice, bob, rtpPayload),
+		ethernetFrame(bob, alice, rtcpPayload),
+		ethernetFrame(carol, bob, otherPayload),
+	)
+
+	readAll := func(opts ...ReaderOption) []Packet {
+		reader, err := NewReader(bytes.NewReader(capture), opts...)
+		assert.NoError(t, err)
+
+		var packets []Packet
+		for {
+			packet, err := reader.Next()
+			if err == io.EOF {
+				return packets
+			}
+			assert.NoError(t, err)
+			packets = append(packets, packet)
+		}
+	}
+
+	packets := readAll()
+	assert.Equal(t, 3, len(packets))
+	assert.Equal(t, time.Unix(1600000000, int64(time.Millisecond)), packets[0].Timestamp)
+	assert.Equal(t, alice.String(), packets[0].Source.String())
+	assert.Equal(t, bob.String(), packets[0].Destination.String())
+	assert.False(t, packets[0].IsRTCP)
+	assert.Equal(t, rtpPayload, packets[0].Payload)
+	assert.True(t, packets[1].IsRTCP)
+
+	packet, err := packets[0].RTP()
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0x1234), packet.SSRC)
+	assert.Equal(t, []byte{0x01, 0x02}, packet.Payload)
+
+	rtcpPackets, err := packets[1].RTCP()
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{0x1234}, rtcpPackets[0].DestinationSSRC())
+
+	packets = readAll(WithSSRC(0x1234))
+	assert.Equal(t, 2, len(packets))
+	assert.Equal(t, rtpPayload, packets[0].Payload)
+	assert.Equal(t, rtcpPayload, packets[1].Payload)
+
+	packets = readAll(WithPort(8000))
+	assert.Equal(t, 1, len(packets))
+	assert.Equal(t, otherPayload, packets[0].Payload)
+}
+
+func TestReader_Errors(t *testing.T) {
+	for _, data := range [][]byte{
+		nil,
+		[]byte("#!rtpplay1.0 224.2.0.1/3456\n"),
+		{0x0A, 0x0D, 0x0D, 0x0A, 0x1C, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+	} {
+		_, err := NewReader(bytes.NewReader(data))
+		assert.Equal(t, errUnknownFormat, err)
+	}
+
+	// Truncated record
+	capture := classicCapture([]byte{0x00, 0x01})
+	reader, err := NewReader(bytes.NewReader(capture[:len(capture)-1]))
+	assert.NoError(t, err)
+	_, err = reader.Next()
+	assert.Equal(t, errMalformed, err)
+}
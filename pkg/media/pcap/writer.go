@@ -0,0 +1,139 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+// Addresses of the packets written with WriteRTP and WriteRTCP
+var (
+	DefaultSource      = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5000}
+	DefaultDestination = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5002}
+)
+
+// RTPReader is the interface of the RTP streams written by WriteTrack, it is
+// implemented by webrtc.TrackRemote
+type RTPReader interface {
+	ReadRTP() (*rtp.Packet, interceptor.Attributes, error)
+}
+
+// Writer writes RTP and RTCP packets to a pcapng capture, as UDP datagrams of
+// raw IP packets
+type Writer struct {
+	writerMu sync.Mutex
+	writer   io.Writer
+}
+
+// NewWriter returns a new Writer, the section header and the interface
+// description are written immediately
+func NewWriter(w io.Writer) (*Writer, error) {
+	sectionHeader := make([]byte, 16)
+	binary.LittleEndian.PutUint32(sectionHeader[0:4], pcapngByteOrderMagic)
+	binary.LittleEndian.PutUint16(sectionHeader[4:6], 1) // Version 1.0
+	// The section length is unknown
+	binary.LittleEndian.PutUint64(sectionHeader[8:16], ^uint64(0))
+
+	// Timestamps are in nanoseconds
+	interfaceDescription := make([]byte, 20)
+	binary.LittleEndian.PutUint16(interfaceDescription[0:2], linkTypeRaw)
+	binary.LittleEndian.PutUint16(interfaceDescription[8:10], optionInterfaceTSResol)
+	binary.LittleEndian.PutUint16(interfaceDescription[10:12], 1)
+	interfaceDescription[12] = 9
+
+	writer := &Writer{writer: w}
+	if err := writer.writeBlock(blockTypeSectionHeader, sectionHeader); err != nil {
+		return nil, err
+	}
+	if err := writer.writeBlock(blockTypeInterfaceDescription, interfaceDescription); err != nil {
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+// WritePacket writes a packet to the capture
+func (w *Writer) WritePacket(p Packet) error {
+	data := encodeUDP(p.Source, p.Destination, p.Payload)
+
+	body := make([]byte, 20, 20+len(data)+3)
+	timestamp := uint64(p.Timestamp.UnixNano())
+	binary.LittleEndian.PutUint32(body[4:8], uint32(timestamp>>32))
+	binary.LittleEndian.PutUint32(body[8:12], uint32(timestamp))
+	binary.LittleEndian.PutUint32(body[12:16], uint32(len(data)))
+	binary.LittleEndian.PutUint32(body[16:20], uint32(len(data)))
+	body = append(body, data...)
+	body = append(body, make([]byte, (4-len(data)%4)%4)...)
+
+	w.writerMu.Lock()
+	defer w.writerMu.Unlock()
+
+	return w.writeBlock(blockTypeEnhancedPacket, body)
+}
+
+// WriteRTP writes a RTP packet, sent from DefaultSource to DefaultDestination
+func (w *Writer) WriteRTP(packet *rtp.Packet, timestamp time.Time) error {
+	payload, err := packet.Marshal()
+	if err != nil {
+		return err
+	}
+
+	return w.WritePacket(Packet{
+		Timestamp:   timestamp,
+		Source:      DefaultSource,
+		Destination: DefaultDestination,
+		Payload:     payload,
+	})
+}
+
+// WriteRTCP writes a compound RTCP packet, sent from DefaultSource to
+// DefaultDestination
+func (w *Writer) WriteRTCP(pkts []rtcp.Packet, timestamp time.Time) error {
+	payload, err := rtcp.Marshal(pkts)
+	if err != nil {
+		return err
+	}
+
+	return w.WritePacket(Packet{
+		Timestamp:   timestamp,
+		Source:      DefaultSource,
+		Destination: DefaultDestination,
+		IsRTCP:      true,
+		Payload:     payload,
+	})
+}
+
+// WriteTrack writes the decrypted RTP packets of a track as they are read,
+// until reading fails. Its error is returned, io.EOF when the track ends.
+func (w *Writer) WriteTrack(track RTPReader) error {
+	for {
+		packet, _, err := track.ReadRTP()
+		if err != nil {
+			return err
+		}
+
+		if err := w.WriteRTP(packet, time.Now()); err != nil {
+			return err
+		}
+	}
+}
+
+func (w *Writer) writeBlock(blockType uint32, body []byte) error {
+	blockLen := uint32(pcapngBlockHeaderLen + len(body) + 4)
+
+	block := make([]byte, 0, blockLen)
+	block = append(block, make([]byte, pcapngBlockHeaderLen)...)
+	binary.LittleEndian.PutUint32(block[0:4], blockType)
+	binary.LittleEndian.PutUint32(block[4:8], blockLen)
+	block = append(block, body...)
+	block = append(block, block[4:8]...)
+
+	_, err := w.writer.Write(block)
+	return err
+}
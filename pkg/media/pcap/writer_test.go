@@ -0,0 +1,113 @@
+package pcap
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriter_RoundTrip(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer, err := NewWriter(buffer)
+	assert.NoError(t, err)
+
+	timestamp := time.Unix(1600000000, 123456789)
+	rtpPacket := &rtp.Packet{
+		Header:  rtp.Header{Version: 2, PayloadType: 111, SequenceNumber: 7, Timestamp: 960, SSRC: 0xABCD},
+		Payload: []byte{0xFC, 0x01, 0x02},
+	}
+	assert.NoError(t, writer.WriteRTP(rtpPacket, timestamp))
+	assert.NoError(t, writer.WriteRTCP([]rtcp.Packet{&rtcp.ReceiverReport{SSRC: 0xABCD}}, timestamp.Add(time.Second)))
+
+	ipv6 := Packet{
+		Timestamp:   timestamp.Add(2 * time.Second),
+		Source:      &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 1234},
+		Destination: &net.UDPAddr{IP: net.ParseIP("2001:db8::2"), Port: 5678},
+		Payload:     []byte{0x80, 0x60, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0xAA},
+	}
+	assert.NoError(t, writer.WritePacket(ipv6))
+
+	reader, err := NewReader(buffer)
+	assert.NoError(t, err)
+
+	packet, err := reader.Next()
+	assert.NoError(t, err)
+	assert.True(t, timestamp.Equal(packet.Timestamp))
+	assert.Equal(t, DefaultSource.String(), packet.Source.String())
+	assert.Equal(t, DefaultDestination.String(), packet.Destination.String())
+	assert.False(t, packet.IsRTCP)
+	decoded, err := packet.RTP()
+	assert.NoError(t, err)
+	assert.Equal(t, rtpPacket.SSRC, decoded.SSRC)
+	assert.Equal(t, rtpPacket.Payload, decoded.Payload)
+
+	packet, err = reader.Next()
+	assert.NoError(t, err)
+	assert.True(t, packet.IsRTCP)
+	assert.True(t, timestamp.Add(time.Second).Equal(packet.Timestamp))
+
+	packet, err = reader.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, ipv6.Source.String(), packet.Source.String())
+	assert.Equal(t, ipv6.Destination.String(), packet.Destination.String())
+	assert.Equal(t, ipv6.Payload, packet.Payload)
+
+	_, err = reader.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestChecksum(t *testing.T) {
+	// A valid checksum makes the sum of the packet zero
+	data := encodeUDP(DefaultSource, DefaultDestination, []byte{0x80, 0x00, 0x01})
+	assert.Equal(t, uint16(0), checksum(data[:ipv4HeaderLen]))
+
+	pseudoHeader := append(append([]byte{}, data[12:20]...), 0, protocolUDP, data[24], data[25])
+	assert.Equal(t, uint16(0), checksum(append(pseudoHeader, data[ipv4HeaderLen:]...)))
+}
+
+type fakeTrack struct {
+	packets []*rtp.Packet
+}
+
+func (f *fakeTrack) ReadRTP() (*rtp.Packet, interceptor.Attributes, error) {
+	if len(f.packets) == 0 {
+		return nil, nil, io.EOF
+	}
+	packet := f.packets[0]
+	f.packets = f.packets[1:]
+	return packet, nil, nil
+}
+
+func TestWriter_WriteTrack(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer, err := NewWriter(buffer)
+	assert.NoError(t, err)
+
+	track := &fakeTrack{}
+	for i := uint16(0); i < 3; i++ {
+		track.packets = append(track.packets, &rtp.Packet{
+			Header:  rtp.Header{Version: 2, SequenceNumber: i, SSRC: 1},
+			Payload: []byte{byte(i)},
+		})
+	}
+	assert.Equal(t, io.EOF, writer.WriteTrack(track))
+
+	reader, err := NewReader(buffer, WithSSRC(1))
+	assert.NoError(t, err)
+	for i := uint16(0); i < 3; i++ {
+		packet, err := reader.Next()
+		assert.NoError(t, err)
+		decoded, err := packet.RTP()
+		assert.NoError(t, err)
+		assert.Equal(t, i, decoded.SequenceNumber)
+	}
+	_, err = reader.Next()
+	assert.Equal(t, io.EOF, err)
+}
@@ -26,6 +26,9 @@ type SampleBuilder struct {
 	// reference to some packet.
 	packetReleaseHandler func(*rtp.Packet)
 
+	// captureTime returns the capture time of a RTP timestamp, if it is known
+	captureTime func(rtpTimestamp uint32) (time.Time, bool)
+
 	// filled contains the head/tail of the packets inserted into the buffer
 	filled sampleSequenceLocation
 
@@ -39,12 +42,20 @@ type SampleBuilder struct {
 	droppedPackets uint16
 }
 
+// KeyFrameDetector is implemented by depacketizers that can tell if a sample, made
+// of the concatenated outputs of Unmarshal, is a key frame. The IsKeyFrame field of
+// the samples is set when the depacketizer of the SampleBuilder implements it.
+type KeyFrameDetector interface {
+	IsKeyFrame(sample []byte) bool
+}
+
 // New constructs a new SampleBuilder.
 // maxLate is how long to wait until we can construct a completed media.Sample.
 // maxLate is measured in RTP packet sequence numbers.
 // A large maxLate will result in less packet loss but higher latency.
 // The depacketizer extracts media samples from RTP packets.
-// Several depacketizers are available in package github.com/pion/rtp/codecs.
+// Several depacketizers are available in package github.com/pion/rtp/codecs,
+// the ones for H265 and AV1 are in package github.com/pion/webrtc/v3/pkg/rtpcodecs.
 func New(maxLate uint16, depacketizer rtp.Depacketizer, sampleRate uint32, opts ...Option) *SampleBuilder {
 	s := &SampleBuilder{maxLate: maxLate, depacketizer: depacketizer, sampleRate: sampleRate}
 	for _, o := range opts {
@@ -266,6 +277,16 @@ func (s *SampleBuilder) buildSample(purgingBuffers bool) *media.Sample {
 		PrevDroppedPackets: s.droppedPackets,
 	}
 
+	if detector, ok := s.depacketizer.(KeyFrameDetector); ok {
+		sample.IsKeyFrame = detector.IsKeyFrame(data)
+	}
+
+	if s.captureTime != nil {
+		if captureTime, ok := s.captureTime(sampleTimestamp); ok {
+			sample.CaptureTime = captureTime
+		}
+	}
+
 	s.droppedPackets = 0
 
 	s.preparedSamples[s.prepared.tail] = sample
@@ -346,3 +367,12 @@ func WithMaxTimeDelay(maxLateDuration time.Duration) Option {
 		o.maxLateTimestamp = uint32(int64(o.sampleRate) * totalMillis / 1000)
 	}
 }
+
+// WithCaptureTime sets the CaptureTime of the samples from their RTP timestamp,
+// with a function like TrackRemote.CaptureTime. It is left unset while the
+// function doesn't know the capture time.
+func WithCaptureTime(f func(rtpTimestamp uint32) (time.Time, bool)) Option {
+	return func(o *SampleBuilder) {
+		o.captureTime = f
+	}
+}
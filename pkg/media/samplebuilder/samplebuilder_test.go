@@ -7,6 +7,7 @@ import (
 
 	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/rtpcodecs"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -395,6 +396,29 @@ func TestPopWithTimestamp(t *testing.T) {
 	})
 }
 
+func TestSampleBuilderWithCaptureTime(t *testing.T) {
+	reference := time.Unix(1600000000, 0)
+	s := New(10, &fakeDepacketizer{}, 1, WithCaptureTime(func(rtpTimestamp uint32) (time.Time, bool) {
+		// The capture time of the first sample isn't known yet
+		if rtpTimestamp < 20 {
+			return time.Time{}, false
+		}
+		return reference.Add(time.Duration(rtpTimestamp) * time.Second), true
+	}))
+
+	for i, timestamp := range []uint32{10, 20, 30} {
+		s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: uint16(i), Timestamp: timestamp}, Payload: []byte{0x01}})
+	}
+
+	sample := s.Pop()
+	assert.NotNil(t, sample)
+	assert.True(t, sample.CaptureTime.IsZero())
+
+	sample = s.Pop()
+	assert.NotNil(t, sample)
+	assert.Equal(t, reference.Add(20*time.Second), sample.CaptureTime)
+}
+
 type truePartitionHeadChecker struct{}
 
 func (f *truePartitionHeadChecker) IsPartitionHead(payload []byte) bool {
@@ -430,6 +454,32 @@ func TestSampleBuilderData(t *testing.T) {
 	assert.Equal(t, j, 0x1FFFF)
 }
 
+func TestSampleBuilderAV1KeyFrame(t *testing.T) {
+	s := New(10, &rtpcodecs.AV1Depacketizer{}, 90000)
+
+	for _, p := range []*rtp.Packet{
+		// Sequence header and the start of a frame OBU
+		{Header: rtp.Header{SequenceNumber: 5000, Timestamp: 5}, Payload: []byte{0x48, 0x04, 0x0A, 0x02, 0xAA, 0xBB, 0x02, 0x32, 0x03}},
+		// End of the frame OBU
+		{Header: rtp.Header{SequenceNumber: 5001, Timestamp: 5, Marker: true}, Payload: []byte{0x80, 0x03, 0x01, 0x02, 0x03}},
+		// Frame OBU without size field
+		{Header: rtp.Header{SequenceNumber: 5002, Timestamp: 3005, Marker: true}, Payload: []byte{0x10, 0x30, 0x04, 0x05, 0x06}},
+		{Header: rtp.Header{SequenceNumber: 5003, Timestamp: 6005, Marker: true}, Payload: []byte{0x10, 0x30, 0x07}},
+	} {
+		s.Push(p)
+	}
+
+	sample := s.Pop()
+	assert.NotNil(t, sample)
+	assert.True(t, sample.IsKeyFrame)
+	assert.Equal(t, []byte{0x0A, 0x02, 0xAA, 0xBB, 0x32, 0x03, 0x01, 0x02, 0x03}, sample.Data)
+
+	sample = s.Pop()
+	assert.NotNil(t, sample)
+	assert.False(t, sample.IsKeyFrame)
+	assert.Equal(t, []byte{0x32, 0x03, 0x04, 0x05, 0x06}, sample.Data)
+}
+
 func BenchmarkSampleBuilderSequential(b *testing.B) {
 	s := New(100, &fakeDepacketizer{}, 1)
 	b.ResetTimer()
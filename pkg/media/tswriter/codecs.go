@@ -0,0 +1,106 @@
+package tswriter
+
+import (
+	"github.com/pion/webrtc/v3/pkg/media"
+)
+
+const (
+	h264NaluTypeIDR = 5
+	h264NaluTypeAUD = 9
+	h265NaluTypeAUD = 35
+
+	opusControlHeaderPrefix = 0x7FE0
+)
+
+var (
+	// Access unit delimiters, MPEG-TS requires each access unit to start with one
+	h264AUD = []byte{0x00, 0x00, 0x00, 0x01, 0x09, 0xF0}
+	h265AUD = []byte{0x00, 0x00, 0x00, 0x01, 0x46, 0x01, 0x50}
+)
+
+// splitAnnexB returns the NAL units of an Annex-B access unit, without their
+// start codes
+func splitAnnexB(data []byte) [][]byte {
+	var nalus [][]byte
+
+	start, zeros := -1, 0
+	for i, b := range data {
+		switch {
+		case b == 0:
+			zeros++
+			continue
+		case b == 1 && zeros >= 2:
+			if start >= 0 {
+				nalus = append(nalus, data[start:i-zeros])
+			}
+			start = i + 1
+		}
+		zeros = 0
+	}
+	if start >= 0 && start < len(data) {
+		nalus = append(nalus, data[start:])
+	}
+
+	return nalus
+}
+
+// convertH264 prepends an access unit delimiter to the access unit, a key frame
+// is an access unit with an IDR picture
+func convertH264(sample *media.Sample) ([]byte, bool) {
+	nalus := splitAnnexB(sample.Data)
+	if len(nalus) == 0 {
+		return nil, false
+	}
+
+	keyFrame := false
+	for _, nalu := range nalus {
+		if len(nalu) > 0 && nalu[0]&0x1F == h264NaluTypeIDR {
+			keyFrame = true
+		}
+	}
+
+	if len(nalus[0]) > 0 && nalus[0][0]&0x1F == h264NaluTypeAUD {
+		return sample.Data, keyFrame
+	}
+	return append(append([]byte{}, h264AUD...), sample.Data...), keyFrame
+}
+
+// convertH265 prepends an access unit delimiter to the access unit, key frames
+// are detected by the depacketizer
+func convertH265(sample *media.Sample) ([]byte, bool) {
+	nalus := splitAnnexB(sample.Data)
+	if len(nalus) == 0 {
+		return nil, false
+	}
+
+	if len(nalus[0]) > 0 && (nalus[0][0]>>1)&0x3F == h265NaluTypeAUD {
+		return sample.Data, sample.IsKeyFrame
+	}
+	return append(append([]byte{}, h265AUD...), sample.Data...), sample.IsKeyFrame
+}
+
+// convertOpus prepends the control header of the Opus in MPEG-TS mapping to the
+// Opus packet, every packet is a random access point
+func convertOpus(sample *media.Sample) ([]byte, bool) {
+	if len(sample.Data) == 0 {
+		return nil, false
+	}
+
+	payload := []byte{opusControlHeaderPrefix >> 8, opusControlHeaderPrefix & 0xFF}
+	size := len(sample.Data)
+	for ; size >= 0xFF; size -= 0xFF {
+		payload = append(payload, 0xFF)
+	}
+	payload = append(payload, byte(size))
+
+	return append(payload, sample.Data...), true
+}
+
+// opusDescriptors returns the PMT descriptors of an Opus stream, a registration
+// descriptor and the channel configuration in an extension descriptor
+func opusDescriptors(channels byte) []byte {
+	return []byte{
+		0x05, 4, 'O', 'p', 'u', 's',
+		0x7F, 2, 0x80, channels,
+	}
+}
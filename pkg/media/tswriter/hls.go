@@ -0,0 +1,213 @@
+package tswriter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	defaultSegmentDuration = 2 * time.Second
+
+	// PlaylistName is the name of the HLS playlist written by the HLSSegmenter
+	PlaylistName = "index.m3u8"
+)
+
+var (
+	errNoDirectory               = errors.New("no directory for the HLS segments")
+	errInvalidMaxSegmentDuration = errors.New("MaxSegmentDuration must not be less than SegmentDuration")
+)
+
+// HLSSegmenterConfig configures a HLSSegmenter
+type HLSSegmenterConfig struct {
+	// Directory where the playlist and the segments are written
+	Directory string
+
+	// SegmentDuration is the minimum duration of a segment, segments are cut
+	// on the first key frame after it. Defaults to 2 seconds.
+	SegmentDuration time.Duration
+
+	// MaxSegmentDuration is the maximum duration of a segment, a segment is cut
+	// without a key frame before it exceeds it. It is the target duration of the
+	// playlist. Defaults to twice the SegmentDuration.
+	MaxSegmentDuration time.Duration
+
+	// PlaylistSize is the number of segments listed in a live playlist, older
+	// segments are removed. If 0, all segments are kept in an event playlist.
+	PlaylistSize int
+}
+
+type hlsSegment struct {
+	sequence uint64
+	duration float64
+}
+
+func (s hlsSegment) name() string {
+	return fmt.Sprintf("segment%d.ts", s.sequence)
+}
+
+// HLSSegmenter writes RTP packets as MPEG-TS segments cut on key frames, and
+// the HLS playlist that lists them
+type HLSSegmenter struct {
+	*TSWriter
+
+	config HLSSegmenterConfig
+
+	segments     []hlsSegment
+	nextSequence uint64
+	segmentStart uint64
+	hasSegment   bool
+	closed       bool
+
+	// targetDuration can't change between playlists (RFC 8216 section 6.2.1)
+	targetDuration int
+}
+
+// NewHLSSegmenter builds a new HLS segmenter, the options configure the tracks
+// of the MPEG-TS segments
+func NewHLSSegmenter(config HLSSegmenterConfig, opts ...Option) (*HLSSegmenter, error) {
+	if config.Directory == "" {
+		return nil, errNoDirectory
+	}
+	if config.SegmentDuration <= 0 {
+		config.SegmentDuration = defaultSegmentDuration
+	}
+	if config.MaxSegmentDuration == 0 {
+		config.MaxSegmentDuration = 2 * config.SegmentDuration
+	} else if config.MaxSegmentDuration < config.SegmentDuration {
+		return nil, errInvalidMaxSegmentDuration
+	}
+
+	// Nothing is written until the first random access point opens a segment
+	writer, err := NewWith(ioutil.Discard, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	segmenter := &HLSSegmenter{
+		TSWriter:       writer,
+		config:         config,
+		targetDuration: int(math.Ceil(config.MaxSegmentDuration.Seconds())),
+	}
+	writer.onRandomAccess = segmenter.onRandomAccess
+	writer.cutBefore = segmenter.exceedsMaxDuration
+
+	return segmenter, nil
+}
+
+// exceedsMaxDuration returns true if the current segment would be longer than
+// MaxSegmentDuration if it ended at end
+func (s *HLSSegmenter) exceedsMaxDuration(end uint64) bool {
+	return s.hasSegment && time.Duration(end-s.segmentStart)*time.Second/videoClockRate > s.config.MaxSegmentDuration
+}
+
+func (s *HLSSegmenter) onRandomAccess(pts uint64, forced bool) error {
+	if s.hasSegment {
+		if !forced && time.Duration(pts-s.segmentStart)*time.Second/videoClockRate < s.config.SegmentDuration {
+			return nil
+		}
+		if err := s.finishSegment(pts); err != nil {
+			return err
+		}
+		if err := s.writePlaylist(false); err != nil {
+			return err
+		}
+	}
+
+	segment := hlsSegment{sequence: s.nextSequence}
+	f, err := os.Create(filepath.Join(s.config.Directory, segment.name()))
+	if err != nil {
+		return err
+	}
+
+	s.ioWriter = f
+	s.segmentStart, s.hasSegment = pts, true
+	return nil
+}
+
+// finishSegment closes the current segment, and removes the ones that are no
+// longer listed by a live playlist
+func (s *HLSSegmenter) finishSegment(endPTS uint64) error {
+	if closer, ok := s.ioWriter.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	s.ioWriter = ioutil.Discard
+
+	s.segments = append(s.segments, hlsSegment{
+		sequence: s.nextSequence,
+		duration: float64(endPTS-s.segmentStart) / videoClockRate,
+	})
+	s.nextSequence++
+	s.hasSegment = false
+
+	// Segments stay on disk for a while after they leave the playlist, clients
+	// may still be downloading them
+	if s.config.PlaylistSize > 0 && len(s.segments) > 2*s.config.PlaylistSize {
+		removed := s.segments[0]
+		s.segments = s.segments[1:]
+		if err := os.Remove(filepath.Join(s.config.Directory, removed.name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writePlaylist replaces the playlist atomically, so that clients never read a
+// partial one
+func (s *HLSSegmenter) writePlaylist(ended bool) error {
+	listed := s.segments
+	if s.config.PlaylistSize > 0 && len(listed) > s.config.PlaylistSize {
+		listed = listed[len(listed)-s.config.PlaylistSize:]
+	}
+
+	playlist := &bytes.Buffer{}
+	fmt.Fprintf(playlist, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:%d\n", s.targetDuration)
+	if s.config.PlaylistSize == 0 {
+		fmt.Fprintf(playlist, "#EXT-X-PLAYLIST-TYPE:EVENT\n")
+	}
+	mediaSequence := uint64(0)
+	if len(listed) > 0 {
+		mediaSequence = listed[0].sequence
+	}
+	fmt.Fprintf(playlist, "#EXT-X-MEDIA-SEQUENCE:%d\n", mediaSequence)
+	for _, segment := range listed {
+		fmt.Fprintf(playlist, "#EXTINF:%.3f,\n%s\n", segment.duration, segment.name())
+	}
+	if ended {
+		fmt.Fprintf(playlist, "#EXT-X-ENDLIST\n")
+	}
+
+	path := filepath.Join(s.config.Directory, PlaylistName)
+	if err := ioutil.WriteFile(path+".tmp", playlist.Bytes(), 0o644); err != nil { //nolint:gosec
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// Close finishes the last segment, and ends the playlist
+func (s *HLSSegmenter) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	if s.hasSegment {
+		if err := s.finishSegment(s.endPTS); err != nil {
+			return err
+		}
+	}
+	if err := s.writePlaylist(true); err != nil {
+		return err
+	}
+
+	return s.TSWriter.Close()
+}
@@ -0,0 +1,134 @@
+package tswriter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHLSSegmenter(t *testing.T) {
+	directory, err := ioutil.TempDir("", "hls")
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(directory))
+	}()
+
+	_, err = NewHLSSegmenter(HLSSegmenterConfig{}, WithVideoCodec(mimeTypeH264))
+	assert.Equal(t, errNoDirectory, err)
+
+	segmenter, err := NewHLSSegmenter(HLSSegmenterConfig{
+		Directory:       directory,
+		SegmentDuration: time.Second,
+	}, WithVideoCodec(mimeTypeH264), WithAudioCodec(mimeTypeOpus))
+	assert.NoError(t, err)
+	writeTestStream(t, segmenter, 4)
+
+	// The playlist is updated when a segment is finished
+	playlist, err := ioutil.ReadFile(filepath.Join(directory, PlaylistName))
+	assert.NoError(t, err)
+	assert.Equal(t, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:2\n#EXT-X-PLAYLIST-TYPE:EVENT\n#EXT-X-MEDIA-SEQUENCE:0\n"+
+		"#EXTINF:1.000,\nsegment0.ts\n#EXTINF:1.000,\nsegment1.ts\n#EXTINF:1.000,\nsegment2.ts\n", string(playlist))
+
+	assert.NoError(t, segmenter.Close())
+	assert.NoError(t, segmenter.Close())
+
+	playlist, err = ioutil.ReadFile(filepath.Join(directory, PlaylistName))
+	assert.NoError(t, err)
+	assert.Equal(t, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:2\n#EXT-X-PLAYLIST-TYPE:EVENT\n#EXT-X-MEDIA-SEQUENCE:0\n"+
+		"#EXTINF:1.000,\nsegment0.ts\n#EXTINF:1.000,\nsegment1.ts\n#EXTINF:1.000,\nsegment2.ts\n#EXTINF:1.000,\nsegment3.ts\n"+
+		"#EXT-X-ENDLIST\n", string(playlist))
+
+	// Each segment starts with the PAT and PMT, and a video key frame
+	for _, name := range []string{"segment0.ts", "segment1.ts", "segment2.ts", "segment3.ts"} {
+		data, err := ioutil.ReadFile(filepath.Join(directory, name))
+		assert.NoError(t, err)
+
+		packets := parsePackets(t, data)
+		assert.Equal(t, uint16(patPID), packets[0].pid)
+		assert.Equal(t, uint16(pmtPID), packets[1].pid)
+		assert.Equal(t, uint16(videoPID), packets[2].pid)
+		assert.True(t, packets[2].randomAccess)
+	}
+
+	files, err := ioutil.ReadDir(directory)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, len(files))
+}
+
+func TestHLSSegmenter_PlaylistSize(t *testing.T) {
+	directory, err := ioutil.TempDir("", "hls")
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(directory))
+	}()
+
+	segmenter, err := NewHLSSegmenter(HLSSegmenterConfig{
+		Directory:       directory,
+		SegmentDuration: time.Second,
+		PlaylistSize:    1,
+	}, WithVideoCodec(mimeTypeH264), WithAudioCodec(mimeTypeOpus))
+	assert.NoError(t, err)
+	writeTestStream(t, segmenter, 4)
+	assert.NoError(t, segmenter.Close())
+
+	playlist, err := ioutil.ReadFile(filepath.Join(directory, PlaylistName))
+	assert.NoError(t, err)
+	assert.Equal(t, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:2\n#EXT-X-MEDIA-SEQUENCE:3\n"+
+		"#EXTINF:1.000,\nsegment3.ts\n#EXT-X-ENDLIST\n", string(playlist))
+
+	// Segments are removed once they are out of the playlist for a while
+	var names []string
+	files, err := ioutil.ReadDir(directory)
+	assert.NoError(t, err)
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	assert.Equal(t, []string{PlaylistName, "segment2.ts", "segment3.ts"}, names)
+}
+
+// Assert that segments are cut before exceeding the target duration of the playlist,
+// even when key frames are too far apart
+func TestHLSSegmenter_MaxSegmentDuration(t *testing.T) {
+	directory, err := ioutil.TempDir("", "hls")
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(directory))
+	}()
+
+	_, err = NewHLSSegmenter(HLSSegmenterConfig{
+		Directory:          directory,
+		SegmentDuration:    time.Second,
+		MaxSegmentDuration: 500 * time.Millisecond,
+	}, WithVideoCodec(mimeTypeH264))
+	assert.Equal(t, errInvalidMaxSegmentDuration, err)
+
+	segmenter, err := NewHLSSegmenter(HLSSegmenterConfig{
+		Directory:          directory,
+		SegmentDuration:    time.Second,
+		MaxSegmentDuration: 1500 * time.Millisecond,
+	}, WithVideoCodec(mimeTypeH264))
+	assert.NoError(t, err)
+
+	// 4 seconds of video at 10 fps, with a single key frame
+	for i := 0; i <= 40; i++ {
+		payload := []byte{0x41, 0x9A, byte(i)}
+		if i == 0 {
+			payload = []byte{0x65, 0x88, byte(i)}
+		}
+		assert.NoError(t, segmenter.WriteVideoRTP(&rtp.Packet{
+			Header:  rtp.Header{SequenceNumber: uint16(i), Timestamp: 1000 + uint32(i)*9000, Marker: true},
+			Payload: payload,
+		}))
+	}
+	assert.NoError(t, segmenter.Close())
+
+	playlist, err := ioutil.ReadFile(filepath.Join(directory, PlaylistName))
+	assert.NoError(t, err)
+	assert.Equal(t, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:2\n#EXT-X-PLAYLIST-TYPE:EVENT\n#EXT-X-MEDIA-SEQUENCE:0\n"+
+		"#EXTINF:1.500,\nsegment0.ts\n#EXTINF:1.500,\nsegment1.ts\n#EXTINF:1.000,\nsegment2.ts\n#EXT-X-ENDLIST\n", string(playlist))
+}
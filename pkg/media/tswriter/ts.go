@@ -0,0 +1,208 @@
+package tswriter
+
+const (
+	packetSize        = 188
+	packetHeaderSize  = 4
+	packetPayloadSize = packetSize - packetHeaderSize
+	syncByte          = 0x47
+
+	patPID = 0x0000
+	pmtPID = 0x1000
+
+	programNumber     = 1
+	transportStreamID = 1
+
+	tableIDPAT = 0x00
+	tableIDPMT = 0x02
+
+	adaptationFieldFlagRandomAccess = 0x40
+	adaptationFieldFlagPCR          = 0x10
+
+	ptsDTSFlagPTS    = 0x80
+	ptsDTSFlagPTSDTS = 0xC0
+
+	// 33 bits timestamps
+	timestampMask = 1<<33 - 1
+)
+
+// elementaryStream is a stream of the program, with its PES stream id and its
+// PMT stream type and descriptors
+type elementaryStream struct {
+	pid         uint16
+	streamType  byte
+	streamID    byte
+	descriptors []byte
+
+	continuityCounter byte
+}
+
+// crc32MPEG2 computes the CRC of the PSI sections
+func crc32MPEG2(data []byte) uint32 {
+	crc := uint32(0xFFFFFFFF)
+	for _, b := range data {
+		crc ^= uint32(b) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04C11DB7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return crc
+}
+
+// psiSection returns a long form PSI section with its CRC
+func psiSection(tableID byte, tableIDExtension uint16, content []byte) []byte {
+	sectionLength := 5 + len(content) + 4
+	section := []byte{
+		tableID,
+		0xB0 | byte(sectionLength>>8), byte(sectionLength), // section_syntax_indicator, reserved, section_length
+		byte(tableIDExtension >> 8), byte(tableIDExtension),
+		0xC1, // reserved, version_number 0, current_next_indicator
+		0, 0, // section_number, last_section_number
+	}
+	section = append(section, content...)
+
+	crc := crc32MPEG2(section)
+	return append(section, byte(crc>>24), byte(crc>>16), byte(crc>>8), byte(crc))
+}
+
+// patSection returns the Program Association Table of the single program
+func patSection() []byte {
+	return psiSection(tableIDPAT, transportStreamID, []byte{
+		0, programNumber,
+		0xE0 | byte(pmtPID>>8), byte(pmtPID & 0xFF),
+	})
+}
+
+// pmtSection returns the Program Map Table of the program
+func pmtSection(pcrPID uint16, streams []*elementaryStream) []byte {
+	content := []byte{
+		0xE0 | byte(pcrPID>>8), byte(pcrPID),
+		0xF0, 0, // program_info_length
+	}
+	for _, s := range streams {
+		content = append(content,
+			s.streamType,
+			0xE0|byte(s.pid>>8), byte(s.pid),
+			0xF0|byte(len(s.descriptors)>>8), byte(len(s.descriptors)),
+		)
+		content = append(content, s.descriptors...)
+	}
+
+	return psiSection(tableIDPMT, programNumber, content)
+}
+
+// packetHeader returns the header of a TS packet
+func packetHeader(pid uint16, payloadUnitStart, hasAdaptationField bool, continuityCounter byte) []byte {
+	header := []byte{syncByte, byte(pid>>8) & 0x1F, byte(pid), 0x10 | continuityCounter&0x0F}
+	if payloadUnitStart {
+		header[1] |= 0x40
+	}
+	if hasAdaptationField {
+		header[3] |= 0x20
+	}
+
+	return header
+}
+
+// psiPacket returns a TS packet carrying a whole PSI section
+func psiPacket(pid uint16, section []byte, continuityCounter byte) []byte {
+	packet := packetHeader(pid, true, false, continuityCounter)
+	packet = append(packet, 0) // pointer_field
+	packet = append(packet, section...)
+	for len(packet) < packetSize {
+		packet = append(packet, 0xFF)
+	}
+
+	return packet
+}
+
+// appendTimestamp appends a PTS or a DTS field with the given 4 bits prefix
+func appendTimestamp(b []byte, prefix byte, ts uint64) []byte {
+	return append(b,
+		prefix<<4|byte(ts>>29)&0x0E|1,
+		byte(ts>>22),
+		byte(ts>>14)|1,
+		byte(ts>>7),
+		byte(ts<<1)|1,
+	)
+}
+
+// pesHeader returns the header of a PES packet, the DTS is omitted when it is
+// equal to the PTS
+func pesHeader(streamID byte, payloadSize int, pts, dts uint64) []byte {
+	header := []byte{0, 0, 1, streamID, 0, 0, 0x80, ptsDTSFlagPTS, 5}
+	if dts != pts {
+		header[7], header[8] = ptsDTSFlagPTSDTS, 10
+	}
+
+	// The length may only be unbounded for video streams
+	if pesLength := len(header) - 6 + int(header[8]) + payloadSize; pesLength <= 0xFFFF {
+		header[4], header[5] = byte(pesLength>>8), byte(pesLength)
+	}
+
+	if dts != pts {
+		header = appendTimestamp(header, 0x3, pts)
+		return appendTimestamp(header, 0x1, dts)
+	}
+
+	return appendTimestamp(header, 0x2, pts)
+}
+
+// pesPackets splits a PES packet into TS packets. The first one carries the PCR
+// and the random access indicator when they are set.
+func pesPackets(s *elementaryStream, pes []byte, pcr *uint64, randomAccess bool) []byte {
+	packets := make([]byte, 0, (len(pes)/packetPayloadSize+2)*packetSize)
+
+	first := true
+	for len(pes) > 0 {
+		var adaptationField []byte
+		if first && (pcr != nil || randomAccess) {
+			adaptationField = []byte{1, 0}
+			if randomAccess {
+				adaptationField[1] |= adaptationFieldFlagRandomAccess
+			}
+			if pcr != nil {
+				adaptationField[1] |= adaptationFieldFlagPCR
+				base := *pcr & timestampMask
+				adaptationField = append(adaptationField,
+					byte(base>>25), byte(base>>17), byte(base>>9), byte(base>>1),
+					byte(base<<7)|0x7E, 0, // reserved bits, 9 bits extension of 0
+				)
+			}
+		}
+
+		// The last packet is completed by stuffing bytes in the adaptation field
+		if space := packetPayloadSize - len(adaptationField); len(pes) < space {
+			stuffing := space - len(pes)
+			if adaptationField == nil {
+				// A single byte adaptation field only has its length
+				adaptationField = []byte{0}
+				if stuffing--; stuffing > 0 {
+					adaptationField = append(adaptationField, 0)
+					stuffing--
+				}
+			}
+			for ; stuffing > 0; stuffing-- {
+				adaptationField = append(adaptationField, 0xFF)
+			}
+		}
+		if len(adaptationField) > 0 {
+			adaptationField[0] = byte(len(adaptationField) - 1)
+		}
+
+		packets = append(packets, packetHeader(s.pid, first, adaptationField != nil, s.continuityCounter)...)
+		packets = append(packets, adaptationField...)
+		n := packetPayloadSize - len(adaptationField)
+		packets = append(packets, pes[:n]...)
+		pes = pes[n:]
+
+		s.continuityCounter = (s.continuityCounter + 1) & 0x0F
+		first = false
+	}
+
+	return packets
+}
@@ -0,0 +1,342 @@
+// Package tswriter implements a MPEG-TS media container writer, and a HLS
+// segmenter built on it
+package tswriter
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/media/samplebuilder"
+	"github.com/pion/webrtc/v3/pkg/rtpcodecs"
+)
+
+var (
+	errFileNotOpened    = errors.New("file not opened")
+	errInvalidNilPacket = errors.New("invalid nil packet")
+	errCodecAlreadySet  = errors.New("codec is already set")
+	errNoSuchCodec      = errors.New("no codec for this MimeType")
+	errNoTracks         = errors.New("no audio or video codec configured")
+	errNoVideoTrack     = errors.New("no video codec configured")
+	errNoAudioTrack     = errors.New("no audio codec configured")
+	errAmbiguousTrack   = errors.New("WriteRTP requires a single track, use WriteVideoRTP or WriteAudioRTP")
+)
+
+const (
+	mimeTypeH264 = "video/H264"
+	mimeTypeH265 = "video/H265"
+	mimeTypeOpus = "audio/opus"
+
+	videoClockRate = 90000
+	opusClockRate  = 48000
+	opusChannels   = 2
+
+	videoMaxLate = 512
+	audioMaxLate = 50
+
+	videoPID = 0x0100
+	audioPID = 0x0101
+
+	streamTypeH264    = 0x1B
+	streamTypeH265    = 0x24
+	streamTypePrivate = 0x06
+
+	streamIDVideo          = 0xE0
+	streamIDPrivateStream1 = 0xBD
+
+	// Timestamps start after 1 second so that the PCR, sent 700ms ahead of the
+	// decoding time, never wraps
+	timestampOffset = 90000
+	pcrDelay        = 63000
+
+	// PAT and PMT are repeated every second for audio only streams, and before
+	// every key frame for video
+	audioOnlyPSIInterval = 90000
+)
+
+type track struct {
+	stream    *elementaryStream
+	isVideo   bool
+	clockRate uint32
+	builder   *samplebuilder.SampleBuilder
+
+	// convert returns the PES payload of a sample, and if it is a random access point
+	convert func(sample *media.Sample) (payload []byte, keyFrame bool)
+
+	started       bool
+	lastTimestamp uint32
+	ticks         uint64
+
+	// lastPTS and lastDuration are used to estimate the end of the track
+	lastPTS, lastDuration uint64
+}
+
+// TSWriter is used to take RTP packets of an audio and a video track, and
+// write them as a MPEG-TS stream
+type TSWriter struct {
+	ioWriter io.Writer
+
+	videoTrack, audioTrack *track
+	tracks                 []*track
+
+	patContinuityCounter byte
+	pmtContinuityCounter byte
+	hasKeyFrame          bool
+	lastPSI              uint64
+	hasPSI               bool
+
+	// endPTS is the end of the last sample written, estimated from the
+	// duration of the previous sample of its track
+	endPTS uint64
+
+	// onRandomAccess is called before the PAT and PMT preceding a random access
+	// point are written, the HLS segmenter cuts segments there. They are also
+	// written before a sample that isn't one if cutBefore returns true for its end,
+	// then forced is true.
+	onRandomAccess func(pts uint64, forced bool) error
+	cutBefore      func(end uint64) bool
+}
+
+// New builds a new MPEG-TS writer
+func New(fileName string, opts ...Option) (*TSWriter, error) {
+	f, err := os.Create(fileName) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+	writer, err := NewWith(f, opts...)
+	if err != nil {
+		return nil, err
+	}
+	writer.ioWriter = f
+	return writer, nil
+}
+
+// NewWith initialize a new MPEG-TS writer with an io.Writer output
+func NewWith(out io.Writer, opts ...Option) (*TSWriter, error) {
+	if out == nil {
+		return nil, errFileNotOpened
+	}
+
+	writer := &TSWriter{
+		ioWriter: out,
+	}
+
+	for _, o := range opts {
+		if err := o(writer); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, t := range []*track{writer.videoTrack, writer.audioTrack} {
+		if t != nil {
+			writer.tracks = append(writer.tracks, t)
+		}
+	}
+	if len(writer.tracks) == 0 {
+		return nil, errNoTracks
+	}
+
+	return writer, nil
+}
+
+// WriteRTP adds a new packet to the only track of the writer
+func (w *TSWriter) WriteRTP(packet *rtp.Packet) error {
+	if len(w.tracks) != 1 {
+		return errAmbiguousTrack
+	}
+
+	return w.writeRTP(w.tracks[0], packet)
+}
+
+// WriteVideoRTP adds a new packet to the video track
+func (w *TSWriter) WriteVideoRTP(packet *rtp.Packet) error {
+	if w.videoTrack == nil {
+		return errNoVideoTrack
+	}
+
+	return w.writeRTP(w.videoTrack, packet)
+}
+
+// WriteAudioRTP adds a new packet to the audio track
+func (w *TSWriter) WriteAudioRTP(packet *rtp.Packet) error {
+	if w.audioTrack == nil {
+		return errNoAudioTrack
+	}
+
+	return w.writeRTP(w.audioTrack, packet)
+}
+
+func (w *TSWriter) writeRTP(t *track, packet *rtp.Packet) error {
+	if w.ioWriter == nil {
+		return errFileNotOpened
+	} else if packet == nil {
+		return errInvalidNilPacket
+	}
+
+	t.builder.Push(packet)
+	for s := t.builder.Pop(); s != nil; s = t.builder.Pop() {
+		if err := w.writeSample(t, s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *TSWriter) writeSample(t *track, s *media.Sample) error {
+	payload, keyFrame := t.convert(s)
+	if len(payload) == 0 {
+		return nil
+	}
+
+	// Everything is dropped until the video starts with a key frame
+	if w.videoTrack != nil && !w.hasKeyFrame {
+		if t != w.videoTrack || !keyFrame {
+			return nil
+		}
+		w.hasKeyFrame = true
+	}
+
+	// RTP timestamps are unwrapped, and converted to the 90kHz clock
+	if !t.started {
+		t.started, t.lastTimestamp = true, s.PacketTimestamp
+	}
+	t.ticks += uint64(s.PacketTimestamp - t.lastTimestamp)
+	t.lastTimestamp = s.PacketTimestamp
+	pts := timestampOffset + t.ticks*videoClockRate/uint64(t.clockRate)
+
+	randomAccess := keyFrame && t.isVideo
+	if w.videoTrack == nil && (!w.hasPSI || pts-w.lastPSI >= audioOnlyPSIInterval) {
+		randomAccess = true
+	}
+	forced := !randomAccess && w.cutBefore != nil && w.cutBefore(pts+t.lastDuration)
+	if randomAccess || forced {
+		if err := w.writePSI(pts, forced); err != nil {
+			return err
+		}
+	}
+
+	// The PCR is carried by the first track, video if there is one
+	var pcr *uint64
+	if t == w.tracks[0] {
+		value := pts - pcrDelay
+		pcr = &value
+	}
+
+	// DTS and PTS are the same, WebRTC streams have no B frames
+	pes := append(pesHeader(t.stream.streamID, len(payload), pts, pts), payload...)
+	if _, err := w.ioWriter.Write(pesPackets(t.stream, pes, pcr, randomAccess)); err != nil {
+		return err
+	}
+
+	if t.lastPTS != 0 && pts > t.lastPTS {
+		t.lastDuration = pts - t.lastPTS
+	}
+	t.lastPTS = pts
+	if end := pts + t.lastDuration; end > w.endPTS {
+		w.endPTS = end
+	}
+
+	return nil
+}
+
+func (w *TSWriter) writePSI(pts uint64, forced bool) error {
+	if w.onRandomAccess != nil {
+		if err := w.onRandomAccess(pts, forced); err != nil {
+			return err
+		}
+	}
+
+	streams := make([]*elementaryStream, 0, len(w.tracks))
+	for _, t := range w.tracks {
+		streams = append(streams, t.stream)
+	}
+
+	psi := append(
+		psiPacket(patPID, patSection(), w.patContinuityCounter),
+		psiPacket(pmtPID, pmtSection(w.tracks[0].stream.pid, streams), w.pmtContinuityCounter)...,
+	)
+	w.patContinuityCounter = (w.patContinuityCounter + 1) & 0x0F
+	w.pmtContinuityCounter = (w.pmtContinuityCounter + 1) & 0x0F
+	w.lastPSI, w.hasPSI = pts, true
+
+	_, err := w.ioWriter.Write(psi)
+	return err
+}
+
+// Close stops the recording
+func (w *TSWriter) Close() error {
+	if w.ioWriter == nil {
+		// Returns no error as it may be convenient to call
+		// Close() multiple times
+		return nil
+	}
+
+	defer func() {
+		w.ioWriter = nil
+	}()
+
+	if closer, ok := w.ioWriter.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// An Option configures a TSWriter.
+type Option func(w *TSWriter) error
+
+// WithVideoCodec adds a H264 or H265 video track to the TSWriter
+func WithVideoCodec(mimeType string) Option {
+	return func(w *TSWriter) error {
+		if w.videoTrack != nil {
+			return errCodecAlreadySet
+		}
+
+		t := &track{isVideo: true, clockRate: videoClockRate}
+		switch {
+		case strings.EqualFold(mimeType, mimeTypeH264):
+			t.stream = &elementaryStream{pid: videoPID, streamType: streamTypeH264, streamID: streamIDVideo}
+			t.convert = convertH264
+			t.builder = samplebuilder.New(videoMaxLate, &codecs.H264Packet{}, videoClockRate)
+		case strings.EqualFold(mimeType, mimeTypeH265):
+			t.stream = &elementaryStream{pid: videoPID, streamType: streamTypeH265, streamID: streamIDVideo}
+			t.convert = convertH265
+			t.builder = samplebuilder.New(videoMaxLate, &rtpcodecs.H265Depacketizer{}, videoClockRate)
+		default:
+			return errNoSuchCodec
+		}
+
+		w.videoTrack = t
+		return nil
+	}
+}
+
+// WithAudioCodec adds an Opus audio track to the TSWriter
+func WithAudioCodec(mimeType string) Option {
+	return func(w *TSWriter) error {
+		if w.audioTrack != nil {
+			return errCodecAlreadySet
+		}
+		if !strings.EqualFold(mimeType, mimeTypeOpus) {
+			return errNoSuchCodec
+		}
+
+		w.audioTrack = &track{
+			stream: &elementaryStream{
+				pid:         audioPID,
+				streamType:  streamTypePrivate,
+				streamID:    streamIDPrivateStream1,
+				descriptors: opusDescriptors(opusChannels),
+			},
+			clockRate: opusClockRate,
+			convert:   convertOpus,
+			builder:   samplebuilder.New(audioMaxLate, &codecs.OpusPacket{}, opusClockRate),
+		}
+		return nil
+	}
+}
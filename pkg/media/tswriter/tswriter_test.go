@@ -0,0 +1,268 @@
+package tswriter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+type tsPacket struct {
+	pid                  uint16
+	payloadUnitStart     bool
+	continuityCounter    byte
+	adaptationField      []byte
+	payload              []byte
+	hasAdaptationField   bool
+	randomAccess, hasPCR bool
+}
+
+func parsePackets(t *testing.T, data []byte) []tsPacket {
+	assert.Equal(t, 0, len(data)%packetSize)
+
+	var packets []tsPacket
+	for ; len(data) >= packetSize; data = data[packetSize:] {
+		assert.Equal(t, byte(syncByte), data[0])
+		p := tsPacket{
+			pid:                uint16(data[1]&0x1F)<<8 | uint16(data[2]),
+			payloadUnitStart:   data[1]&0x40 != 0,
+			continuityCounter:  data[3] & 0x0F,
+			hasAdaptationField: data[3]&0x20 != 0,
+			payload:            data[4:packetSize],
+		}
+		if p.hasAdaptationField {
+			length := int(p.payload[0])
+			p.adaptationField = p.payload[1 : 1+length]
+			p.payload = p.payload[1+length:]
+			if length > 0 {
+				p.randomAccess = p.adaptationField[0]&adaptationFieldFlagRandomAccess != 0
+				p.hasPCR = p.adaptationField[0]&adaptationFieldFlagPCR != 0
+			}
+		}
+		packets = append(packets, p)
+	}
+
+	return packets
+}
+
+func parseTimestamp(b []byte) uint64 {
+	return uint64(b[0]>>1&0x07)<<30 | uint64(b[1])<<22 | uint64(b[2]>>1)<<15 | uint64(b[3])<<7 | uint64(b[4]>>1)
+}
+
+func TestPESHeader(t *testing.T) {
+	header := pesHeader(streamIDVideo, 10, 0x1FFFFFFFF, 0x1FFFFFFFF)
+	assert.Equal(t, []byte{0x00, 0x00, 0x01, 0xE0, 0x00, 0x12, 0x80, 0x80, 0x05}, header[:9])
+	assert.Equal(t, uint64(0x1FFFFFFFF), parseTimestamp(header[9:]))
+
+	header = pesHeader(streamIDVideo, 0x10000, 3003, 0)
+	assert.Equal(t, []byte{0x00, 0x00, 0x01, 0xE0, 0x00, 0x00, 0x80, 0xC0, 0x0A}, header[:9])
+	assert.Equal(t, byte(0x31), header[9])
+	assert.Equal(t, uint64(3003), parseTimestamp(header[9:]))
+	assert.Equal(t, byte(0x11), header[14])
+	assert.Equal(t, uint64(0), parseTimestamp(header[14:]))
+}
+
+func TestPESPackets(t *testing.T) {
+	s := &elementaryStream{pid: audioPID, continuityCounter: 15}
+	for _, size := range []int{1, 182, 183, 184, 185, 400} {
+		pes := bytes.Repeat([]byte{0xAB}, size)
+		packets := parsePackets(t, pesPackets(s, pes, nil, false))
+
+		var payload []byte
+		for i, p := range packets {
+			assert.Equal(t, audioPID, int(p.pid))
+			assert.Equal(t, i == 0, p.payloadUnitStart)
+			payload = append(payload, p.payload...)
+		}
+		assert.Equal(t, pes, payload, "size %d", size)
+	}
+
+	pcr := uint64(90000)
+	packets := parsePackets(t, pesPackets(s, []byte{0xAB}, &pcr, true))
+	assert.Equal(t, 1, len(packets))
+	assert.True(t, packets[0].randomAccess)
+	assert.True(t, packets[0].hasPCR)
+	assert.Equal(t, []byte{0x00, 0x00, 0xAF, 0xC8, 0x7E, 0x00}, packets[0].adaptationField[1:7])
+	assert.Equal(t, []byte{0xAB}, packets[0].payload)
+}
+
+func TestPSI(t *testing.T) {
+	streams := []*elementaryStream{
+		{pid: videoPID, streamType: streamTypeH264},
+		{pid: audioPID, streamType: streamTypePrivate, descriptors: opusDescriptors(2)},
+	}
+	for _, section := range [][]byte{patSection(), pmtSection(videoPID, streams)} {
+		// The CRC of a section including its CRC is 0
+		assert.Equal(t, uint32(0), crc32MPEG2(section))
+		assert.Equal(t, len(section)-3, int(section[1]&0x0F)<<8|int(section[2]))
+	}
+
+	assert.Equal(t, []byte{
+		0x00, 0xB0, 0x0D, 0x00, 0x01, 0xC1, 0x00, 0x00,
+		0x00, 0x01, 0xF0, 0x00,
+		0x2A, 0xB1, 0x04, 0xB2,
+	}, patSection())
+}
+
+func TestTSWriter_Errors(t *testing.T) {
+	_, err := NewWith(nil, WithAudioCodec(mimeTypeOpus))
+	assert.Equal(t, errFileNotOpened, err)
+
+	_, err = NewWith(&bytes.Buffer{})
+	assert.Equal(t, errNoTracks, err)
+
+	_, err = NewWith(&bytes.Buffer{}, WithVideoCodec("video/VP8"))
+	assert.Equal(t, errNoSuchCodec, err)
+
+	_, err = NewWith(&bytes.Buffer{}, WithVideoCodec(mimeTypeH264), WithVideoCodec(mimeTypeH265))
+	assert.Equal(t, errCodecAlreadySet, err)
+
+	writer, err := NewWith(&bytes.Buffer{}, WithVideoCodec(mimeTypeH264), WithAudioCodec(mimeTypeOpus))
+	assert.NoError(t, err)
+	assert.Equal(t, errAmbiguousTrack, writer.WriteRTP(&rtp.Packet{}))
+	assert.Equal(t, errInvalidNilPacket, writer.WriteVideoRTP(nil))
+	assert.NoError(t, writer.Close())
+	assert.NoError(t, writer.Close())
+	assert.Equal(t, errFileNotOpened, writer.WriteAudioRTP(&rtp.Packet{}))
+
+	writer, err = NewWith(&bytes.Buffer{}, WithAudioCodec(mimeTypeOpus))
+	assert.NoError(t, err)
+	assert.Equal(t, errNoVideoTrack, writer.WriteVideoRTP(&rtp.Packet{}))
+}
+
+func TestConvert(t *testing.T) {
+	payload, keyFrame := convertH264(&media.Sample{Data: []byte{0x00, 0x00, 0x00, 0x01, 0x67, 0x42, 0x00, 0x00, 0x01, 0x65, 0x88}})
+	assert.True(t, keyFrame)
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x01, 0x09, 0xF0, 0x00, 0x00, 0x00, 0x01, 0x67, 0x42, 0x00, 0x00, 0x01, 0x65, 0x88}, payload)
+
+	payload, keyFrame = convertH264(&media.Sample{Data: []byte{0x00, 0x00, 0x00, 0x01, 0x09, 0xF0, 0x00, 0x00, 0x00, 0x01, 0x41, 0x9A}})
+	assert.False(t, keyFrame)
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x01, 0x09, 0xF0, 0x00, 0x00, 0x00, 0x01, 0x41, 0x9A}, payload)
+
+	payload, keyFrame = convertH265(&media.Sample{Data: []byte{0x00, 0x00, 0x00, 0x01, 0x26, 0x01, 0xAF}, IsKeyFrame: true})
+	assert.True(t, keyFrame)
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x01, 0x46, 0x01, 0x50, 0x00, 0x00, 0x00, 0x01, 0x26, 0x01, 0xAF}, payload)
+
+	payload, _ = convertOpus(&media.Sample{Data: bytes.Repeat([]byte{0xFC}, 300)})
+	assert.Equal(t, []byte{0x7F, 0xE0, 0xFF, 0x2D}, payload[:4])
+	assert.Equal(t, 304, len(payload))
+}
+
+// writeTestStream writes seconds of H264 at 10 fps with a key frame every
+// second, and of Opus with 20ms packets
+func writeTestStream(t *testing.T, w interface {
+	WriteVideoRTP(*rtp.Packet) error
+	WriteAudioRTP(*rtp.Packet) error
+}, seconds int) {
+	videoSequenceNumber, audioSequenceNumber := uint16(0), uint16(0)
+	audioTimestamp := uint32(0)
+	for i := 0; i <= seconds*10; i++ {
+		payload := []byte{0x41, 0x9A, byte(i)}
+		if i%10 == 0 {
+			payload = []byte{0x65, 0x88, byte(i)}
+		}
+		assert.NoError(t, w.WriteVideoRTP(&rtp.Packet{
+			Header:  rtp.Header{SequenceNumber: videoSequenceNumber, Timestamp: 1000 + uint32(i)*9000, Marker: true},
+			Payload: payload,
+		}))
+		videoSequenceNumber++
+
+		for j := 0; j < 5; j++ {
+			assert.NoError(t, w.WriteAudioRTP(&rtp.Packet{
+				Header:  rtp.Header{SequenceNumber: audioSequenceNumber, Timestamp: audioTimestamp},
+				Payload: []byte{0xFC, byte(audioSequenceNumber)},
+			}))
+			audioSequenceNumber++
+			audioTimestamp += 960
+		}
+	}
+}
+
+func TestTSWriter_H264Opus(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer, err := NewWith(buffer, WithVideoCodec(mimeTypeH264), WithAudioCodec(mimeTypeOpus))
+	assert.NoError(t, err)
+	writeTestStream(t, writer, 2)
+	assert.NoError(t, writer.Close())
+
+	packets := parsePackets(t, buffer.Bytes())
+
+	continuityCounters := map[uint16]byte{}
+	var videoPTS []uint64
+	pats, pmts, audioPES, randomAccess := 0, 0, 0, 0
+	for i, p := range packets {
+		if cc, ok := continuityCounters[p.pid]; ok {
+			assert.Equal(t, (cc+1)&0x0F, p.continuityCounter)
+		}
+		continuityCounters[p.pid] = p.continuityCounter
+
+		switch p.pid {
+		case patPID:
+			pats++
+			assert.Equal(t, patSection(), p.payload[1:1+len(patSection())])
+		case pmtPID:
+			pmts++
+			assert.Equal(t, uint16(patPID), packets[i-1].pid)
+		case videoPID:
+			if !p.payloadUnitStart {
+				continue
+			}
+			// The video carries the PCR
+			assert.True(t, p.hasPCR)
+			if p.randomAccess {
+				randomAccess++
+				assert.Equal(t, uint16(pmtPID), packets[i-1].pid)
+			}
+			assert.Equal(t, []byte{0x00, 0x00, 0x01, streamIDVideo}, p.payload[:4])
+			videoPTS = append(videoPTS, parseTimestamp(p.payload[9:]))
+			assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x01, 0x09, 0xF0}, p.payload[14:20])
+		case audioPID:
+			if p.payloadUnitStart {
+				audioPES++
+				assert.False(t, p.hasPCR)
+				assert.Equal(t, []byte{0x00, 0x00, 0x01, streamIDPrivateStream1}, p.payload[:4])
+				assert.Equal(t, []byte{0x7F, 0xE0, 0x02, 0xFC}, p.payload[14:18])
+			}
+		default:
+			assert.Fail(t, "unexpected PID", p.pid)
+		}
+	}
+
+	// The last frame is never popped by the sample builder
+	assert.Equal(t, 2, pats)
+	assert.Equal(t, 2, pmts)
+	assert.Equal(t, 2, randomAccess)
+	assert.Equal(t, 20, len(videoPTS))
+	for i, pts := range videoPTS {
+		assert.Equal(t, uint64(timestampOffset+i*9000), pts)
+	}
+	assert.Equal(t, 100, audioPES)
+}
+
+func TestTSWriter_AudioOnly(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer, err := NewWith(buffer, WithAudioCodec(mimeTypeOpus))
+	assert.NoError(t, err)
+
+	for i := uint16(0); i <= 100; i++ {
+		assert.NoError(t, writer.WriteRTP(&rtp.Packet{
+			Header:  rtp.Header{SequenceNumber: i, Timestamp: uint32(i) * 960},
+			Payload: []byte{0xFC, byte(i)},
+		}))
+	}
+	assert.NoError(t, writer.Close())
+
+	// The PAT and PMT are repeated every second
+	pats := 0
+	for _, p := range parsePackets(t, buffer.Bytes()) {
+		if p.pid == patPID {
+			pats++
+		}
+		if p.pid == audioPID {
+			assert.True(t, p.hasPCR)
+		}
+	}
+	assert.Equal(t, 2, pats)
+}
@@ -0,0 +1,503 @@
+// Package webmreader implements a WebM and Matroska media container reader
+package webmreader
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/pion/webrtc/v3/internal/ebml"
+	"github.com/pion/webrtc/v3/pkg/media"
+)
+
+var (
+	errNilStream         = errors.New("stream is nil")
+	errNotWebM           = errors.New("stream is not a WebM or Matroska file")
+	errNoTracks          = errors.New("no tracks found before the first cluster")
+	errUnknownSize       = errors.New("element of unknown size can't be skipped")
+	errElementTooLarge   = errors.New("element is too large")
+	errShortBlock        = errors.New("block is too short")
+	errInvalidLacing     = errors.New("invalid block lacing")
+	errNoTimecodeInBlock = errors.New("block found before its cluster timecode")
+)
+
+const (
+	defaultTimecodeScale = 1000000
+
+	// Elements read in memory are limited, only the clusters may be larger
+	maxElementSize = 64 * 1024 * 1024
+
+	blockFlagKeyFrame  = 0x80
+	blockFlagLacing    = 0x06
+	blockLacingXiph    = 0x02
+	blockLacingFixed   = 0x04
+	blockLacingEBML    = 0x06
+	blockHeaderMinSize = 4
+)
+
+// TrackType is the type of a track
+type TrackType uint8
+
+// TrackType enums
+const (
+	TrackTypeVideo TrackType = 1
+	TrackTypeAudio TrackType = 2
+)
+
+// Track describes a track of the file
+type Track struct {
+	Number       uint64
+	Type         TrackType
+	CodecID      string
+	CodecPrivate []byte
+
+	// MimeType is the MIME type of the codec used by pion, empty for other codecs
+	MimeType string
+
+	// Video tracks
+	Width, Height uint64
+
+	// Audio tracks
+	SamplingFrequency float64
+	Channels          uint64
+}
+
+// Sample is a frame of one of the tracks of the file
+type Sample struct {
+	media.Sample
+
+	// TrackNumber is the Number of the Track of the sample
+	TrackNumber uint64
+	// PresentationTime is the time of the sample since the start of the file
+	PresentationTime time.Duration
+}
+
+// WebMReader is used to read WebM files and return the samples of their tracks
+type WebMReader struct {
+	stream *bufio.Reader
+	tracks []*Track
+
+	timecodeScale   uint64
+	clusterTimecode uint64
+	hasTimecode     bool
+
+	// The last sample of every track is held back until the next one gives its duration
+	pending   map[uint64]*Sample
+	durations map[uint64]time.Duration
+	ready     []*Sample
+	eof       bool
+}
+
+// NewWith returns a new WebM reader and the tracks of the file, read until the
+// first cluster, with an io.Reader input
+func NewWith(in io.Reader) (*WebMReader, []*Track, error) {
+	if in == nil {
+		return nil, nil, errNilStream
+	}
+
+	reader := &WebMReader{
+		stream:        bufio.NewReader(in),
+		timecodeScale: defaultTimecodeScale,
+		pending:       map[uint64]*Sample{},
+		durations:     map[uint64]time.Duration{},
+	}
+
+	if err := reader.readEBMLHeader(); err != nil {
+		return nil, nil, err
+	}
+	if err := reader.readHeaders(); err != nil {
+		return nil, nil, err
+	}
+
+	return reader, reader.tracks, nil
+}
+
+func (r *WebMReader) readEBMLHeader() error {
+	id, size, err := ebml.ReadElementHeader(r.stream)
+	if err != nil {
+		return err
+	}
+	if id != ebml.IDEBML {
+		return errNotWebM
+	}
+
+	children, err := r.readChildren(size)
+	if err != nil {
+		return err
+	}
+	for _, c := range children {
+		if c.ID == ebml.IDDocType && (string(c.Data) == "webm" || string(c.Data) == "matroska") {
+			return nil
+		}
+	}
+
+	return errNotWebM
+}
+
+// readHeaders reads the top level elements until the first cluster
+func (r *WebMReader) readHeaders() error {
+	for {
+		id, size, err := ebml.ReadElementHeader(r.stream)
+		if err != nil {
+			return err
+		}
+
+		switch id {
+		case ebml.IDSegment:
+			// Descend into the segment
+		case ebml.IDInfo:
+			if err = r.readInfo(size); err != nil {
+				return err
+			}
+		case ebml.IDTracks:
+			if err = r.readTracks(size); err != nil {
+				return err
+			}
+		case ebml.IDCluster:
+			if len(r.tracks) == 0 {
+				return errNoTracks
+			}
+			return nil
+		default:
+			if err = r.skip(size); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (r *WebMReader) readInfo(size uint64) error {
+	children, err := r.readChildren(size)
+	if err != nil {
+		return err
+	}
+	for _, c := range children {
+		if c.ID == ebml.IDTimecodeScale {
+			if scale := ebml.ParseUint(c.Data); scale != 0 {
+				r.timecodeScale = scale
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *WebMReader) readTracks(size uint64) error {
+	entries, err := r.readChildren(size)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.ID != ebml.IDTrackEntry {
+			continue
+		}
+		children, err := ebml.Children(entry.Data)
+		if err != nil {
+			return err
+		}
+
+		track := &Track{}
+		for _, c := range children {
+			switch c.ID {
+			case ebml.IDTrackNumber:
+				track.Number = ebml.ParseUint(c.Data)
+			case ebml.IDTrackType:
+				track.Type = TrackType(ebml.ParseUint(c.Data))
+			case ebml.IDCodecID:
+				track.CodecID = string(c.Data)
+			case ebml.IDCodecPrivate:
+				track.CodecPrivate = c.Data
+			case ebml.IDVideo, ebml.IDAudio:
+				if err = parseTrackSettings(track, c.Data); err != nil {
+					return err
+				}
+			}
+		}
+		track.MimeType = mimeTypes[track.CodecID]
+
+		r.tracks = append(r.tracks, track)
+	}
+
+	return nil
+}
+
+// nolint:gochecknoglobals
+var mimeTypes = map[string]string{
+	"V_VP8":  "video/VP8",
+	"V_VP9":  "video/VP9",
+	"V_AV1":  "video/AV1",
+	"A_OPUS": "audio/opus",
+}
+
+func parseTrackSettings(track *Track, data []byte) error {
+	children, err := ebml.Children(data)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range children {
+		switch c.ID {
+		case ebml.IDPixelWidth:
+			track.Width = ebml.ParseUint(c.Data)
+		case ebml.IDPixelHeight:
+			track.Height = ebml.ParseUint(c.Data)
+		case ebml.IDChannels:
+			track.Channels = ebml.ParseUint(c.Data)
+		case ebml.IDSamplingFrequency:
+			if track.SamplingFrequency, err = ebml.ParseFloat(c.Data); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// NextSample returns the next sample of the file, samples of a track are
+// returned in order with their duration.
+// io.EOF is returned once the stream is exhausted.
+func (r *WebMReader) NextSample() (*Sample, error) {
+	for len(r.ready) == 0 {
+		if r.eof {
+			return nil, io.EOF
+		}
+		if err := r.readBlock(); err != nil {
+			return nil, err
+		}
+	}
+
+	sample := r.ready[0]
+	r.ready = r.ready[1:]
+	return sample, nil
+}
+
+// readBlock reads the stream until the next block, or until the end of the stream
+func (r *WebMReader) readBlock() error {
+	for {
+		id, size, err := ebml.ReadElementHeader(r.stream)
+		if errors.Is(err, io.EOF) {
+			r.flush()
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		switch id {
+		case ebml.IDSegment, ebml.IDCluster:
+			// Descend into the segment and the clusters, they may have an unknown size
+		case ebml.IDTimecode:
+			data, err := r.read(size)
+			if err != nil {
+				return err
+			}
+			r.clusterTimecode, r.hasTimecode = ebml.ParseUint(data), true
+		case ebml.IDSimpleBlock:
+			data, err := r.read(size)
+			if err != nil {
+				return err
+			}
+			return r.parseBlock(data, false)
+		case ebml.IDBlockGroup:
+			return r.readBlockGroup(size)
+		default:
+			if err = r.skip(size); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (r *WebMReader) readBlockGroup(size uint64) error {
+	children, err := r.readChildren(size)
+	if err != nil {
+		return err
+	}
+
+	// Blocks without references are key frames
+	var blockData []byte
+	keyFrame := true
+	for _, c := range children {
+		switch c.ID {
+		case ebml.IDBlock:
+			blockData = c.Data
+		case ebml.IDReferenceBlock:
+			keyFrame = false
+		}
+	}
+	if blockData == nil {
+		return nil
+	}
+
+	return r.parseBlock(blockData, keyFrame)
+}
+
+// parseBlock parses a SimpleBlock or a Block
+// https://www.matroska.org/technical/basics.html#block-structure
+func (r *WebMReader) parseBlock(data []byte, keyFrame bool) error {
+	if !r.hasTimecode {
+		return errNoTimecodeInBlock
+	}
+
+	trackNumber, n, err := ebml.ReadVint(data)
+	if err != nil {
+		return err
+	}
+	if len(data) < n+3 || len(data) < blockHeaderMinSize {
+		return errShortBlock
+	}
+	relative := int16(uint16(data[n])<<8 | uint16(data[n+1]))
+	flags := data[n+2]
+	keyFrame = keyFrame || flags&blockFlagKeyFrame != 0
+
+	frames, err := splitLacedFrames(data[n+3:], flags&blockFlagLacing)
+	if err != nil {
+		return err
+	}
+
+	timecode := int64(r.clusterTimecode) + int64(relative)
+	if timecode < 0 {
+		timecode = 0
+	}
+	presentationTime := time.Duration(uint64(timecode) * r.timecodeScale)
+
+	for _, frame := range frames {
+		r.push(&Sample{
+			Sample:           media.Sample{Data: frame, IsKeyFrame: keyFrame},
+			TrackNumber:      trackNumber,
+			PresentationTime: presentationTime,
+		})
+	}
+
+	return nil
+}
+
+func (r *WebMReader) push(sample *Sample) {
+	if pending, ok := r.pending[sample.TrackNumber]; ok {
+		if sample.PresentationTime > pending.PresentationTime {
+			pending.Duration = sample.PresentationTime - pending.PresentationTime
+		}
+		r.durations[sample.TrackNumber] = pending.Duration
+		r.ready = append(r.ready, pending)
+	}
+	r.pending[sample.TrackNumber] = sample
+}
+
+// flush releases the last samples of the tracks, their duration is unknown
+// and the duration of the previous sample is used
+func (r *WebMReader) flush() {
+	r.eof = true
+
+	for _, t := range r.tracks {
+		pending, ok := r.pending[t.Number]
+		if !ok {
+			continue
+		}
+		pending.Duration = r.durations[t.Number]
+		r.ready = append(r.ready, pending)
+		delete(r.pending, t.Number)
+	}
+}
+
+// splitLacedFrames returns the frames of a block
+func splitLacedFrames(data []byte, lacing byte) ([][]byte, error) { //nolint:gocognit
+	if lacing == 0 {
+		return [][]byte{data}, nil
+	}
+
+	if len(data) < 1 {
+		return nil, errInvalidLacing
+	}
+	count := int(data[0]) + 1
+	data = data[1:]
+
+	sizes := make([]int, count-1)
+	switch lacing {
+	case blockLacingXiph:
+		for i := range sizes {
+			for {
+				if len(data) == 0 {
+					return nil, errInvalidLacing
+				}
+				b := data[0]
+				data = data[1:]
+				sizes[i] += int(b)
+				if b != 0xFF {
+					break
+				}
+			}
+		}
+	case blockLacingFixed:
+		if len(data)%count != 0 {
+			return nil, errInvalidLacing
+		}
+		for i := range sizes {
+			sizes[i] = len(data) / count
+		}
+	case blockLacingEBML:
+		for i := range sizes {
+			value, n, err := ebml.ReadVint(data)
+			if err != nil {
+				return nil, errInvalidLacing
+			}
+			data = data[n:]
+
+			if i == 0 {
+				sizes[i] = int(value)
+				continue
+			}
+			// Signed differences with the previous size
+			sizes[i] = sizes[i-1] + int(value) - (1<<(7*uint(n)-1) - 1)
+		}
+	}
+
+	frames := make([][]byte, 0, count)
+	for _, size := range sizes {
+		if size < 0 || size > len(data) {
+			return nil, errInvalidLacing
+		}
+		frames = append(frames, data[:size])
+		data = data[size:]
+	}
+
+	return append(frames, data), nil
+}
+
+func (r *WebMReader) readChildren(size uint64) ([]ebml.Element, error) {
+	data, err := r.read(size)
+	if err != nil {
+		return nil, err
+	}
+
+	return ebml.Children(data)
+}
+
+func (r *WebMReader) read(size uint64) ([]byte, error) {
+	switch {
+	case size == ebml.UnknownSize:
+		return nil, errUnknownSize
+	case size > maxElementSize:
+		return nil, errElementTooLarge
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r.stream, data); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	return data, nil
+}
+
+func (r *WebMReader) skip(size uint64) error {
+	if size == ebml.UnknownSize {
+		return errUnknownSize
+	}
+
+	if _, err := io.CopyN(ioutil.Discard, r.stream, int64(size)); err != nil {
+		return io.ErrUnexpectedEOF
+	}
+
+	return nil
+}
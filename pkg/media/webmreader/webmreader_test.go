@@ -0,0 +1,132 @@
+package webmreader
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3/internal/ebml"
+	"github.com/stretchr/testify/assert"
+)
+
+func buildWebM(docType string, clusters ...[]byte) []byte {
+	file := ebml.Master(ebml.IDEBML, ebml.String(ebml.IDDocType, docType))
+
+	// Segment and clusters of unknown size, as written by live encoders
+	file = ebml.AppendSizeWithLength(ebml.AppendID(file, ebml.IDSegment), ebml.UnknownSize, 8)
+	file = append(file, ebml.Master(ebml.IDInfo, ebml.Uint(ebml.IDTimecodeScale, 1000000))...)
+	file = append(file, ebml.Master(ebml.IDTracks,
+		ebml.Master(ebml.IDTrackEntry,
+			ebml.Uint(ebml.IDTrackNumber, 1),
+			ebml.Uint(ebml.IDTrackType, uint64(TrackTypeVideo)),
+			ebml.String(ebml.IDCodecID, "V_VP9"),
+			ebml.Master(ebml.IDVideo, ebml.Uint(ebml.IDPixelWidth, 320), ebml.Uint(ebml.IDPixelHeight, 240)),
+		),
+		ebml.Master(ebml.IDTrackEntry,
+			ebml.Uint(ebml.IDTrackNumber, 2),
+			ebml.Uint(ebml.IDTrackType, uint64(TrackTypeAudio)),
+			ebml.String(ebml.IDCodecID, "A_OPUS"),
+			ebml.Master(ebml.IDAudio, ebml.Float(ebml.IDSamplingFrequency, 48000), ebml.Uint(ebml.IDChannels, 1)),
+		),
+	)...)
+
+	for _, c := range clusters {
+		file = ebml.AppendSizeWithLength(ebml.AppendID(file, ebml.IDCluster), ebml.UnknownSize, 8)
+		file = append(file, c...)
+	}
+
+	return file
+}
+
+func simpleBlock(track byte, timecode int16, flags byte, data ...byte) []byte {
+	return ebml.Binary(ebml.IDSimpleBlock, append([]byte{0x80 | track, byte(uint16(timecode) >> 8), byte(timecode), flags}, data...))
+}
+
+func TestWebMReader(t *testing.T) {
+	file := buildWebM("webm",
+		append(append(append(append(
+			ebml.Uint(ebml.IDTimecode, 1000),
+			simpleBlock(1, 0, blockFlagKeyFrame, 0x01)...),
+			// Xiph lacing of 2 frames of 1 and 2 bytes
+			simpleBlock(2, 0, blockFlagKeyFrame|blockLacingXiph, 0x01, 0x01, 0xA1, 0xA2, 0xA2)...),
+			simpleBlock(2, 20, blockFlagKeyFrame, 0xA3)...),
+			simpleBlock(1, 40, 0, 0x02)...),
+		append(append(
+			ebml.Uint(ebml.IDTimecode, 1080),
+			// Block group with a reference, so not a key frame
+			ebml.Master(ebml.IDBlockGroup,
+				ebml.Binary(ebml.IDBlock, []byte{0x81, 0x00, 0x00, 0x00, 0x03}),
+				ebml.Uint(ebml.IDReferenceBlock, 1),
+			)...),
+			// EBML lacing of 3 frames of 2, 1 and 2 bytes
+			simpleBlock(2, 0, blockFlagKeyFrame|blockLacingEBML, 0x02, 0x82, 0x5F, 0xFE, 0xA4, 0xA4, 0xA5, 0xA6, 0xA6)...),
+	)
+
+	reader, tracks, err := NewWith(bytes.NewReader(file))
+	assert.NoError(t, err)
+	assert.Equal(t, []*Track{
+		{Number: 1, Type: TrackTypeVideo, CodecID: "V_VP9", MimeType: "video/VP9", Width: 320, Height: 240},
+		{Number: 2, Type: TrackTypeAudio, CodecID: "A_OPUS", MimeType: "audio/opus", SamplingFrequency: 48000, Channels: 1},
+	}, tracks)
+
+	type expectedSample struct {
+		track            uint64
+		data             []byte
+		keyFrame         bool
+		presentationTime time.Duration
+		duration         time.Duration
+	}
+	var samples []expectedSample
+	for {
+		sample, err := reader.NextSample()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		samples = append(samples, expectedSample{
+			sample.TrackNumber, sample.Data, sample.IsKeyFrame, sample.PresentationTime, sample.Duration,
+		})
+	}
+
+	ms := time.Millisecond
+	assert.Equal(t, []expectedSample{
+		{2, []byte{0xA1}, true, 1000 * ms, 0},
+		{2, []byte{0xA2, 0xA2}, true, 1000 * ms, 20 * ms},
+		{1, []byte{0x01}, true, 1000 * ms, 40 * ms},
+		{1, []byte{0x02}, false, 1040 * ms, 40 * ms},
+		{2, []byte{0xA3}, true, 1020 * ms, 60 * ms},
+		{2, []byte{0xA4, 0xA4}, true, 1080 * ms, 0},
+		{2, []byte{0xA5}, true, 1080 * ms, 0},
+		{1, []byte{0x03}, false, 1080 * ms, 40 * ms},
+		{2, []byte{0xA6, 0xA6}, true, 1080 * ms, 0},
+	}, samples)
+}
+
+func TestWebMReader_Errors(t *testing.T) {
+	_, _, err := NewWith(nil)
+	assert.Equal(t, errNilStream, err)
+
+	_, _, err = NewWith(bytes.NewReader(ebml.Master(ebml.IDEBML, ebml.String(ebml.IDDocType, "mkv3d"))))
+	assert.Equal(t, errNotWebM, err)
+
+	_, _, err = NewWith(bytes.NewReader([]byte("OggS\x00\x02")))
+	assert.Equal(t, errNotWebM, err)
+
+	file := ebml.Master(ebml.IDEBML, ebml.String(ebml.IDDocType, "matroska"))
+	file = append(file, ebml.Master(ebml.IDSegment, ebml.Master(ebml.IDCluster))...)
+	_, _, err = NewWith(bytes.NewReader(file))
+	assert.Equal(t, errNoTracks, err)
+
+	reader, _, err := NewWith(bytes.NewReader(buildWebM("webm", simpleBlock(1, 0, 0, 0x01))))
+	assert.NoError(t, err)
+	_, err = reader.NextSample()
+	assert.Equal(t, errNoTimecodeInBlock, err)
+
+	reader, _, err = NewWith(bytes.NewReader(buildWebM("webm",
+		append(ebml.Uint(ebml.IDTimecode, 0), simpleBlock(1, 0, blockLacingXiph, 0x01, 0x05, 0x01)...),
+	)))
+	assert.NoError(t, err)
+	_, err = reader.NextSample()
+	assert.Equal(t, errInvalidLacing, err)
+}
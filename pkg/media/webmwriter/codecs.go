@@ -0,0 +1,76 @@
+package webmwriter
+
+import (
+	"encoding/binary"
+)
+
+// vp8KeyFrame parses the frame header of a VP8 key frame
+// https://tools.ietf.org/html/rfc6386#section-9.1
+func vp8KeyFrame(frame []byte) (isKeyFrame bool, width, height uint16) {
+	if len(frame) < 10 || frame[0]&0x01 != 0 {
+		return false, 0, 0
+	}
+	if frame[3] != 0x9D || frame[4] != 0x01 || frame[5] != 0x2A {
+		return false, 0, 0
+	}
+
+	return true, binary.LittleEndian.Uint16(frame[6:]) & 0x3FFF, binary.LittleEndian.Uint16(frame[8:]) & 0x3FFF
+}
+
+// vp9KeyFrame parses the uncompressed header of a VP9 key frame
+// https://storage.googleapis.com/downloads.webmproject.org/docs/vp9/vp9-bitstream-specification-v0.6-20160331-draft.pdf 6.2
+func vp9KeyFrame(frame []byte) (isKeyFrame bool, width, height uint16) {
+	const (
+		syncCode      = 0x498342
+		colorSpaceRGB = 7
+	)
+
+	pos := 0
+	readBits := func(n int) (v uint32) {
+		for i := 0; i < n; i++ {
+			if pos >= len(frame)*8 {
+				isKeyFrame = false
+				return 0
+			}
+			v = v<<1 | uint32(frame[pos/8]>>(7-uint(pos%8))&1)
+			pos++
+		}
+		return v
+	}
+
+	isKeyFrame = true
+	if readBits(2) != 2 { // frame_marker
+		return false, 0, 0
+	}
+	profile := readBits(1) | readBits(1)<<1
+	if profile == 3 {
+		readBits(1) // reserved_zero
+	}
+	// show_existing_frame, frame_type
+	if readBits(1) != 0 || readBits(1) != 0 {
+		return false, 0, 0
+	}
+	readBits(2) // show_frame, error_resilient_mode
+	if readBits(24) != syncCode {
+		return false, 0, 0
+	}
+
+	if profile >= 2 {
+		readBits(1) // ten_or_twelve_bit
+	}
+	if readBits(3) != colorSpaceRGB {
+		readBits(1) // color_range
+		if profile == 1 || profile == 3 {
+			readBits(3) // subsampling_x, subsampling_y, reserved_zero
+		}
+	} else if profile == 1 || profile == 3 {
+		readBits(1) // reserved_zero
+	}
+
+	width, height = uint16(readBits(16)+1), uint16(readBits(16)+1)
+	if !isKeyFrame {
+		return false, 0, 0
+	}
+
+	return true, width, height
+}
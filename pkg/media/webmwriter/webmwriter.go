@@ -0,0 +1,546 @@
+// Package webmwriter implements a WebM media container writer
+package webmwriter
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v3/internal/ebml"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/media/samplebuilder"
+)
+
+var (
+	errFileNotOpened    = errors.New("file not opened")
+	errInvalidNilPacket = errors.New("invalid nil packet")
+	errCodecAlreadySet  = errors.New("codec is already set")
+	errNoSuchCodec      = errors.New("no codec for this MimeType")
+	errNoTracks         = errors.New("no audio or video codec configured")
+	errNoVideoTrack     = errors.New("no video codec configured")
+	errNoAudioTrack     = errors.New("no audio codec configured")
+	errAmbiguousTrack   = errors.New("WriteRTP requires a single track, use WriteVideoRTP or WriteAudioRTP")
+	errBlockTooLate     = errors.New("sample is too late for the current cluster")
+)
+
+const (
+	mimeTypeVP8  = "video/VP8"
+	mimeTypeVP9  = "video/VP9"
+	mimeTypeOpus = "audio/opus"
+
+	videoClockRate = 90000
+	opusClockRate  = 48000
+	opusChannels   = 2
+
+	videoMaxLate = 512
+	audioMaxLate = 50
+
+	trackTypeVideo = 1
+	trackTypeAudio = 2
+
+	// Timecodes are in milliseconds
+	timecodeScale = 1000000
+
+	// Block timecodes are signed 16 bits values relative to their cluster
+	maxClusterDuration        = 32767
+	maxBlockLate              = 32768
+	audioOnlyClusterTimecodes = 5000
+
+	simpleBlockFlagKeyFrame = 0x80
+
+	// Reserved at the start of the segment for a SeekHead, written on Close
+	seekHeadReservedSize = 80
+	// Reserved in the Info element for the Duration, written on Close
+	durationSize = 11
+	// Length of the Segment size field, rewritten on Close
+	segmentSizeLength = 8
+)
+
+type block struct {
+	track    uint64
+	timecode uint64
+	keyFrame bool
+	data     []byte
+}
+
+type cluster struct {
+	timecode uint64
+	blocks   []block
+}
+
+type cuePoint struct {
+	time     uint64
+	track    uint64
+	position uint64
+}
+
+type track struct {
+	number    uint64
+	isVideo   bool
+	codecID   string
+	clockRate uint32
+	builder   *samplebuilder.SampleBuilder
+
+	// keyFrame returns if a frame is a key frame, and the size of the video
+	keyFrame      func(frame []byte) (isKeyFrame bool, width, height uint16)
+	width, height uint16
+
+	started       bool
+	lastTimestamp uint32
+	ticks         uint64
+	// Timecode of the first sample of the track
+	offset uint64
+}
+
+// WebMWriter is used to take RTP packets of an audio and a video track, and
+// write them to a WebM file. The tracks are aligned on the wall clock time
+// their first sample is written at.
+type WebMWriter struct {
+	ioWriter io.Writer
+
+	videoTrack, audioTrack *track
+	tracks                 []*track
+
+	initialized bool
+	// Number of bytes written, and offsets of the fields rewritten on Close
+	position          uint64
+	segmentSizeOffset uint64
+	segmentDataOffset uint64
+	seekHeadOffset    uint64
+	durationOffset    uint64
+	infoPosition      uint64
+	tracksPosition    uint64
+
+	cluster  *cluster
+	cues     []cuePoint
+	duration uint64
+
+	// Wall clock time when the first track started
+	start time.Time
+	now   func() time.Time
+}
+
+// New builds a new WebM writer
+func New(fileName string, opts ...Option) (*WebMWriter, error) {
+	f, err := os.Create(fileName) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+	writer, err := NewWith(f, opts...)
+	if err != nil {
+		return nil, err
+	}
+	writer.ioWriter = f
+	return writer, nil
+}
+
+// NewWith initialize a new WebM writer with an io.Writer output
+func NewWith(out io.Writer, opts ...Option) (*WebMWriter, error) {
+	if out == nil {
+		return nil, errFileNotOpened
+	}
+
+	writer := &WebMWriter{
+		ioWriter: out,
+		now:      time.Now,
+	}
+
+	for _, o := range opts {
+		if err := o(writer); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, t := range []*track{writer.videoTrack, writer.audioTrack} {
+		if t != nil {
+			t.number = uint64(len(writer.tracks) + 1)
+			writer.tracks = append(writer.tracks, t)
+		}
+	}
+	if len(writer.tracks) == 0 {
+		return nil, errNoTracks
+	}
+
+	return writer, nil
+}
+
+// WriteRTP adds a new packet to the only track of the writer
+func (w *WebMWriter) WriteRTP(packet *rtp.Packet) error {
+	if len(w.tracks) != 1 {
+		return errAmbiguousTrack
+	}
+
+	return w.writeRTP(w.tracks[0], packet)
+}
+
+// WriteVideoRTP adds a new packet to the video track
+func (w *WebMWriter) WriteVideoRTP(packet *rtp.Packet) error {
+	if w.videoTrack == nil {
+		return errNoVideoTrack
+	}
+
+	return w.writeRTP(w.videoTrack, packet)
+}
+
+// WriteAudioRTP adds a new packet to the audio track
+func (w *WebMWriter) WriteAudioRTP(packet *rtp.Packet) error {
+	if w.audioTrack == nil {
+		return errNoAudioTrack
+	}
+
+	return w.writeRTP(w.audioTrack, packet)
+}
+
+func (w *WebMWriter) writeRTP(t *track, packet *rtp.Packet) error {
+	if w.ioWriter == nil {
+		return errFileNotOpened
+	} else if packet == nil {
+		return errInvalidNilPacket
+	}
+
+	t.builder.Push(packet)
+	for s := t.builder.Pop(); s != nil; s = t.builder.Pop() {
+		if err := w.writeSample(t, s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *WebMWriter) writeSample(t *track, s *media.Sample) error {
+	if len(s.Data) == 0 {
+		return nil
+	}
+
+	keyFrame, width, height := t.keyFrame(s.Data)
+	if keyFrame && t.isVideo {
+		t.width, t.height = width, height
+	}
+
+	if !w.initialized {
+		// Everything is dropped until the video starts with a key frame, which
+		// carries the size of the video
+		if w.videoTrack != nil && (t != w.videoTrack || !keyFrame) {
+			return nil
+		}
+		if err := w.writeHeader(); err != nil {
+			return err
+		}
+	}
+
+	// RTP timestamps are unwrapped. The first track starts at 0, and a track
+	// starting later is offset by the wall clock time elapsed since then
+	if !t.started {
+		t.started, t.lastTimestamp = true, s.PacketTimestamp
+
+		now := w.now()
+		if w.start.IsZero() {
+			w.start = now
+		}
+		t.offset = uint64(now.Sub(w.start) / time.Millisecond)
+	}
+	t.ticks += uint64(s.PacketTimestamp - t.lastTimestamp)
+	t.lastTimestamp = s.PacketTimestamp
+	timecode := t.offset + t.ticks*1000/uint64(t.clockRate)
+
+	if w.cluster == nil || w.startsCluster(t, keyFrame, timecode) {
+		if err := w.writeCluster(); err != nil {
+			return err
+		}
+		w.cluster = &cluster{timecode: timecode}
+
+		if keyFrame && (t.isVideo || w.videoTrack == nil) {
+			w.cues = append(w.cues, cuePoint{time: timecode, track: t.number, position: w.position - w.segmentDataOffset})
+		}
+	} else if timecode+maxBlockLate < w.cluster.timecode {
+		// Its timecode relative to the cluster wouldn't fit in a block
+		return errBlockTooLate
+	}
+
+	w.cluster.blocks = append(w.cluster.blocks, block{track: t.number, timecode: timecode, keyFrame: keyFrame, data: s.Data})
+	if timecode > w.duration {
+		w.duration = timecode
+	}
+
+	return nil
+}
+
+// startsCluster is true when a video key frame starts a new cluster, or when
+// the cluster is too long
+func (w *WebMWriter) startsCluster(t *track, keyFrame bool, timecode uint64) bool {
+	switch {
+	case timecode < w.cluster.timecode:
+		return false
+	case timecode-w.cluster.timecode > maxClusterDuration:
+		return true
+	case w.videoTrack == nil:
+		return timecode-w.cluster.timecode >= audioOnlyClusterTimecodes
+	default:
+		return t.isVideo && keyFrame
+	}
+}
+
+func (w *WebMWriter) write(data []byte) error {
+	if _, err := w.ioWriter.Write(data); err != nil {
+		return err
+	}
+	w.position += uint64(len(data))
+
+	return nil
+}
+
+func (w *WebMWriter) writeHeader() error {
+	header := ebml.Master(ebml.IDEBML,
+		ebml.Uint(ebml.IDEBMLVersion, 1),
+		ebml.Uint(ebml.IDEBMLReadVersion, 1),
+		ebml.Uint(ebml.IDEBMLMaxIDLength, 4),
+		ebml.Uint(ebml.IDEBMLMaxSizeLength, 8),
+		ebml.String(ebml.IDDocType, "webm"),
+		ebml.Uint(ebml.IDDocTypeVersion, 4),
+		ebml.Uint(ebml.IDDocTypeReadVersion, 2),
+	)
+
+	// The Segment is written with an unknown size, rewritten on Close if the
+	// writer is seekable
+	header = ebml.AppendID(header, ebml.IDSegment)
+	w.segmentSizeOffset = uint64(len(header))
+	header = ebml.AppendSizeWithLength(header, ebml.UnknownSize, segmentSizeLength)
+	w.segmentDataOffset = uint64(len(header))
+
+	w.seekHeadOffset = uint64(len(header))
+	header = append(header, ebml.Void(seekHeadReservedSize)...)
+
+	infoContent := ebml.Uint(ebml.IDTimecodeScale, timecodeScale)
+	durationOffset := len(infoContent)
+	infoContent = append(infoContent, ebml.Void(durationSize)...)
+	infoContent = append(infoContent, ebml.String(ebml.IDMuxingApp, "pion")...)
+	infoContent = append(infoContent, ebml.String(ebml.IDWritingApp, "pion")...)
+	info := ebml.Master(ebml.IDInfo, infoContent)
+
+	w.infoPosition = uint64(len(header)) - w.segmentDataOffset
+	w.durationOffset = uint64(len(header) + len(info) - len(infoContent) + durationOffset)
+	header = append(header, info...)
+
+	entries := make([][]byte, 0, len(w.tracks))
+	for _, t := range w.tracks {
+		entries = append(entries, t.entry())
+	}
+	w.tracksPosition = uint64(len(header)) - w.segmentDataOffset
+	header = append(header, ebml.Master(ebml.IDTracks, entries...)...)
+
+	if err := w.write(header); err != nil {
+		return err
+	}
+	w.initialized = true
+
+	return nil
+}
+
+func (t *track) entry() []byte {
+	elements := [][]byte{
+		ebml.Uint(ebml.IDTrackNumber, t.number),
+		ebml.Uint(ebml.IDTrackUID, t.number),
+		ebml.String(ebml.IDCodecID, t.codecID),
+	}
+
+	if t.isVideo {
+		return ebml.Master(ebml.IDTrackEntry, append(elements,
+			ebml.Uint(ebml.IDTrackType, trackTypeVideo),
+			ebml.Master(ebml.IDVideo,
+				ebml.Uint(ebml.IDPixelWidth, uint64(t.width)),
+				ebml.Uint(ebml.IDPixelHeight, uint64(t.height)),
+			),
+		)...)
+	}
+
+	return ebml.Master(ebml.IDTrackEntry, append(elements,
+		ebml.Uint(ebml.IDTrackType, trackTypeAudio),
+		ebml.Binary(ebml.IDCodecPrivate, opusHead()),
+		ebml.Uint(ebml.IDCodecDelay, 0),
+		ebml.Uint(ebml.IDSeekPreRoll, 80000000),
+		ebml.Master(ebml.IDAudio,
+			ebml.Float(ebml.IDSamplingFrequency, opusClockRate),
+			ebml.Uint(ebml.IDChannels, opusChannels),
+		),
+	)...)
+}
+
+// opusHead returns the identification header of the Opus stream
+// https://tools.ietf.org/html/rfc7845.html#section-5.1
+func opusHead() []byte {
+	head := make([]byte, 19)
+	copy(head, "OpusHead")
+	head[8] = 1 // Version
+	head[9] = opusChannels
+	binary.LittleEndian.PutUint16(head[10:], 0) // Pre-skip
+	binary.LittleEndian.PutUint32(head[12:], opusClockRate)
+
+	return head
+}
+
+func (w *WebMWriter) writeCluster() error {
+	if w.cluster == nil {
+		return nil
+	}
+
+	elements := [][]byte{ebml.Uint(ebml.IDTimecode, w.cluster.timecode)}
+	for _, b := range w.cluster.blocks {
+		header := ebml.AppendSize(nil, b.track)
+		relative := int16(int64(b.timecode) - int64(w.cluster.timecode))
+		header = append(header, byte(uint16(relative)>>8), byte(relative), 0)
+		if b.keyFrame {
+			header[len(header)-1] = simpleBlockFlagKeyFrame
+		}
+		elements = append(elements, ebml.Master(ebml.IDSimpleBlock, header, b.data))
+	}
+	w.cluster = nil
+
+	return w.write(ebml.Master(ebml.IDCluster, elements...))
+}
+
+// Close writes the pending samples and the cues, and stops the recording
+func (w *WebMWriter) Close() error {
+	if w.ioWriter == nil {
+		// Returns no error as it may be convenient to call
+		// Close() multiple times
+		return nil
+	}
+
+	defer func() {
+		w.ioWriter = nil
+	}()
+
+	if w.initialized {
+		if err := w.finalize(); err != nil {
+			return err
+		}
+	}
+
+	if closer, ok := w.ioWriter.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+func (w *WebMWriter) finalize() error {
+	if err := w.writeCluster(); err != nil {
+		return err
+	}
+
+	cuesPosition := w.position - w.segmentDataOffset
+	cuePoints := make([][]byte, 0, len(w.cues))
+	for _, c := range w.cues {
+		cuePoints = append(cuePoints, ebml.Master(ebml.IDCuePoint,
+			ebml.Uint(ebml.IDCueTime, c.time),
+			ebml.Master(ebml.IDCueTrackPositions,
+				ebml.Uint(ebml.IDCueTrack, c.track),
+				ebml.Uint(ebml.IDCueClusterPosition, c.position),
+			),
+		))
+	}
+	if len(cuePoints) != 0 {
+		if err := w.write(ebml.Master(ebml.IDCues, cuePoints...)); err != nil {
+			return err
+		}
+	}
+
+	ws, ok := w.ioWriter.(io.WriteSeeker)
+	if !ok {
+		return nil
+	}
+
+	// Now that they are known, the segment size, the positions of the top
+	// level elements and the duration are written in the reserved space
+	seeks := [][]byte{
+		seekEntry(ebml.IDInfo, w.infoPosition),
+		seekEntry(ebml.IDTracks, w.tracksPosition),
+	}
+	if len(cuePoints) != 0 {
+		seeks = append(seeks, seekEntry(ebml.IDCues, cuesPosition))
+	}
+	seekHead := ebml.Master(ebml.IDSeekHead, seeks...)
+	seekHead = append(seekHead, ebml.Void(seekHeadReservedSize-len(seekHead))...)
+
+	for _, patch := range []struct {
+		offset uint64
+		data   []byte
+	}{
+		{w.segmentSizeOffset, ebml.AppendSizeWithLength(nil, w.position-w.segmentDataOffset, segmentSizeLength)},
+		{w.seekHeadOffset, seekHead},
+		{w.durationOffset, ebml.Float(ebml.IDDuration, float64(w.duration))},
+	} {
+		if _, err := ws.Seek(int64(patch.offset), io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := ws.Write(patch.data); err != nil {
+			return err
+		}
+	}
+
+	_, err := ws.Seek(0, io.SeekEnd)
+	return err
+}
+
+// seekEntry returns a Seek element, with a fixed size position
+func seekEntry(id uint32, position uint64) []byte {
+	return ebml.Master(ebml.IDSeek,
+		ebml.Binary(ebml.IDSeekID, ebml.AppendID(nil, id)),
+		ebml.UintWithLength(ebml.IDSeekPosition, position, 8),
+	)
+}
+
+// An Option configures a WebMWriter.
+type Option func(w *WebMWriter) error
+
+// WithVideoCodec adds a VP8 or VP9 video track to the WebMWriter
+func WithVideoCodec(mimeType string) Option {
+	return func(w *WebMWriter) error {
+		if w.videoTrack != nil {
+			return errCodecAlreadySet
+		}
+
+		t := &track{isVideo: true, clockRate: videoClockRate}
+		switch {
+		case strings.EqualFold(mimeType, mimeTypeVP8):
+			t.codecID, t.keyFrame = "V_VP8", vp8KeyFrame
+			t.builder = samplebuilder.New(videoMaxLate, &codecs.VP8Packet{}, videoClockRate)
+		case strings.EqualFold(mimeType, mimeTypeVP9):
+			t.codecID, t.keyFrame = "V_VP9", vp9KeyFrame
+			t.builder = samplebuilder.New(videoMaxLate, &codecs.VP9Packet{}, videoClockRate)
+		default:
+			return errNoSuchCodec
+		}
+
+		w.videoTrack = t
+		return nil
+	}
+}
+
+// WithAudioCodec adds an Opus audio track to the WebMWriter
+func WithAudioCodec(mimeType string) Option {
+	return func(w *WebMWriter) error {
+		if w.audioTrack != nil {
+			return errCodecAlreadySet
+		}
+		if !strings.EqualFold(mimeType, mimeTypeOpus) {
+			return errNoSuchCodec
+		}
+
+		w.audioTrack = &track{
+			codecID:   "A_OPUS",
+			clockRate: opusClockRate,
+			builder:   samplebuilder.New(audioMaxLate, &codecs.OpusPacket{}, opusClockRate),
+			keyFrame: func([]byte) (bool, uint16, uint16) {
+				return true, 0, 0
+			},
+		}
+		return nil
+	}
+}
@@ -0,0 +1,268 @@
+package webmwriter
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/internal/ebml"
+	"github.com/pion/webrtc/v3/pkg/media/webmreader"
+	"github.com/stretchr/testify/assert"
+)
+
+// VP8 key frame header of a 640x480 picture
+var testVP8KeyFrame = []byte{0x10, 0x02, 0x00, 0x9D, 0x01, 0x2A, 0x80, 0x02, 0xE0, 0x01, 0xAA}
+
+func TestKeyFrameParsers(t *testing.T) {
+	keyFrame, width, height := vp8KeyFrame(testVP8KeyFrame)
+	assert.True(t, keyFrame)
+	assert.Equal(t, uint16(640), width)
+	assert.Equal(t, uint16(480), height)
+
+	keyFrame, _, _ = vp8KeyFrame([]byte{0x11, 0x02, 0x00, 0x9D, 0x01, 0x2A, 0x80, 0x02, 0xE0, 0x01})
+	assert.False(t, keyFrame)
+
+	keyFrame, width, height = vp9KeyFrame([]byte{0x82, 0x49, 0x83, 0x42, 0x00, 0x27, 0xF0, 0x1D, 0xF0})
+	assert.True(t, keyFrame)
+	assert.Equal(t, uint16(640), width)
+	assert.Equal(t, uint16(480), height)
+
+	keyFrame, _, _ = vp9KeyFrame([]byte{0x86, 0x00, 0x40})
+	assert.False(t, keyFrame)
+
+	keyFrame, _, _ = vp9KeyFrame([]byte{0x82, 0x49, 0x83})
+	assert.False(t, keyFrame)
+}
+
+func TestWebMWriter_Errors(t *testing.T) {
+	_, err := NewWith(nil, WithAudioCodec(mimeTypeOpus))
+	assert.Equal(t, errFileNotOpened, err)
+
+	_, err = NewWith(&bytes.Buffer{})
+	assert.Equal(t, errNoTracks, err)
+
+	_, err = NewWith(&bytes.Buffer{}, WithVideoCodec("video/H264"))
+	assert.Equal(t, errNoSuchCodec, err)
+
+	_, err = NewWith(&bytes.Buffer{}, WithVideoCodec(mimeTypeVP8), WithVideoCodec(mimeTypeVP9))
+	assert.Equal(t, errCodecAlreadySet, err)
+
+	writer, err := NewWith(&bytes.Buffer{}, WithVideoCodec(mimeTypeVP8), WithAudioCodec(mimeTypeOpus))
+	assert.NoError(t, err)
+	assert.Equal(t, errAmbiguousTrack, writer.WriteRTP(&rtp.Packet{}))
+	assert.Equal(t, errInvalidNilPacket, writer.WriteVideoRTP(nil))
+	assert.NoError(t, writer.Close())
+	assert.Equal(t, errFileNotOpened, writer.WriteAudioRTP(&rtp.Packet{}))
+}
+
+// writeTestStream writes 2 seconds of VP8 at 10 fps with a key frame every second,
+// and of Opus with 20ms packets
+func writeTestStream(t *testing.T, writer *WebMWriter) {
+	videoSequenceNumber, audioSequenceNumber := uint16(0), uint16(0)
+	audioTimestamp := uint32(0)
+	for i := uint32(0); i <= 20; i++ {
+		payload := []byte{0x90, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+		if i%10 == 0 {
+			payload = append([]byte{0x90, 0x00}, testVP8KeyFrame...)
+		}
+		assert.NoError(t, writer.WriteVideoRTP(&rtp.Packet{
+			Header:  rtp.Header{SequenceNumber: videoSequenceNumber, Timestamp: 1000 + i*9000, Marker: true},
+			Payload: payload,
+		}))
+		videoSequenceNumber++
+
+		for j := 0; j < 5; j++ {
+			assert.NoError(t, writer.WriteAudioRTP(&rtp.Packet{
+				Header:  rtp.Header{SequenceNumber: audioSequenceNumber, Timestamp: audioTimestamp},
+				Payload: []byte{0xFC, byte(audioSequenceNumber)},
+			}))
+			audioSequenceNumber++
+			audioTimestamp += 960
+		}
+	}
+}
+
+func TestWebMWriter_RoundTrip(t *testing.T) {
+	f, err := ioutil.TempFile("", "webmwriter")
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+	defer func() {
+		assert.NoError(t, os.Remove(f.Name()))
+	}()
+
+	writer, err := New(f.Name(), WithVideoCodec(mimeTypeVP8), WithAudioCodec(mimeTypeOpus))
+	assert.NoError(t, err)
+	writeTestStream(t, writer)
+	assert.NoError(t, writer.Close())
+
+	data, err := ioutil.ReadFile(f.Name())
+	assert.NoError(t, err)
+
+	// The segment size, the SeekHead and the duration are written on Close
+	segment := data[bytes.Index(data, []byte{0x18, 0x53, 0x80, 0x67}):]
+	segmentSize, n, err := ebml.ReadVint(segment[4:])
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(len(segment)-4-n), segmentSize)
+
+	children, err := ebml.Children(segment[4+n:])
+	assert.NoError(t, err)
+	var types []uint32
+	for _, c := range children {
+		types = append(types, c.ID)
+	}
+	assert.Equal(t, []uint32{
+		ebml.IDSeekHead, ebml.IDVoid, ebml.IDInfo, ebml.IDTracks,
+		ebml.IDCluster, ebml.IDCluster, ebml.IDCues,
+	}, types)
+
+	seeks, err := ebml.Children(children[0].Data)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(seeks))
+	for _, seek := range seeks {
+		entry, err := ebml.Children(seek.Data)
+		assert.NoError(t, err)
+		position := ebml.ParseUint(entry[1].Data)
+		assert.Equal(t, entry[0].Data, segment[4+uint64(n)+position:][:4])
+	}
+
+	info, err := ebml.Children(children[2].Data)
+	assert.NoError(t, err)
+	assert.Equal(t, ebml.IDDuration, info[1].ID)
+	duration, err := ebml.ParseFloat(info[1].Data)
+	assert.NoError(t, err)
+	assert.Equal(t, 1980.0, duration)
+
+	cues, err := ebml.Children(children[6].Data)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(cues))
+
+	reader, tracks, err := webmreader.NewWith(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(tracks))
+	assert.Equal(t, "video/VP8", tracks[0].MimeType)
+	assert.Equal(t, uint64(640), tracks[0].Width)
+	assert.Equal(t, uint64(480), tracks[0].Height)
+	assert.Equal(t, "audio/opus", tracks[1].MimeType)
+	assert.Equal(t, 48000.0, tracks[1].SamplingFrequency)
+	assert.Equal(t, uint64(2), tracks[1].Channels)
+	assert.Equal(t, []byte("OpusHead"), tracks[1].CodecPrivate[:8])
+
+	videoSamples, audioSamples, keyFrames := 0, 0, 0
+	for {
+		sample, err := reader.NextSample()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+
+		switch sample.TrackNumber {
+		case tracks[0].Number:
+			assert.Equal(t, time.Duration(videoSamples)*100*time.Millisecond, sample.PresentationTime)
+			assert.Equal(t, 100*time.Millisecond, sample.Duration)
+			if sample.IsKeyFrame {
+				keyFrames++
+			}
+			videoSamples++
+		case tracks[1].Number:
+			assert.Equal(t, 20*time.Millisecond, sample.Duration)
+			audioSamples++
+		}
+	}
+
+	// The last packet of each track is never popped by the sample builder, and
+	// the audio is dropped until the first video frame is popped
+	assert.Equal(t, 20, videoSamples)
+	assert.Equal(t, 2, keyFrames)
+	assert.Equal(t, 100, audioSamples)
+}
+
+func TestWebMWriter_NotSeekable(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer, err := NewWith(buffer, WithVideoCodec(mimeTypeVP8), WithAudioCodec(mimeTypeOpus))
+	assert.NoError(t, err)
+	writeTestStream(t, writer)
+	assert.NoError(t, writer.Close())
+
+	// The segment has an unknown size, and it can still be read
+	assert.True(t, bytes.Contains(buffer.Bytes(), []byte{0x18, 0x53, 0x80, 0x67, 0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}))
+
+	reader, _, err := webmreader.NewWith(buffer)
+	assert.NoError(t, err)
+	count := 0
+	for {
+		if _, err = reader.NextSample(); err != nil {
+			break
+		}
+		count++
+	}
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 120, count)
+}
+
+// Assert that a track starting after the other one is offset by the time elapsed since
+func TestWebMWriter_TrackOffset(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer, err := NewWith(buffer, WithVideoCodec(mimeTypeVP8), WithAudioCodec(mimeTypeOpus))
+	assert.NoError(t, err)
+
+	now := time.Now()
+	writer.now = func() time.Time { return now }
+
+	for i := uint32(0); i < 2; i++ {
+		assert.NoError(t, writer.WriteVideoRTP(&rtp.Packet{
+			Header:  rtp.Header{SequenceNumber: uint16(i), Timestamp: 1000 + i*9000, Marker: true},
+			Payload: append([]byte{0x90, 0x00}, testVP8KeyFrame...),
+		}))
+	}
+
+	now = now.Add(2 * time.Second)
+	for i := uint32(0); i < 2; i++ {
+		assert.NoError(t, writer.WriteAudioRTP(&rtp.Packet{
+			Header:  rtp.Header{SequenceNumber: uint16(i), Timestamp: 5000 + i*960},
+			Payload: []byte{0xFC, byte(i)},
+		}))
+	}
+	assert.NoError(t, writer.Close())
+
+	reader, tracks, err := webmreader.NewWith(buffer)
+	assert.NoError(t, err)
+
+	var presentationTimes []time.Duration
+	for {
+		sample, err := reader.NextSample()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		if sample.TrackNumber == tracks[1].Number {
+			presentationTimes = append(presentationTimes, sample.PresentationTime)
+		}
+	}
+	assert.Equal(t, []time.Duration{2 * time.Second}, presentationTimes)
+}
+
+// Assert that a sample too far behind the cluster is rejected, instead of
+// wrapping its relative timecode
+func TestWebMWriter_BlockTooLate(t *testing.T) {
+	writer, err := NewWith(&bytes.Buffer{}, WithVideoCodec(mimeTypeVP8), WithAudioCodec(mimeTypeOpus))
+	assert.NoError(t, err)
+
+	now := time.Now()
+	writer.now = func() time.Time { return now }
+
+	// 40 seconds of video are written at once, the audio starts with the same wall clock time
+	for i := uint32(0); i <= 41; i++ {
+		assert.NoError(t, writer.WriteVideoRTP(&rtp.Packet{
+			Header:  rtp.Header{SequenceNumber: uint16(i), Timestamp: i * 90000, Marker: true},
+			Payload: append([]byte{0x90, 0x00}, testVP8KeyFrame...),
+		}))
+	}
+
+	assert.NoError(t, writer.WriteAudioRTP(&rtp.Packet{Header: rtp.Header{SequenceNumber: 0}, Payload: []byte{0xFC, 0x00}}))
+	assert.ErrorIs(t, writer.WriteAudioRTP(&rtp.Packet{Header: rtp.Header{SequenceNumber: 1, Timestamp: 960}, Payload: []byte{0xFC, 0x01}}), errBlockTooLate)
+	assert.NoError(t, writer.Close())
+}
@@ -0,0 +1,495 @@
+package nack
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/logging"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/internal/ntp"
+)
+
+// rtxOSNLen is the size of the original sequence number at the start of RTX payloads
+const rtxOSNLen = 2
+
+// rrtrInterval is how often a Receiver Reference Time report is sent, for the remote peer to
+// answer it with a DLRR report from which the round trip time is measured
+const rrtrInterval = time.Second
+
+var errInvalidOption = errors.New("nack: interval, size and max age must be positive")
+
+// NewPeerConnectionCallback is called with the GeneratorInterceptor built for each PeerConnection
+type NewPeerConnectionCallback func(id string, g *GeneratorInterceptor)
+
+// GeneratorInterceptorFactory is a interceptor.Factory for a GeneratorInterceptor
+type GeneratorInterceptorFactory struct {
+	opts              []GeneratorOption
+	addPeerConnection NewPeerConnectionCallback
+}
+
+// NewGeneratorInterceptor constructs a new GeneratorInterceptorFactory
+func NewGeneratorInterceptor(opts ...GeneratorOption) (*GeneratorInterceptorFactory, error) {
+	return &GeneratorInterceptorFactory{opts: opts}, nil
+}
+
+// OnNewPeerConnection sets a callback that is called when a new GeneratorInterceptor is created
+func (f *GeneratorInterceptorFactory) OnNewPeerConnection(cb NewPeerConnectionCallback) {
+	f.addPeerConnection = cb
+}
+
+// NewInterceptor constructs a new GeneratorInterceptor
+func (f *GeneratorInterceptorFactory) NewInterceptor(id string) (interceptor.Interceptor, error) {
+	g := &GeneratorInterceptor{
+		interval:   100 * time.Millisecond,
+		size:       512,
+		maxAge:     time.Second,
+		maxRetries: 10,
+		senderSSRC: rand.Uint32(), // #nosec
+		rtt:        100 * time.Millisecond,
+		log:        logging.NewDefaultLoggerFactory().NewLogger("nack_generator"),
+		streams:    map[uint32]*stream{},
+		rtxStreams: map[uint32]uint32{},
+		rrtrs:      map[uint32]rrtr{},
+		close:      make(chan struct{}),
+	}
+
+	for _, opt := range f.opts {
+		if err := opt(g); err != nil {
+			return nil, err
+		}
+	}
+
+	if g.interval <= 0 || g.size == 0 || g.maxAge <= 0 {
+		return nil, errInvalidOption
+	}
+
+	if f.addPeerConnection != nil {
+		f.addPeerConnection(id, g)
+	}
+
+	return g, nil
+}
+
+// GeneratorInterceptor sends NACKs for the missing packets of remote streams. A packet is
+// requested again when it is still missing a round trip time after its previous request,
+// until it is received or given up on.
+//
+// The round trip time is measured from the reception reports the remote peer sends about the
+// local streams. As those only exist if media is sent, the generator also sends XR Receiver
+// Reference Time reports while it receives media, and measures the round trip time from the
+// XR DLRR reports the remote peer answers them with. It answers the Receiver Reference Time
+// reports of the remote peer the same way.
+type GeneratorInterceptor struct {
+	interceptor.NoOp
+	interval   time.Duration
+	size       uint16
+	maxAge     time.Duration
+	maxRetries uint
+	senderSSRC uint32
+	log        logging.LeveledLogger
+
+	mu         sync.Mutex
+	rtt        time.Duration
+	hasRTT     bool
+	streams    map[uint32]*stream
+	rtxStreams map[uint32]uint32
+	rrtrs      map[uint32]rrtr
+
+	wg    sync.WaitGroup
+	close chan struct{}
+}
+
+type missingPacket struct {
+	sequenceNumber uint16
+	missingSince   time.Time
+	lastNACK       time.Time
+	retries        uint
+}
+
+// rrtr is the last Receiver Reference Time report received from a remote SSRC, to be
+// answered with a DLRR report
+type rrtr struct {
+	lastRR     uint32
+	receivedAt time.Time
+}
+
+// stream tracks the missing packets of a remote stream, in sequence number order
+type stream struct {
+	started bool
+	highest uint16
+	missing []missingPacket
+	stats   Stats
+}
+
+// Stats returns the counters of the remote stream with the given SSRC
+func (g *GeneratorInterceptor) Stats(ssrc uint32) (Stats, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	s, ok := g.streams[ssrc]
+	if !ok {
+		return Stats{}, false
+	}
+
+	return s.stats, true
+}
+
+// SenderSSRC returns the SSRC the generator sends its RTCP packets from. The DLRR reports
+// answering its Receiver Reference Time reports are addressed to it.
+func (g *GeneratorInterceptor) SenderSSRC() uint32 {
+	return g.senderSSRC
+}
+
+// RTT returns the round trip time used to schedule the retries
+func (g *GeneratorInterceptor) RTT() time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.rtt
+}
+
+// BindRTCPReader lets you modify any incoming RTCP packets. It is called once per sender/receiver, however this might
+// change in the future. The returned method will be called once per packet batch.
+func (g *GeneratorInterceptor) BindRTCPReader(reader interceptor.RTCPReader) interceptor.RTCPReader {
+	return interceptor.RTCPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		n, attr, err := reader.Read(b, a)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if attr == nil {
+			attr = make(interceptor.Attributes)
+		}
+		pkts, err := attr.GetRTCPPackets(b[:n])
+		if err != nil {
+			return 0, nil, err
+		}
+
+		now := time.Now()
+		for _, pkt := range pkts {
+			switch pkt := pkt.(type) {
+			case *rtcp.ReceiverReport:
+				g.onReceptionReports(now, pkt.Reports)
+			case *rtcp.SenderReport:
+				g.onReceptionReports(now, pkt.Reports)
+			case *rtcp.ExtendedReport:
+				g.onExtendedReport(now, pkt)
+			}
+		}
+
+		return n, attr, nil
+	})
+}
+
+func (g *GeneratorInterceptor) onReceptionReports(now time.Time, reports []rtcp.ReceptionReport) {
+	for _, report := range reports {
+		if rtt, ok := roundTripTime(now, report.LastSenderReport, report.Delay); ok {
+			g.updateRTT(rtt)
+		}
+	}
+}
+
+func (g *GeneratorInterceptor) onExtendedReport(now time.Time, xr *rtcp.ExtendedReport) {
+	for _, block := range xr.Reports {
+		switch block := block.(type) {
+		case *rtcp.ReceiverReferenceTimeReportBlock:
+			g.mu.Lock()
+			g.rrtrs[xr.SenderSSRC] = rrtr{lastRR: uint32(block.NTPTimestamp >> 16), receivedAt: now}
+			g.mu.Unlock()
+		case *rtcp.DLRRReportBlock:
+			for _, report := range block.Reports {
+				if report.SSRC != g.senderSSRC {
+					continue
+				}
+				if rtt, ok := roundTripTime(now, report.LastRR, report.DLRR); ok {
+					g.updateRTT(rtt)
+				}
+			}
+		}
+	}
+}
+
+// updateRTT smooths the round trip time measurements like TCP does
+func (g *GeneratorInterceptor) updateRTT(rtt time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.hasRTT {
+		g.rtt, g.hasRTT = rtt, true
+		return
+	}
+	g.rtt = (7*g.rtt + rtt) / 8
+}
+
+// BindRTCPWriter lets you modify any outgoing RTCP packets. It is called once per PeerConnection. The returned method
+// will be called once per packet batch.
+func (g *GeneratorInterceptor) BindRTCPWriter(writer interceptor.RTCPWriter) interceptor.RTCPWriter {
+	if g.isClosed() {
+		return writer
+	}
+
+	g.wg.Add(1)
+	go g.loop(writer)
+
+	return writer
+}
+
+// BindRemoteStream lets you modify any incoming RTP packets. It is called once for per RemoteStream. The returned method
+// will be called once per rtp packet.
+func (g *GeneratorInterceptor) BindRemoteStream(info *interceptor.StreamInfo, reader interceptor.RTPReader) interceptor.RTPReader {
+	if !streamSupportsNack(info) {
+		return reader
+	}
+
+	rtxStream, isRTX := getRTXStream(info)
+
+	g.mu.Lock()
+	if isRTX {
+		g.rtxStreams[info.SSRC] = rtxStream.ProtectedSSRC
+	} else {
+		g.streams[info.SSRC] = &stream{}
+	}
+	g.mu.Unlock()
+
+	return interceptor.RTPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		n, attr, err := reader.Read(b, a)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if attr == nil {
+			attr = make(interceptor.Attributes)
+		}
+		header, err := attr.GetRTPHeader(b[:n])
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if !isRTX {
+			g.onPacket(info.SSRC, header.SequenceNumber, time.Now())
+			return n, attr, nil
+		}
+
+		packet := &rtp.Packet{}
+		if err = packet.Unmarshal(b[:n]); err == nil && len(packet.Payload) >= rtxOSNLen {
+			osn := uint16(packet.Payload[0])<<8 | uint16(packet.Payload[1])
+			g.onPacket(rtxStream.ProtectedSSRC, osn, time.Now())
+		}
+
+		return n, attr, nil
+	})
+}
+
+// UnbindRemoteStream is called when the Stream is removed. It can be used to clean up any data related to that track.
+func (g *GeneratorInterceptor) UnbindRemoteStream(info *interceptor.StreamInfo) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.streams, info.SSRC)
+	delete(g.rtxStreams, info.SSRC)
+}
+
+// Close closes the interceptor
+func (g *GeneratorInterceptor) Close() error {
+	defer g.wg.Wait()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.isClosed() {
+		close(g.close)
+	}
+
+	return nil
+}
+
+func (g *GeneratorInterceptor) isClosed() bool {
+	select {
+	case <-g.close:
+		return true
+	default:
+		return false
+	}
+}
+
+func (g *GeneratorInterceptor) onPacket(ssrc uint32, sequenceNumber uint16, now time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	s, ok := g.streams[ssrc]
+	if !ok {
+		return
+	}
+
+	if !s.started {
+		s.started, s.highest = true, sequenceNumber
+		return
+	}
+
+	diff := sequenceNumber - s.highest
+	switch {
+	case diff == 0:
+	case diff < 0x8000:
+		missing := diff - 1
+		if missing > g.size {
+			// Packets too old to be tracked are lost
+			s.stats.Unrecovered += uint64(missing - g.size)
+			missing = g.size
+		}
+		for i := missing; i > 0; i-- {
+			s.missing = append(s.missing, missingPacket{sequenceNumber: sequenceNumber - i, missingSince: now})
+		}
+		s.highest = sequenceNumber
+	default:
+		for i := range s.missing {
+			if s.missing[i].sequenceNumber != sequenceNumber {
+				continue
+			}
+			if s.missing[i].retries > 0 {
+				s.stats.Recovered++
+			}
+			s.missing = append(s.missing[:i], s.missing[i+1:]...)
+			break
+		}
+	}
+
+	if overflow := len(s.missing) - int(g.size); overflow > 0 {
+		s.stats.Unrecovered += uint64(overflow)
+		s.missing = append(s.missing[:0], s.missing[overflow:]...)
+	}
+}
+
+// buildNacks returns the NACKs of the missing packets that should be requested at now, and
+// gives up on the packets that are too old or were requested too many times
+func (g *GeneratorInterceptor) buildNacks(senderSSRC uint32, now time.Time) []rtcp.Packet {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	retryInterval := g.rtt + g.rtt/4
+	if retryInterval < g.interval {
+		retryInterval = g.interval
+	}
+
+	var pkts []rtcp.Packet
+	for ssrc, s := range g.streams {
+		var sequenceNumbers []uint16
+		missing := s.missing[:0]
+		for _, p := range s.missing {
+			sinceNACK := now.Sub(p.lastNACK)
+			switch {
+			case now.Sub(p.missingSince) > g.maxAge,
+				p.retries >= g.maxRetries && sinceNACK >= retryInterval:
+				s.stats.Unrecovered++
+				continue
+			case p.retries < g.maxRetries && (p.retries == 0 || sinceNACK >= retryInterval):
+				if p.retries == 0 {
+					s.stats.NACKed++
+				}
+				p.retries++
+				p.lastNACK = now
+				sequenceNumbers = append(sequenceNumbers, p.sequenceNumber)
+			}
+			missing = append(missing, p)
+		}
+		s.missing = missing
+
+		if len(sequenceNumbers) != 0 {
+			pkts = append(pkts, &rtcp.TransportLayerNack{
+				SenderSSRC: senderSSRC,
+				MediaSSRC:  ssrc,
+				Nacks:      rtcp.NackPairsFromSequenceNumbers(sequenceNumbers),
+			})
+		}
+	}
+
+	return pkts
+}
+
+// buildRRTR returns the Receiver Reference Time report sent at now, nil if no remote stream
+// is received
+func (g *GeneratorInterceptor) buildRRTR(now time.Time) rtcp.Packet {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.streams) == 0 {
+		return nil
+	}
+
+	// The remote streams are listed in a DLRR block with a zero LRR, meaning that no RRTR
+	// was received from them, so that peers demuxing RTCP by SSRC route the report to them
+	dlrr := &rtcp.DLRRReportBlock{}
+	for ssrc := range g.streams {
+		dlrr.Reports = append(dlrr.Reports, rtcp.DLRRReport{SSRC: ssrc})
+	}
+
+	return &rtcp.ExtendedReport{
+		SenderSSRC: g.senderSSRC,
+		Reports: []rtcp.ReportBlock{
+			&rtcp.ReceiverReferenceTimeReportBlock{NTPTimestamp: ntp.FromTime(now)},
+			dlrr,
+		},
+	}
+}
+
+// buildDLRR returns the DLRR report answering the Receiver Reference Time reports received
+// since the previous one, nil if there are none
+func (g *GeneratorInterceptor) buildDLRR(now time.Time) rtcp.Packet {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.rrtrs) == 0 {
+		return nil
+	}
+
+	dlrr := &rtcp.DLRRReportBlock{}
+	for ssrc, r := range g.rrtrs {
+		dlrr.Reports = append(dlrr.Reports, rtcp.DLRRReport{
+			SSRC:   ssrc,
+			LastRR: r.lastRR,
+			// 1/65536 seconds
+			DLRR: uint32(uint64(now.Sub(r.receivedAt)) << 16 / uint64(time.Second)),
+		})
+		delete(g.rrtrs, ssrc)
+	}
+
+	return &rtcp.ExtendedReport{SenderSSRC: g.senderSSRC, Reports: []rtcp.ReportBlock{dlrr}}
+}
+
+func (g *GeneratorInterceptor) loop(writer interceptor.RTCPWriter) {
+	defer g.wg.Done()
+
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+
+	rrtrTicker := time.NewTicker(rrtrInterval)
+	defer rrtrTicker.Stop()
+
+	for {
+		var pkts []rtcp.Packet
+		select {
+		case <-g.close:
+			return
+		case now := <-rrtrTicker.C:
+			if pkt := g.buildRRTR(now); pkt != nil {
+				pkts = append(pkts, pkt)
+			}
+		case now := <-ticker.C:
+			pkts = g.buildNacks(g.senderSSRC, now)
+			if pkt := g.buildDLRR(now); pkt != nil {
+				pkts = append(pkts, pkt)
+			}
+		}
+
+		if len(pkts) == 0 {
+			continue
+		}
+
+		if _, err := writer.Write(pkts, interceptor.Attributes{}); err != nil {
+			g.log.Warnf("failed sending nack: %v", err)
+		}
+	}
+}
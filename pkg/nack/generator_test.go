@@ -0,0 +1,184 @@
+package nack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/internal/ntp"
+	"github.com/stretchr/testify/assert"
+)
+
+var nackFeedback = []interceptor.RTCPFeedback{{Type: "nack"}}
+
+func newGenerator(t *testing.T, opts ...GeneratorOption) *GeneratorInterceptor {
+	f, err := NewGeneratorInterceptor(opts...)
+	assert.NoError(t, err)
+
+	i, err := f.NewInterceptor("")
+	assert.NoError(t, err)
+
+	return i.(*GeneratorInterceptor) //nolint:forcetypeassert
+}
+
+func nackedSequenceNumbers(pkts []rtcp.Packet) []uint16 {
+	var sequenceNumbers []uint16
+	for _, pkt := range pkts {
+		for _, pair := range pkt.(*rtcp.TransportLayerNack).Nacks { //nolint:forcetypeassert
+			sequenceNumbers = append(sequenceNumbers, pair.PacketList()...)
+		}
+	}
+
+	return sequenceNumbers
+}
+
+func TestGeneratorRetries(t *testing.T) {
+	g := newGenerator(t, GeneratorMaxRetries(2))
+	g.BindRemoteStream(&interceptor.StreamInfo{SSRC: 1, RTCPFeedback: nackFeedback}, nil)
+
+	start := time.Now()
+	g.onPacket(1, 10, start)
+	g.onPacket(1, 13, start)
+
+	assert.Equal(t, []uint16{11, 12}, nackedSequenceNumbers(g.buildNacks(0, start)))
+	// Retries wait for a round trip time
+	assert.Empty(t, g.buildNacks(0, start.Add(50*time.Millisecond)))
+
+	g.onPacket(1, 11, start.Add(60*time.Millisecond))
+	assert.Equal(t, []uint16{12}, nackedSequenceNumbers(g.buildNacks(0, start.Add(130*time.Millisecond))))
+
+	// Given up on once the last retry went unanswered
+	assert.Empty(t, g.buildNacks(0, start.Add(200*time.Millisecond)))
+	assert.Empty(t, g.buildNacks(0, start.Add(300*time.Millisecond)))
+
+	stats, ok := g.Stats(1)
+	assert.True(t, ok)
+	assert.Equal(t, Stats{NACKed: 2, Recovered: 1, Unrecovered: 1}, stats)
+
+	assert.NoError(t, g.Close())
+}
+
+func TestGeneratorMaxAgeAndSize(t *testing.T) {
+	g := newGenerator(t, GeneratorSize(2), GeneratorMaxAge(100*time.Millisecond))
+	g.BindRemoteStream(&interceptor.StreamInfo{SSRC: 1, RTCPFeedback: nackFeedback}, nil)
+
+	start := time.Now()
+	g.onPacket(1, 65534, start)
+	g.onPacket(1, 3, start)
+	assert.Equal(t, []uint16{1, 2}, nackedSequenceNumbers(g.buildNacks(0, start)))
+
+	// A packet received before being NACKed isn't recovered
+	g.onPacket(1, 5, start)
+	g.onPacket(1, 4, start)
+
+	assert.Empty(t, g.buildNacks(0, start.Add(200*time.Millisecond)))
+
+	stats, ok := g.Stats(1)
+	assert.True(t, ok)
+	assert.Equal(t, Stats{NACKed: 2, Unrecovered: 4}, stats)
+
+	assert.NoError(t, g.Close())
+}
+
+func TestGeneratorRTX(t *testing.T) {
+	g := newGenerator(t)
+
+	rtxInfo := &interceptor.StreamInfo{SSRC: 2, RTCPFeedback: nackFeedback}
+	SetRTXStream(rtxInfo, RTXStream{ProtectedSSRC: 1, SSRC: 2})
+
+	g.BindRemoteStream(&interceptor.StreamInfo{SSRC: 1, RTCPFeedback: nackFeedback}, nil)
+	rtxReader := g.BindRemoteStream(rtxInfo, interceptor.RTPReaderFunc(func(b []byte, _ interceptor.Attributes) (int, interceptor.Attributes, error) {
+		raw, err := (&rtp.Packet{Header: rtp.Header{Version: 2, SSRC: 2, SequenceNumber: 100}, Payload: []byte{0x00, 0x0B, 0xAA}}).Marshal()
+		return copy(b, raw), nil, err
+	}))
+
+	start := time.Now()
+	g.onPacket(1, 10, start)
+	g.onPacket(1, 12, start)
+	assert.Equal(t, []uint16{11}, nackedSequenceNumbers(g.buildNacks(0, start)))
+
+	_, _, err := rtxReader.Read(make([]byte, 1500), nil)
+	assert.NoError(t, err)
+
+	stats, ok := g.Stats(1)
+	assert.True(t, ok)
+	assert.Equal(t, Stats{NACKed: 1, Recovered: 1}, stats)
+
+	assert.NoError(t, g.Close())
+}
+
+func TestGeneratorRTT(t *testing.T) {
+	readRTCP := func(g *GeneratorInterceptor, pkts ...rtcp.Packet) {
+		raw, err := rtcp.Marshal(pkts)
+		assert.NoError(t, err)
+
+		reader := g.BindRTCPReader(interceptor.RTCPReaderFunc(func(b []byte, _ interceptor.Attributes) (int, interceptor.Attributes, error) {
+			return copy(b, raw), nil, nil
+		}))
+		_, _, err = reader.Read(make([]byte, 1500), nil)
+		assert.NoError(t, err)
+	}
+
+	t.Run("Reception report", func(t *testing.T) {
+		g := newGenerator(t, GeneratorInitialRTT(time.Second))
+		assert.Equal(t, time.Second, g.RTT())
+
+		readRTCP(g, &rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{{
+			SSRC:             1,
+			LastSenderReport: ntp.Compact(time.Now().Add(-300 * time.Millisecond)),
+			// 1/65536 seconds
+			Delay: 65536 / 10,
+		}}})
+		assert.InDelta(t, 200*time.Millisecond, g.RTT(), float64(10*time.Millisecond))
+
+		assert.NoError(t, g.Close())
+	})
+
+	t.Run("DLRR", func(t *testing.T) {
+		g := newGenerator(t, GeneratorInitialRTT(time.Second))
+
+		readRTCP(g, &rtcp.ExtendedReport{Reports: []rtcp.ReportBlock{&rtcp.DLRRReportBlock{Reports: []rtcp.DLRRReport{{
+			SSRC:   g.SenderSSRC(),
+			LastRR: ntp.Compact(time.Now().Add(-300 * time.Millisecond)),
+			DLRR:   65536 / 10,
+		}}}}})
+		assert.InDelta(t, 200*time.Millisecond, g.RTT(), float64(10*time.Millisecond))
+
+		assert.NoError(t, g.Close())
+	})
+
+	// A peer that only receives media measures the round trip time from the DLRR reports
+	// answering its Receiver Reference Time reports
+	t.Run("Receive only", func(t *testing.T) {
+		receiver := newGenerator(t, GeneratorInitialRTT(time.Second))
+		sender := newGenerator(t)
+
+		start := time.Now()
+		assert.Nil(t, receiver.buildRRTR(start))
+
+		receiver.BindRemoteStream(&interceptor.StreamInfo{SSRC: 1, RTCPFeedback: nackFeedback}, nil)
+		rrtr := receiver.buildRRTR(start.Add(-300 * time.Millisecond))
+		assert.Equal(t, []uint32{1}, rrtr.DestinationSSRC())
+
+		readRTCP(sender, rrtr)
+		dlrr := sender.buildDLRR(time.Now().Add(100 * time.Millisecond))
+		assert.Equal(t, []uint32{receiver.SenderSSRC()}, dlrr.DestinationSSRC())
+		assert.Nil(t, sender.buildDLRR(time.Now()))
+
+		readRTCP(receiver, dlrr)
+		assert.InDelta(t, 200*time.Millisecond, receiver.RTT(), float64(10*time.Millisecond))
+
+		assert.NoError(t, receiver.Close())
+		assert.NoError(t, sender.Close())
+	})
+}
+
+func TestGeneratorInvalidOptions(t *testing.T) {
+	f, err := NewGeneratorInterceptor(GeneratorSize(0))
+	assert.NoError(t, err)
+
+	_, err = f.NewInterceptor("")
+	assert.ErrorIs(t, err, errInvalidOption)
+}
@@ -0,0 +1,75 @@
+// Package nack implements a NACK generator interceptor that schedules its retries from the
+// round trip time measured from RTCP, and counts the packets that were recovered or not
+package nack
+
+import (
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/webrtc/v3/internal/ntp"
+)
+
+// Stats counts the packets of a remote stream that were requested in NACKs
+type Stats struct {
+	// NACKed is the number of packets requested in at least one NACK
+	NACKed uint64
+	// Recovered is the number of NACKed packets that were received
+	Recovered uint64
+	// Unrecovered is the number of missing packets that were given up on
+	Unrecovered uint64
+}
+
+// RTXStream describes a RFC 4588 repair flow and the media stream it carries the
+// retransmissions of. It must be set on the interceptor.StreamInfo of the repair flow so
+// the retransmissions are counted as recovered packets of the media stream.
+type RTXStream struct {
+	// ProtectedSSRC is the SSRC of the media stream
+	ProtectedSSRC uint32
+	// SSRC is the SSRC of the repair flow
+	SSRC uint32
+}
+
+type rtxStreamAttributeKey struct{}
+
+// SetRTXStream stores a RTXStream in the Attributes of a StreamInfo
+func SetRTXStream(info *interceptor.StreamInfo, stream RTXStream) {
+	if info.Attributes == nil {
+		info.Attributes = interceptor.Attributes{}
+	}
+
+	info.Attributes.Set(rtxStreamAttributeKey{}, stream)
+}
+
+func getRTXStream(info *interceptor.StreamInfo) (RTXStream, bool) {
+	if info == nil || info.Attributes == nil {
+		return RTXStream{}, false
+	}
+
+	stream, ok := info.Attributes.Get(rtxStreamAttributeKey{}).(RTXStream)
+	return stream, ok
+}
+
+func streamSupportsNack(info *interceptor.StreamInfo) bool {
+	for _, fb := range info.RTCPFeedback {
+		if fb.Type == "nack" && fb.Parameter == "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// roundTripTime computes the round trip time from the LSR/LRR and DLSR/DLRR fields of a
+// report block received at now. ok is false if the block doesn't echo a report.
+func roundTripTime(now time.Time, last, delay uint32) (time.Duration, bool) {
+	if last == 0 {
+		return 0, false
+	}
+
+	rtt := ntp.Compact(now) - last - delay
+	if rtt >= 1<<31 {
+		return 0, false
+	}
+
+	return time.Duration(uint64(rtt) * uint64(time.Second) >> 16), true
+}
@@ -0,0 +1,63 @@
+package nack
+
+import (
+	"time"
+
+	"github.com/pion/logging"
+)
+
+// GeneratorOption can be used to configure GeneratorInterceptor
+type GeneratorOption func(g *GeneratorInterceptor) error
+
+// GeneratorInterval sets how often missing packets are checked and NACKs are sent,
+// defaults to 100ms
+func GeneratorInterval(interval time.Duration) GeneratorOption {
+	return func(g *GeneratorInterceptor) error {
+		g.interval = interval
+		return nil
+	}
+}
+
+// GeneratorSize sets the maximum number of missing packets tracked for a stream, defaults
+// to 512. The oldest ones are given up on when more packets are missing.
+func GeneratorSize(size uint16) GeneratorOption {
+	return func(g *GeneratorInterceptor) error {
+		g.size = size
+		return nil
+	}
+}
+
+// GeneratorMaxAge sets how long a missing packet is requested before it is given up on,
+// defaults to one second
+func GeneratorMaxAge(maxAge time.Duration) GeneratorOption {
+	return func(g *GeneratorInterceptor) error {
+		g.maxAge = maxAge
+		return nil
+	}
+}
+
+// GeneratorMaxRetries sets how many times a missing packet is requested, defaults to 10.
+// A packet still missing a round trip time after its last request is given up on.
+func GeneratorMaxRetries(maxRetries uint) GeneratorOption {
+	return func(g *GeneratorInterceptor) error {
+		g.maxRetries = maxRetries
+		return nil
+	}
+}
+
+// GeneratorInitialRTT sets the round trip time used before it is measured from RTCP,
+// defaults to 100ms
+func GeneratorInitialRTT(rtt time.Duration) GeneratorOption {
+	return func(g *GeneratorInterceptor) error {
+		g.rtt = rtt
+		return nil
+	}
+}
+
+// GeneratorLog sets a logger for the interceptor
+func GeneratorLog(log logging.LeveledLogger) GeneratorOption {
+	return func(g *GeneratorInterceptor) error {
+		g.log = log
+		return nil
+	}
+}
@@ -0,0 +1,269 @@
+package remb
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// Packets sent within burstInterval of the first packet of a group belong to the same group
+	burstInterval = 5 * time.Millisecond
+
+	trendlineWindowSize = 20
+	trendlineSmoothing  = 0.9
+	trendlineGain       = 4.0
+	maxTrendlineDeltas  = 60
+
+	initialThreshold = 12.5
+	minThreshold     = 6.0
+	maxThreshold     = 600.0
+	thresholdUp      = 0.0087
+	thresholdDown    = 0.039
+	overuseTime      = 10 * time.Millisecond
+
+	rateWindow       = time.Second
+	decreaseFactor   = 0.85
+	decreaseInterval = 200 * time.Millisecond
+	increasePerSec   = 0.08
+)
+
+type usage int
+
+const (
+	usageNormal usage = iota
+	usageOver
+	usageUnder
+)
+
+type packetGroup struct {
+	firstSend   time.Time
+	lastSend    time.Time
+	lastArrival time.Time
+}
+
+type delaySample struct {
+	arrival float64 // ms since the first group
+	delay   float64 // smoothed accumulated delay in ms
+}
+
+type rateSample struct {
+	arrival time.Time
+	size    int
+}
+
+// estimator is a delay based bandwidth estimator. It detects the growth of the
+// one way delay between groups of packets, and controls the estimate with AIMD.
+type estimator struct {
+	minBitrate int
+	maxBitrate int
+	target     int
+
+	group     *packetGroup
+	prevGroup *packetGroup
+
+	firstArrival     time.Time
+	accumulatedDelay float64
+	smoothedDelay    float64
+	samples          []delaySample
+	numDeltas        int
+
+	threshold       float64
+	lastThreshold   time.Time
+	prevTrend       float64
+	overuseStart    time.Time
+	overuseDetected bool
+
+	rates        []rateSample
+	lastIncrease time.Time
+	lastDecrease time.Time
+}
+
+func newEstimator(initialBitrate, minBitrate, maxBitrate int) *estimator {
+	return &estimator{
+		minBitrate: minBitrate,
+		maxBitrate: maxBitrate,
+		target:     initialBitrate,
+		threshold:  initialThreshold,
+	}
+}
+
+// onPacket updates the estimate with a received packet of size bytes
+func (e *estimator) onPacket(sendTime, arrival time.Time, size int) {
+	e.rates = append(e.rates, rateSample{arrival: arrival, size: size})
+	for len(e.rates) > 0 && arrival.Sub(e.rates[0].arrival) > rateWindow {
+		e.rates = e.rates[1:]
+	}
+
+	switch {
+	case e.group == nil:
+		e.group = &packetGroup{firstSend: sendTime, lastSend: sendTime, lastArrival: arrival}
+	case sendTime.Before(e.group.firstSend):
+		// Reordered packets are only used for the incoming rate
+	case sendTime.Sub(e.group.firstSend) <= burstInterval:
+		if sendTime.After(e.group.lastSend) {
+			e.group.lastSend = sendTime
+		}
+		e.group.lastArrival = arrival
+	default:
+		if e.prevGroup != nil {
+			sendDelta := e.group.lastSend.Sub(e.prevGroup.lastSend)
+			arrivalDelta := e.group.lastArrival.Sub(e.prevGroup.lastArrival)
+			e.onDelayDelta(durationMs(arrivalDelta-sendDelta), e.group.lastArrival)
+		}
+		e.prevGroup = e.group
+		e.group = &packetGroup{firstSend: sendTime, lastSend: sendTime, lastArrival: arrival}
+	}
+}
+
+// bitrate returns the current estimate in bits per second
+func (e *estimator) bitrate() int {
+	return e.target
+}
+
+func (e *estimator) onDelayDelta(delta float64, arrival time.Time) {
+	if e.firstArrival.IsZero() {
+		e.firstArrival = arrival
+	}
+	if e.numDeltas < maxTrendlineDeltas {
+		e.numDeltas++
+	}
+
+	e.accumulatedDelay += delta
+	e.smoothedDelay = trendlineSmoothing*e.smoothedDelay + (1-trendlineSmoothing)*e.accumulatedDelay
+
+	e.samples = append(e.samples, delaySample{arrival: durationMs(arrival.Sub(e.firstArrival)), delay: e.smoothedDelay})
+	if len(e.samples) > trendlineWindowSize {
+		e.samples = e.samples[1:]
+	}
+
+	trend := e.prevTrend
+	if len(e.samples) == trendlineWindowSize {
+		trend = linearFitSlope(e.samples) * float64(e.numDeltas) * trendlineGain
+	}
+
+	e.updateRate(e.detect(trend, arrival), arrival)
+}
+
+// detect compares the delay trend with an adaptive threshold
+func (e *estimator) detect(trend float64, arrival time.Time) usage {
+	u := usageNormal
+	switch {
+	case trend > e.threshold:
+		if e.overuseStart.IsZero() {
+			e.overuseStart = arrival
+		}
+		if arrival.Sub(e.overuseStart) >= overuseTime && trend >= e.prevTrend {
+			e.overuseDetected = true
+		}
+		if e.overuseDetected {
+			u = usageOver
+		}
+	case trend < -e.threshold:
+		e.overuseStart = time.Time{}
+		e.overuseDetected = false
+		u = usageUnder
+	default:
+		e.overuseStart = time.Time{}
+		e.overuseDetected = false
+	}
+
+	if !e.lastThreshold.IsZero() && math.Abs(trend) <= e.threshold+15 {
+		k := thresholdUp
+		if math.Abs(trend) < e.threshold {
+			k = thresholdDown
+		}
+		elapsed := math.Min(durationMs(arrival.Sub(e.lastThreshold)), 100)
+		e.threshold = math.Max(minThreshold, math.Min(maxThreshold, e.threshold+k*(math.Abs(trend)-e.threshold)*elapsed))
+	}
+	e.lastThreshold = arrival
+	e.prevTrend = trend
+
+	return u
+}
+
+// updateRate decreases the estimate below the incoming rate on overuse, and
+// increases it multiplicatively otherwise
+func (e *estimator) updateRate(u usage, arrival time.Time) {
+	incoming := e.incomingBitrate()
+	target := e.target
+
+	switch u {
+	case usageOver:
+		if arrival.Sub(e.lastDecrease) >= decreaseInterval {
+			if incoming > 0 {
+				target = int(decreaseFactor * float64(incoming))
+			} else {
+				target = int(decreaseFactor * float64(target))
+			}
+			e.lastDecrease = arrival
+		}
+		e.lastIncrease = arrival
+	case usageUnder:
+		e.lastIncrease = arrival
+	case usageNormal:
+		if !e.lastIncrease.IsZero() {
+			elapsed := math.Min(arrival.Sub(e.lastIncrease).Seconds(), 1)
+			target = int(float64(target) * (1 + increasePerSec*elapsed))
+
+			// Don't increase far beyond what is actually received
+			if limit := int(1.5*float64(incoming)) + 10000; incoming > 0 && target > limit {
+				target = maxInt(e.target, limit)
+			}
+		}
+		e.lastIncrease = arrival
+	}
+
+	e.target = maxInt(e.minBitrate, minInt(e.maxBitrate, target))
+}
+
+func (e *estimator) incomingBitrate() int {
+	if len(e.rates) < 2 {
+		return 0
+	}
+
+	size := 0
+	for _, r := range e.rates {
+		size += r.size
+	}
+
+	return int(float64(size*8) / rateWindow.Seconds())
+}
+
+func linearFitSlope(samples []delaySample) float64 {
+	var sumX, sumY float64
+	for _, s := range samples {
+		sumX += s.arrival
+		sumY += s.delay
+	}
+	meanX := sumX / float64(len(samples))
+	meanY := sumY / float64(len(samples))
+
+	var numerator, denominator float64
+	for _, s := range samples {
+		numerator += (s.arrival - meanX) * (s.delay - meanY)
+		denominator += (s.arrival - meanX) * (s.arrival - meanX)
+	}
+	if denominator == 0 {
+		return 0
+	}
+
+	return numerator / denominator
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
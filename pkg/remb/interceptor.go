@@ -0,0 +1,330 @@
+// Package remb implements receiver estimated maximum bitrate (REMB) feedback.
+// The receiver estimates the available bandwidth from the abs-send-time header extension,
+// https://webrtc.googlesource.com/src/+/refs/heads/main/docs/native-code/rtp-hdrext/abs-send-time
+// and reports it with REMB packets,
+// https://datatracker.ietf.org/doc/html/draft-alvestrand-rmcat-remb-03
+package remb
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/logging"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
+)
+
+// A REMB packet is sent before the interval when the estimate drops below this ratio of the last one sent
+const significantDecrease = 0.97
+
+var errInvalidBitrate = errors.New("remb: invalid initial, min or max bitrate")
+
+// NewPeerConnectionCallback is called with the Interceptor built for each PeerConnection
+type NewPeerConnectionCallback func(id string, i *Interceptor)
+
+// InterceptorFactory is a interceptor.Factory for a Interceptor
+type InterceptorFactory struct {
+	opts              []Option
+	addPeerConnection NewPeerConnectionCallback
+}
+
+// NewInterceptor constructs a new InterceptorFactory
+func NewInterceptor(opts ...Option) (*InterceptorFactory, error) {
+	return &InterceptorFactory{opts: opts}, nil
+}
+
+// OnNewPeerConnection sets a callback that is called when a new Interceptor is created
+func (f *InterceptorFactory) OnNewPeerConnection(cb NewPeerConnectionCallback) {
+	f.addPeerConnection = cb
+}
+
+// NewInterceptor constructs a new Interceptor
+func (f *InterceptorFactory) NewInterceptor(id string) (interceptor.Interceptor, error) {
+	i := &Interceptor{
+		initialBitrate: 300000,
+		minBitrate:     30000,
+		maxBitrate:     10000000,
+		interval:       time.Second,
+		log:            logging.NewDefaultLoggerFactory().NewLogger("remb"),
+		ssrcs:          map[uint32]struct{}{},
+		decreased:      make(chan struct{}, 1),
+		close:          make(chan struct{}),
+	}
+
+	for _, opt := range f.opts {
+		if err := opt(i); err != nil {
+			return nil, err
+		}
+	}
+
+	if i.minBitrate <= 0 || i.minBitrate > i.maxBitrate ||
+		i.initialBitrate < i.minBitrate || i.initialBitrate > i.maxBitrate {
+		return nil, errInvalidBitrate
+	}
+
+	i.estimator = newEstimator(i.initialBitrate, i.minBitrate, i.maxBitrate)
+
+	if f.addPeerConnection != nil {
+		f.addPeerConnection(id, i)
+	}
+
+	return i, nil
+}
+
+// Interceptor stamps local streams with the abs-send-time header extension, estimates the
+// bandwidth of remote streams from it and sends the estimate in REMB packets. REMB packets
+// received from the remote peer update the target bitrate of the local streams.
+type Interceptor struct {
+	interceptor.NoOp
+	initialBitrate int
+	minBitrate     int
+	maxBitrate     int
+	interval       time.Duration
+	log            logging.LeveledLogger
+
+	mu        sync.Mutex
+	estimator *estimator
+	ssrcs     map[uint32]struct{}
+	lastSent  int
+
+	targetBitrate         int
+	onTargetBitrateChange func(bitrate int)
+
+	wg        sync.WaitGroup
+	decreased chan struct{}
+	close     chan struct{}
+}
+
+// GetTargetBitrate returns the bitrate in bits per second last reported by the remote peer,
+// or 0 when no REMB has been received yet
+func (i *Interceptor) GetTargetBitrate() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.targetBitrate
+}
+
+// OnTargetBitrateChange sets a callback that is called when the remote peer reports a new bitrate
+func (i *Interceptor) OnTargetBitrateChange(f func(bitrate int)) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.onTargetBitrateChange = f
+}
+
+// BindRTCPReader lets you modify any incoming RTCP packets. It is called once per sender/receiver, however this might
+// change in the future. The returned method will be called once per packet batch.
+func (i *Interceptor) BindRTCPReader(reader interceptor.RTCPReader) interceptor.RTCPReader {
+	return interceptor.RTCPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		n, attr, err := reader.Read(b, a)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if attr == nil {
+			attr = make(interceptor.Attributes)
+		}
+		pkts, err := attr.GetRTCPPackets(b[:n])
+		if err != nil {
+			return 0, nil, err
+		}
+
+		for _, pkt := range pkts {
+			if remb, ok := pkt.(*rtcp.ReceiverEstimatedMaximumBitrate); ok {
+				i.setTargetBitrate(int(remb.Bitrate))
+			}
+		}
+
+		return n, attr, nil
+	})
+}
+
+func (i *Interceptor) setTargetBitrate(bitrate int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if bitrate == i.targetBitrate {
+		return
+	}
+
+	i.targetBitrate = bitrate
+	if i.onTargetBitrateChange != nil {
+		go i.onTargetBitrateChange(bitrate)
+	}
+}
+
+// BindRTCPWriter lets you modify any outgoing RTCP packets. It is called once per PeerConnection. The returned method
+// will be called once per packet batch.
+func (i *Interceptor) BindRTCPWriter(writer interceptor.RTCPWriter) interceptor.RTCPWriter {
+	if i.isClosed() {
+		return writer
+	}
+
+	i.wg.Add(1)
+	go i.loop(writer)
+
+	return writer
+}
+
+// BindLocalStream lets you modify any outgoing RTP packets. It is called once for per LocalStream. The returned method
+// will be called once per rtp packet.
+func (i *Interceptor) BindLocalStream(info *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+	hdrExtID := absSendTimeExtensionID(info)
+	if hdrExtID == 0 {
+		return writer
+	}
+
+	return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+		ext, err := rtp.NewAbsSendTimeExtension(time.Now()).Marshal()
+		if err != nil {
+			return 0, err
+		}
+		if err = header.SetExtension(hdrExtID, ext); err != nil {
+			return 0, err
+		}
+
+		return writer.Write(header, payload, attributes)
+	})
+}
+
+// BindRemoteStream lets you modify any incoming RTP packets. It is called once for per RemoteStream. The returned method
+// will be called once per rtp packet.
+func (i *Interceptor) BindRemoteStream(info *interceptor.StreamInfo, reader interceptor.RTPReader) interceptor.RTPReader {
+	hdrExtID := absSendTimeExtensionID(info)
+	if hdrExtID == 0 {
+		return reader
+	}
+
+	i.mu.Lock()
+	i.ssrcs[info.SSRC] = struct{}{}
+	i.mu.Unlock()
+
+	return interceptor.RTPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		n, attr, err := reader.Read(b, a)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if attr == nil {
+			attr = make(interceptor.Attributes)
+		}
+		header, err := attr.GetRTPHeader(b[:n])
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if ext := header.GetExtension(hdrExtID); ext != nil {
+			var absSendTime rtp.AbsSendTimeExtension
+			if err = absSendTime.Unmarshal(ext); err != nil {
+				return 0, nil, err
+			}
+
+			now := time.Now()
+			i.onPacket(absSendTime.Estimate(now), now, n)
+		}
+
+		return n, attr, nil
+	})
+}
+
+func (i *Interceptor) onPacket(sendTime, arrival time.Time, size int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.estimator.onPacket(sendTime, arrival, size)
+	if i.lastSent != 0 && float64(i.estimator.bitrate()) < significantDecrease*float64(i.lastSent) {
+		select {
+		case i.decreased <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// UnbindRemoteStream is called when the Stream is removed. It can be used to clean up any data related to that track.
+func (i *Interceptor) UnbindRemoteStream(info *interceptor.StreamInfo) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	delete(i.ssrcs, info.SSRC)
+}
+
+// Close closes the interceptor
+func (i *Interceptor) Close() error {
+	defer i.wg.Wait()
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if !i.isClosed() {
+		close(i.close)
+	}
+
+	return nil
+}
+
+func (i *Interceptor) isClosed() bool {
+	select {
+	case <-i.close:
+		return true
+	default:
+		return false
+	}
+}
+
+func (i *Interceptor) loop(writer interceptor.RTCPWriter) {
+	defer i.wg.Done()
+
+	senderSSRC := rand.Uint32() // #nosec
+	ticker := time.NewTicker(i.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-i.close:
+			return
+		case <-ticker.C:
+		case <-i.decreased:
+		}
+
+		if pkt := i.buildREMB(senderSSRC); pkt != nil {
+			if _, err := writer.Write([]rtcp.Packet{pkt}, interceptor.Attributes{}); err != nil {
+				i.log.Warnf("failed sending REMB: %v", err)
+			}
+		}
+	}
+}
+
+func (i *Interceptor) buildREMB(senderSSRC uint32) *rtcp.ReceiverEstimatedMaximumBitrate {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if len(i.ssrcs) == 0 {
+		return nil
+	}
+
+	ssrcs := make([]uint32, 0, len(i.ssrcs))
+	for ssrc := range i.ssrcs {
+		ssrcs = append(ssrcs, ssrc)
+	}
+
+	i.lastSent = i.estimator.bitrate()
+	return &rtcp.ReceiverEstimatedMaximumBitrate{
+		SenderSSRC: senderSSRC,
+		Bitrate:    float32(i.lastSent),
+		SSRCs:      ssrcs,
+	}
+}
+
+func absSendTimeExtensionID(info *interceptor.StreamInfo) uint8 {
+	for _, e := range info.RTPHeaderExtensions {
+		if e.URI == sdp.ABSSendTimeURI {
+			return uint8(e.ID)
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,51 @@
+package remb
+
+import (
+	"time"
+
+	"github.com/pion/logging"
+)
+
+// Option can be used to configure Interceptor
+type Option func(i *Interceptor) error
+
+// InitialBitrate sets the estimate reported to the remote peer before the first measurement, defaults to 300 kbit/s
+func InitialBitrate(bitrate int) Option {
+	return func(i *Interceptor) error {
+		i.initialBitrate = bitrate
+		return nil
+	}
+}
+
+// MinBitrate sets the lowest estimate reported to the remote peer, defaults to 30 kbit/s
+func MinBitrate(bitrate int) Option {
+	return func(i *Interceptor) error {
+		i.minBitrate = bitrate
+		return nil
+	}
+}
+
+// MaxBitrate sets the highest estimate reported to the remote peer, defaults to 10 Mbit/s
+func MaxBitrate(bitrate int) Option {
+	return func(i *Interceptor) error {
+		i.maxBitrate = bitrate
+		return nil
+	}
+}
+
+// SendInterval sets how often REMB packets are sent, defaults to one second.
+// A REMB packet is also sent as soon as the estimate decreases significantly.
+func SendInterval(interval time.Duration) Option {
+	return func(i *Interceptor) error {
+		i.interval = interval
+		return nil
+	}
+}
+
+// Log sets a logger for the interceptor
+func Log(log logging.LeveledLogger) Option {
+	return func(i *Interceptor) error {
+		i.log = log
+		return nil
+	}
+}
@@ -0,0 +1,130 @@
+package remb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+// feed sends a 1000 byte packet every 10ms for duration, each one queued
+// extraDelay longer than the previous one
+func feed(e *estimator, start time.Time, duration, extraDelay time.Duration) time.Time {
+	delay := time.Duration(0)
+	sendTime := start
+	for ; sendTime.Sub(start) < duration; sendTime = sendTime.Add(10 * time.Millisecond) {
+		e.onPacket(sendTime, sendTime.Add(20*time.Millisecond+delay), 1000)
+		delay += extraDelay
+	}
+
+	return sendTime
+}
+
+func TestEstimator(t *testing.T) {
+	e := newEstimator(300000, 30000, 10000000)
+	now := time.Unix(0, 0)
+
+	// Stable delay, the estimate grows towards the incoming rate of 800 kbit/s
+	now = feed(e, now, 5*time.Second, 0)
+	stable := e.bitrate()
+	assert.Greater(t, stable, 300000)
+	assert.LessOrEqual(t, stable, 1500000)
+
+	// Growing delay, the estimate drops below the incoming rate
+	feed(e, now, time.Second, 2*time.Millisecond)
+	assert.Less(t, e.bitrate(), 800000)
+	assert.GreaterOrEqual(t, e.bitrate(), 30000)
+}
+
+func TestEstimator_Bounds(t *testing.T) {
+	e := newEstimator(300000, 30000, 400000)
+	feed(e, time.Unix(0, 0), 10*time.Second, 0)
+	assert.Equal(t, 400000, e.bitrate())
+}
+
+func TestInterceptor(t *testing.T) {
+	factory, err := NewInterceptor(SendInterval(10 * time.Millisecond))
+	assert.NoError(t, err)
+
+	var built *Interceptor
+	factory.OnNewPeerConnection(func(_ string, i *Interceptor) {
+		built = i
+	})
+
+	i, err := factory.NewInterceptor("")
+	assert.NoError(t, err)
+	assert.Equal(t, i, built)
+	assert.Equal(t, 0, built.GetTargetBitrate())
+
+	info := &interceptor.StreamInfo{
+		SSRC:                1234,
+		RTPHeaderExtensions: []interceptor.RTPHeaderExtension{{URI: sdp.ABSSendTimeURI, ID: 3}},
+	}
+
+	// Local streams are stamped with abs-send-time
+	var written *rtp.Header
+	writer := i.BindLocalStream(info, interceptor.RTPWriterFunc(func(header *rtp.Header, _ []byte, _ interceptor.Attributes) (int, error) {
+		written = header
+		return 0, nil
+	}))
+	_, err = writer.Write(&rtp.Header{SSRC: 1234}, []byte{0x00}, nil)
+	assert.NoError(t, err)
+	assert.Len(t, written.GetExtension(3), 3)
+
+	// REMB is sent for remote streams with abs-send-time
+	rembs := make(chan *rtcp.ReceiverEstimatedMaximumBitrate, 10)
+	i.BindRTCPWriter(interceptor.RTCPWriterFunc(func(pkts []rtcp.Packet, _ interceptor.Attributes) (int, error) {
+		for _, pkt := range pkts {
+			if remb, ok := pkt.(*rtcp.ReceiverEstimatedMaximumBitrate); ok {
+				select {
+				case rembs <- remb:
+				default:
+				}
+			}
+		}
+		return 0, nil
+	}))
+
+	b, err := (&rtp.Packet{Header: *written, Payload: []byte{0x00}}).Marshal()
+	assert.NoError(t, err)
+	reader := i.BindRemoteStream(info, interceptor.RTPReaderFunc(func(in []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		return copy(in, b), a, nil
+	}))
+	_, _, err = reader.Read(make([]byte, 1500), nil)
+	assert.NoError(t, err)
+
+	remb := <-rembs
+	assert.Equal(t, []uint32{1234}, remb.SSRCs)
+	assert.Equal(t, float32(300000), remb.Bitrate)
+
+	// REMB received from the remote peer updates the target bitrate
+	changed := make(chan int, 1)
+	built.OnTargetBitrateChange(func(bitrate int) {
+		changed <- bitrate
+	})
+
+	rtcpPacket, err := (&rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 150000, SSRCs: []uint32{1234}}).Marshal()
+	assert.NoError(t, err)
+	rtcpReader := i.BindRTCPReader(interceptor.RTCPReaderFunc(func(in []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		return copy(in, rtcpPacket), a, nil
+	}))
+	_, _, err = rtcpReader.Read(make([]byte, 1500), nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 150000, <-changed)
+	assert.Equal(t, 150000, built.GetTargetBitrate())
+
+	assert.NoError(t, i.Close())
+}
+
+func TestInterceptor_InvalidBitrate(t *testing.T) {
+	factory, err := NewInterceptor(MinBitrate(500000), MaxBitrate(100000))
+	assert.NoError(t, err)
+
+	_, err = factory.NewInterceptor("")
+	assert.Equal(t, errInvalidBitrate, err)
+}
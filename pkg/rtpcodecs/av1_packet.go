@@ -0,0 +1,170 @@
+package rtpcodecs
+
+import (
+	"github.com/pion/rtp/pkg/obu"
+)
+
+// https://aomediacodec.github.io/av1-rtp-spec/#44-av1-aggregation-header
+const (
+	av1ZMask = byte(0b10000000)
+	av1YMask = byte(0b01000000)
+	av1WMask = byte(0b00110000)
+	av1NMask = byte(0b00001000)
+
+	av1WBitshift = 4
+
+	av1AggregationHeaderSize = 1
+)
+
+// https://aomediacodec.github.io/av1-spec/#obu-header-syntax
+const (
+	av1OBUForbiddenBitMask    = byte(0b10000000)
+	av1OBUTypeMask            = byte(0b01111000)
+	av1OBUExtensionFlagMask   = byte(0b00000100)
+	av1OBUHasSizeFieldBitMask = byte(0b00000010)
+
+	av1OBUTypeBitshift = 3
+
+	av1OBUTypeSequenceHeader = 1
+)
+
+func av1OBUType(header byte) byte {
+	return (header & av1OBUTypeMask) >> av1OBUTypeBitshift
+}
+
+// appendLEB128 appends the leb128 encoding of value to b
+func appendLEB128(b []byte, value uint) []byte {
+	for {
+		if value < 0x80 {
+			return append(b, byte(value))
+		}
+		b = append(b, byte(value&0x7F)|0x80)
+		value >>= 7
+	}
+}
+
+// AV1Depacketizer converts the payloads of AV1 RTP packets to OBUs in the low overhead
+// bitstream format, every OBU having a size field. OBUs fragmented across packets are
+// buffered until their last fragment is received, fragments without a start are dropped.
+type AV1Depacketizer struct {
+	obuBuffer []byte
+}
+
+// Unmarshal parses the passed byte slice and returns the complete OBUs it carries
+func (d *AV1Depacketizer) Unmarshal(packet []byte) ([]byte, error) {
+	if packet == nil {
+		return nil, errNilPacket
+	} else if len(packet) <= av1AggregationHeaderSize {
+		return nil, errShortPacket
+	}
+
+	z := packet[0]&av1ZMask != 0
+	y := packet[0]&av1YMask != 0
+	w := int((packet[0] & av1WMask) >> av1WBitshift)
+	if z && packet[0]&av1NMask != 0 {
+		return nil, errAV1KeyFrameAndFragment
+	}
+
+	var out []byte
+	payload := packet[av1AggregationHeaderSize:]
+	for i := 1; len(payload) > 0; i++ {
+		// When W is set, the last OBU element has no length field
+		elementLength := uint(len(payload))
+		if i != w {
+			length, n, err := obu.ReadLeb128(payload)
+			if err != nil {
+				return nil, err
+			}
+			if uint(len(payload))-n < length {
+				return nil, errShortPacket
+			}
+			elementLength = length
+			payload = payload[n:]
+		}
+		element := payload[:elementLength]
+		payload = payload[elementLength:]
+
+		if i == 1 && z {
+			if d.obuBuffer == nil {
+				// The start of the OBU was lost
+				continue
+			}
+			element = append(d.obuBuffer, element...)
+			d.obuBuffer = nil
+		}
+
+		if y && len(payload) == 0 {
+			d.obuBuffer = append([]byte{}, element...)
+			break
+		}
+
+		out = appendOBU(out, element)
+	}
+
+	return out, nil
+}
+
+// appendOBU appends element to b, adding a size field to its OBU header when it has none
+func appendOBU(b, element []byte) []byte {
+	if len(element) == 0 || element[0]&av1OBUHasSizeFieldBitMask != 0 {
+		return append(b, element...)
+	}
+
+	headerSize := 1
+	if element[0]&av1OBUExtensionFlagMask != 0 {
+		headerSize = 2
+	}
+	if len(element) < headerSize {
+		return b
+	}
+
+	b = append(b, element[0]|av1OBUHasSizeFieldBitMask)
+	b = append(b, element[1:headerSize]...)
+	b = appendLEB128(b, uint(len(element)-headerSize))
+
+	return append(b, element[headerSize:]...)
+}
+
+// IsPartitionHead checks if this packet does not start with the continuation of a fragmented OBU
+func (d *AV1Depacketizer) IsPartitionHead(payload []byte) bool {
+	if len(payload) == 0 {
+		return false
+	}
+
+	return payload[0]&av1ZMask == 0
+}
+
+// IsPartitionTail checks if this is the last packet of a temporal unit
+func (d *AV1Depacketizer) IsPartitionTail(marker bool, _ []byte) bool {
+	return marker
+}
+
+// IsKeyFrame returns true if the OBUs returned by Unmarshal for a temporal unit
+// contain a sequence header, which is sent with every key frame
+func (d *AV1Depacketizer) IsKeyFrame(sample []byte) bool {
+	for len(sample) > 0 {
+		header := sample[0]
+		if header&av1OBUForbiddenBitMask != 0 || header&av1OBUHasSizeFieldBitMask == 0 {
+			return false
+		}
+		if av1OBUType(header) == av1OBUTypeSequenceHeader {
+			return true
+		}
+
+		headerSize := uint(1)
+		if header&av1OBUExtensionFlagMask != 0 {
+			headerSize = 2
+		}
+		if uint(len(sample)) < headerSize {
+			return false
+		}
+
+		size, n, err := obu.ReadLeb128(sample[headerSize:])
+		if err != nil || uint(len(sample))-headerSize-n < size {
+			return false
+		}
+		sample = sample[headerSize+n+size:]
+	}
+
+	return false
+}
@@ -0,0 +1,112 @@
+package rtpcodecs
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/rtp/pkg/obu"
+)
+
+func TestAppendLEB128(t *testing.T) {
+	for _, value := range []uint{0, 5, 127, 128, 999999} {
+		encoded := appendLEB128(nil, value)
+		decoded, n, err := obu.ReadLeb128(encoded)
+		if err != nil || decoded != value || n != uint(len(encoded)) {
+			t.Fatalf("leb128 round trip of %d failed: %v %d %v", value, encoded, decoded, err)
+		}
+	}
+}
+
+func TestAV1Depacketizer_Unmarshal(t *testing.T) {
+	d := &AV1Depacketizer{}
+
+	if _, err := d.Unmarshal(nil); !errors.Is(err, errNilPacket) {
+		t.Fatal("Unmarshal did not fail on nil payload")
+	}
+	if _, err := d.Unmarshal([]byte{0x00}); !errors.Is(err, errShortPacket) {
+		t.Fatal("Unmarshal did not fail on short payload")
+	}
+	if _, err := d.Unmarshal([]byte{0x88, 0x01, 0x00}); !errors.Is(err, errAV1KeyFrameAndFragment) {
+		t.Fatal("Unmarshal did not fail with Z and N set")
+	}
+	if _, err := d.Unmarshal([]byte{0x00, 0x05, 0x32}); !errors.Is(err, errShortPacket) {
+		t.Fatal("Unmarshal did not fail on truncated OBU element")
+	}
+
+	// W=2, the last element has no length field, size fields are added to the OBUs
+	res, err := d.Unmarshal([]byte{0x20, 0x03, 0x08, 0xAA, 0xBB, 0x30, 0x01, 0x02})
+	if err != nil || !bytes.Equal(res, []byte{0x0A, 0x02, 0xAA, 0xBB, 0x32, 0x02, 0x01, 0x02}) {
+		t.Fatalf("Unexpected OBUs %v %v", res, err)
+	}
+
+	// OBU with extension header
+	res, err = d.Unmarshal([]byte{0x10, 0x34, 0x28, 0x01})
+	if err != nil || !bytes.Equal(res, []byte{0x36, 0x28, 0x01, 0x01}) {
+		t.Fatalf("Unexpected OBU with extension %v %v", res, err)
+	}
+
+	// Continuation without a start is dropped
+	res, err = d.Unmarshal([]byte{0x80, 0x02, 0x01, 0x02})
+	if err != nil || len(res) != 0 {
+		t.Fatalf("Unexpected OBUs for a fragment without a start %v %v", res, err)
+	}
+
+	// OBU fragmented over three packets
+	for _, payload := range [][]byte{{0x50, 0x30, 0x01}, {0xD0, 0x02}} {
+		if res, err = d.Unmarshal(payload); err != nil || len(res) != 0 {
+			t.Fatalf("Unexpected OBUs before the last fragment %v %v", res, err)
+		}
+	}
+	res, err = d.Unmarshal([]byte{0x90, 0x03})
+	if err != nil || !bytes.Equal(res, []byte{0x32, 0x03, 0x01, 0x02, 0x03}) {
+		t.Fatalf("Unexpected fragmented OBU %v %v", res, err)
+	}
+}
+
+func TestAV1Depacketizer_RoundTrip(t *testing.T) {
+	temporalUnit := []byte{0x0A, 0x02, 0xAA, 0xBB, 0x32, 0x80, 0x08}
+	frame := make([]byte, 1024)
+	for i := range frame {
+		frame[i] = byte(i)
+	}
+	temporalUnit = append(temporalUnit, frame...)
+
+	d := &AV1Depacketizer{}
+	var out []byte
+	for _, payload := range (&codecs.AV1Payloader{}).Payload(500, temporalUnit) {
+		res, err := d.Unmarshal(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, res...)
+	}
+
+	if !bytes.Equal(temporalUnit, out) {
+		t.Fatal("Depacketized temporal unit does not match the payloaded one")
+	}
+	if !d.IsKeyFrame(out) {
+		t.Fatal("Temporal unit with a sequence header must be a key frame")
+	}
+}
+
+func TestAV1Depacketizer_IsKeyFrame(t *testing.T) {
+	d := &AV1Depacketizer{}
+
+	for _, test := range []struct {
+		sample   []byte
+		keyFrame bool
+	}{
+		{nil, false},
+		{[]byte{0x0A, 0x00}, true},
+		{[]byte{0x32, 0x01, 0x00, 0x0A, 0x00}, true},
+		{[]byte{0x36, 0x28, 0x01, 0x00}, false},
+		{[]byte{0x32, 0x05, 0x00}, false},
+		{[]byte{0x08, 0x00}, false},
+	} {
+		if d.IsKeyFrame(test.sample) != test.keyFrame {
+			t.Fatalf("Unexpected key frame detection for %v", test.sample)
+		}
+	}
+}
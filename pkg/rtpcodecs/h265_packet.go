@@ -0,0 +1,310 @@
+package rtpcodecs
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// https://www.rfc-editor.org/rfc/rfc7798#section-4.4
+const (
+	h265NaluHeaderSize = 2
+	h265FuHeaderSize   = 1
+	h265NaluSizeLength = 2
+	h265DONLLength     = 2
+	h265DONDLength     = 1
+
+	h265NaluAggregationPacketType   = 48
+	h265NaluFragmentationUnitType   = 49
+	h265NaluPACIPacketType          = 50
+	h265NaluAccessUnitDelimiterType = 35
+	h265NaluFillerDataType          = 38
+
+	h265ForbiddenBitMask = 0x80
+	h265TypeMask         = 0x3F
+	h265LayerIDMask      = 0x1F8
+	h265TIDMask          = 0x07
+
+	h265FuStartBitmask = 0x80
+	h265FuEndBitmask   = 0x40
+)
+
+func h265NaluType(header byte) uint8 {
+	return (header >> 1) & h265TypeMask
+}
+
+// H265Payloader payloads H265 Annex-B access units as described in RFC 7798.
+// Small NAL units are grouped in aggregation packets, and NAL units larger than
+// the MTU are split in fragmentation units.
+type H265Payloader struct {
+	// SkipAggregation sends every NAL unit that fits in the MTU in its own packet
+	SkipAggregation bool
+}
+
+// Payload fragments a H265 access unit across one or more byte arrays
+func (p *H265Payloader) Payload(mtu uint16, payload []byte) [][]byte {
+	var payloads [][]byte
+	if len(payload) == 0 || int(mtu) <= h265NaluHeaderSize+h265FuHeaderSize {
+		return payloads
+	}
+
+	var aggregated [][]byte
+	aggregatedSize := 0
+
+	flushAggregated := func() {
+		switch len(aggregated) {
+		case 0:
+		case 1:
+			out := make([]byte, len(aggregated[0]))
+			copy(out, aggregated[0])
+			payloads = append(payloads, out)
+		default:
+			// The aggregation packet has the lowest LayerId and TID of its NAL units
+			layerID, tid := uint16(h265LayerIDMask), uint16(h265TIDMask)
+			for _, nalu := range aggregated {
+				header := binary.BigEndian.Uint16(nalu)
+				if l := header & h265LayerIDMask; l < layerID {
+					layerID = l
+				}
+				if t := header & h265TIDMask; t < tid {
+					tid = t
+				}
+			}
+
+			out := make([]byte, h265NaluHeaderSize, aggregatedSize)
+			binary.BigEndian.PutUint16(out, h265NaluAggregationPacketType<<9|layerID|tid)
+			for _, nalu := range aggregated {
+				out = append(out, byte(len(nalu)>>8), byte(len(nalu)))
+				out = append(out, nalu...)
+			}
+			payloads = append(payloads, out)
+		}
+
+		aggregated = nil
+		aggregatedSize = 0
+	}
+
+	emitNalus(payload, func(nalu []byte) {
+		if len(nalu) <= h265NaluHeaderSize {
+			return
+		}
+
+		naluType := h265NaluType(nalu[0])
+		if naluType == h265NaluAccessUnitDelimiterType || naluType == h265NaluFillerDataType {
+			return
+		}
+
+		if len(nalu) <= int(mtu) {
+			if len(aggregated) != 0 && aggregatedSize+h265NaluSizeLength+len(nalu) > int(mtu) {
+				flushAggregated()
+			}
+			if len(aggregated) == 0 {
+				aggregatedSize = h265NaluHeaderSize
+			}
+
+			aggregated = append(aggregated, nalu)
+			aggregatedSize += h265NaluSizeLength + len(nalu)
+
+			if p.SkipAggregation {
+				flushAggregated()
+			}
+			return
+		}
+
+		flushAggregated()
+
+		// The payload header of the fragmentation units keeps the F, LayerId and TID of the NAL unit
+		header := []byte{
+			(nalu[0] &^ (h265TypeMask << 1)) | h265NaluFragmentationUnitType<<1,
+			nalu[1],
+			naluType | h265FuStartBitmask,
+		}
+		data := nalu[h265NaluHeaderSize:]
+		maxFragmentSize := int(mtu) - h265NaluHeaderSize - h265FuHeaderSize
+
+		for len(data) > 0 {
+			fragmentSize := maxFragmentSize
+			if len(data) <= fragmentSize {
+				fragmentSize = len(data)
+				header[2] |= h265FuEndBitmask
+			}
+
+			out := make([]byte, 0, len(header)+fragmentSize)
+			out = append(out, header...)
+			out = append(out, data[:fragmentSize]...)
+			payloads = append(payloads, out)
+
+			data = data[fragmentSize:]
+			header[2] &^= h265FuStartBitmask
+		}
+	})
+
+	flushAggregated()
+
+	return payloads
+}
+
+// H265Depacketizer converts the payloads of RFC 7798 RTP packets to a H265 Annex-B
+// byte stream. Fragmentation units are buffered until the last fragment is received.
+// PACI packets are not supported.
+type H265Depacketizer struct {
+	// DONL must be set when sprop-max-don-diff is greater than 0, so the decoding
+	// order numbers carried in the packets are skipped
+	DONL bool
+
+	fuBuffer []byte
+}
+
+// Unmarshal parses the passed byte slice and returns the NAL units it carries in Annex-B format
+func (d *H265Depacketizer) Unmarshal(packet []byte) ([]byte, error) {
+	if packet == nil {
+		return nil, errNilPacket
+	} else if len(packet) <= h265NaluHeaderSize {
+		return nil, fmt.Errorf("%w: %d <= %v", errShortPacket, len(packet), h265NaluHeaderSize)
+	}
+
+	if packet[0]&h265ForbiddenBitMask != 0 {
+		return nil, errH265CorruptedPacket
+	}
+
+	switch h265NaluType(packet[0]) {
+	case h265NaluAggregationPacketType:
+		return d.parseAggregationPacket(packet[h265NaluHeaderSize:])
+	case h265NaluFragmentationUnitType:
+		return d.parseFragmentationUnit(packet)
+	case h265NaluPACIPacketType:
+		return nil, errH265PACIUnsupported
+	default:
+		out := make([]byte, 0, len(annexBStartCode)+len(packet))
+		out = append(out, annexBStartCode...)
+		if !d.DONL {
+			return append(out, packet...), nil
+		}
+
+		if len(packet) <= h265NaluHeaderSize+h265DONLLength {
+			return nil, fmt.Errorf("%w: %d <= %v", errShortPacket, len(packet), h265NaluHeaderSize+h265DONLLength)
+		}
+		out = append(out, packet[:h265NaluHeaderSize]...)
+		return append(out, packet[h265NaluHeaderSize+h265DONLLength:]...), nil
+	}
+}
+
+func (d *H265Depacketizer) parseAggregationPacket(payload []byte) ([]byte, error) {
+	var out []byte
+
+	for i := 0; len(payload) > 0; i++ {
+		if d.DONL {
+			// The first unit carries a DONL, the following ones a DOND
+			skip := h265DONDLength
+			if i == 0 {
+				skip = h265DONLLength
+			}
+			if len(payload) < skip {
+				return nil, fmt.Errorf("%w: %d < %v", errShortPacket, len(payload), skip)
+			}
+			payload = payload[skip:]
+		}
+
+		if len(payload) < h265NaluSizeLength {
+			return nil, fmt.Errorf("%w: %d < %v", errShortPacket, len(payload), h265NaluSizeLength)
+		}
+		naluSize := int(binary.BigEndian.Uint16(payload))
+		payload = payload[h265NaluSizeLength:]
+
+		if len(payload) < naluSize {
+			return nil, fmt.Errorf("%w: %d < %v", errShortPacket, len(payload), naluSize)
+		}
+		out = append(out, annexBStartCode...)
+		out = append(out, payload[:naluSize]...)
+		payload = payload[naluSize:]
+	}
+
+	return out, nil
+}
+
+func (d *H265Depacketizer) parseFragmentationUnit(packet []byte) ([]byte, error) {
+	if len(packet) <= h265NaluHeaderSize+h265FuHeaderSize {
+		return nil, fmt.Errorf("%w: %d <= %v", errShortPacket, len(packet), h265NaluHeaderSize+h265FuHeaderSize)
+	}
+
+	fuHeader := packet[h265NaluHeaderSize]
+	data := packet[h265NaluHeaderSize+h265FuHeaderSize:]
+
+	if fuHeader&h265FuStartBitmask != 0 {
+		if d.DONL {
+			if len(data) < h265DONLLength {
+				return nil, fmt.Errorf("%w: %d < %v", errShortPacket, len(data), h265DONLLength)
+			}
+			data = data[h265DONLLength:]
+		}
+
+		// Rebuild the header of the fragmented NAL unit from the payload header and the FU type
+		d.fuBuffer = append([]byte{}, annexBStartCode...)
+		d.fuBuffer = append(d.fuBuffer,
+			(packet[0]&^(h265TypeMask<<1))|(fuHeader&h265TypeMask)<<1,
+			packet[1],
+		)
+	} else if d.fuBuffer == nil {
+		// The start of the NAL unit was lost
+		return nil, nil
+	}
+
+	d.fuBuffer = append(d.fuBuffer, data...)
+
+	if fuHeader&h265FuEndBitmask == 0 {
+		return nil, nil
+	}
+
+	out := d.fuBuffer
+	d.fuBuffer = nil
+
+	return out, nil
+}
+
+// IsPartitionHead checks if this is the head of a packetized NAL unit
+func (d *H265Depacketizer) IsPartitionHead(payload []byte) bool {
+	if len(payload) <= h265NaluHeaderSize {
+		return false
+	}
+
+	if h265NaluType(payload[0]) == h265NaluFragmentationUnitType {
+		return payload[h265NaluHeaderSize]&h265FuStartBitmask != 0
+	}
+
+	return true
+}
+
+// IsPartitionTail checks if this is the last packet of an access unit
+func (d *H265Depacketizer) IsPartitionTail(marker bool, _ []byte) bool {
+	return marker
+}
+
+// H265IsKeyFrame returns true if the payload of a RFC 7798 RTP packet starts with
+// a parameter set or an IRAP picture
+func H265IsKeyFrame(payload []byte) bool {
+	const (
+		typeIRAPFirst = 16
+		typeIRAPLast  = 23
+		typeVPS       = 32
+		typeSPS       = 33
+		typePPS       = 34
+	)
+
+	if len(payload) <= h265NaluHeaderSize {
+		return false
+	}
+
+	naluType := h265NaluType(payload[0])
+	switch naluType {
+	case h265NaluAggregationPacketType:
+		// Without DONL, the first aggregated NAL unit starts after its size
+		if len(payload) <= h265NaluHeaderSize+h265NaluSizeLength {
+			return false
+		}
+		naluType = h265NaluType(payload[h265NaluHeaderSize+h265NaluSizeLength])
+	case h265NaluFragmentationUnitType:
+		naluType = payload[h265NaluHeaderSize] & h265TypeMask
+	}
+
+	return (naluType >= typeIRAPFirst && naluType <= typeIRAPLast) ||
+		naluType == typeVPS || naluType == typeSPS || naluType == typePPS
+}
@@ -0,0 +1,195 @@
+package rtpcodecs
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestH265Payloader_Payload(t *testing.T) {
+	p := &H265Payloader{}
+
+	if res := p.Payload(1500, []byte{}); len(res) != 0 {
+		t.Fatal("Generated payload should be empty")
+	}
+	if res := p.Payload(2, []byte{0x00, 0x00, 0x01, 0x40, 0x01, 0x0c}); len(res) != 0 {
+		t.Fatal("Generated payload should be empty when the MTU is too small")
+	}
+
+	// Single NAL unit, the AUD is dropped
+	res := p.Payload(1500, []byte{0x00, 0x00, 0x00, 0x01, 0x46, 0x01, 0x50, 0x00, 0x00, 0x01, 0x26, 0x01, 0xaf, 0x08})
+	if len(res) != 1 || !bytes.Equal(res[0], []byte{0x26, 0x01, 0xaf, 0x08}) {
+		t.Fatalf("Unexpected single NAL unit payload %v", res)
+	}
+
+	// VPS, SPS and PPS are aggregated
+	vps := []byte{0x40, 0x01, 0x0c, 0x01}
+	sps := []byte{0x42, 0x01, 0x01, 0x01, 0x60}
+	pps := []byte{0x44, 0x01, 0xc1, 0x72}
+	var au []byte
+	for _, nalu := range [][]byte{vps, sps, pps} {
+		au = append(au, annexBStartCode...)
+		au = append(au, nalu...)
+	}
+
+	res = p.Payload(1500, au)
+	expected := []byte{
+		0x60, 0x01,
+		0x00, 0x04, 0x40, 0x01, 0x0c, 0x01,
+		0x00, 0x05, 0x42, 0x01, 0x01, 0x01, 0x60,
+		0x00, 0x04, 0x44, 0x01, 0xc1, 0x72,
+	}
+	if len(res) != 1 || !bytes.Equal(res[0], expected) {
+		t.Fatalf("Unexpected aggregation packet %v", res)
+	}
+
+	// The aggregation packet is split when it does not fit in the MTU
+	res = p.Payload(16, au)
+	if len(res) != 2 || len(res[0]) != 15 || !bytes.Equal(res[1], pps) {
+		t.Fatalf("Unexpected split aggregation packets %v", res)
+	}
+
+	res = (&H265Payloader{SkipAggregation: true}).Payload(1500, au)
+	if len(res) != 3 || !bytes.Equal(res[0], vps) || !bytes.Equal(res[1], sps) || !bytes.Equal(res[2], pps) {
+		t.Fatalf("Unexpected payloads without aggregation %v", res)
+	}
+
+	// IDR slice fragmented in fragmentation units
+	idr := []byte{0x26, 0x01, 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06}
+	res = p.Payload(6, idr)
+	expectedFUs := [][]byte{
+		{0x62, 0x01, 0x93, 0x00, 0x01, 0x02},
+		{0x62, 0x01, 0x13, 0x03, 0x04, 0x05},
+		{0x62, 0x01, 0x53, 0x06},
+	}
+	if len(res) != len(expectedFUs) {
+		t.Fatalf("Unexpected fragmentation units %v", res)
+	}
+	for i := range res {
+		if !bytes.Equal(res[i], expectedFUs[i]) {
+			t.Fatalf("Unexpected fragmentation unit %d: %v", i, res[i])
+		}
+	}
+}
+
+func TestH265Depacketizer_Unmarshal(t *testing.T) {
+	d := &H265Depacketizer{}
+
+	if _, err := d.Unmarshal(nil); !errors.Is(err, errNilPacket) {
+		t.Fatal("Unmarshal did not fail on nil payload")
+	}
+	if _, err := d.Unmarshal([]byte{0x26, 0x01}); !errors.Is(err, errShortPacket) {
+		t.Fatal("Unmarshal did not fail on short payload")
+	}
+	if _, err := d.Unmarshal([]byte{0x80, 0x01, 0x00}); !errors.Is(err, errH265CorruptedPacket) {
+		t.Fatal("Unmarshal did not fail on forbidden bit")
+	}
+	if _, err := d.Unmarshal([]byte{0x64, 0x01, 0x00, 0x00}); !errors.Is(err, errH265PACIUnsupported) {
+		t.Fatal("Unmarshal did not fail on PACI packet")
+	}
+	if _, err := d.Unmarshal([]byte{0x60, 0x01, 0x00, 0x05, 0x40}); !errors.Is(err, errShortPacket) {
+		t.Fatal("Unmarshal did not fail on truncated aggregation packet")
+	}
+
+	res, err := d.Unmarshal([]byte{0x26, 0x01, 0xaf, 0x08})
+	if err != nil || !bytes.Equal(res, []byte{0x00, 0x00, 0x00, 0x01, 0x26, 0x01, 0xaf, 0x08}) {
+		t.Fatalf("Unexpected single NAL unit %v %v", res, err)
+	}
+
+	res, err = d.Unmarshal([]byte{0x60, 0x01, 0x00, 0x03, 0x40, 0x01, 0x0c, 0x00, 0x02, 0x42, 0x01})
+	if err != nil || !bytes.Equal(res, []byte{0x00, 0x00, 0x00, 0x01, 0x40, 0x01, 0x0c, 0x00, 0x00, 0x00, 0x01, 0x42, 0x01}) {
+		t.Fatalf("Unexpected aggregation packet %v %v", res, err)
+	}
+
+	// Fragments without a start are dropped
+	if res, err = d.Unmarshal([]byte{0x62, 0x01, 0x13, 0x03}); err != nil || res != nil {
+		t.Fatalf("Unexpected output for a fragment without a start %v %v", res, err)
+	}
+
+	for _, fu := range [][]byte{{0x62, 0x01, 0x93, 0x00, 0x01, 0x02}, {0x62, 0x01, 0x13, 0x03, 0x04, 0x05}} {
+		if res, err = d.Unmarshal(fu); err != nil || res != nil {
+			t.Fatalf("Unexpected output before the last fragment %v %v", res, err)
+		}
+	}
+	res, err = d.Unmarshal([]byte{0x62, 0x01, 0x53, 0x06})
+	if err != nil || !bytes.Equal(res, []byte{0x00, 0x00, 0x00, 0x01, 0x26, 0x01, 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06}) {
+		t.Fatalf("Unexpected fragmented NAL unit %v %v", res, err)
+	}
+
+	// DONL and DOND are skipped
+	d = &H265Depacketizer{DONL: true}
+	res, err = d.Unmarshal([]byte{0x26, 0x01, 0x00, 0x07, 0xaf, 0x08})
+	if err != nil || !bytes.Equal(res, []byte{0x00, 0x00, 0x00, 0x01, 0x26, 0x01, 0xaf, 0x08}) {
+		t.Fatalf("Unexpected single NAL unit with DONL %v %v", res, err)
+	}
+
+	res, err = d.Unmarshal([]byte{0x60, 0x01, 0x00, 0x07, 0x00, 0x03, 0x40, 0x01, 0x0c, 0x01, 0x00, 0x02, 0x42, 0x01})
+	if err != nil || !bytes.Equal(res, []byte{0x00, 0x00, 0x00, 0x01, 0x40, 0x01, 0x0c, 0x00, 0x00, 0x00, 0x01, 0x42, 0x01}) {
+		t.Fatalf("Unexpected aggregation packet with DONL %v %v", res, err)
+	}
+}
+
+func TestH265_RoundTrip(t *testing.T) {
+	au := []byte{0x00, 0x00, 0x00, 0x01, 0x40, 0x01, 0x0c, 0x01, 0x00, 0x00, 0x00, 0x01, 0x42, 0x01, 0x01}
+	idr := make([]byte, 3000)
+	idr[0], idr[1] = 0x26, 0x01
+	for i := 2; i < len(idr); i++ {
+		idr[i] = byte(i%250) + 2
+	}
+	au = append(au, annexBStartCode...)
+	au = append(au, idr...)
+
+	p := &H265Payloader{}
+	d := &H265Depacketizer{}
+
+	var out []byte
+	for _, payload := range p.Payload(1200, au) {
+		res, err := d.Unmarshal(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, res...)
+	}
+
+	if !bytes.Equal(au, out) {
+		t.Fatal("Depacketized access unit does not match the payloaded one")
+	}
+}
+
+func TestH265Depacketizer_IsPartitionHead(t *testing.T) {
+	d := &H265Depacketizer{}
+
+	if d.IsPartitionHead(nil) {
+		t.Fatal("nil must not be a partition head")
+	}
+	if !d.IsPartitionHead([]byte{0x26, 0x01, 0xaf}) {
+		t.Fatal("single NAL unit must be a partition head")
+	}
+	if !d.IsPartitionHead([]byte{0x62, 0x01, 0x93, 0x00}) {
+		t.Fatal("first fragment must be a partition head")
+	}
+	if d.IsPartitionHead([]byte{0x62, 0x01, 0x13, 0x00}) {
+		t.Fatal("middle fragment must not be a partition head")
+	}
+	if !d.IsPartitionTail(true, nil) || d.IsPartitionTail(false, nil) {
+		t.Fatal("partition tail must follow the marker bit")
+	}
+}
+
+func TestH265IsKeyFrame(t *testing.T) {
+	for _, test := range []struct {
+		payload  []byte
+		keyFrame bool
+	}{
+		{nil, false},
+		{[]byte{0x26, 0x01, 0xaf}, true},
+		{[]byte{0x02, 0x01, 0xaf}, false},
+		{[]byte{0x60, 0x01, 0x00, 0x03, 0x40, 0x01, 0x0c}, true},
+		{[]byte{0x62, 0x01, 0x93, 0x00}, true},
+		{[]byte{0x62, 0x01, 0x81, 0x00}, false},
+	} {
+		if H265IsKeyFrame(test.payload) != test.keyFrame {
+			t.Fatalf("Unexpected key frame detection for %v", test.payload)
+		}
+	}
+}
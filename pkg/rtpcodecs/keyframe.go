@@ -0,0 +1,14 @@
+package rtpcodecs
+
+// IsKeyFrame returns true if the Annex-B access unit returned by Unmarshal
+// contains a parameter set or an IRAP picture
+func (d *H265Depacketizer) IsKeyFrame(sample []byte) bool {
+	keyFrame := false
+	emitNalus(sample, func(nalu []byte) {
+		if H265IsKeyFrame(nalu) {
+			keyFrame = true
+		}
+	})
+
+	return keyFrame
+}
@@ -0,0 +1,23 @@
+package rtpcodecs
+
+import (
+	"testing"
+)
+
+func TestH265Depacketizer_IsKeyFrame(t *testing.T) {
+	d := &H265Depacketizer{}
+
+	for _, test := range []struct {
+		sample   []byte
+		keyFrame bool
+	}{
+		{nil, false},
+		{[]byte{0x00, 0x00, 0x00, 0x01, 0x26, 0x01, 0xaf}, true},
+		{[]byte{0x00, 0x00, 0x00, 0x01, 0x02, 0x01, 0xaf}, false},
+		{[]byte{0x00, 0x00, 0x00, 0x01, 0x46, 0x01, 0x10, 0x00, 0x00, 0x00, 0x01, 0x40, 0x01, 0x0c}, true},
+	} {
+		if d.IsKeyFrame(test.sample) != test.keyFrame {
+			t.Fatalf("Unexpected key frame detection for %v", test.sample)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Package rtpcodecs implements the RTP payload formats that github.com/pion/rtp/codecs
+// does not provide yet
+package rtpcodecs
+
+import (
+	"errors"
+)
+
+var (
+	errShortPacket         = errors.New("packet is not large enough")
+	errNilPacket           = errors.New("invalid nil packet")
+	errH265CorruptedPacket = errors.New("H265 packet has the forbidden zero bit set")
+	errH265PACIUnsupported = errors.New("H265 PACI packets are not supported")
+
+	errAV1KeyFrameAndFragment = errors.New("bits Z and N are set. Not possible to have OBU be tail fragment and be keyframe")
+)
+
+var annexBStartCode = []byte{0x00, 0x00, 0x00, 0x01} // nolint:gochecknoglobals
+
+// emitNalus calls emit with every NAL unit of an Annex-B byte stream, without start codes
+func emitNalus(nals []byte, emit func([]byte)) {
+	nextInd := func(nalu []byte, start int) (indStart int, indLen int) {
+		zeroCount := 0
+
+		for i, b := range nalu[start:] {
+			if b == 0 {
+				zeroCount++
+				continue
+			} else if b == 1 && zeroCount >= 2 {
+				return start + i - zeroCount, zeroCount + 1
+			}
+			zeroCount = 0
+		}
+		return -1, -1
+	}
+
+	nextIndStart, nextIndLen := nextInd(nals, 0)
+	if nextIndStart == -1 {
+		emit(nals)
+		return
+	}
+
+	for nextIndStart != -1 {
+		prevStart := nextIndStart + nextIndLen
+		nextIndStart, nextIndLen = nextInd(nals, prevStart)
+		if nextIndStart != -1 {
+			emit(nals[prevStart:nextIndStart])
+		} else {
+			// Emit until end of stream, no end indicator found
+			emit(nals[prevStart:])
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package signaling
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pion/webrtc/v3"
+)
+
+const sdpContentType = "application/sdp"
+
+// HTTPClientConfig configures a HTTPClient
+type HTTPClientConfig struct {
+	// Endpoint is the URL of the WHIP or WHEP endpoint
+	Endpoint string
+	// Token is sent as a bearer token when not empty
+	Token string
+	// Client sends the requests, http.DefaultClient is used when nil
+	Client *http.Client
+}
+
+// HTTPClient is a Signaler for WHIP (ingest) and WHEP (egress) endpoints. The offer is sent in
+// a POST request to the endpoint, which creates a session resource and returns the answer.
+// Trickle ICE candidates are sent to the resource with PATCH requests, and Close deletes it.
+// https://www.rfc-editor.org/rfc/rfc9725
+type HTTPClient struct {
+	config HTTPClientConfig
+
+	mu                sync.Mutex
+	resource          string
+	etag              string
+	pendingCandidates []webrtc.ICECandidateInit
+	closed            bool
+	onDescription     func(desc webrtc.SessionDescription)
+	onCandidate       func(candidate webrtc.ICECandidateInit)
+}
+
+// NewHTTPClient creates a HTTPClient
+func NewHTTPClient(config HTTPClientConfig) *HTTPClient {
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+
+	return &HTTPClient{config: config}
+}
+
+// SendDescription sends the offer to the endpoint, and reports the answer to the OnDescription handler
+func (c *HTTPClient) SendDescription(desc webrtc.SessionDescription) error {
+	if desc.Type != webrtc.SDPTypeOffer {
+		return fmt.Errorf("%w: %s", errUnexpectedType, desc.Type)
+	}
+
+	res, body, err := c.do(http.MethodPost, c.config.Endpoint, sdpContentType, desc.SDP)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusCreated {
+		return fmt.Errorf("%w: %s", errUnexpectedStatus, res.Status)
+	}
+
+	location, err := res.Location()
+	if err != nil {
+		return errMissingLocation
+	}
+
+	c.mu.Lock()
+	c.resource = location.String()
+	c.etag = res.Header.Get("ETag")
+	onDescription := c.onDescription
+	c.mu.Unlock()
+
+	if onDescription != nil {
+		onDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: body})
+	}
+
+	// Candidates gathered before the session resource existed
+	c.mu.Lock()
+	candidates := c.pendingCandidates
+	c.pendingCandidates = nil
+	c.mu.Unlock()
+
+	if len(candidates) == 0 {
+		return nil
+	}
+	return c.patch(candidates)
+}
+
+// SendCandidate sends a local ICE candidate to the session resource. Candidates are
+// held back until the session resource has been created by SendDescription.
+func (c *HTTPClient) SendCandidate(candidate webrtc.ICECandidateInit) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return errClosed
+	}
+	if c.resource == "" {
+		c.pendingCandidates = append(c.pendingCandidates, candidate)
+		c.mu.Unlock()
+		return nil
+	}
+	c.mu.Unlock()
+
+	return c.patch([]webrtc.ICECandidateInit{candidate})
+}
+
+func (c *HTTPClient) patch(candidates []webrtc.ICECandidateInit) error {
+	c.mu.Lock()
+	resource := c.resource
+	c.mu.Unlock()
+
+	res, body, err := c.do(http.MethodPatch, resource, sdpFragmentContentType, marshalSDPFragment(candidates))
+	if err != nil {
+		return err
+	}
+
+	switch res.StatusCode {
+	case http.StatusNoContent:
+		return nil
+	case http.StatusOK:
+		c.mu.Lock()
+		onCandidate := c.onCandidate
+		c.mu.Unlock()
+
+		if onCandidate != nil {
+			for _, candidate := range unmarshalSDPFragment(body) {
+				onCandidate(candidate)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", errUnexpectedStatus, res.Status)
+	}
+}
+
+// OnDescription sets a handler called with the answer of the endpoint
+func (c *HTTPClient) OnDescription(f func(desc webrtc.SessionDescription)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.onDescription = f
+}
+
+// OnCandidate sets a handler called with the ICE candidates returned by the endpoint
+func (c *HTTPClient) OnCandidate(f func(candidate webrtc.ICECandidateInit)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.onCandidate = f
+}
+
+// Close deletes the session resource
+func (c *HTTPClient) Close() error {
+	c.mu.Lock()
+	resource := c.resource
+	closed := c.closed
+	c.closed = true
+	c.mu.Unlock()
+
+	if closed || resource == "" {
+		return nil
+	}
+
+	res, _, err := c.do(http.MethodDelete, resource, "", "")
+	if err != nil {
+		return err
+	}
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("%w: %s", errUnexpectedStatus, res.Status)
+	}
+
+	return nil
+}
+
+// do sends a request and reads the whole response body
+func (c *HTTPClient) do(method, target, contentType, body string) (*http.Response, string, error) {
+	req, err := http.NewRequest(method, target, strings.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	}
+
+	c.mu.Lock()
+	if method == http.MethodPatch && c.etag != "" {
+		req.Header.Set("If-Match", c.etag)
+	}
+	c.mu.Unlock()
+
+	res, err := c.config.Client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return res, string(b), nil
+}
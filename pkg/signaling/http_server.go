@@ -0,0 +1,400 @@
+package signaling
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/randutil"
+	"github.com/pion/webrtc/v3"
+)
+
+const (
+	defaultAnswerTimeout = 10 * time.Second
+
+	// maxBodySize bounds the size of the offers and SDP fragments sent by clients
+	maxBodySize = 64 * 1024
+
+	sessionIDLength  = 16
+	sessionIDCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+// HTTPServerConfig configures a HTTPServer
+type HTTPServerConfig struct {
+	// Token is the bearer token clients must send, no authorization is required when empty
+	Token string
+	// OnSession is called with each new session, before the offer is delivered to it.
+	// The session must be answered with SendDescription.
+	OnSession func(s *HTTPSession)
+	// AnswerTimeout bounds the time waiting for the answer of a session and for the end of
+	// its candidates, 10 seconds when zero
+	AnswerTimeout time.Duration
+	// Path is the path of the endpoint, offers are only accepted on it. When empty, offers
+	// are accepted on any path but the ones of the sessions.
+	Path string
+}
+
+// HTTPServer is a http.Handler for WHIP (ingest) and WHEP (egress) endpoints. It must be
+// served for both the endpoint path and its sub paths, e.g. "/whip" and "/whip/", as
+// session resources are created below the endpoint.
+type HTTPServer struct {
+	config HTTPServerConfig
+
+	mu       sync.Mutex
+	sessions map[string]*HTTPSession
+}
+
+// NewHTTPServer creates a HTTPServer
+func NewHTTPServer(config HTTPServerConfig) *HTTPServer {
+	if config.AnswerTimeout == 0 {
+		config.AnswerTimeout = defaultAnswerTimeout
+	}
+
+	return &HTTPServer{
+		config:   config,
+		sessions: map[string]*HTTPSession{},
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (s *HTTPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.config.Token != "" &&
+		subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.config.Token)) != 1 {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	session := s.session(path.Base(r.URL.Path))
+	if r.Method == http.MethodPost {
+		if session != nil || !s.isEndpoint(r.URL.Path) {
+			w.Header().Set("Allow", "PATCH, DELETE")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		s.handleOffer(w, r)
+		return
+	}
+
+	if session == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPatch:
+		s.handleCandidates(w, r, session)
+	case http.MethodDelete:
+		if err := session.Close(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *HTTPServer) handleOffer(w http.ResponseWriter, r *http.Request) {
+	if !hasContentType(r, sdpContentType) {
+		http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	offer, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := randutil.GenerateCryptoRandomString(sessionIDLength, sessionIDCharset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	session := &HTTPSession{
+		server:   s,
+		id:       id,
+		answer:   make(chan webrtc.SessionDescription, 1),
+		gathered: make(chan struct{}),
+	}
+	s.mu.Lock()
+	s.sessions[id] = session
+	s.mu.Unlock()
+
+	if s.config.OnSession != nil {
+		s.config.OnSession(session)
+	}
+
+	session.mu.Lock()
+	onDescription := session.onDescription
+	session.mu.Unlock()
+
+	if onDescription == nil {
+		s.removeSession(id)
+		http.Error(w, errNoDescriptionHandler.Error(), http.StatusInternalServerError)
+		return
+	}
+	onDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: string(offer)})
+
+	timer := time.NewTimer(s.config.AnswerTimeout)
+	defer timer.Stop()
+
+	var answer webrtc.SessionDescription
+	select {
+	case answer = <-session.answer:
+	case <-timer.C:
+		_ = session.Close()
+		http.Error(w, http.StatusText(http.StatusGatewayTimeout), http.StatusGatewayTimeout)
+		return
+	case <-r.Context().Done():
+		_ = session.Close()
+		return
+	}
+
+	// The client only gets the candidates of the server in the responses to its requests, so
+	// the ones gathered after its last PATCH request would be lost. Unless the answer already
+	// has all of them, gathering is waited for and the candidates are added to the answer.
+	// Candidates gathered after the timeout are still returned to the next PATCH requests.
+	if !strings.Contains(answer.SDP, attributeEndOfCandidates) {
+		select {
+		case <-session.gathered:
+		case <-timer.C:
+		case <-r.Context().Done():
+			_ = session.Close()
+			return
+		}
+	}
+
+	description, err := session.addPendingCandidates(answer.SDP)
+	if err != nil {
+		_ = session.Close()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", sdpContentType)
+	w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/")+"/"+id)
+	w.Header().Set("ETag", session.etag())
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write([]byte(description))
+}
+
+func (s *HTTPServer) handleCandidates(w http.ResponseWriter, r *http.Request, session *HTTPSession) {
+	if !hasContentType(r, sdpFragmentContentType) {
+		http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+		return
+	}
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && ifMatch != "*" && ifMatch != session.etag() {
+		http.Error(w, http.StatusText(http.StatusPreconditionFailed), http.StatusPreconditionFailed)
+		return
+	}
+
+	fragment, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+
+	session.mu.Lock()
+	onCandidate := session.onCandidate
+	session.mu.Unlock()
+
+	if onCandidate != nil {
+		for _, candidate := range unmarshalSDPFragment(string(fragment)) {
+			onCandidate(candidate)
+		}
+	}
+
+	// Candidates of the server are returned in the response of the next PATCH request
+	session.mu.Lock()
+	candidates := session.pendingCandidates
+	session.pendingCandidates = nil
+	session.mu.Unlock()
+
+	if len(candidates) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", sdpFragmentContentType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(marshalSDPFragment(candidates)))
+}
+
+// isEndpoint returns true if offers are accepted on the path
+func (s *HTTPServer) isEndpoint(p string) bool {
+	if s.config.Path == "" {
+		return true
+	}
+
+	return strings.TrimSuffix(p, "/") == strings.TrimSuffix(s.config.Path, "/")
+}
+
+// readBody reads the body of the request, it responds with an error and returns false if
+// the body can't be read or is larger than maxBodySize
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	switch {
+	case err != nil && len(body) >= maxBodySize:
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return nil, false
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	return body, true
+}
+
+// hasContentType returns true if the media type of the request is mediaType, parameters are ignored
+func hasContentType(r *http.Request, mediaType string) bool {
+	parsed, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && parsed == mediaType
+}
+
+func (s *HTTPServer) session(id string) *HTTPSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sessions[id]
+}
+
+// removeSession returns false when the session was already removed
+func (s *HTTPServer) removeSession(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[id]; !ok {
+		return false
+	}
+	delete(s.sessions, id)
+
+	return true
+}
+
+// HTTPSession is the Signaler of a session created on a HTTPServer. OnDescription is called
+// once with the offer of the client, which must be answered with SendDescription.
+type HTTPSession struct {
+	server   *HTTPServer
+	id       string
+	answer   chan webrtc.SessionDescription
+	gathered chan struct{}
+
+	mu                sync.Mutex
+	answered          bool
+	gatheringComplete bool
+	pendingCandidates []webrtc.ICECandidateInit
+	onDescription     func(desc webrtc.SessionDescription)
+	onCandidate       func(candidate webrtc.ICECandidateInit)
+	onClose           func()
+}
+
+// ID returns the identifier of the session resource
+func (s *HTTPSession) ID() string {
+	return s.id
+}
+
+func (s *HTTPSession) etag() string {
+	return `"` + s.id + `"`
+}
+
+// SendDescription sends the answer to the offer of the client
+func (s *HTTPSession) SendDescription(desc webrtc.SessionDescription) error {
+	if desc.Type != webrtc.SDPTypeAnswer {
+		return fmt.Errorf("%w: %s", errUnexpectedType, desc.Type)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.answered {
+		return errAnswerAlreadySent
+	}
+	s.answered = true
+	s.answer <- desc
+
+	return nil
+}
+
+// SendCandidate queues a local ICE candidate. The candidates sent before the answer is
+// returned to the client are added to it, the later ones are sent in the response to the
+// next PATCH request of the client. An empty candidate signals the end of the candidates,
+// the answer is held back until it is sent unless it already has all the candidates.
+func (s *HTTPSession) SendCandidate(candidate webrtc.ICECandidateInit) error {
+	if s.server.session(s.id) == nil {
+		return errClosed
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pendingCandidates = append(s.pendingCandidates, candidate)
+	if candidate.Candidate == "" && !s.gatheringComplete {
+		s.gatheringComplete = true
+		close(s.gathered)
+	}
+
+	return nil
+}
+
+// addPendingCandidates adds the queued candidates to the answer
+func (s *HTTPSession) addPendingCandidates(answer string) (string, error) {
+	s.mu.Lock()
+	candidates := s.pendingCandidates
+	s.pendingCandidates = nil
+	s.mu.Unlock()
+
+	if len(candidates) == 0 {
+		return answer, nil
+	}
+
+	return addCandidatesToSDP(answer, candidates)
+}
+
+// OnDescription sets a handler called with the offer of the client
+func (s *HTTPSession) OnDescription(f func(desc webrtc.SessionDescription)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onDescription = f
+}
+
+// OnCandidate sets a handler called with the ICE candidates of the client
+func (s *HTTPSession) OnCandidate(f func(candidate webrtc.ICECandidateInit)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onCandidate = f
+}
+
+// OnClose sets a handler called once the session is closed, either by the client
+// deleting the session resource or by Close
+func (s *HTTPSession) OnClose(f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onClose = f
+}
+
+// Close removes the session resource
+func (s *HTTPSession) Close() error {
+	if !s.server.removeSession(s.id) {
+		return nil
+	}
+
+	s.mu.Lock()
+	onClose := s.onClose
+	s.mu.Unlock()
+
+	if onClose != nil {
+		onClose()
+	}
+
+	return nil
+}
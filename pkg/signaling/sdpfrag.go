@@ -0,0 +1,141 @@
+package signaling
+
+import (
+	"bufio"
+	"strings"
+
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3"
+)
+
+const (
+	sdpFragmentContentType = "application/trickle-ice-sdpfrag"
+
+	attributeICEUfrag        = "a=ice-ufrag:"
+	attributeMid             = "a=mid:"
+	attributeCandidate       = "a=candidate:"
+	attributeEndOfCandidates = "a=end-of-candidates"
+
+	// mediaPlaceholder starts a media section of a SDP fragment
+	mediaPlaceholder = "m=audio 9 UDP/TLS/RTP/SAVPF 0"
+)
+
+// marshalSDPFragment encodes candidates as a SDP fragment, used by WHIP and WHEP for trickle ICE
+// https://www.rfc-editor.org/rfc/rfc8840#section-9
+// Each media section of the fragment stands for the media section of the session with the same
+// index, so placeholder sections are added up to the m-line index of the candidates. The m= lines
+// themselves carry no information and are ignored when parsing.
+func marshalSDPFragment(candidates []webrtc.ICECandidateInit) string {
+	var b strings.Builder
+	if len(candidates) > 0 && candidates[0].UsernameFragment != nil && *candidates[0].UsernameFragment != "" {
+		b.WriteString(attributeICEUfrag + *candidates[0].UsernameFragment + "\r\n")
+	}
+
+	section, mid := -1, ""
+	for _, candidate := range candidates {
+		candidateMid := ""
+		if candidate.SDPMid != nil {
+			candidateMid = *candidate.SDPMid
+		}
+
+		switch {
+		case candidate.SDPMLineIndex != nil && int(*candidate.SDPMLineIndex) > section:
+			for section < int(*candidate.SDPMLineIndex) {
+				b.WriteString(mediaPlaceholder + "\r\n")
+				section, mid = section+1, ""
+			}
+		case candidate.SDPMLineIndex == nil && candidateMid != "" && candidateMid != mid:
+			b.WriteString(mediaPlaceholder + "\r\n")
+			section, mid = section+1, ""
+		}
+
+		if candidateMid != "" && candidateMid != mid {
+			mid = candidateMid
+			b.WriteString(attributeMid + mid + "\r\n")
+		}
+
+		// An empty candidate signals the end of the candidates
+		if candidate.Candidate == "" {
+			b.WriteString(attributeEndOfCandidates + "\r\n")
+		} else {
+			b.WriteString(attributeCandidate + strings.TrimPrefix(candidate.Candidate, "candidate:") + "\r\n")
+		}
+	}
+
+	return b.String()
+}
+
+// unmarshalSDPFragment returns the candidates of a SDP fragment
+func unmarshalSDPFragment(fragment string) []webrtc.ICECandidateInit {
+	candidates := []webrtc.ICECandidateInit{}
+
+	var ufrag, mid *string
+	var index *uint16
+	scanner := bufio.NewScanner(strings.NewReader(fragment))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "m="):
+			next := uint16(0)
+			if index != nil {
+				next = *index + 1
+			}
+			index, mid = &next, nil
+		case strings.HasPrefix(line, attributeICEUfrag):
+			value := strings.TrimPrefix(line, attributeICEUfrag)
+			ufrag = &value
+		case strings.HasPrefix(line, attributeMid):
+			value := strings.TrimPrefix(line, attributeMid)
+			mid = &value
+		case strings.HasPrefix(line, attributeCandidate):
+			candidates = append(candidates, webrtc.ICECandidateInit{
+				Candidate:        strings.TrimPrefix(line, "a="),
+				SDPMid:           mid,
+				SDPMLineIndex:    index,
+				UsernameFragment: ufrag,
+			})
+		case line == attributeEndOfCandidates:
+			candidates = append(candidates, webrtc.ICECandidateInit{SDPMid: mid, SDPMLineIndex: index, UsernameFragment: ufrag})
+		}
+	}
+
+	return candidates
+}
+
+// addCandidatesToSDP adds candidates to the media sections of a session description they
+// belong to, found from their mid or else their m-line index. Candidates with neither are
+// added to the first media section, which carries the transport when bundling.
+func addCandidatesToSDP(description string, candidates []webrtc.ICECandidateInit) (string, error) {
+	parsed := &sdp.SessionDescription{}
+	if err := parsed.Unmarshal([]byte(description)); err != nil {
+		return "", err
+	}
+	if len(parsed.MediaDescriptions) == 0 {
+		return description, nil
+	}
+
+	for _, candidate := range candidates {
+		media := parsed.MediaDescriptions[0]
+		for i, m := range parsed.MediaDescriptions {
+			mid, _ := m.Attribute(sdp.AttrKeyMID)
+			if (candidate.SDPMid != nil && *candidate.SDPMid != "" && *candidate.SDPMid == mid) ||
+				(candidate.SDPMid == nil && candidate.SDPMLineIndex != nil && int(*candidate.SDPMLineIndex) == i) {
+				media = m
+				break
+			}
+		}
+
+		if candidate.Candidate == "" {
+			media.WithPropertyAttribute(sdp.AttrKeyEndOfCandidates)
+		} else {
+			media.WithValueAttribute(sdp.AttrKeyCandidate, strings.TrimPrefix(candidate.Candidate, "candidate:"))
+		}
+	}
+
+	out, err := parsed.Marshal()
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
@@ -0,0 +1,34 @@
+// Package signaling provides transports to exchange session descriptions and
+// trickle ICE candidates between two peers.
+package signaling
+
+import (
+	"errors"
+
+	"github.com/pion/webrtc/v3"
+)
+
+var (
+	errClosed               = errors.New("signaling: closed")
+	errUnexpectedType       = errors.New("signaling: unexpected description type")
+	errUnexpectedStatus     = errors.New("signaling: unexpected HTTP status")
+	errMissingLocation      = errors.New("signaling: response has no Location header")
+	errAnswerAlreadySent    = errors.New("signaling: answer already sent")
+	errUnknownMessageType   = errors.New("signaling: unknown message type")
+	errNoDescriptionHandler = errors.New("signaling: no OnDescription handler to answer the offer")
+)
+
+// Signaler sends local session descriptions and trickle ICE candidates to the remote peer,
+// and reports the ones received from it
+type Signaler interface {
+	// SendDescription sends a local offer or answer
+	SendDescription(desc webrtc.SessionDescription) error
+	// SendCandidate sends a local ICE candidate
+	SendCandidate(candidate webrtc.ICECandidateInit) error
+	// OnDescription sets a handler called with each description received from the remote peer
+	OnDescription(f func(desc webrtc.SessionDescription))
+	// OnCandidate sets a handler called with each ICE candidate received from the remote peer
+	OnCandidate(f func(candidate webrtc.ICECandidateInit))
+	// Close ends the signaling session
+	Close() error
+}
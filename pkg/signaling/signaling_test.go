@@ -0,0 +1,346 @@
+//go:build !js
+// +build !js
+
+package signaling
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/v2/test"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func stringPtr(s string) *string { return &s }
+
+func uint16Ptr(i uint16) *uint16 { return &i }
+
+func TestSDPFragment(t *testing.T) {
+	candidates := []webrtc.ICECandidateInit{
+		{
+			Candidate:        "candidate:1 1 udp 2130706431 192.168.1.1 5000 typ host",
+			SDPMid:           stringPtr("0"),
+			SDPMLineIndex:    uint16Ptr(0),
+			UsernameFragment: stringPtr("ufrag"),
+		},
+		{
+			Candidate:        "candidate:2 1 udp 1694498815 1.2.3.4 6000 typ srflx raddr 0.0.0.0 rport 0",
+			SDPMid:           stringPtr("0"),
+			SDPMLineIndex:    uint16Ptr(0),
+			UsernameFragment: stringPtr("ufrag"),
+		},
+		{
+			SDPMid:           stringPtr("0"),
+			SDPMLineIndex:    uint16Ptr(0),
+			UsernameFragment: stringPtr("ufrag"),
+		},
+	}
+
+	fragment := marshalSDPFragment(candidates)
+	assert.Equal(t, "a=ice-ufrag:ufrag\r\n"+
+		"m=audio 9 UDP/TLS/RTP/SAVPF 0\r\n"+
+		"a=mid:0\r\n"+
+		"a=candidate:1 1 udp 2130706431 192.168.1.1 5000 typ host\r\n"+
+		"a=candidate:2 1 udp 1694498815 1.2.3.4 6000 typ srflx raddr 0.0.0.0 rport 0\r\n"+
+		"a=end-of-candidates\r\n", fragment)
+	assert.Equal(t, candidates, unmarshalSDPFragment(fragment))
+
+	// A candidate of the second media section, with a mid that isn't its index
+	candidates = []webrtc.ICECandidateInit{
+		{
+			Candidate:     "candidate:1 1 udp 2130706431 192.168.1.1 5000 typ host",
+			SDPMid:        stringPtr("video"),
+			SDPMLineIndex: uint16Ptr(1),
+		},
+	}
+
+	fragment = marshalSDPFragment(candidates)
+	assert.Equal(t, "m=audio 9 UDP/TLS/RTP/SAVPF 0\r\n"+
+		"m=audio 9 UDP/TLS/RTP/SAVPF 0\r\n"+
+		"a=mid:video\r\n"+
+		"a=candidate:1 1 udp 2130706431 192.168.1.1 5000 typ host\r\n", fragment)
+	assert.Equal(t, candidates, unmarshalSDPFragment(fragment))
+}
+
+func TestAddCandidatesToSDP(t *testing.T) {
+	description := "v=0\r\n" +
+		"o=- 0 0 IN IP4 0.0.0.0\r\n" +
+		"s=-\r\n" +
+		"t=0 0\r\n" +
+		"m=audio 9 UDP/TLS/RTP/SAVPF 111\r\n" +
+		"c=IN IP4 0.0.0.0\r\n" +
+		"a=mid:audio\r\n" +
+		"m=video 9 UDP/TLS/RTP/SAVPF 96\r\n" +
+		"c=IN IP4 0.0.0.0\r\n" +
+		"a=mid:video\r\n"
+
+	withCandidates, err := addCandidatesToSDP(description, []webrtc.ICECandidateInit{
+		{Candidate: "candidate:1 1 udp 2130706431 192.168.1.1 5000 typ host", SDPMid: stringPtr("video")},
+		{Candidate: "candidate:2 1 udp 2130706431 192.168.1.1 5002 typ host", SDPMLineIndex: uint16Ptr(0)},
+		{SDPMid: stringPtr("video")},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "v=0\r\n"+
+		"o=- 0 0 IN IP4 0.0.0.0\r\n"+
+		"s=-\r\n"+
+		"t=0 0\r\n"+
+		"m=audio 9 UDP/TLS/RTP/SAVPF 111\r\n"+
+		"c=IN IP4 0.0.0.0\r\n"+
+		"a=mid:audio\r\n"+
+		"a=candidate:2 1 udp 2130706431 192.168.1.1 5002 typ host\r\n"+
+		"m=video 9 UDP/TLS/RTP/SAVPF 96\r\n"+
+		"c=IN IP4 0.0.0.0\r\n"+
+		"a=mid:video\r\n"+
+		"a=candidate:1 1 udp 2130706431 192.168.1.1 5000 typ host\r\n"+
+		"a=end-of-candidates\r\n", withCandidates)
+}
+
+func TestWebSocket(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	serverDescriptions := make(chan webrtc.SessionDescription, 1)
+	serverCandidates := make(chan webrtc.ICECandidateInit, 1)
+	serverClosed := make(chan struct{})
+
+	server := httptest.NewServer(WebSocketHandler(func(w *WebSocket) {
+		w.OnDescription(func(desc webrtc.SessionDescription) {
+			serverDescriptions <- desc
+			assert.NoError(t, w.SendDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: "answer"}))
+		})
+		w.OnCandidate(func(candidate webrtc.ICECandidateInit) {
+			serverCandidates <- candidate
+			assert.NoError(t, w.SendCandidate(webrtc.ICECandidateInit{Candidate: "candidate:server"}))
+		})
+		go func() {
+			<-w.Done()
+			close(serverClosed)
+		}()
+	}))
+	defer server.Close()
+
+	client, err := DialWebSocket("ws"+strings.TrimPrefix(server.URL, "http"), server.URL)
+	assert.NoError(t, err)
+
+	clientDescriptions := make(chan webrtc.SessionDescription, 1)
+	clientCandidates := make(chan webrtc.ICECandidateInit, 1)
+	client.OnDescription(func(desc webrtc.SessionDescription) {
+		clientDescriptions <- desc
+	})
+	client.OnCandidate(func(candidate webrtc.ICECandidateInit) {
+		clientCandidates <- candidate
+	})
+
+	assert.NoError(t, client.SendDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: "offer"}))
+	assert.Equal(t, webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: "offer"}, <-serverDescriptions)
+	assert.Equal(t, webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: "answer"}, <-clientDescriptions)
+
+	assert.NoError(t, client.SendCandidate(webrtc.ICECandidateInit{Candidate: "candidate:client"}))
+	assert.Equal(t, "candidate:client", (<-serverCandidates).Candidate)
+	assert.Equal(t, "candidate:server", (<-clientCandidates).Candidate)
+
+	assert.NoError(t, client.Close())
+	<-serverClosed
+	assert.ErrorIs(t, client.SendCandidate(webrtc.ICECandidateInit{}), errClosed)
+}
+
+// signal connects a PeerConnection to a Signaler
+func signal(t *testing.T, pc *webrtc.PeerConnection, s Signaler) {
+	pc.OnICECandidate(func(c *webrtc.ICECandidate) {
+		// An empty candidate signals the end of the candidates
+		candidate := webrtc.ICECandidateInit{}
+		if c != nil {
+			candidate = c.ToJSON()
+		}
+		assert.NoError(t, s.SendCandidate(candidate))
+	})
+	s.OnCandidate(func(candidate webrtc.ICECandidateInit) {
+		assert.NoError(t, pc.AddICECandidate(candidate))
+	})
+}
+
+func TestHTTP(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	serverPC, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+
+	serverConnected := make(chan struct{})
+	serverPC.OnICEConnectionStateChange(func(state webrtc.ICEConnectionState) {
+		if state == webrtc.ICEConnectionStateConnected {
+			close(serverConnected)
+		}
+	})
+
+	sessionClosed := make(chan struct{})
+	handler := NewHTTPServer(HTTPServerConfig{
+		Token: "secret",
+		OnSession: func(s *HTTPSession) {
+			signal(t, serverPC, s)
+			s.OnDescription(func(offer webrtc.SessionDescription) {
+				assert.NoError(t, serverPC.SetRemoteDescription(offer))
+
+				answer, answerErr := serverPC.CreateAnswer(nil)
+				assert.NoError(t, answerErr)
+				assert.NoError(t, serverPC.SetLocalDescription(answer))
+				assert.NoError(t, s.SendDescription(answer))
+				assert.ErrorIs(t, s.SendDescription(answer), errAnswerAlreadySent)
+			})
+			s.OnClose(func() {
+				close(sessionClosed)
+			})
+		},
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/whip", handler)
+	mux.Handle("/whip/", handler)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// Requests without the token are rejected
+	unauthorized := NewHTTPClient(HTTPClientConfig{Endpoint: server.URL + "/whip"})
+	assert.ErrorIs(t, unauthorized.SendDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: "offer"}), errUnexpectedStatus)
+
+	clientPC, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+
+	clientConnected := make(chan struct{})
+	clientPC.OnICEConnectionStateChange(func(state webrtc.ICEConnectionState) {
+		if state == webrtc.ICEConnectionStateConnected {
+			close(clientConnected)
+		}
+	})
+
+	client := NewHTTPClient(HTTPClientConfig{Endpoint: server.URL + "/whip", Token: "secret"})
+	signal(t, clientPC, client)
+	client.OnDescription(func(answer webrtc.SessionDescription) {
+		assert.NoError(t, clientPC.SetRemoteDescription(answer))
+	})
+
+	_, err = clientPC.AddTransceiverFromKind(webrtc.RTPCodecTypeVideo, webrtc.RTPTransceiverInit{
+		Direction: webrtc.RTPTransceiverDirectionSendonly,
+	})
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, client.SendDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer}), errUnexpectedType)
+
+	offer, err := clientPC.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, clientPC.SetLocalDescription(offer))
+	assert.NoError(t, client.SendDescription(offer))
+
+	<-clientConnected
+	<-serverConnected
+
+	assert.NoError(t, client.Close())
+	<-sessionClosed
+
+	assert.NoError(t, clientPC.Close())
+	assert.NoError(t, serverPC.Close())
+}
+
+// Assert that the candidates the server gathers after the answer is created, and after the
+// last PATCH request of the client, are delivered in the answer
+func TestHTTPServer_LateCandidates(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	answer := "v=0\r\n" +
+		"o=- 0 0 IN IP4 0.0.0.0\r\n" +
+		"s=-\r\n" +
+		"t=0 0\r\n" +
+		"m=video 9 UDP/TLS/RTP/SAVPF 96\r\n" +
+		"c=IN IP4 0.0.0.0\r\n" +
+		"a=mid:0\r\n"
+
+	handler := NewHTTPServer(HTTPServerConfig{
+		Token: "secret",
+		OnSession: func(s *HTTPSession) {
+			s.OnDescription(func(webrtc.SessionDescription) {
+				assert.NoError(t, s.SendDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: answer}))
+
+				go func() {
+					time.Sleep(50 * time.Millisecond)
+					assert.NoError(t, s.SendCandidate(webrtc.ICECandidateInit{
+						Candidate:     "candidate:1 1 udp 2130706431 192.168.1.1 5000 typ host",
+						SDPMid:        stringPtr("0"),
+						SDPMLineIndex: uint16Ptr(0),
+					}))
+					assert.NoError(t, s.SendCandidate(webrtc.ICECandidateInit{SDPMid: stringPtr("0"), SDPMLineIndex: uint16Ptr(0)}))
+				}()
+			})
+		},
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	// The media type is compared without its parameters
+	req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("offer"))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/sdp; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer secret")
+
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	body, err := ioutil.ReadAll(res.Body)
+	assert.NoError(t, err)
+	assert.NoError(t, res.Body.Close())
+
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	assert.Equal(t, answer+
+		"a=candidate:1 1 udp 2130706431 192.168.1.1 5000 typ host\r\n"+
+		"a=end-of-candidates\r\n", string(body))
+}
+
+// Assert that offers are only accepted on the endpoint, and that large bodies are rejected
+func TestHTTPServer_Requests(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	handler := NewHTTPServer(HTTPServerConfig{
+		Path: "/whip",
+		OnSession: func(s *HTTPSession) {
+			s.OnDescription(func(webrtc.SessionDescription) {
+				assert.NoError(t, s.SendDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: "a=end-of-candidates\r\n"}))
+			})
+		},
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	do := func(method, path, contentType, body string) *http.Response {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		assert.NoError(t, res.Body.Close())
+		return res
+	}
+
+	largeBody := strings.Repeat("a", maxBodySize+1)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, do(http.MethodPost, "/whip", sdpContentType, largeBody).StatusCode)
+	assert.Equal(t, http.StatusMethodNotAllowed, do(http.MethodPost, "/other", sdpContentType, "offer").StatusCode)
+
+	res := do(http.MethodPost, "/whip/", sdpContentType, "offer")
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	location := res.Header.Get("Location")
+
+	assert.Equal(t, http.StatusMethodNotAllowed, do(http.MethodPost, location, sdpContentType, "offer").StatusCode)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, do(http.MethodPatch, location, sdpFragmentContentType, largeBody).StatusCode)
+	assert.Equal(t, http.StatusNoContent, do(http.MethodPatch, location, sdpFragmentContentType, "").StatusCode)
+}
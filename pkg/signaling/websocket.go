@@ -0,0 +1,160 @@
+package signaling
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/pion/webrtc/v3"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	messageTypeDescription = "description"
+	messageTypeCandidate   = "candidate"
+)
+
+// message is the JSON encoding of the WebSocket messages
+type message struct {
+	Type        string                     `json:"type"`
+	Description *webrtc.SessionDescription `json:"description,omitempty"`
+	Candidate   *webrtc.ICECandidateInit   `json:"candidate,omitempty"`
+}
+
+// WebSocket is a Signaler exchanging JSON messages over a WebSocket connection.
+// Descriptions are sent as {"type":"description","description":{"type":"offer","sdp":"..."}}
+// and candidates as {"type":"candidate","candidate":{"candidate":"candidate:...",...}}.
+type WebSocket struct {
+	conn   *websocket.Conn
+	sendMu sync.Mutex
+
+	mu            sync.Mutex
+	onDescription func(desc webrtc.SessionDescription)
+	onCandidate   func(candidate webrtc.ICECandidateInit)
+
+	done chan struct{}
+}
+
+// NewWebSocket creates a WebSocket Signaler on an established connection, and starts reading from it
+func NewWebSocket(conn *websocket.Conn) *WebSocket {
+	w := newWebSocket(conn)
+	go w.readLoop()
+
+	return w
+}
+
+func newWebSocket(conn *websocket.Conn) *WebSocket {
+	return &WebSocket{
+		conn: conn,
+		done: make(chan struct{}),
+	}
+}
+
+// DialWebSocket connects to a WebSocket signaling server
+func DialWebSocket(url, origin string) (*WebSocket, error) {
+	conn, err := websocket.Dial(url, "", origin)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWebSocket(conn), nil
+}
+
+// WebSocketHandler returns a http.Handler accepting WebSocket signaling connections.
+// f is called with each new connection before any message is read from it.
+func WebSocketHandler(f func(w *WebSocket)) http.Handler {
+	return websocket.Handler(func(conn *websocket.Conn) {
+		w := newWebSocket(conn)
+		f(w)
+		w.readLoop()
+	})
+}
+
+// SendDescription sends a local offer or answer
+func (w *WebSocket) SendDescription(desc webrtc.SessionDescription) error {
+	return w.send(message{Type: messageTypeDescription, Description: &desc})
+}
+
+// SendCandidate sends a local ICE candidate
+func (w *WebSocket) SendCandidate(candidate webrtc.ICECandidateInit) error {
+	return w.send(message{Type: messageTypeCandidate, Candidate: &candidate})
+}
+
+func (w *WebSocket) send(m message) error {
+	select {
+	case <-w.done:
+		return errClosed
+	default:
+	}
+
+	w.sendMu.Lock()
+	defer w.sendMu.Unlock()
+
+	return websocket.JSON.Send(w.conn, m)
+}
+
+// OnDescription sets a handler called with each description received from the remote peer
+func (w *WebSocket) OnDescription(f func(desc webrtc.SessionDescription)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.onDescription = f
+}
+
+// OnCandidate sets a handler called with each ICE candidate received from the remote peer
+func (w *WebSocket) OnCandidate(f func(candidate webrtc.ICECandidateInit)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.onCandidate = f
+}
+
+// Done returns a channel that is closed once the connection is closed
+func (w *WebSocket) Done() <-chan struct{} {
+	return w.done
+}
+
+// Close closes the connection
+func (w *WebSocket) Close() error {
+	err := w.conn.Close()
+	<-w.done
+
+	return err
+}
+
+func (w *WebSocket) readLoop() {
+	defer close(w.done)
+
+	for {
+		var m message
+		if err := websocket.JSON.Receive(w.conn, &m); err != nil {
+			return
+		}
+
+		if err := w.handle(m); err != nil {
+			_ = w.conn.Close()
+			return
+		}
+	}
+}
+
+func (w *WebSocket) handle(m message) error {
+	w.mu.Lock()
+	onDescription, onCandidate := w.onDescription, w.onCandidate
+	w.mu.Unlock()
+
+	switch {
+	case m.Type == messageTypeDescription && m.Description != nil:
+		if onDescription != nil {
+			onDescription(*m.Description)
+		}
+	case m.Type == messageTypeCandidate && m.Candidate != nil:
+		if onCandidate != nil {
+			onCandidate(*m.Candidate)
+		}
+	default:
+		return fmt.Errorf("%w: %q", errUnknownMessageType, m.Type)
+	}
+
+	return nil
+}
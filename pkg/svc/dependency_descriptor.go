@@ -0,0 +1,155 @@
+package svc
+
+// DependencyDescriptorURI is the URI of the AV1 Dependency Descriptor RTP header extension
+// https://aomediacodec.github.io/av1-rtp-spec/#dependency-descriptor-rtp-header-extension
+const DependencyDescriptorURI = "https://aomediacodec.github.io/av1-rtp-spec/#dependency-descriptor-rtp-header-extension"
+
+const (
+	dependencyDescriptorMandatoryLen = 3
+	templateIDCount                  = 64
+	maxTemplateCount                 = templateIDCount
+)
+
+// next_layer_idc values of the template layers
+const (
+	nextLayerSame     = 0
+	nextLayerTemporal = 1
+	nextLayerSpatial  = 2
+	nextLayerNone     = 3
+)
+
+// DependencyDescriptor is an AV1 Dependency Descriptor header extension. Only the fields
+// needed to find the layers of a frame are parsed.
+type DependencyDescriptor struct {
+	StartOfFrame bool
+	EndOfFrame   bool
+	TemplateID   uint8
+	FrameNumber  uint16
+
+	// Structure is set if the descriptor carries a template dependency structure, they are
+	// usually sent with key frames
+	Structure *DependencyStructure
+}
+
+// DependencyStructure is the template dependency structure of a Dependency Descriptor,
+// the templates map the frames to their spatial and temporal layers
+type DependencyStructure struct {
+	TemplateIDOffset  uint8
+	DecodeTargetCount int
+
+	// TemplateSpatialIDs and TemplateTemporalIDs are the layers of each template
+	TemplateSpatialIDs  []uint8
+	TemplateTemporalIDs []uint8
+}
+
+// Unmarshal parses a Dependency Descriptor header extension
+func (d *DependencyDescriptor) Unmarshal(b []byte) error {
+	if len(b) < dependencyDescriptorMandatoryLen {
+		return errShortDescriptor
+	}
+
+	d.StartOfFrame = b[0]&0x80 != 0
+	d.EndOfFrame = b[0]&0x40 != 0
+	d.TemplateID = b[0] & 0x3F
+	d.FrameNumber = uint16(b[1])<<8 | uint16(b[2])
+	d.Structure = nil
+
+	if len(b) == dependencyDescriptorMandatoryLen {
+		return nil
+	}
+
+	r := &bitReader{data: b[dependencyDescriptorMandatoryLen:]}
+	structurePresent, err := r.readFlag()
+	if err != nil {
+		return err
+	}
+	// active_decode_targets_present, custom_dtis, custom_fdiffs and custom_chains flags
+	if _, err = r.read(4); err != nil {
+		return err
+	}
+	if !structurePresent {
+		return nil
+	}
+
+	structure := &DependencyStructure{}
+	if err := structure.unmarshal(r); err != nil {
+		return err
+	}
+	d.Structure = structure
+
+	return nil
+}
+
+// unmarshal parses the start of a template dependency structure, up to the layers of the
+// templates
+func (s *DependencyStructure) unmarshal(r *bitReader) error {
+	offset, err := r.read(6)
+	if err != nil {
+		return err
+	}
+	decodeTargetCount, err := r.read(5)
+	if err != nil {
+		return err
+	}
+	s.TemplateIDOffset = uint8(offset)
+	s.DecodeTargetCount = int(decodeTargetCount) + 1
+
+	var spatialID, temporalID uint8
+	for {
+		if len(s.TemplateSpatialIDs) == maxTemplateCount {
+			return errInvalidDescriptor
+		}
+		s.TemplateSpatialIDs = append(s.TemplateSpatialIDs, spatialID)
+		s.TemplateTemporalIDs = append(s.TemplateTemporalIDs, temporalID)
+
+		nextLayer, err := r.read(2)
+		if err != nil {
+			return err
+		}
+		switch nextLayer {
+		case nextLayerSame:
+		case nextLayerTemporal:
+			temporalID++
+		case nextLayerSpatial:
+			spatialID, temporalID = spatialID+1, 0
+		case nextLayerNone:
+			return nil
+		}
+	}
+}
+
+// Layers returns the spatial and temporal layers of the frames of a template
+func (s *DependencyStructure) Layers(templateID uint8) (spatial, temporal uint8, ok bool) {
+	index := (int(templateID) + templateIDCount - int(s.TemplateIDOffset)) % templateIDCount
+	if index >= len(s.TemplateSpatialIDs) {
+		return 0, 0, false
+	}
+
+	return s.TemplateSpatialIDs[index], s.TemplateTemporalIDs[index], true
+}
+
+// bitReader reads the big endian bit fields of a Dependency Descriptor
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bitReader) read(bits int) (uint32, error) {
+	if r.pos+bits > len(r.data)*8 {
+		return 0, errShortDescriptor
+	}
+
+	var value uint32
+	for i := 0; i < bits; i++ {
+		bit := r.data[r.pos/8] >> (7 - r.pos%8) & 0x01
+		value = value<<1 | uint32(bit)
+		r.pos++
+	}
+
+	return value, nil
+}
+
+func (r *bitReader) readFlag() (bool, error) {
+	value, err := r.read(1)
+	return value == 1, err
+}
@@ -0,0 +1,244 @@
+// Package svc implements the selection of the spatial and temporal layers of scalable VP9
+// and AV1 streams, for SFUs forwarding a TrackRemote to a TrackLocalStaticRTP
+package svc
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+)
+
+// AllLayers selects all the spatial or temporal layers of a stream
+const AllLayers = 0xFF
+
+const (
+	mimeTypeVP9 = "video/vp9"
+	mimeTypeAV1 = "video/av1"
+
+	// AV1 aggregation header bit set on the first packet of a coded video sequence
+	av1NMask = 0x08
+
+	// offsetHistorySize is the number of sequence numbers before the highest one whose offset
+	// is kept for packets received out of order, it divides the sequence number space
+	offsetHistorySize = 512
+)
+
+var (
+	errUnsupportedCodec  = errors.New("layers can only be selected for VP9 and AV1")
+	errShortDescriptor   = errors.New("dependency descriptor is too short")
+	errInvalidDescriptor = errors.New("invalid dependency descriptor")
+)
+
+// frameInfo is what the filter needs to know about the frame of a packet
+type frameInfo struct {
+	hasLayers         bool
+	spatial, temporal uint8
+
+	// pictureStart is set on the first packet of the base spatial layer of a picture
+	pictureStart bool
+	endOfFrame   bool
+	keyFrame     bool
+	switchingUp  bool
+}
+
+// LayerFilter selects the packets of the spatial and temporal layers of a VP9 or AV1 stream
+// that are forwarded. The layers of VP9 packets are read from their payload descriptor, the
+// ones of AV1 packets from their Dependency Descriptor header extension. Packets without
+// layer information are always forwarded.
+//
+// The sequence numbers of the forwarded packets are rewritten to hide the dropped ones,
+// and the marker bit is set on the last packet of the highest selected spatial layer.
+type LayerFilter struct {
+	mu sync.Mutex
+
+	isAV1        bool
+	descriptorID uint8
+	structure    *DependencyStructure
+
+	spatial, temporal             uint8
+	targetSpatial, targetTemporal uint8
+
+	hasSequenceNumber     bool
+	highestSequenceNumber uint16
+	dropped               uint16
+	offsets               [offsetHistorySize]uint16
+}
+
+// NewLayerFilter returns a LayerFilter for a stream of the given codec, all its layers are
+// selected until SetLayers is called
+func NewLayerFilter(mimeType string, opts ...Option) (*LayerFilter, error) {
+	f := &LayerFilter{
+		spatial:        AllLayers,
+		temporal:       AllLayers,
+		targetSpatial:  AllLayers,
+		targetTemporal: AllLayers,
+	}
+
+	switch strings.ToLower(mimeType) {
+	case mimeTypeVP9:
+	case mimeTypeAV1:
+		f.isAV1 = true
+	default:
+		return nil, errUnsupportedCodec
+	}
+
+	for _, o := range opts {
+		o(f)
+	}
+
+	return f, nil
+}
+
+// SetLayers selects the highest spatial and temporal layers that are forwarded. Lower layers
+// are switched to at the start of the next picture. A higher spatial layer is switched to at
+// the next key frame, and a higher temporal layer at the next switching point.
+func (f *LayerFilter) SetLayers(spatial, temporal uint8) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.targetSpatial, f.targetTemporal = spatial, temporal
+}
+
+// Layers returns the spatial and temporal layers that are currently forwarded
+func (f *LayerFilter) Layers() (spatial, temporal uint8) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.spatial, f.temporal
+}
+
+// Filter returns true if the packet should be forwarded, its sequence number and marker bit
+// are then rewritten. Packets received out of order are given the sequence number offset in
+// effect at their position in the stream, and don't switch layers. Packets older than the
+// last offsetHistorySize sequence numbers are dropped.
+func (f *LayerFilter) Filter(p *rtp.Packet) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := f.frameInfo(p)
+	if err != nil {
+		return false, err
+	}
+
+	distance := p.SequenceNumber - f.highestSequenceNumber
+	isNewer := !f.hasSequenceNumber || (distance != 0 && distance < 0x8000)
+	if !isNewer && f.highestSequenceNumber-p.SequenceNumber >= offsetHistorySize {
+		return false, nil
+	}
+
+	if info.pictureStart && isNewer {
+		f.switchLayers(info)
+	}
+
+	forward := !info.hasLayers || (info.spatial <= f.spatial && info.temporal <= f.temporal)
+
+	if isNewer {
+		// The skipped packets keep the current offset, if they are received later
+		if f.hasSequenceNumber {
+			for i := uint16(1); i < distance && i <= offsetHistorySize; i++ {
+				f.offsets[(p.SequenceNumber-i)%offsetHistorySize] = f.dropped
+			}
+		}
+
+		f.hasSequenceNumber, f.highestSequenceNumber = true, p.SequenceNumber
+		if !forward {
+			f.dropped++
+		}
+		f.offsets[p.SequenceNumber%offsetHistorySize] = f.dropped
+	}
+	if !forward {
+		return false, nil
+	}
+
+	p.SequenceNumber -= f.offsets[p.SequenceNumber%offsetHistorySize]
+	if info.hasLayers && info.endOfFrame && info.spatial == f.spatial {
+		p.Marker = true
+	}
+
+	return true, nil
+}
+
+// switchLayers switches to the selected layers if the picture allows it
+func (f *LayerFilter) switchLayers(info frameInfo) {
+	if f.targetSpatial < f.spatial || (f.targetSpatial > f.spatial && info.keyFrame) {
+		f.spatial = f.targetSpatial
+	}
+	if f.targetTemporal < f.temporal || (f.targetTemporal > f.temporal && info.switchingUp) {
+		f.temporal = f.targetTemporal
+	}
+}
+
+func (f *LayerFilter) frameInfo(p *rtp.Packet) (frameInfo, error) {
+	if len(p.Payload) == 0 {
+		return frameInfo{}, nil
+	}
+
+	if f.isAV1 {
+		return f.av1FrameInfo(p)
+	}
+
+	vp9 := &codecs.VP9Packet{}
+	if _, err := vp9.Unmarshal(p.Payload); err != nil {
+		return frameInfo{}, err
+	}
+	if !vp9.L {
+		return frameInfo{pictureStart: vp9.B, keyFrame: !vp9.P, switchingUp: true}, nil
+	}
+
+	return frameInfo{
+		hasLayers:    true,
+		spatial:      vp9.SID,
+		temporal:     vp9.TID,
+		pictureStart: vp9.B && vp9.SID == 0,
+		endOfFrame:   vp9.E,
+		keyFrame:     !vp9.P,
+		switchingUp:  vp9.U || vp9.TID == 0,
+	}, nil
+}
+
+func (f *LayerFilter) av1FrameInfo(p *rtp.Packet) (frameInfo, error) {
+	extension := p.GetExtension(f.descriptorID)
+	if f.descriptorID == 0 || extension == nil {
+		return frameInfo{}, nil
+	}
+
+	descriptor := &DependencyDescriptor{}
+	if err := descriptor.Unmarshal(extension); err != nil {
+		return frameInfo{}, err
+	}
+	if descriptor.Structure != nil {
+		f.structure = descriptor.Structure
+	}
+	if f.structure == nil {
+		return frameInfo{}, nil
+	}
+
+	spatial, temporal, ok := f.structure.Layers(descriptor.TemplateID)
+	if !ok {
+		return frameInfo{}, errInvalidDescriptor
+	}
+
+	return frameInfo{
+		hasLayers:    true,
+		spatial:      spatial,
+		temporal:     temporal,
+		pictureStart: descriptor.StartOfFrame && spatial == 0,
+		endOfFrame:   descriptor.EndOfFrame,
+		keyFrame:     p.Payload[0]&av1NMask != 0,
+		switchingUp:  temporal == 0,
+	}, nil
+}
+
+// An Option configures a LayerFilter.
+type Option func(f *LayerFilter)
+
+// WithDependencyDescriptorID sets the negotiated ID of the Dependency Descriptor header
+// extension, it is required to select the layers of AV1 streams
+func WithDependencyDescriptorID(id uint8) Option {
+	return func(f *LayerFilter) {
+		f.descriptorID = id
+	}
+}
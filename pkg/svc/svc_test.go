@@ -0,0 +1,163 @@
+package svc
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+// vp9Packet returns a single packet picture of a VP9 stream in non-flexible mode
+func vp9Packet(sequenceNumber uint16, keyFrame bool, spatial, temporal uint8) *rtp.Packet {
+	descriptor := byte(0xAC) // I, L, B and E set
+	if !keyFrame {
+		descriptor |= 0x40
+	}
+
+	return &rtp.Packet{
+		Header:  rtp.Header{SequenceNumber: sequenceNumber},
+		Payload: []byte{descriptor, 0x00, temporal<<5 | spatial<<1, 0x00, 0xAA},
+	}
+}
+
+// l2t2 returns the packets of a VP9 stream with 2 spatial and 2 temporal layers
+func l2t2(firstSequenceNumber uint16, pictures int) []*rtp.Packet {
+	var packets []*rtp.Packet
+	for i := 0; i < pictures; i++ {
+		temporal := uint8(i % 2)
+		for spatial := uint8(0); spatial < 2; spatial++ {
+			packets = append(packets, vp9Packet(firstSequenceNumber+uint16(len(packets)), i == 0, spatial, temporal))
+		}
+	}
+
+	return packets
+}
+
+type forwardedPacket struct {
+	sequenceNumber uint16
+	marker         bool
+}
+
+func filterPackets(t *testing.T, f *LayerFilter, packets []*rtp.Packet) []forwardedPacket {
+	var forwarded []forwardedPacket
+	for _, p := range packets {
+		forward, err := f.Filter(p)
+		assert.NoError(t, err)
+		if forward {
+			forwarded = append(forwarded, forwardedPacket{p.SequenceNumber, p.Marker})
+		}
+	}
+
+	return forwarded
+}
+
+func TestLayerFilter_VP9(t *testing.T) {
+	_, err := NewLayerFilter("video/VP8")
+	assert.Equal(t, errUnsupportedCodec, err)
+
+	f, err := NewLayerFilter("video/VP9")
+	assert.NoError(t, err)
+
+	// All layers are forwarded by default
+	assert.Equal(t, 4, len(filterPackets(t, f, l2t2(65534, 2))))
+
+	// Lower layers are selected at the next picture
+	f.SetLayers(0, 0)
+	assert.Equal(t, []forwardedPacket{{2, true}, {3, true}}, filterPackets(t, f, l2t2(2, 4)))
+	spatial, temporal := f.Layers()
+	assert.Equal(t, uint8(0), spatial)
+	assert.Equal(t, uint8(0), temporal)
+
+	// The temporal layer is raised at the next switching point, the spatial layer waits for a
+	// key frame
+	f.SetLayers(1, 1)
+	assert.Equal(t, []forwardedPacket{{4, true}, {5, true}}, filterPackets(t, f, []*rtp.Packet{
+		vp9Packet(10, false, 0, 0), vp9Packet(11, false, 1, 0),
+		vp9Packet(12, false, 0, 1), vp9Packet(13, false, 1, 1),
+	}))
+	spatial, temporal = f.Layers()
+	assert.Equal(t, uint8(0), spatial)
+	assert.Equal(t, uint8(1), temporal)
+
+	assert.Equal(t, []forwardedPacket{{6, false}, {7, true}, {8, false}, {9, true}}, filterPackets(t, f, l2t2(14, 2)))
+}
+
+func TestDependencyDescriptor(t *testing.T) {
+	d := &DependencyDescriptor{}
+	assert.Equal(t, errShortDescriptor, d.Unmarshal([]byte{0x80, 0x00}))
+
+	assert.NoError(t, d.Unmarshal([]byte{0x81, 0x00, 0x05}))
+	assert.True(t, d.StartOfFrame)
+	assert.False(t, d.EndOfFrame)
+	assert.Equal(t, uint8(1), d.TemplateID)
+	assert.Equal(t, uint16(5), d.FrameNumber)
+	assert.Nil(t, d.Structure)
+
+	// A structure of 2 templates for 2 temporal layers
+	assert.NoError(t, d.Unmarshal([]byte{0xC0, 0x00, 0x01, 0x80, 0x01, 0x70}))
+	assert.True(t, d.EndOfFrame)
+	assert.Equal(t, &DependencyStructure{
+		DecodeTargetCount:   2,
+		TemplateSpatialIDs:  []uint8{0, 0},
+		TemplateTemporalIDs: []uint8{0, 1},
+	}, d.Structure)
+
+	spatial, temporal, ok := d.Structure.Layers(1)
+	assert.True(t, ok)
+	assert.Equal(t, uint8(0), spatial)
+	assert.Equal(t, uint8(1), temporal)
+	_, _, ok = d.Structure.Layers(2)
+	assert.False(t, ok)
+
+	assert.Equal(t, errShortDescriptor, d.Unmarshal([]byte{0xC0, 0x00, 0x01, 0x80}))
+}
+
+func av1Packet(t *testing.T, sequenceNumber uint16, descriptor []byte, keyFrame bool) *rtp.Packet {
+	aggregationHeader := byte(0x10)
+	if keyFrame {
+		aggregationHeader |= av1NMask
+	}
+
+	p := &rtp.Packet{
+		Header:  rtp.Header{Version: 2, SequenceNumber: sequenceNumber},
+		Payload: []byte{aggregationHeader, 0x30, 0xAA},
+	}
+	assert.NoError(t, p.Header.SetExtension(5, descriptor))
+
+	return p
+}
+
+func TestLayerFilter_AV1(t *testing.T) {
+	f, err := NewLayerFilter("video/AV1", WithDependencyDescriptorID(5))
+	assert.NoError(t, err)
+	f.SetLayers(0, 0)
+
+	forwarded := filterPackets(t, f, []*rtp.Packet{
+		av1Packet(t, 100, []byte{0xC0, 0x00, 0x00, 0x80, 0x01, 0x70}, true),
+		av1Packet(t, 101, []byte{0xC1, 0x00, 0x01}, false),
+		av1Packet(t, 102, []byte{0xC0, 0x00, 0x02}, false),
+		av1Packet(t, 103, []byte{0xC1, 0x00, 0x03}, false),
+	})
+	assert.Equal(t, []forwardedPacket{{100, true}, {101, true}}, forwarded)
+
+	_, err = f.Filter(av1Packet(t, 104, []byte{0xC5, 0x00, 0x04}, false))
+	assert.Equal(t, errInvalidDescriptor, err)
+}
+
+// Assert that packets received out of order keep the sequence number offset of their position
+func TestLayerFilter_OutOfOrder(t *testing.T) {
+	f, err := NewLayerFilter("video/VP9")
+	assert.NoError(t, err)
+	f.SetLayers(0, AllLayers)
+
+	// The base layer of the second picture is received after its dropped spatial layer
+	packets := l2t2(100, 3)
+	packets[2], packets[3] = packets[3], packets[2]
+	assert.Equal(t, []forwardedPacket{{100, true}, {101, true}, {102, true}}, filterPackets(t, f, packets))
+
+	// Packets older than the history are dropped
+	highest := uint16(105)
+	forward, err := f.Filter(vp9Packet(highest-offsetHistorySize, false, 0, 0))
+	assert.NoError(t, err)
+	assert.False(t, forward)
+}
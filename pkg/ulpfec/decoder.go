@@ -0,0 +1,228 @@
+package ulpfec
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	// mediaHistorySize is how many media packets are kept to recover from
+	mediaHistorySize = 512
+
+	// maxPendingFECPackets is how many FEC packets are kept while waiting for media packets
+	maxPendingFECPackets = 64
+
+	// fecHistorySize is how many sequence numbers of FEC packets are kept to shift the
+	// sequence numbers of late media packets
+	fecHistorySize = 64
+)
+
+type fecPacket struct {
+	fecHeader
+	body []byte
+}
+
+// decoder recovers lost media packets of a stream from the FEC packets protecting it. As FEC
+// packets are removed from the stream, it also shifts the sequence numbers of the media packets
+// so that the stream has no gaps where FEC packets were received.
+type decoder struct {
+	mu sync.Mutex
+
+	ssrc uint32
+
+	media      map[uint16]protectedPacket
+	mediaOrder []uint16
+	fecPackets []*fecPacket
+
+	// fecSequenceNumbers are the sequence numbers of the latest FEC packets, and
+	// olderFECPackets the number of FEC packets received before them
+	fecSequenceNumbers []uint16
+	olderFECPackets    uint16
+
+	// recovered holds packets that have been restored but not read yet
+	recovered []*rtp.Packet
+}
+
+func newDecoder(ssrc uint32) *decoder {
+	return &decoder{
+		ssrc:  ssrc,
+		media: map[uint16]protectedPacket{},
+	}
+}
+
+// pushMedia stores a received media packet, with the payload type of its primary RED encoding.
+// false is returned if the packet has already been received or recovered, in which case it
+// should be dropped.
+func (d *decoder) pushMedia(packet *rtp.Packet) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.media[packet.SequenceNumber]; ok {
+		return false
+	}
+
+	protected, err := newProtectedPacket(&packet.Header, packet.Payload)
+	if err != nil {
+		return true
+	}
+
+	d.storeMedia(protected)
+	d.recover()
+	return true
+}
+
+// pushFEC stores the FEC block of the RED packet with the given sequence number, and tries to
+// recover the media packets it protects
+func (d *decoder) pushFEC(sequenceNumber uint16, block []byte) error {
+	fec := &fecPacket{}
+	n, err := fec.unmarshal(block)
+	if err != nil {
+		return err
+	}
+	fec.body = append([]byte{}, block[n:]...)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.fecSequenceNumbers) == fecHistorySize {
+		d.fecSequenceNumbers = d.fecSequenceNumbers[1:]
+		d.olderFECPackets++
+	}
+	d.fecSequenceNumbers = append(d.fecSequenceNumbers, sequenceNumber)
+
+	if len(d.fecPackets) == maxPendingFECPackets {
+		d.fecPackets = d.fecPackets[1:]
+	}
+	d.fecPackets = append(d.fecPackets, fec)
+	d.recover()
+	return nil
+}
+
+// popRecovered returns the oldest recovered packet that hasn't been read yet
+func (d *decoder) popRecovered() *rtp.Packet {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.recovered) == 0 {
+		return nil
+	}
+
+	packet := d.recovered[0]
+	d.recovered = d.recovered[1:]
+	return packet
+}
+
+// shift returns the sequence number of a media packet once the FEC packets sent before it
+// have been removed from the stream
+func (d *decoder) shift(sequenceNumber uint16) uint16 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	shift := d.olderFECPackets
+	for _, fecSequenceNumber := range d.fecSequenceNumbers {
+		if diff := sequenceNumber - fecSequenceNumber; diff != 0 && diff < 1<<15 {
+			shift++
+		}
+	}
+
+	return sequenceNumber - shift
+}
+
+func (d *decoder) storeMedia(packet protectedPacket) {
+	if len(d.mediaOrder) == mediaHistorySize {
+		delete(d.media, d.mediaOrder[0])
+		d.mediaOrder = d.mediaOrder[1:]
+	}
+
+	d.media[packet.sequenceNumber] = packet
+	d.mediaOrder = append(d.mediaOrder, packet.sequenceNumber)
+}
+
+// recover repeatedly applies the pending FEC packets until no more media packets can be restored.
+// FEC packets are dropped once all the packets they protect are known.
+func (d *decoder) recover() {
+	for recovered := true; recovered; {
+		recovered = false
+
+		pending := d.fecPackets[:0]
+		for _, fec := range d.fecPackets {
+			missing := 0
+			var missingSequenceNumber uint16
+			for _, offset := range fec.offsets {
+				if _, ok := d.media[fec.snBase+offset]; !ok {
+					missing++
+					missingSequenceNumber = fec.snBase + offset
+				}
+			}
+
+			switch missing {
+			case 0:
+				continue
+			case 1:
+				if packet, ok := d.recoverPacket(fec, missingSequenceNumber); ok {
+					d.recovered = append(d.recovered, packet)
+					recovered = true
+				}
+				continue
+			}
+
+			pending = append(pending, fec)
+		}
+		d.fecPackets = pending
+	}
+}
+
+func (d *decoder) recoverPacket(fec *fecPacket, sequenceNumber uint16) (*rtp.Packet, bool) {
+	firstByte := fec.firstByte
+	secondByte := fec.secondByte
+	length := fec.lengthRecovery
+	timestamp := fec.tsRecovery
+	body := append([]byte{}, fec.body...)
+
+	for _, offset := range fec.offsets {
+		media, ok := d.media[fec.snBase+offset]
+		if !ok {
+			continue
+		}
+
+		firstByte ^= media.firstByte
+		secondByte ^= media.secondByte
+		length ^= uint16(len(media.body))
+		timestamp ^= media.timestamp
+		if len(media.body) > len(body) {
+			return nil, false
+		}
+		for i := range media.body {
+			body[i] ^= media.body[i]
+		}
+	}
+
+	if int(length) > len(body) {
+		return nil, false
+	}
+
+	raw := make([]byte, rtpFixedHeaderSize+int(length))
+	raw[0] = rtpVersion<<6 | firstByte&0x3F
+	raw[1] = secondByte
+	binary.BigEndian.PutUint16(raw[2:], sequenceNumber)
+	binary.BigEndian.PutUint32(raw[4:], timestamp)
+	binary.BigEndian.PutUint32(raw[8:], d.ssrc)
+	copy(raw[rtpFixedHeaderSize:], body[:length])
+
+	packet := &rtp.Packet{}
+	if err := packet.Unmarshal(append([]byte{}, raw...)); err != nil {
+		return nil, false
+	}
+
+	d.storeMedia(protectedPacket{
+		sequenceNumber: sequenceNumber,
+		firstByte:      firstByte & 0x3F,
+		secondByte:     secondByte,
+		timestamp:      timestamp,
+		body:           raw[rtpFixedHeaderSize:],
+	})
+
+	return packet, true
+}
@@ -0,0 +1,119 @@
+package ulpfec
+
+import (
+	"sync"
+
+	"github.com/pion/rtp"
+)
+
+// encoder wraps the packets of a media stream in RED and generates FEC packets for them. Every
+// numMediaPackets media packets numFECPackets FEC packets are generated, each protecting an
+// interleaved subset of the group. FEC packets take sequence numbers of the stream, so the
+// sequence numbers of the following media packets are shifted.
+type encoder struct {
+	mu sync.Mutex
+
+	stream          Stream
+	numMediaPackets int
+	numFECPackets   int
+
+	sequenceNumberOffset uint16
+	packets              []protectedPacket
+}
+
+func newEncoder(stream Stream, numMediaPackets, numFECPackets int) *encoder {
+	return &encoder{
+		stream:          stream,
+		numMediaPackets: numMediaPackets,
+		numFECPackets:   numFECPackets,
+		packets:         make([]protectedPacket, 0, numMediaPackets),
+	}
+}
+
+// encode returns the packets to send for a media packet: the media packet wrapped in RED,
+// followed by the FEC packets once the group of the media packet is complete
+func (e *encoder) encode(header *rtp.Header, payload []byte) ([]*rtp.Packet, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	mediaHeader := header.Clone()
+	mediaHeader.SequenceNumber += e.sequenceNumberOffset
+
+	// FEC is computed on the media packet as it is restored by the receiver, without RED
+	protected, err := newProtectedPacket(&mediaHeader, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	redHeader := mediaHeader.Clone()
+	redHeader.PayloadType = e.stream.REDPayloadType
+	redPayload := make([]byte, 1+len(payload))
+	redPayload[0] = mediaHeader.PayloadType & 0x7F
+	copy(redPayload[1:], payload)
+	packets := []*rtp.Packet{{Header: redHeader, Payload: redPayload}}
+
+	// A FEC packet can't describe sequence numbers that are too far apart, start a new group
+	if len(e.packets) != 0 && protected.sequenceNumber-e.packets[0].sequenceNumber >= maxProtectedPackets {
+		e.packets = e.packets[:0]
+	}
+
+	e.packets = append(e.packets, protected)
+	if len(e.packets) < e.numMediaPackets {
+		return packets, nil
+	}
+
+	for i := 0; i < e.numFECPackets; i++ {
+		group := []protectedPacket{}
+		for j := i; j < len(e.packets); j += e.numFECPackets {
+			group = append(group, e.packets[j])
+		}
+
+		packets = append(packets, e.fecPacket(group, mediaHeader.SequenceNumber+uint16(i+1), &mediaHeader))
+	}
+
+	e.sequenceNumberOffset += uint16(e.numFECPackets)
+	e.packets = e.packets[:0]
+	return packets, nil
+}
+
+// fecPacket builds the FEC packet protecting group, sent after the media packet with the given header
+func (e *encoder) fecPacket(group []protectedPacket, sequenceNumber uint16, mediaHeader *rtp.Header) *rtp.Packet {
+	h := fecHeader{
+		snBase:  group[0].sequenceNumber,
+		offsets: make([]uint16, 0, len(group)),
+	}
+
+	for i := range group {
+		if len(group[i].body) > int(h.protectionLength) {
+			h.protectionLength = uint16(len(group[i].body))
+		}
+	}
+	body := make([]byte, h.protectionLength)
+
+	for i := range group {
+		h.firstByte ^= group[i].firstByte
+		h.secondByte ^= group[i].secondByte
+		h.tsRecovery ^= group[i].timestamp
+		h.lengthRecovery ^= uint16(len(group[i].body))
+		h.offsets = append(h.offsets, group[i].sequenceNumber-h.snBase)
+
+		for j := range group[i].body {
+			body[j] ^= group[i].body[j]
+		}
+	}
+
+	payload := make([]byte, 1+h.marshalSize()+len(body))
+	payload[0] = e.stream.ULPFECPayloadType & 0x7F
+	copy(payload[1+h.marshalTo(payload[1:]):], body)
+
+	return &rtp.Packet{
+		Header: rtp.Header{
+			Version:        rtpVersion,
+			PayloadType:    e.stream.REDPayloadType,
+			SequenceNumber: sequenceNumber,
+			Timestamp:      mediaHeader.Timestamp,
+			SSRC:           mediaHeader.SSRC,
+		},
+		Payload: payload,
+	}
+}
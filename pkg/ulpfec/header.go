@@ -0,0 +1,178 @@
+package ulpfec
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/pion/rtp"
+)
+
+var (
+	errPacketTooShort        = errors.New("ulpfec: packet is too short")
+	errUnsupportedFECHeader  = errors.New("ulpfec: FEC headers with the extension flag are not supported")
+	errInvalidREDBlockLength = errors.New("ulpfec: RED block is longer than the packet")
+)
+
+// protectedPacket is the part of a media packet covered by FEC: everything following the fixed
+// RTP header, which is the CSRC list, the header extension and the payload.
+type protectedPacket struct {
+	sequenceNumber uint16
+	// firstByte holds the P, X and CC fields of the RTP header
+	firstByte uint8
+	// secondByte holds the M and PT fields of the RTP header
+	secondByte uint8
+	timestamp  uint32
+	body       []byte
+}
+
+func newProtectedPacket(header *rtp.Header, payload []byte) (protectedPacket, error) {
+	raw, err := header.Marshal()
+	if err != nil {
+		return protectedPacket{}, err
+	}
+
+	body := make([]byte, len(raw)-rtpFixedHeaderSize+len(payload))
+	copy(body[copy(body, raw[rtpFixedHeaderSize:]):], payload)
+
+	return protectedPacket{
+		sequenceNumber: header.SequenceNumber,
+		firstByte:      raw[0] & 0x3F,
+		secondByte:     raw[1],
+		timestamp:      header.Timestamp,
+		body:           body,
+	}, nil
+}
+
+// fecHeader is the FEC header of a FEC packet followed by its level 0 header
+//
+//	 0                   1                   2                   3
+//	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|E|L|P|X|  CC   |M| PT recovery |            SN base            |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                          TS recovery                          |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|        length recovery        |       Protection Length       |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|             mask              |    mask cont. (if L = 1)      |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|    mask cont. (if L = 1)      |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+type fecHeader struct {
+	firstByte        uint8
+	secondByte       uint8
+	snBase           uint16
+	tsRecovery       uint32
+	lengthRecovery   uint16
+	protectionLength uint16
+	// offsets are the protected sequence numbers relative to snBase
+	offsets []uint16
+}
+
+func (h *fecHeader) longMask() bool {
+	for _, offset := range h.offsets {
+		if offset >= 16 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h *fecHeader) marshalSize() int {
+	if h.longMask() {
+		return headerSize + levelHeaderSize + 4
+	}
+
+	return headerSize + levelHeaderSize
+}
+
+func (h *fecHeader) marshalTo(b []byte) int {
+	long := h.longMask()
+
+	b[0] = h.firstByte & 0x3F
+	if long {
+		b[0] |= 0x40
+	}
+	b[1] = h.secondByte
+	binary.BigEndian.PutUint16(b[2:], h.snBase)
+	binary.BigEndian.PutUint32(b[4:], h.tsRecovery)
+	binary.BigEndian.PutUint16(b[8:], h.lengthRecovery)
+	binary.BigEndian.PutUint16(b[10:], h.protectionLength)
+
+	var mask uint64
+	for _, offset := range h.offsets {
+		mask |= 1 << (maxProtectedPackets - 1 - offset)
+	}
+	binary.BigEndian.PutUint16(b[12:], uint16(mask>>32))
+	if long {
+		binary.BigEndian.PutUint32(b[14:], uint32(mask))
+	}
+
+	return h.marshalSize()
+}
+
+// unmarshal parses the FEC header and the level 0 header, and returns their size
+func (h *fecHeader) unmarshal(b []byte) (int, error) {
+	if len(b) < headerSize+levelHeaderSize {
+		return 0, errPacketTooShort
+	}
+
+	if b[0]&0x80 != 0 {
+		return 0, errUnsupportedFECHeader
+	}
+
+	h.firstByte = b[0] & 0x3F
+	h.secondByte = b[1]
+	h.snBase = binary.BigEndian.Uint16(b[2:])
+	h.tsRecovery = binary.BigEndian.Uint32(b[4:])
+	h.lengthRecovery = binary.BigEndian.Uint16(b[8:])
+	h.protectionLength = binary.BigEndian.Uint16(b[10:])
+
+	size := headerSize + levelHeaderSize
+	mask := uint64(binary.BigEndian.Uint16(b[12:])) << 32
+	if b[0]&0x40 != 0 {
+		if len(b) < size+4 {
+			return 0, errPacketTooShort
+		}
+		mask |= uint64(binary.BigEndian.Uint32(b[14:]))
+		size += 4
+	}
+
+	h.offsets = h.offsets[:0]
+	for i := uint16(0); i < maxProtectedPackets; i++ {
+		if mask&(1<<(maxProtectedPackets-1-i)) != 0 {
+			h.offsets = append(h.offsets, i)
+		}
+	}
+
+	return size, nil
+}
+
+// primaryBlock returns the payload type and the data of the primary encoding of a RED
+// payload. The redundant encodings that precede it are skipped.
+func primaryBlock(payload []byte) (uint8, []byte, error) {
+	offset, redundantLength := 0, 0
+	for {
+		if offset >= len(payload) {
+			return 0, nil, errPacketTooShort
+		}
+
+		if payload[offset]&0x80 == 0 {
+			break
+		}
+
+		if offset+4 > len(payload) {
+			return 0, nil, errPacketTooShort
+		}
+		redundantLength += int(binary.BigEndian.Uint16(payload[offset+2:]) & 0x03FF)
+		offset += 4
+	}
+
+	start := offset + 1 + redundantLength
+	if start > len(payload) {
+		return 0, nil, errInvalidREDBlockLength
+	}
+
+	return payload[offset] & 0x7F, payload[start:], nil
+}
@@ -0,0 +1,162 @@
+package ulpfec
+
+import (
+	"errors"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/logging"
+	"github.com/pion/rtp"
+)
+
+var errInvalidProtection = errors.New("ulpfec: invalid number of media or FEC packets")
+
+// InterceptorFactory is a interceptor.Factory for a Interceptor
+type InterceptorFactory struct {
+	opts []Option
+}
+
+// NewInterceptor constructs a new InterceptorFactory
+func NewInterceptor(opts ...Option) (*InterceptorFactory, error) {
+	return &InterceptorFactory{opts: opts}, nil
+}
+
+// NewInterceptor constructs a new Interceptor
+func (f *InterceptorFactory) NewInterceptor(id string) (interceptor.Interceptor, error) {
+	i := &Interceptor{
+		numMediaPackets: 5,
+		numFECPackets:   1,
+		log:             logging.NewDefaultLoggerFactory().NewLogger("ulpfec"),
+	}
+
+	for _, opt := range f.opts {
+		if err := opt(i); err != nil {
+			return nil, err
+		}
+	}
+
+	if i.numMediaPackets < 1 || i.numFECPackets < 1 || i.numFECPackets > i.numMediaPackets ||
+		i.numMediaPackets > maxProtectedPackets {
+		return nil, errInvalidProtection
+	}
+
+	return i, nil
+}
+
+// Interceptor wraps the packets of local streams in RED and adds FEC packets to them, and
+// recovers lost packets of remote streams before they are read. Remote packets are returned
+// without their RED encapsulation, and with sequence numbers that skip the FEC packets.
+// Streams are only processed when their StreamInfo carries a Stream, see SetStream.
+//
+// Interceptors that add RTP header extensions to outgoing packets, and the NACK responder,
+// must be registered before this one: FEC must protect the header extensions, and NACKs
+// request the sequence numbers of the RED packets.
+type Interceptor struct {
+	interceptor.NoOp
+	numMediaPackets int
+	numFECPackets   int
+	log             logging.LeveledLogger
+}
+
+// BindLocalStream lets you modify any outgoing RTP packets. It is called once for per LocalStream. The returned method
+// will be called once per rtp packet.
+func (i *Interceptor) BindLocalStream(info *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+	stream, ok := getStream(info)
+	if !ok {
+		return writer
+	}
+
+	encoder := newEncoder(stream, i.numMediaPackets, i.numFECPackets)
+	return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+		packets, err := encoder.encode(header, payload)
+		if err != nil {
+			return 0, err
+		}
+
+		n, err := writer.Write(&packets[0].Header, packets[0].Payload, attributes)
+		if err != nil {
+			return n, err
+		}
+
+		for _, fecPacket := range packets[1:] {
+			if _, fecErr := writer.Write(&fecPacket.Header, fecPacket.Payload, interceptor.Attributes{}); fecErr != nil {
+				i.log.Warnf("failed sending FEC packet: %v", fecErr)
+			}
+		}
+
+		return n, nil
+	})
+}
+
+// BindRemoteStream lets you modify any incoming RTP packets. It is called once for per RemoteStream. The returned method
+// will be called once per rtp packet.
+func (i *Interceptor) BindRemoteStream(info *interceptor.StreamInfo, reader interceptor.RTPReader) interceptor.RTPReader {
+	stream, ok := getStream(info)
+	if !ok {
+		return reader
+	}
+
+	d := newDecoder(info.SSRC)
+	return interceptor.RTPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		for {
+			if recovered := d.popRecovered(); recovered != nil {
+				recovered.SequenceNumber = d.shift(recovered.SequenceNumber)
+				n, err := recovered.MarshalTo(b)
+				return n, withoutRTPHeader(a), err
+			}
+
+			n, attr, err := reader.Read(b, a)
+			if err != nil {
+				return n, attr, err
+			}
+
+			packet := &rtp.Packet{}
+			if err = packet.Unmarshal(append([]byte{}, b[:n]...)); err != nil {
+				return n, attr, nil
+			}
+
+			if packet.PayloadType == stream.REDPayloadType {
+				payloadType, block, redErr := primaryBlock(packet.Payload)
+				if redErr != nil {
+					i.log.Debugf("dropping RED packet: %v", redErr)
+					continue
+				}
+
+				if payloadType == stream.ULPFECPayloadType {
+					if fecErr := d.pushFEC(packet.SequenceNumber, block); fecErr != nil {
+						i.log.Debugf("dropping FEC packet: %v", fecErr)
+					}
+					continue
+				}
+
+				packet.PayloadType = payloadType
+				packet.Payload = block
+			}
+
+			// Packets that have already been recovered are dropped
+			if !d.pushMedia(packet) {
+				continue
+			}
+
+			packet.SequenceNumber = d.shift(packet.SequenceNumber)
+			n, err = packet.MarshalTo(b)
+			return n, withoutRTPHeader(attr), err
+		}
+	})
+}
+
+// withoutRTPHeader returns a copy of the attributes without the RTP header parsed by the
+// previous interceptors, as the packet has been rewritten
+func withoutRTPHeader(attributes interceptor.Attributes) interceptor.Attributes {
+	if attributes == nil {
+		return nil
+	}
+
+	copied := interceptor.Attributes{}
+	for key, value := range attributes {
+		if _, ok := value.(*rtp.Header); !ok {
+			copied[key] = value
+		}
+	}
+
+	return copied
+}
@@ -0,0 +1,31 @@
+package ulpfec
+
+import "github.com/pion/logging"
+
+// Option can be used to configure Interceptor
+type Option func(i *Interceptor) error
+
+// NumMediaPackets sets how many media packets are protected together, defaults to 5
+func NumMediaPackets(n int) Option {
+	return func(i *Interceptor) error {
+		i.numMediaPackets = n
+		return nil
+	}
+}
+
+// NumFECPackets sets how many FEC packets are generated for each group of media packets, defaults to 1.
+// Media packets are interleaved between the FEC packets, so up to n consecutive losses can be recovered.
+func NumFECPackets(n int) Option {
+	return func(i *Interceptor) error {
+		i.numFECPackets = n
+		return nil
+	}
+}
+
+// Log sets a logger for the interceptor
+func Log(log logging.LeveledLogger) Option {
+	return func(i *Interceptor) error {
+		i.log = log
+		return nil
+	}
+}
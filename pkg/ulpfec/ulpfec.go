@@ -0,0 +1,58 @@
+// Package ulpfec implements ULPFEC (RFC 5109) forward error correction for RTP streams. Media and
+// FEC packets are encapsulated in RED (RFC 2198) and sent on the SSRC of the media, which allows a
+// receiver to recover lost media packets without waiting for a retransmission.
+package ulpfec
+
+import (
+	"github.com/pion/interceptor"
+)
+
+const (
+	// MimeTypeRED is the MIME type of the RED encapsulation of video streams
+	MimeTypeRED = "video/red"
+
+	// MimeType is the MIME type of ULPFEC
+	MimeType = "video/ulpfec"
+
+	// headerSize is the size of the FEC header
+	headerSize = 10
+
+	// levelHeaderSize is the size of the level 0 header with a 16 bit mask, 4 bytes are added
+	// when the long mask is used
+	levelHeaderSize = 4
+
+	// maxProtectedPackets is the largest span of sequence numbers a FEC packet can describe
+	maxProtectedPackets = 48
+
+	rtpFixedHeaderSize = 12
+	rtpVersion         = 2
+)
+
+// Stream describes the RED and ULPFEC payload types negotiated for a media stream. It must be
+// set on the interceptor.StreamInfo of the stream for the interceptor to process it.
+type Stream struct {
+	// REDPayloadType is the payload type of the RED packets
+	REDPayloadType uint8
+	// ULPFECPayloadType is the payload type of the FEC blocks carried in RED
+	ULPFECPayloadType uint8
+}
+
+type streamAttributeKey struct{}
+
+// SetStream stores a Stream in the Attributes of a StreamInfo
+func SetStream(info *interceptor.StreamInfo, stream Stream) {
+	if info.Attributes == nil {
+		info.Attributes = interceptor.Attributes{}
+	}
+
+	info.Attributes.Set(streamAttributeKey{}, stream)
+}
+
+func getStream(info *interceptor.StreamInfo) (Stream, bool) {
+	if info == nil || info.Attributes == nil {
+		return Stream{}, false
+	}
+
+	stream, ok := info.Attributes.Get(streamAttributeKey{}).(Stream)
+	return stream, ok
+}
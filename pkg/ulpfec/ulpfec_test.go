@@ -0,0 +1,242 @@
+package ulpfec
+
+import (
+	"testing"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/internal/fectest"
+	"github.com/stretchr/testify/assert"
+)
+
+var testStream = Stream{REDPayloadType: 114, ULPFECPayloadType: 116}
+
+// fecBlock returns the FEC block of a RED packet
+func fecBlock(t *testing.T, packet *rtp.Packet) []byte {
+	payloadType, block, err := primaryBlock(packet.Payload)
+	assert.NoError(t, err)
+	assert.Equal(t, testStream.ULPFECPayloadType, payloadType)
+
+	return block
+}
+
+func TestFECHeader_RoundTrip(t *testing.T) {
+	fectest.HeaderRoundTrip(t, [][]uint16{
+		{0, 1, 2, 15},
+		{0, 16, 47},
+	}, func(offsets []uint16) (interface{}, []byte) {
+		h := fecHeader{
+			firstByte:        0x12,
+			secondByte:       0xE0,
+			snBase:           65530,
+			tsRecovery:       0xDEADBEEF,
+			lengthRecovery:   1200,
+			protectionLength: 1210,
+			offsets:          offsets,
+		}
+
+		b := make([]byte, h.marshalSize())
+		assert.Equal(t, len(b), h.marshalTo(b))
+
+		return h, b
+	}, func(b []byte) (interface{}, int, error) {
+		h := fecHeader{}
+		n, err := h.unmarshal(b)
+
+		return h, n, err
+	})
+
+	_, err := (&fecHeader{}).unmarshal(make([]byte, headerSize))
+	assert.Equal(t, errPacketTooShort, err)
+}
+
+func TestPrimaryBlock(t *testing.T) {
+	payloadType, block, err := primaryBlock([]byte{96, 0xAA, 0xBB})
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(96), payloadType)
+	assert.Equal(t, []byte{0xAA, 0xBB}, block)
+
+	// A redundant block of 1 byte precedes the primary one
+	payloadType, block, err = primaryBlock([]byte{0x80 | 96, 0x00, 0x00, 0x01, 96, 0xCC, 0xAA})
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(96), payloadType)
+	assert.Equal(t, []byte{0xAA}, block)
+
+	_, _, err = primaryBlock([]byte{0x80 | 96, 0x00, 0x00, 0x05, 96})
+	assert.Equal(t, errInvalidREDBlockLength, err)
+	_, _, err = primaryBlock([]byte{})
+	assert.Equal(t, errPacketTooShort, err)
+}
+
+func TestEncoder(t *testing.T) {
+	e := newEncoder(testStream, 2, 1)
+
+	packets, err := e.encode(&fectest.MediaPacket(10, []byte{0xAA}).Header, []byte{0xAA})
+	assert.NoError(t, err)
+	assert.Len(t, packets, 1)
+	assert.Equal(t, testStream.REDPayloadType, packets[0].PayloadType)
+	assert.Equal(t, uint16(10), packets[0].SequenceNumber)
+	assert.Equal(t, []byte{96, 0xAA}, packets[0].Payload)
+
+	packets, err = e.encode(&fectest.MediaPacket(11, []byte{0xBB}).Header, []byte{0xBB})
+	assert.NoError(t, err)
+	assert.Len(t, packets, 2)
+	assert.Equal(t, uint16(11), packets[0].SequenceNumber)
+	assert.Equal(t, uint16(12), packets[1].SequenceNumber)
+	assert.Equal(t, testStream.REDPayloadType, packets[1].PayloadType)
+	fecBlock(t, packets[1])
+
+	// The following media packets are shifted after the FEC packet
+	packets, err = e.encode(&fectest.MediaPacket(12, []byte{0xCC}).Header, []byte{0xCC})
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(13), packets[0].SequenceNumber)
+}
+
+func TestDecoder_Recover(t *testing.T) {
+	for _, lost := range []uint16{65534, 65535, 0, 1} {
+		e := newEncoder(testStream, 5, 1)
+		d := newDecoder(fectest.ProtectedSSRC)
+
+		var media []*rtp.Packet
+		var fecPackets []*rtp.Packet
+		for i := 0; i < 5; i++ {
+			packet := fectest.MediaPacket(uint16(65533+i), make([]byte, 10+i))
+			for j := range packet.Payload {
+				packet.Payload[j] = byte(i * j)
+			}
+			if i == 2 {
+				packet.CSRC = []uint32{42, 43}
+			}
+			if i == 3 {
+				assert.NoError(t, packet.Header.SetExtension(1, []byte{0x01, 0x02}))
+			}
+
+			packets, err := e.encode(&packet.Header, packet.Payload)
+			assert.NoError(t, err)
+			media = append(media, packet)
+			fecPackets = append(fecPackets, packets[1:]...)
+		}
+		assert.Len(t, fecPackets, 1)
+		assert.Equal(t, uint32(fectest.ProtectedSSRC), fecPackets[0].SSRC)
+
+		var expected *rtp.Packet
+		for _, packet := range media {
+			if packet.SequenceNumber == lost {
+				expected = packet
+				continue
+			}
+			assert.True(t, d.pushMedia(packet))
+		}
+		assert.Nil(t, d.popRecovered())
+
+		assert.NoError(t, d.pushFEC(fecPackets[0].SequenceNumber, fecBlock(t, fecPackets[0])))
+		recovered := d.popRecovered()
+		if assert.NotNil(t, recovered) {
+			assert.Equal(t, expected, recovered)
+		}
+
+		// A late copy of the recovered packet is dropped
+		assert.False(t, d.pushMedia(expected))
+	}
+}
+
+func TestDecoder_InterleavedBurst(t *testing.T) {
+	e := newEncoder(testStream, 6, 2)
+	d := newDecoder(fectest.ProtectedSSRC)
+
+	var fecPackets []*rtp.Packet
+	for i := uint16(0); i < 6; i++ {
+		packet := fectest.MediaPacket(i, []byte{byte(i), 0xAA, 0xBB})
+		packets, err := e.encode(&packet.Header, packet.Payload)
+		assert.NoError(t, err)
+		fecPackets = append(fecPackets, packets[1:]...)
+
+		// Lose two consecutive packets
+		if i != 2 && i != 3 {
+			assert.True(t, d.pushMedia(packet))
+		}
+	}
+	assert.Len(t, fecPackets, 2)
+
+	for _, fecPacket := range fecPackets {
+		assert.NoError(t, d.pushFEC(fecPacket.SequenceNumber, fecBlock(t, fecPacket)))
+	}
+
+	recovered := map[uint16][]byte{}
+	for packet := d.popRecovered(); packet != nil; packet = d.popRecovered() {
+		recovered[packet.SequenceNumber] = packet.Payload
+	}
+	assert.Equal(t, map[uint16][]byte{2: {2, 0xAA, 0xBB}, 3: {3, 0xAA, 0xBB}}, recovered)
+}
+
+func TestDecoder_Shift(t *testing.T) {
+	d := newDecoder(fectest.ProtectedSSRC)
+	block := make([]byte, headerSize+levelHeaderSize)
+
+	assert.NoError(t, d.pushFEC(65535, block))
+	assert.NoError(t, d.pushFEC(3, block))
+
+	assert.Equal(t, uint16(65534), d.shift(65534))
+	assert.Equal(t, uint16(65535), d.shift(0))
+	assert.Equal(t, uint16(1), d.shift(2))
+	assert.Equal(t, uint16(3), d.shift(5))
+}
+
+func TestInterceptor(t *testing.T) {
+	factory, err := NewInterceptor(NumMediaPackets(2))
+	assert.NoError(t, err)
+
+	i, err := factory.NewInterceptor("")
+	assert.NoError(t, err)
+
+	info := &interceptor.StreamInfo{SSRC: fectest.ProtectedSSRC}
+	SetStream(info, testStream)
+
+	// Sender, media is wrapped in RED and followed by FEC on the same SSRC
+	var written []*rtp.Packet
+	writer := i.BindLocalStream(info, interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, _ interceptor.Attributes) (int, error) {
+		written = append(written, &rtp.Packet{Header: header.Clone(), Payload: append([]byte{}, payload...)})
+		return header.MarshalSize() + len(payload), nil
+	}))
+
+	for seq := uint16(0); seq < 4; seq++ {
+		packet := fectest.MediaPacket(seq, []byte{byte(seq), 0xFF})
+		_, err = writer.Write(&packet.Header, packet.Payload, nil)
+		assert.NoError(t, err)
+	}
+	assert.Len(t, written, 6)
+	for _, packet := range written {
+		assert.Equal(t, testStream.REDPayloadType, packet.PayloadType)
+		assert.Equal(t, uint32(fectest.ProtectedSSRC), packet.SSRC)
+	}
+
+	// Receiver, the first media packet is lost and returned before the next one read. The
+	// packets are unwrapped, and the sequence numbers of the FEC packets are skipped.
+	queue := make(chan *rtp.Packet, 6)
+	reader := i.BindRemoteStream(info, interceptor.RTPReaderFunc(func(in []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+		n, err := (<-queue).MarshalTo(in)
+		return n, a, err
+	}))
+	for _, packet := range written[1:] {
+		queue <- packet
+	}
+
+	b := make([]byte, 1500)
+	read := func() *rtp.Packet {
+		n, _, readErr := reader.Read(b, nil)
+		assert.NoError(t, readErr)
+
+		packet := &rtp.Packet{}
+		assert.NoError(t, packet.Unmarshal(append([]byte{}, b[:n]...)))
+		return packet
+	}
+
+	for _, seq := range []uint16{1, 0, 2, 3} {
+		packet := read()
+		assert.Equal(t, seq, packet.SequenceNumber)
+		assert.Equal(t, uint8(96), packet.PayloadType)
+		assert.Equal(t, []byte{byte(seq), 0xFF}, packet.Payload)
+	}
+
+	assert.NoError(t, i.Close())
+}
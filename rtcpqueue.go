@@ -0,0 +1,76 @@
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"io"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/transport/v2/deadline"
+	"github.com/pion/transport/v2/packetio"
+)
+
+// rtcpQueueSize is the number of RTCP packets kept for the application once an RTPSender
+// or an RTPReceiver reads the RTCP of a stream itself. Older packets are dropped if they
+// aren't read.
+const rtcpQueueSize = 64
+
+type rtcpQueueItem struct {
+	data       []byte
+	attributes interceptor.Attributes
+}
+
+// rtcpQueue holds the RTCP packets read by an RTPSender or an RTPReceiver until the
+// application reads them
+type rtcpQueue struct {
+	packets      chan rtcpQueueItem
+	readDeadline *deadline.Deadline
+	err          error
+}
+
+func newRTCPQueue() *rtcpQueue {
+	return &rtcpQueue{
+		packets:      make(chan rtcpQueueItem, rtcpQueueSize),
+		readDeadline: deadline.New(),
+	}
+}
+
+func (q *rtcpQueue) push(b []byte, attributes interceptor.Attributes) {
+	item := rtcpQueueItem{data: append([]byte{}, b...), attributes: attributes}
+	for {
+		select {
+		case q.packets <- item:
+			return
+		default:
+		}
+
+		// The queue is full, the oldest packet is dropped to make room
+		select {
+		case <-q.packets:
+		default:
+		}
+	}
+}
+
+// close makes read return err once the queued packets are read
+func (q *rtcpQueue) close(err error) {
+	q.err = err
+	close(q.packets)
+}
+
+func (q *rtcpQueue) read(b []byte) (int, interceptor.Attributes, error) {
+	select {
+	case item, ok := <-q.packets:
+		if !ok {
+			return 0, nil, q.err
+		}
+		if len(b) < len(item.data) {
+			return 0, nil, io.ErrShortBuffer
+		}
+
+		return copy(b, item.data), item.attributes, nil
+	case <-q.readDeadline.Done():
+		return 0, nil, packetio.ErrTimeout
+	}
+}
@@ -0,0 +1,38 @@
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/v2/packetio"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RTCPQueue(t *testing.T) {
+	q := newRTCPQueue()
+
+	// The oldest packets are dropped once the queue is full
+	for i := 0; i < rtcpQueueSize+2; i++ {
+		q.push([]byte{byte(i)}, nil)
+	}
+
+	b := make([]byte, 1)
+	for i := 2; i < rtcpQueueSize+2; i++ {
+		n, _, err := q.read(b)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{byte(i)}, b[:n])
+	}
+
+	q.readDeadline.Set(time.Now())
+	_, _, err := q.read(b)
+	assert.ErrorIs(t, err, packetio.ErrTimeout)
+
+	q.readDeadline.Set(time.Time{})
+	q.close(io.EOF)
+	_, _, err = q.read(b)
+	assert.ErrorIs(t, err, io.EOF)
+}
@@ -5,4 +5,20 @@ package webrtc
 // http://draft.ortc.org/#dom-rtcrtpencodingparameters
 type RTPEncodingParameters struct {
 	RTPCodingParameters
+
+	// Active indicates that this encoding is being sent. Packets of an inactive
+	// encoding are dropped before reaching the network.
+	Active bool `json:"active"`
+
+	// MaxBitrate is the maximum bitrate in bits per second the encoding should
+	// be sent with. A value of 0 means no limit.
+	MaxBitrate uint64 `json:"maxBitrate"`
+
+	// MaxFramerate is the maximum number of frames per second the encoding
+	// should be sent with. A value of 0 means no limit.
+	MaxFramerate float64 `json:"maxFramerate"`
+
+	// ScaleResolutionDownBy is the factor the resolution of the encoding should
+	// be scaled down by. A value of 0 means no scaling.
+	ScaleResolutionDownBy float64 `json:"scaleResolutionDownBy"`
 }
@@ -4,15 +4,21 @@
 package webrtc
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"sync"
 	"time"
 
 	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/stats"
 	"github.com/pion/rtcp"
 	"github.com/pion/srtp/v2"
 	"github.com/pion/webrtc/v3/internal/util"
+	"github.com/pion/webrtc/v3/pkg/flexfec"
+	"github.com/pion/webrtc/v3/pkg/lipsync"
+	"github.com/pion/webrtc/v3/pkg/nack"
 )
 
 // trackStreams maintains a mapping of RTP/RTCP streams to a specific track
@@ -28,11 +34,39 @@ type trackStreams struct {
 	rtcpReadStream  *srtp.ReadStreamSRTCP
 	rtcpInterceptor interceptor.RTCPReader
 
+	// rtcpQueue is set once the RTPReceiver reads the RTCP of the track itself
+	rtcpQueue *rtcpQueue
+
 	repairReadStream  *srtp.ReadStreamSRTP
 	repairInterceptor interceptor.RTPReader
 
 	repairRtcpReadStream  *srtp.ReadStreamSRTCP
 	repairRtcpInterceptor interceptor.RTCPReader
+
+	fecStreamInfo     *interceptor.StreamInfo
+	fecReadStream     *srtp.ReadStreamSRTP
+	fecInterceptor    interceptor.RTPReader
+	fecRtcpReadStream *srtp.ReadStreamSRTCP
+}
+
+// rtcpReader returns the reader of the RTCP of the track, the queue if the RTPReceiver reads it itself
+func (t *trackStreams) rtcpReader() interceptor.RTCPReader {
+	if queue := t.rtcpQueue; queue != nil {
+		return interceptor.RTCPReaderFunc(func(b []byte, _ interceptor.Attributes) (int, interceptor.Attributes, error) {
+			return queue.read(b)
+		})
+	}
+
+	return t.rtcpInterceptor
+}
+
+func (t *trackStreams) setRTCPReadDeadline(deadline time.Time) error {
+	if t.rtcpQueue != nil {
+		t.rtcpQueue.readDeadline.Set(deadline)
+		return nil
+	}
+
+	return t.rtcpReadStream.SetReadDeadline(deadline)
 }
 
 // RTPReceiver allows an application to inspect the receipt of a TrackRemote
@@ -47,6 +81,9 @@ type RTPReceiver struct {
 
 	tr *RTPTransceiver
 
+	// lipSynchronizer is set when the RTPReceiver reads the RTCP of its tracks itself
+	lipSynchronizer *lipsync.Interceptor
+
 	// A reference to the associated api object
 	api *API
 }
@@ -178,6 +215,12 @@ func (r *RTPReceiver) startReceive(parameters RTPReceiveParameters) error {
 
 		if parameters.Encodings[i].SSRC != 0 {
 			t.streamInfo = createStreamInfo("", parameters.Encodings[i].SSRC, 0, codec, globalParams.HeaderExtensions)
+			if fecSsrc := parameters.Encodings[i].FEC.SSRC; fecSsrc != 0 {
+				// The FEC stream is paired with the media stream before the media stream is bound
+				flexfec.SetFECStream(t.streamInfo, flexfec.FECStream{ProtectedSSRC: uint32(parameters.Encodings[i].SSRC), SSRC: uint32(fecSsrc)})
+			}
+			setULPFECStream(t.streamInfo, globalParams.Codecs)
+
 			var err error
 			if t.rtpReadStream, t.rtpInterceptor, t.rtcpReadStream, t.rtcpInterceptor, err = r.transport.streamsForSSRC(parameters.Encodings[i].SSRC, *t.streamInfo); err != nil {
 				return err
@@ -186,6 +229,7 @@ func (r *RTPReceiver) startReceive(parameters RTPReceiveParameters) error {
 
 		if rtxSsrc := parameters.Encodings[i].RTX.SSRC; rtxSsrc != 0 {
 			streamInfo := createStreamInfo("", rtxSsrc, 0, codec, globalParams.HeaderExtensions)
+			nack.SetRTXStream(streamInfo, nack.RTXStream{ProtectedSSRC: uint32(parameters.Encodings[i].SSRC), SSRC: uint32(rtxSsrc)})
 			rtpReadStream, rtpInterceptor, rtcpReadStream, rtcpInterceptor, err := r.transport.streamsForSSRC(rtxSsrc, *streamInfo)
 			if err != nil {
 				return err
@@ -195,6 +239,18 @@ func (r *RTPReceiver) startReceive(parameters RTPReceiveParameters) error {
 				return err
 			}
 		}
+
+		if fecSsrc := parameters.Encodings[i].FEC.SSRC; fecSsrc != 0 && parameters.Encodings[i].SSRC != 0 {
+			t.fecStreamInfo = createStreamInfo("", fecSsrc, 0, codec, globalParams.HeaderExtensions)
+			flexfec.SetFECStream(t.fecStreamInfo, flexfec.FECStream{ProtectedSSRC: uint32(parameters.Encodings[i].SSRC), SSRC: uint32(fecSsrc)})
+
+			var err error
+			if t.fecReadStream, t.fecInterceptor, t.fecRtcpReadStream, _, err = r.transport.streamsForSSRC(fecSsrc, *t.fecStreamInfo); err != nil {
+				return err
+			}
+
+			r.receiveForFEC(t)
+		}
 	}
 
 	return nil
@@ -210,7 +266,11 @@ func (r *RTPReceiver) Receive(parameters RTPReceiveParameters) error {
 func (r *RTPReceiver) Read(b []byte) (n int, a interceptor.Attributes, err error) {
 	select {
 	case <-r.received:
-		return r.tracks[0].rtcpInterceptor.Read(b, a)
+		r.mu.RLock()
+		reader := r.tracks[0].rtcpReader()
+		r.mu.RUnlock()
+
+		return reader.Read(b, a)
 	case <-r.closed:
 		return 0, nil, io.ErrClosedPipe
 	}
@@ -220,12 +280,20 @@ func (r *RTPReceiver) Read(b []byte) (n int, a interceptor.Attributes, err error
 func (r *RTPReceiver) ReadSimulcast(b []byte, rid string) (n int, a interceptor.Attributes, err error) {
 	select {
 	case <-r.received:
-		for _, t := range r.tracks {
-			if t.track != nil && t.track.rid == rid {
-				return t.rtcpInterceptor.Read(b, a)
+		var reader interceptor.RTCPReader
+		r.mu.RLock()
+		for i := range r.tracks {
+			if r.tracks[i].track != nil && r.tracks[i].track.rid == rid {
+				reader = r.tracks[i].rtcpReader()
+				break
 			}
 		}
-		return 0, nil, fmt.Errorf("%w: %s", errRTPReceiverForRIDTrackStreamNotFound, rid)
+		r.mu.RUnlock()
+
+		if reader == nil {
+			return 0, nil, fmt.Errorf("%w: %s", errRTPReceiverForRIDTrackStreamNotFound, rid)
+		}
+		return reader.Read(b, a)
 	case <-r.closed:
 		return 0, nil, io.ErrClosedPipe
 	}
@@ -302,6 +370,14 @@ func (r *RTPReceiver) Stop() error {
 				errs = append(errs, r.tracks[i].repairRtcpReadStream.Close())
 			}
 
+			if r.tracks[i].fecReadStream != nil {
+				errs = append(errs, r.tracks[i].fecReadStream.Close())
+			}
+
+			if r.tracks[i].fecRtcpReadStream != nil {
+				errs = append(errs, r.tracks[i].fecRtcpReadStream.Close())
+			}
+
 			if r.tracks[i].streamInfo != nil {
 				r.api.interceptor.UnbindRemoteStream(r.tracks[i].streamInfo)
 			}
@@ -310,6 +386,10 @@ func (r *RTPReceiver) Stop() error {
 				r.api.interceptor.UnbindRemoteStream(r.tracks[i].repairStreamInfo)
 			}
 
+			if r.tracks[i].fecStreamInfo != nil {
+				r.api.interceptor.UnbindRemoteStream(r.tracks[i].fecStreamInfo)
+			}
+
 			err = util.FlattenErrs(errs)
 		}
 	default:
@@ -402,12 +482,65 @@ func (r *RTPReceiver) receiveForRtx(ssrc SSRC, rsid string, streamInfo *intercep
 	return nil
 }
 
+// receiveForFEC starts a routine that reads the FEC stream of a track. The packets are
+// consumed by the interceptors, which use them to recover lost packets of the track.
+func (r *RTPReceiver) receiveForFEC(track *trackStreams) {
+	fecInterceptor := track.fecInterceptor
+	go func() {
+		b := make([]byte, r.api.settingEngine.getReceiveMTU())
+		for {
+			if _, _, readErr := fecInterceptor.Read(b, nil); readErr != nil {
+				return
+			}
+		}
+	}()
+}
+
+// startReadingRTCP makes the RTPReceiver read the RTCP of its tracks itself, so that their Sender
+// Reports reach the synchronizer even if the application doesn't read it. It is called again for
+// the tracks that are received later.
+func (r *RTPReceiver) startReadingRTCP(synchronizer *lipsync.Interceptor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lipSynchronizer = synchronizer
+	for i := range r.tracks {
+		if r.tracks[i].rtcpInterceptor == nil || r.tracks[i].rtcpQueue != nil {
+			continue
+		}
+
+		r.tracks[i].rtcpQueue = newRTCPQueue()
+		go r.readRTCP(r.tracks[i].rtcpReadStream, r.tracks[i].rtcpInterceptor, r.tracks[i].rtcpQueue)
+	}
+}
+
+func (r *RTPReceiver) readRTCP(rtcpReadStream *srtp.ReadStreamSRTCP, rtcpInterceptor interceptor.RTCPReader, queue *rtcpQueue) {
+	b := make([]byte, r.api.settingEngine.getReceiveMTU())
+	for {
+		n, attributes, err := rtcpInterceptor.Read(b, nil)
+		if err != nil {
+			// A deadline set before the RTPReceiver started reading now applies to the queue
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				if err = rtcpReadStream.SetReadDeadline(time.Time{}); err == nil {
+					continue
+				}
+			}
+
+			queue.close(err)
+			return
+		}
+
+		queue.push(b[:n], attributes)
+	}
+}
+
 // SetReadDeadline sets the max amount of time the RTCP stream will block before returning. 0 is forever.
 func (r *RTPReceiver) SetReadDeadline(t time.Time) error {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	if err := r.tracks[0].rtcpReadStream.SetReadDeadline(t); err != nil {
+	if err := r.tracks[0].setRTCPReadDeadline(t); err != nil {
 		return err
 	}
 	return nil
@@ -418,14 +551,91 @@ func (r *RTPReceiver) SetReadDeadlineSimulcast(deadline time.Time, rid string) e
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	for _, t := range r.tracks {
-		if t.track != nil && t.track.rid == rid {
-			return t.rtcpReadStream.SetReadDeadline(deadline)
+	for i := range r.tracks {
+		if r.tracks[i].track != nil && r.tracks[i].track.rid == rid {
+			return r.tracks[i].setRTCPReadDeadline(deadline)
 		}
 	}
 	return fmt.Errorf("%w: %s", errRTPReceiverForRIDTrackStreamNotFound, rid)
 }
 
+func (r *RTPReceiver) collectStats(collector *statsReportCollector, getter stats.Getter, nackGenerator *nack.GeneratorInterceptor) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for i := range r.tracks {
+		track := r.tracks[i].track
+		if track == nil {
+			continue
+		}
+
+		ssrc := track.SSRC()
+		streamStats := getter.Get(uint32(ssrc))
+		if streamStats == nil {
+			continue
+		}
+
+		codec := track.Codec()
+		codecID := r.api.mediaEngine.getCodecStatsID(codec, r.kind)
+		inboundID := fmt.Sprintf("inbound-rtp-%d", ssrc)
+		remoteOutboundID := fmt.Sprintf("remote-outbound-rtp-%d", ssrc)
+		now := statsTimestampNow()
+
+		jitter := streamStats.InboundRTPStreamStats.Jitter
+		if codec.ClockRate != 0 {
+			jitter /= float64(codec.ClockRate)
+		}
+
+		inbound := InboundRTPStreamStats{
+			Timestamp:       now,
+			Type:            StatsTypeInboundRTP,
+			ID:              inboundID,
+			SSRC:            ssrc,
+			Kind:            r.kind.String(),
+			TransportID:     "iceTransport",
+			CodecID:         codecID,
+			FIRCount:        streamStats.InboundRTPStreamStats.FIRCount,
+			PLICount:        streamStats.InboundRTPStreamStats.PLICount,
+			NACKCount:       streamStats.InboundRTPStreamStats.NACKCount,
+			PacketsReceived: uint32(streamStats.InboundRTPStreamStats.PacketsReceived),
+			PacketsLost:     int32(streamStats.InboundRTPStreamStats.PacketsLost),
+			Jitter:          jitter,
+			BytesReceived:   streamStats.InboundRTPStreamStats.BytesReceived,
+		}
+		if nackGenerator != nil {
+			if nackStats, ok := nackGenerator.Stats(uint32(ssrc)); ok {
+				inbound.PacketsNACKed = uint32(nackStats.NACKed)
+				inbound.PacketsRecovered = uint32(nackStats.Recovered)
+				inbound.PacketsUnrecovered = uint32(nackStats.Unrecovered)
+			}
+		}
+		if !streamStats.LastPacketReceivedTimestamp.IsZero() {
+			inbound.LastPacketReceivedTimestamp = statsTimestampFrom(streamStats.LastPacketReceivedTimestamp)
+		}
+		if streamStats.RemoteOutboundRTPStreamStats.ReportsSent > 0 {
+			inbound.RemoteID = remoteOutboundID
+
+			collector.Collecting()
+			collector.Collect(remoteOutboundID, RemoteOutboundRTPStreamStats{
+				Timestamp:       now,
+				Type:            StatsTypeRemoteOutboundRTP,
+				ID:              remoteOutboundID,
+				SSRC:            ssrc,
+				Kind:            r.kind.String(),
+				TransportID:     "iceTransport",
+				CodecID:         codecID,
+				PacketsSent:     uint32(streamStats.RemoteOutboundRTPStreamStats.PacketsSent),
+				BytesSent:       streamStats.RemoteOutboundRTPStreamStats.BytesSent,
+				LocalID:         inboundID,
+				RemoteTimestamp: statsTimestampFrom(streamStats.RemoteOutboundRTPStreamStats.RemoteTimeStamp),
+			})
+		}
+
+		collector.Collecting()
+		collector.Collect(inboundID, inbound)
+	}
+}
+
 // setRTPReadDeadline sets the max amount of time the RTP stream will block before returning. 0 is forever.
 // This should be fired by calling SetReadDeadline on the TrackRemote
 func (r *RTPReceiver) setRTPReadDeadline(deadline time.Time, reader *TrackRemote) error {
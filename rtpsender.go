@@ -4,16 +4,23 @@
 package webrtc
 
 import (
+	"encoding/binary"
 	"fmt"
 	"io"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/stats"
 	"github.com/pion/randutil"
 	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
 	"github.com/pion/webrtc/v3/internal/util"
+	"github.com/pion/webrtc/v3/pkg/flexfec"
+	"github.com/pion/webrtc/v3/pkg/rtcerr"
 )
 
 type trackEncoding struct {
@@ -27,6 +34,35 @@ type trackEncoding struct {
 	context TrackLocalContext
 
 	ssrc SSRC
+
+	// rtxSsrc and rtxSrtpStream carry the RFC 4588 retransmissions of this encoding
+	rtxSsrc       SSRC
+	rtxSrtpStream *srtpWriterFuture
+
+	// fecSsrc and fecSrtpStream carry the FlexFEC packets protecting this encoding
+	fecSsrc       SSRC
+	fecSrtpStream *srtpWriterFuture
+	fecStreamInfo interceptor.StreamInfo
+
+	parameters atomic.Value // RTPEncodingParameters
+
+	// writeStream writes the RTP of the encoding, sequencer shifts the sequence numbers of
+	// the track to make room for the DTMF packets
+	writeStream *interceptorToTrackLocalWriter
+	sequencer   dtmfSequencer
+
+	// rtcpQueue is set once the RTPSender reads the RTCP of this encoding itself, to handle
+	// key frame requests. Read then returns the queued packets.
+	rtcpQueue    atomic.Value // *rtcpQueue
+	readRTCPOnce sync.Once
+	keyFrameMu   sync.Mutex
+	lastKeyFrame time.Time
+	firSequences map[uint32]uint8
+}
+
+func (t *trackEncoding) getParameters() RTPEncodingParameters {
+	parameters, _ := t.parameters.Load().(RTPEncodingParameters)
+	return parameters
 }
 
 // RTPSender allows an application to control how a given Track is encoded and transmitted to a remote peer
@@ -48,8 +84,15 @@ type RTPSender struct {
 	api *API
 	id  string
 
+	// transactionID is handed out by GetParameters and checked by SetParameters
+	transactionID string
+
 	rtpTransceiver *RTPTransceiver
 
+	onKeyFrameRequestHandler atomic.Value // func(KeyFrameRequest)
+
+	dtmf *DTMFSender
+
 	mu                     sync.RWMutex
 	sendCalled, stopCalled chan struct{}
 }
@@ -76,6 +119,10 @@ func (api *API) NewRTPSender(track TrackLocal, transport *DTLSTransport) (*RTPSe
 		kind:       track.Kind(),
 	}
 
+	if r.kind == RTPCodecTypeAudio {
+		r.dtmf = &DTMFSender{rtpSender: r}
+	}
+
 	r.addEncoding(track)
 
 	return r, nil
@@ -99,6 +146,12 @@ func (r *RTPSender) setRTPTransceiver(rtpTransceiver *RTPTransceiver) {
 	r.rtpTransceiver = rtpTransceiver
 }
 
+// DTMF returns the DTMFSender used to send DTMF tones on the audio of the RTPSender, it is
+// nil for video senders
+func (r *RTPSender) DTMF() *DTMFSender {
+	return r.dtmf
+}
+
 // Transport returns the currently-configured *DTLSTransport or nil
 // if one has not yet been configured
 func (r *RTPSender) Transport() *DTLSTransport {
@@ -114,37 +167,111 @@ func (r *RTPSender) getParameters() RTPSendParameters {
 		if trackEncoding.track != nil {
 			rid = trackEncoding.track.RID()
 		}
-		encodings = append(encodings, RTPEncodingParameters{
-			RTPCodingParameters: RTPCodingParameters{
-				RID:         rid,
-				SSRC:        trackEncoding.ssrc,
-				PayloadType: r.payloadType,
-			},
-		})
+		encoding := trackEncoding.getParameters()
+		encoding.RTPCodingParameters = RTPCodingParameters{
+			RID:         rid,
+			SSRC:        trackEncoding.ssrc,
+			PayloadType: r.payloadType,
+		}
+		encodings = append(encodings, encoding)
 	}
 	sendParameters := RTPSendParameters{
 		RTPParameters: r.api.mediaEngine.getRTPParametersByKind(
 			r.kind,
 			[]RTPTransceiverDirection{RTPTransceiverDirectionSendonly},
 		),
-		Encodings: encodings,
+		Encodings:     encodings,
+		TransactionID: r.transactionID,
 	}
 	if r.rtpTransceiver != nil {
 		sendParameters.Codecs = r.rtpTransceiver.getCodecs()
 	} else {
 		sendParameters.Codecs = r.api.mediaEngine.getCodecsByKind(r.kind)
 	}
+
+	// Only announce repair flows whose codec can be negotiated. Each simulcast encoding
+	// has its own RTX repair flow, FlexFEC is only used without simulcast.
+	for _, codec := range sendParameters.Codecs {
+		switch {
+		case strings.EqualFold(codec.MimeType, MimeTypeRTX):
+			for i, trackEncoding := range r.trackEncodings {
+				sendParameters.Encodings[i].RTX.SSRC = trackEncoding.rtxSsrc
+			}
+		case strings.EqualFold(codec.MimeType, flexfec.MimeType) && len(r.trackEncodings) == 1:
+			sendParameters.Encodings[0].FEC.SSRC = r.trackEncodings[0].fecSsrc
+		}
+	}
 	return sendParameters
 }
 
 // GetParameters describes the current configuration for the encoding and
-// transmission of media on the sender's track.
+// transmission of media on the sender's track. Each call returns a new TransactionID,
+// which invalidates the ones returned before.
 func (r *RTPSender) GetParameters() RTPSendParameters {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.transactionID = util.MathRandAlpha(16)
+	return r.getParameters()
+}
+
+// currentParameters returns the parameters of the sender without invalidating the
+// TransactionID handed out to the user
+func (r *RTPSender) currentParameters() RTPSendParameters {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	return r.getParameters()
 }
 
+// SetParameters updates the configuration of the encodings of the sender's track.
+// The parameters must have been returned by the latest GetParameters call, and only the
+// Active, MaxBitrate, MaxFramerate and ScaleResolutionDownBy fields of the encodings may
+// be modified. Inactive encodings stop being sent without requiring negotiation.
+func (r *RTPSender) SetParameters(parameters RTPSendParameters) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.hasStopped() {
+		return &rtcerr.InvalidStateError{Err: errRTPSenderStopped}
+	}
+
+	if r.transactionID == "" || parameters.TransactionID != r.transactionID {
+		return &rtcerr.InvalidModificationError{Err: errRTPSenderTransactionMismatch}
+	}
+
+	current := r.getParameters()
+	if len(parameters.Encodings) != len(current.Encodings) {
+		return &rtcerr.InvalidModificationError{Err: errRTPSenderEncodingsChanged}
+	}
+
+	for i, encoding := range parameters.Encodings {
+		if encoding.RID != current.Encodings[i].RID || encoding.SSRC != current.Encodings[i].SSRC {
+			return &rtcerr.InvalidModificationError{Err: errRTPSenderEncodingsChanged}
+		}
+
+		if encoding.ScaleResolutionDownBy != 0 && encoding.ScaleResolutionDownBy < 1 {
+			return &rtcerr.RangeError{Err: errRTPSenderInvalidScaleDownBy}
+		}
+
+		if encoding.MaxFramerate < 0 {
+			return &rtcerr.RangeError{Err: errRTPSenderInvalidMaxFramerate}
+		}
+	}
+
+	for i, trackEncoding := range r.trackEncodings {
+		trackEncoding.parameters.Store(RTPEncodingParameters{
+			RTPCodingParameters:   current.Encodings[i].RTPCodingParameters,
+			Active:                parameters.Encodings[i].Active,
+			MaxBitrate:            parameters.Encodings[i].MaxBitrate,
+			MaxFramerate:          parameters.Encodings[i].MaxFramerate,
+			ScaleResolutionDownBy: parameters.Encodings[i].ScaleResolutionDownBy,
+		})
+	}
+	r.transactionID = ""
+
+	return nil
+}
+
 // AddEncoding adds an encoding to RTPSender. Used by simulcast senders.
 func (r *RTPSender) AddEncoding(track TrackLocal) error {
 	r.mu.Lock()
@@ -194,12 +321,24 @@ func (r *RTPSender) AddEncoding(track TrackLocal) error {
 
 func (r *RTPSender) addEncoding(track TrackLocal) {
 	ssrc := SSRC(randutil.NewMathRandomGenerator().Uint32())
+	rtxSsrc := SSRC(randutil.NewMathRandomGenerator().Uint32())
+	fecSsrc := SSRC(randutil.NewMathRandomGenerator().Uint32())
 	trackEncoding := &trackEncoding{
-		track:      track,
-		srtpStream: &srtpWriterFuture{ssrc: ssrc},
-		ssrc:       ssrc,
-	}
+		track:         track,
+		srtpStream:    &srtpWriterFuture{ssrc: ssrc},
+		ssrc:          ssrc,
+		rtxSsrc:       rtxSsrc,
+		rtxSrtpStream: &srtpWriterFuture{ssrc: rtxSsrc},
+		fecSsrc:       fecSsrc,
+		fecSrtpStream: &srtpWriterFuture{ssrc: fecSsrc},
+	}
+	trackEncoding.parameters.Store(RTPEncodingParameters{
+		RTPCodingParameters: RTPCodingParameters{RID: track.RID(), SSRC: ssrc},
+		Active:              true,
+	})
 	trackEncoding.srtpStream.rtpSender = r
+	trackEncoding.rtxSrtpStream.rtpSender = r
+	trackEncoding.fecSrtpStream.rtpSender = r
 	trackEncoding.rtcpInterceptor = r.api.interceptor.BindRTCPReader(
 		interceptor.RTPReaderFunc(func(in []byte, a interceptor.Attributes) (n int, attributes interceptor.Attributes, err error) {
 			n, err = trackEncoding.srtpStream.Read(in)
@@ -256,11 +395,12 @@ func (r *RTPSender) ReplaceTrack(track TrackLocal) error {
 	}
 
 	codec, err := track.Bind(TrackLocalContext{
-		id:              context.id,
-		params:          r.api.mediaEngine.getRTPParametersByKind(track.Kind(), []RTPTransceiverDirection{RTPTransceiverDirectionSendonly}),
-		ssrc:            context.ssrc,
-		writeStream:     context.writeStream,
-		rtcpInterceptor: context.rtcpInterceptor,
+		id:                 context.id,
+		params:             r.api.mediaEngine.getRTPParametersByKind(track.Kind(), []RTPTransceiverDirection{RTPTransceiverDirectionSendonly}),
+		ssrc:               context.ssrc,
+		writeStream:        context.writeStream,
+		rtcpInterceptor:    context.rtcpInterceptor,
+		encodingParameters: context.encodingParameters,
 	})
 	if err != nil {
 		// Re-bind the original track
@@ -277,6 +417,10 @@ func (r *RTPSender) ReplaceTrack(track TrackLocal) error {
 	}
 
 	r.trackEncodings[0].track = track
+	if r.handlesKeyFrameRequests(track) {
+		r.startReadingRTCP(r.trackEncodings[0])
+	}
+
 	return nil
 }
 
@@ -294,14 +438,18 @@ func (r *RTPSender) Send(parameters RTPSendParameters) error {
 
 	for idx, trackEncoding := range r.trackEncodings {
 		writeStream := &interceptorToTrackLocalWriter{}
+		encoding := trackEncoding
 		trackEncoding.context = TrackLocalContext{
-			id:              r.id,
-			params:          r.api.mediaEngine.getRTPParametersByKind(trackEncoding.track.Kind(), []RTPTransceiverDirection{RTPTransceiverDirectionSendonly}),
-			ssrc:            parameters.Encodings[idx].SSRC,
-			writeStream:     writeStream,
-			rtcpInterceptor: trackEncoding.rtcpInterceptor,
+			id:                 r.id,
+			params:             r.api.mediaEngine.getRTPParametersByKind(trackEncoding.track.Kind(), []RTPTransceiverDirection{RTPTransceiverDirectionSendonly}),
+			ssrc:               parameters.Encodings[idx].SSRC,
+			writeStream:        writeStream,
+			rtcpInterceptor:    trackEncoding.rtcpInterceptor,
+			encodingParameters: encoding.getParameters,
 		}
 
+		trackEncoding.writeStream = writeStream
+
 		codec, err := trackEncoding.track.Bind(trackEncoding.context)
 		if err != nil {
 			return err
@@ -315,20 +463,154 @@ func (r *RTPSender) Send(parameters RTPSendParameters) error {
 			codec.RTPCodecCapability,
 			parameters.HeaderExtensions,
 		)
+		r.bindFECStream(trackEncoding, parameters.Encodings[idx].FEC.SSRC, parameters.HeaderExtensions)
+		setULPFECStream(&trackEncoding.streamInfo, r.api.mediaEngine.getCodecsByKind(r.kind))
+
 		srtpStream := trackEncoding.srtpStream
+		rtxWriter := r.rtxWriter(trackEncoding, codec.PayloadType, parameters.Encodings[idx].RTX.SSRC, parameters.HeaderExtensions)
 		rtpInterceptor := r.api.interceptor.BindLocalStream(
 			&trackEncoding.streamInfo,
 			interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+				if rtxWriter != nil && isRetransmission(attributes) {
+					return rtxWriter(header, payload)
+				}
+
 				return srtpStream.WriteRTP(header, payload)
 			}),
 		)
-		writeStream.interceptor.Store(rtpInterceptor)
+		writeStream.interceptor.Store(interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+			// Packets of inactive encodings are dropped before any interceptor sees them
+			if !encoding.getParameters().Active {
+				return header.MarshalSize() + len(payload), nil
+			}
+
+			if attributes.Get(dtmfPacketAttribute{}) == nil {
+				encoding.sequencer.onTrackPacket(header)
+			}
+
+			return rtpInterceptor.Write(header, payload, attributes)
+		}))
 	}
 
 	close(r.sendCalled)
+
+	for _, trackEncoding := range r.trackEncodings {
+		if r.handlesKeyFrameRequests(trackEncoding.track) {
+			r.startReadingRTCP(trackEncoding)
+		}
+	}
+
 	return nil
 }
 
+// rtxWriter returns a function that sends retransmissions of the given encoding as a RFC 4588 repair flow.
+// nil is returned if no repair flow has been negotiated, in which case retransmissions use the original SSRC.
+// Packets whose payload type has no RTX codec, like the RED packets of ULPFEC when only the media codec
+// has one, are also resent with the original SSRC.
+// The retransmissions of a simulcast encoding carry its RID in the repaired-rtp-stream-id header
+// extension instead of the rtp-stream-id one, so the receiver can tell which encoding they repair.
+func (r *RTPSender) rtxWriter(trackEncoding *trackEncoding, payloadType PayloadType, rtxSsrc SSRC, headerExtensions []RTPHeaderExtensionParameter) func(*rtp.Header, []byte) (int, error) {
+	codecs := r.api.mediaEngine.getCodecsByKind(r.kind)
+	if rtxSsrc == 0 || rtxSsrc != trackEncoding.rtxSsrc || findRTXPayloadType(payloadType, codecs) == 0 {
+		return nil
+	}
+
+	var rid string
+	var streamIDExtensionID, repairStreamIDExtensionID uint8
+	if trackEncoding.track != nil {
+		rid = trackEncoding.track.RID()
+	}
+	for _, extension := range headerExtensions {
+		switch extension.URI {
+		case sdp.SDESRTPStreamIDURI:
+			streamIDExtensionID = uint8(extension.ID)
+		case sdesRepairRTPStreamIDURI:
+			repairStreamIDExtensionID = uint8(extension.ID)
+		}
+	}
+
+	// RTCP of the repair flow isn't exposed, but it still needs to be read
+	rtxSrtpStream := trackEncoding.rtxSrtpStream
+	go func() {
+		b := make([]byte, r.api.settingEngine.getReceiveMTU())
+		for {
+			if _, err := rtxSrtpStream.Read(b); err != nil {
+				return
+			}
+		}
+	}()
+
+	srtpStream := trackEncoding.srtpStream
+	sequencer := rtp.NewRandomSequencer()
+	return func(header *rtp.Header, payload []byte) (int, error) {
+		rtxPayloadType := findRTXPayloadType(PayloadType(header.PayloadType), codecs)
+		if rtxPayloadType == 0 {
+			return srtpStream.WriteRTP(header, payload)
+		}
+
+		rtxHeader := header.Clone()
+		rtxHeader.SSRC = uint32(rtxSsrc)
+		rtxHeader.PayloadType = uint8(rtxPayloadType)
+		rtxHeader.SequenceNumber = sequencer.NextSequenceNumber()
+		rtxHeader.Padding = false
+
+		if rid != "" && repairStreamIDExtensionID != 0 {
+			if streamIDExtensionID != 0 {
+				_ = rtxHeader.DelExtension(streamIDExtensionID)
+			}
+			if err := rtxHeader.SetExtension(repairStreamIDExtensionID, []byte(rid)); err != nil {
+				return 0, err
+			}
+		}
+
+		// The RTX payload is the original sequence number followed by the original payload
+		rtxPayload := make([]byte, 2+len(payload))
+		binary.BigEndian.PutUint16(rtxPayload, header.SequenceNumber)
+		copy(rtxPayload[2:], payload)
+
+		return rtxSrtpStream.WriteRTP(&rtxHeader, rtxPayload)
+	}
+}
+
+// bindFECStream binds the FlexFEC repair flow of the given encoding, if one has been negotiated.
+// It must be called before the media stream is bound so the interceptor can pair both streams.
+func (r *RTPSender) bindFECStream(trackEncoding *trackEncoding, fecSsrc SSRC, headerExtensions []RTPHeaderExtensionParameter) {
+	if fecSsrc == 0 || fecSsrc != trackEncoding.fecSsrc {
+		return
+	}
+
+	for _, codec := range r.api.mediaEngine.getCodecsByKind(r.kind) {
+		if !strings.EqualFold(codec.MimeType, flexfec.MimeType) {
+			continue
+		}
+
+		fecStream := flexfec.FECStream{ProtectedSSRC: uint32(trackEncoding.ssrc), SSRC: uint32(fecSsrc)}
+		flexfec.SetFECStream(&trackEncoding.streamInfo, fecStream)
+
+		trackEncoding.fecStreamInfo = *createStreamInfo(r.id, fecSsrc, codec.PayloadType, codec.RTPCodecCapability, headerExtensions)
+		flexfec.SetFECStream(&trackEncoding.fecStreamInfo, fecStream)
+
+		fecSrtpStream := trackEncoding.fecSrtpStream
+		r.api.interceptor.BindLocalStream(
+			&trackEncoding.fecStreamInfo,
+			interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+				return fecSrtpStream.WriteRTP(header, payload)
+			}),
+		)
+
+		// RTCP of the repair flow isn't exposed, but it still needs to be read
+		go func() {
+			b := make([]byte, r.api.settingEngine.getReceiveMTU())
+			for {
+				if _, err := fecSrtpStream.Read(b); err != nil {
+					return
+				}
+			}
+		}()
+		return
+	}
+}
+
 // Stop irreversibly stops the RTPSender
 func (r *RTPSender) Stop() error {
 	r.mu.Lock()
@@ -353,6 +635,11 @@ func (r *RTPSender) Stop() error {
 	for _, trackEncoding := range r.trackEncodings {
 		r.api.interceptor.UnbindLocalStream(&trackEncoding.streamInfo)
 		errs = append(errs, trackEncoding.srtpStream.Close())
+		errs = append(errs, trackEncoding.rtxSrtpStream.Close())
+		if trackEncoding.fecStreamInfo.SSRC != 0 {
+			r.api.interceptor.UnbindLocalStream(&trackEncoding.fecStreamInfo)
+		}
+		errs = append(errs, trackEncoding.fecSrtpStream.Close())
 	}
 
 	return util.FlattenErrs(errs)
@@ -362,7 +649,7 @@ func (r *RTPSender) Stop() error {
 func (r *RTPSender) Read(b []byte) (n int, a interceptor.Attributes, err error) {
 	select {
 	case <-r.sendCalled:
-		return r.trackEncodings[0].rtcpInterceptor.Read(b, a)
+		return r.trackEncodings[0].readRTCP(b, a)
 	case <-r.stopCalled:
 		return 0, nil, io.ErrClosedPipe
 	}
@@ -388,12 +675,19 @@ func (r *RTPSender) ReadRTCP() ([]rtcp.Packet, interceptor.Attributes, error) {
 func (r *RTPSender) ReadSimulcast(b []byte, rid string) (n int, a interceptor.Attributes, err error) {
 	select {
 	case <-r.sendCalled:
+		r.mu.RLock()
+		var encoding *trackEncoding
 		for _, t := range r.trackEncodings {
 			if t.track != nil && t.track.RID() == rid {
-				return t.rtcpInterceptor.Read(b, a)
+				encoding = t
 			}
 		}
-		return 0, nil, fmt.Errorf("%w: %s", errRTPSenderNoTrackForRID, rid)
+		r.mu.RUnlock()
+
+		if encoding == nil {
+			return 0, nil, fmt.Errorf("%w: %s", errRTPSenderNoTrackForRID, rid)
+		}
+		return encoding.readRTCP(b, a)
 	case <-r.stopCalled:
 		return 0, nil, io.ErrClosedPipe
 	}
@@ -414,7 +708,7 @@ func (r *RTPSender) ReadSimulcastRTCP(rid string) ([]rtcp.Packet, interceptor.At
 // SetReadDeadline sets the deadline for the Read operation.
 // Setting to zero means no deadline.
 func (r *RTPSender) SetReadDeadline(t time.Time) error {
-	return r.trackEncodings[0].srtpStream.SetReadDeadline(t)
+	return r.trackEncodings[0].setReadDeadline(t)
 }
 
 // SetReadDeadlineSimulcast sets the max amount of time the RTCP stream for a given rid will block before returning. 0 is forever.
@@ -424,12 +718,76 @@ func (r *RTPSender) SetReadDeadlineSimulcast(deadline time.Time, rid string) err
 
 	for _, t := range r.trackEncodings {
 		if t.track != nil && t.track.RID() == rid {
-			return t.srtpStream.SetReadDeadline(deadline)
+			return t.setReadDeadline(deadline)
 		}
 	}
 	return fmt.Errorf("%w: %s", errRTPSenderNoTrackForRID, rid)
 }
 
+func (r *RTPSender) collectStats(collector *statsReportCollector, getter stats.Getter) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if !r.hasSent() {
+		return
+	}
+
+	for _, trackEncoding := range r.trackEncodings {
+		streamStats := getter.Get(uint32(trackEncoding.ssrc))
+		if streamStats == nil {
+			continue
+		}
+
+		var codecID string
+		if codecs := trackEncoding.context.CodecParameters(); len(codecs) != 0 {
+			codecID = r.api.mediaEngine.getCodecStatsID(codecs[0], r.kind)
+		}
+
+		outboundID := fmt.Sprintf("outbound-rtp-%d", trackEncoding.ssrc)
+		remoteInboundID := fmt.Sprintf("remote-inbound-rtp-%d", trackEncoding.ssrc)
+		now := statsTimestampNow()
+
+		outbound := OutboundRTPStreamStats{
+			Timestamp:   now,
+			Type:        StatsTypeOutboundRTP,
+			ID:          outboundID,
+			SSRC:        trackEncoding.ssrc,
+			Kind:        r.kind.String(),
+			TransportID: "iceTransport",
+			CodecID:     codecID,
+			FIRCount:    streamStats.OutboundRTPStreamStats.FIRCount,
+			PLICount:    streamStats.OutboundRTPStreamStats.PLICount,
+			NACKCount:   streamStats.OutboundRTPStreamStats.NACKCount,
+			PacketsSent: uint32(streamStats.OutboundRTPStreamStats.PacketsSent),
+			BytesSent:   streamStats.OutboundRTPStreamStats.BytesSent,
+			SenderID:    r.id,
+		}
+		if streamStats.RemoteInboundRTPStreamStats.PacketsReceived > 0 || streamStats.RemoteInboundRTPStreamStats.RoundTripTimeMeasurements > 0 {
+			outbound.RemoteID = remoteInboundID
+
+			collector.Collecting()
+			collector.Collect(remoteInboundID, RemoteInboundRTPStreamStats{
+				Timestamp:       now,
+				Type:            StatsTypeRemoteInboundRTP,
+				ID:              remoteInboundID,
+				SSRC:            trackEncoding.ssrc,
+				Kind:            r.kind.String(),
+				TransportID:     "iceTransport",
+				CodecID:         codecID,
+				PacketsReceived: uint32(streamStats.RemoteInboundRTPStreamStats.PacketsReceived),
+				PacketsLost:     int32(streamStats.RemoteInboundRTPStreamStats.PacketsLost),
+				Jitter:          streamStats.RemoteInboundRTPStreamStats.Jitter,
+				LocalID:         outboundID,
+				RoundTripTime:   streamStats.RemoteInboundRTPStreamStats.RoundTripTime.Seconds(),
+				FractionLost:    streamStats.RemoteInboundRTPStreamStats.FractionLost,
+			})
+		}
+
+		collector.Collecting()
+		collector.Collect(outboundID, outbound)
+	}
+}
+
 // hasSent tells if data has been ever sent for this instance
 func (r *RTPSender) hasSent() bool {
 	select {
@@ -0,0 +1,183 @@
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+)
+
+// KeyFrameRequest is a request of the remote peer for a key frame of an encoding of an
+// RTPSender, sent as a Picture Loss Indication or a Full Intra Request
+type KeyFrameRequest struct {
+	SSRC SSRC
+	RID  string
+
+	// IsFIR is set if the request is a Full Intra Request
+	IsFIR bool
+}
+
+// keyFrameRequestHandler is implemented by the TrackLocals that can be notified of the key
+// frame requests for their encoding
+type keyFrameRequestHandler interface {
+	handlesKeyFrameRequests() bool
+	handleKeyFrameRequest()
+}
+
+// readRTCP reads the RTCP of the encoding, from the queue if the RTPSender reads it itself
+func (t *trackEncoding) readRTCP(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+	if queue, ok := t.rtcpQueue.Load().(*rtcpQueue); ok {
+		return queue.read(b)
+	}
+
+	return t.rtcpInterceptor.Read(b, a)
+}
+
+func (t *trackEncoding) setReadDeadline(deadline time.Time) error {
+	if queue, ok := t.rtcpQueue.Load().(*rtcpQueue); ok {
+		queue.readDeadline.Set(deadline)
+		return nil
+	}
+
+	return t.srtpStream.SetReadDeadline(deadline)
+}
+
+// isNewFIR tells if a FIR entry isn't a retransmission of the previous request of its sender
+func (t *trackEncoding) isNewFIR(senderSSRC uint32, sequenceNumber uint8) bool {
+	t.keyFrameMu.Lock()
+	defer t.keyFrameMu.Unlock()
+
+	if t.firSequences == nil {
+		t.firSequences = map[uint32]uint8{}
+	}
+	if last, ok := t.firSequences[senderSSRC]; ok && last == sequenceNumber {
+		return false
+	}
+	t.firSequences[senderSSRC] = sequenceNumber
+
+	return true
+}
+
+// allowKeyFrameRequest rate limits the key frame requests of the encoding
+func (t *trackEncoding) allowKeyFrameRequest(interval time.Duration) bool {
+	t.keyFrameMu.Lock()
+	defer t.keyFrameMu.Unlock()
+
+	now := time.Now()
+	if interval > 0 && !t.lastKeyFrame.IsZero() && now.Sub(t.lastKeyFrame) < interval {
+		return false
+	}
+	t.lastKeyFrame = now
+
+	return true
+}
+
+// OnKeyFrameRequest sets an event handler which is called when the remote peer requests a key
+// frame of one of the encodings of the RTPSender. Retransmitted FIRs are ignored, and requests
+// are rate limited by SettingEngine.SetKeyFrameRequestInterval.
+//
+// Once a handler is set the RTPSender reads the incoming RTCP itself, so the application
+// doesn't have to. The packets are still returned by Read and ReadRTCP.
+func (r *RTPSender) OnKeyFrameRequest(f func(KeyFrameRequest)) {
+	r.onKeyFrameRequestHandler.Store(f)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if !r.hasSent() {
+		return
+	}
+	for _, trackEncoding := range r.trackEncodings {
+		r.startReadingRTCP(trackEncoding)
+	}
+}
+
+// handlesKeyFrameRequests tells if the key frame requests for the track have a handler
+func (r *RTPSender) handlesKeyFrameRequests(track TrackLocal) bool {
+	if handler, ok := r.onKeyFrameRequestHandler.Load().(func(KeyFrameRequest)); ok && handler != nil {
+		return true
+	}
+
+	handler, ok := track.(keyFrameRequestHandler)
+	return ok && handler.handlesKeyFrameRequests()
+}
+
+// startReadingRTCP makes the RTPSender read the RTCP of the encoding itself
+func (r *RTPSender) startReadingRTCP(trackEncoding *trackEncoding) {
+	trackEncoding.readRTCPOnce.Do(func() {
+		queue := newRTCPQueue()
+		trackEncoding.rtcpQueue.Store(queue)
+		go r.readRTCP(trackEncoding, queue)
+	})
+}
+
+func (r *RTPSender) readRTCP(trackEncoding *trackEncoding, queue *rtcpQueue) {
+	b := make([]byte, r.api.settingEngine.getReceiveMTU())
+	for {
+		n, attributes, err := trackEncoding.rtcpInterceptor.Read(b, nil)
+		if err != nil {
+			// A deadline set before the RTPSender started reading now applies to the queue
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				if err = trackEncoding.srtpStream.SetReadDeadline(time.Time{}); err == nil {
+					continue
+				}
+			}
+
+			queue.close(err)
+			return
+		}
+
+		queue.push(b[:n], attributes)
+
+		pkts, err := rtcp.Unmarshal(b[:n])
+		if err != nil {
+			continue
+		}
+		r.handleKeyFrameRequests(trackEncoding, pkts)
+	}
+}
+
+func (r *RTPSender) handleKeyFrameRequests(trackEncoding *trackEncoding, pkts []rtcp.Packet) {
+	for _, pkt := range pkts {
+		switch pkt := pkt.(type) {
+		case *rtcp.PictureLossIndication:
+			if SSRC(pkt.MediaSSRC) == trackEncoding.ssrc {
+				r.dispatchKeyFrameRequest(trackEncoding, false)
+			}
+		case *rtcp.FullIntraRequest:
+			for _, entry := range pkt.FIR {
+				if SSRC(entry.SSRC) == trackEncoding.ssrc && trackEncoding.isNewFIR(pkt.SenderSSRC, entry.SequenceNumber) {
+					r.dispatchKeyFrameRequest(trackEncoding, true)
+				}
+			}
+		}
+	}
+}
+
+func (r *RTPSender) dispatchKeyFrameRequest(trackEncoding *trackEncoding, isFIR bool) {
+	if !trackEncoding.allowKeyFrameRequest(r.api.settingEngine.keyFrameRequestInterval) {
+		return
+	}
+
+	r.mu.RLock()
+	track := trackEncoding.track
+	r.mu.RUnlock()
+
+	request := KeyFrameRequest{SSRC: trackEncoding.ssrc, IsFIR: isFIR}
+	if track != nil {
+		request.RID = track.RID()
+	}
+
+	if handler, ok := r.onKeyFrameRequestHandler.Load().(func(KeyFrameRequest)); ok && handler != nil {
+		handler(request)
+	}
+	if handler, ok := track.(keyFrameRequestHandler); ok {
+		handler.handleKeyFrameRequest()
+	}
+}
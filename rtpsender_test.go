@@ -5,12 +5,19 @@ package webrtc
 
 import (
 	"context"
+	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/pion/interceptor"
+	mock_interceptor "github.com/pion/interceptor/pkg/mock"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
 	"github.com/pion/transport/v2/test"
 	"github.com/pion/webrtc/v3/pkg/media"
 	"github.com/stretchr/testify/assert"
@@ -401,3 +408,456 @@ func Test_RTPSender_Add_Encoding(t *testing.T) {
 
 	assert.NoError(t, peerConnection.Close())
 }
+
+func Test_RTPSender_SetParameters(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerer, answerer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+
+	rtpSender, err := offerer.AddTrack(track)
+	assert.NoError(t, err)
+
+	assert.NoError(t, signalPair(offerer, answerer))
+
+	parameters := rtpSender.GetParameters()
+	assert.NotEmpty(t, parameters.TransactionID)
+	assert.Equal(t, 1, len(parameters.Encodings))
+	assert.True(t, parameters.Encodings[0].Active)
+
+	t.Run("Invalid Modification", func(t *testing.T) {
+		invalid := rtpSender.GetParameters()
+		invalid.Encodings = append(invalid.Encodings, invalid.Encodings[0])
+		assert.ErrorIs(t, rtpSender.SetParameters(invalid), errRTPSenderEncodingsChanged)
+
+		invalid = rtpSender.GetParameters()
+		invalid.Encodings[0].SSRC++
+		assert.ErrorIs(t, rtpSender.SetParameters(invalid), errRTPSenderEncodingsChanged)
+
+		invalid = rtpSender.GetParameters()
+		invalid.Encodings[0].ScaleResolutionDownBy = 0.5
+		assert.ErrorIs(t, rtpSender.SetParameters(invalid), errRTPSenderInvalidScaleDownBy)
+
+		invalid = rtpSender.GetParameters()
+		invalid.Encodings[0].MaxFramerate = -1
+		assert.ErrorIs(t, rtpSender.SetParameters(invalid), errRTPSenderInvalidMaxFramerate)
+	})
+
+	// Each GetParameters call invalidates the transaction of the previous ones
+	assert.ErrorIs(t, rtpSender.SetParameters(parameters), errRTPSenderTransactionMismatch)
+
+	parameters = rtpSender.GetParameters()
+	parameters.Encodings[0].Active = false
+	parameters.Encodings[0].MaxBitrate = 500000
+	parameters.Encodings[0].MaxFramerate = 15
+	parameters.Encodings[0].ScaleResolutionDownBy = 2
+	assert.NoError(t, rtpSender.SetParameters(parameters))
+
+	// Parameters can only be set once per GetParameters call
+	assert.ErrorIs(t, rtpSender.SetParameters(parameters), errRTPSenderTransactionMismatch)
+
+	updated := rtpSender.GetParameters()
+	assert.NotEqual(t, parameters.TransactionID, updated.TransactionID)
+	assert.Equal(t, parameters.Encodings, updated.Encodings)
+	assert.Equal(t, parameters.Encodings[0], rtpSender.trackEncodings[0].context.EncodingParameters())
+
+	// Inactive encodings are not sent
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0xAA}, Duration: time.Second}))
+	}
+	outboundID := fmt.Sprintf("outbound-rtp-%d", updated.Encodings[0].SSRC)
+	stats, ok := offerer.GetStats()[outboundID].(OutboundRTPStreamStats)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(0), stats.PacketsSent)
+
+	updated.Encodings[0].Active = true
+	assert.NoError(t, rtpSender.SetParameters(updated))
+	assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0xAA}, Duration: time.Second}))
+
+	assert.Eventually(t, func() bool {
+		stats, ok = offerer.GetStats()[outboundID].(OutboundRTPStreamStats)
+		return ok && stats.PacketsSent == 1
+	}, time.Second, 10*time.Millisecond)
+
+	closePairNow(t, offerer, answerer)
+
+	assert.ErrorIs(t, rtpSender.SetParameters(rtpSender.GetParameters()), errRTPSenderStopped)
+}
+
+// Assert that retransmissions requested by a NACK are sent on the negotiated RTX repair flow
+func Test_RTPSender_RTX(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	var rtxSSRC uint32
+	retransmitted := make(chan uint16, 1)
+
+	m := &MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+
+	ir := &interceptor.Registry{}
+	ir.Add(&mock_interceptor.Factory{
+		NewInterceptorFn: func(_ string) (interceptor.Interceptor, error) {
+			return &mock_interceptor.Interceptor{
+				BindRemoteStreamFn: func(_ *interceptor.StreamInfo, reader interceptor.RTPReader) interceptor.RTPReader {
+					return interceptor.RTPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+						n, a, err := reader.Read(b, a)
+						if err != nil {
+							return n, a, err
+						}
+
+						packet := &rtp.Packet{}
+						if unmarshalErr := packet.Unmarshal(b[:n]); unmarshalErr == nil &&
+							packet.SSRC == atomic.LoadUint32(&rtxSSRC) && len(packet.Payload) >= 2 {
+							select {
+							case retransmitted <- binary.BigEndian.Uint16(packet.Payload):
+							default:
+							}
+						}
+						return n, a, err
+					})
+				},
+			}, nil
+		},
+	})
+
+	offerer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	answerer, err := NewAPI(WithMediaEngine(m), WithInterceptorRegistry(ir)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+
+	rtpSender, err := offerer.AddTrack(track)
+	assert.NoError(t, err)
+
+	// NACKs are only handled by the responder while RTCP is being read
+	go func() {
+		for {
+			if _, _, readErr := rtpSender.ReadRTCP(); readErr != nil {
+				return
+			}
+		}
+	}()
+
+	parameters := rtpSender.GetParameters()
+	assert.NotEqual(t, SSRC(0), parameters.Encodings[0].RTX.SSRC)
+	atomic.StoreUint32(&rtxSSRC, uint32(parameters.Encodings[0].RTX.SSRC))
+
+	offer, err := offerer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, offer.SDP, fmt.Sprintf("a=ssrc-group:FID %d %d", parameters.Encodings[0].SSRC, parameters.Encodings[0].RTX.SSRC))
+
+	firstPacket := make(chan *rtp.Packet, 1)
+	answerer.OnTrack(func(trackRemote *TrackRemote, _ *RTPReceiver) {
+		packet, _, readErr := trackRemote.ReadRTP()
+		assert.NoError(t, readErr)
+		firstPacket <- packet
+	})
+
+	assert.NoError(t, signalPair(offerer, answerer))
+
+	func() {
+		ticker := time.NewTicker(time.Millisecond * 20)
+		defer ticker.Stop()
+
+		var nacked *rtp.Packet
+		for {
+			select {
+			case nacked = <-firstPacket:
+			case sequenceNumber := <-retransmitted:
+				assert.Equal(t, nacked.SequenceNumber, sequenceNumber)
+				return
+			case <-ticker.C:
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0xAA}, Duration: time.Second}))
+				if nacked != nil {
+					assert.NoError(t, answerer.WriteRTCP([]rtcp.Packet{&rtcp.TransportLayerNack{
+						MediaSSRC: nacked.SSRC,
+						Nacks:     rtcp.NackPairsFromSequenceNumbers([]uint16{nacked.SequenceNumber}),
+					}}))
+				}
+			}
+		}
+	}()
+
+	closePairNow(t, offerer, answerer)
+}
+
+// Assert that each simulcast encoding has its own RTX repair flow, whose retransmissions carry
+// the RID of the encoding in the repaired-rtp-stream-id header extension
+func Test_RTPSender_RTX_Simulcast(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	type retransmission struct {
+		ssrc           uint32
+		repairedRID    string
+		sequenceNumber uint16
+	}
+	var rsidID uint32
+	retransmitted := make(chan retransmission, 1)
+
+	m := &MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+	registerSimulcastHeaderExtensions(m, RTPCodecTypeVideo)
+
+	ir := &interceptor.Registry{}
+	ir.Add(&mock_interceptor.Factory{
+		NewInterceptorFn: func(_ string) (interceptor.Interceptor, error) {
+			return &mock_interceptor.Interceptor{
+				BindRemoteStreamFn: func(_ *interceptor.StreamInfo, reader interceptor.RTPReader) interceptor.RTPReader {
+					return interceptor.RTPReaderFunc(func(b []byte, a interceptor.Attributes) (int, interceptor.Attributes, error) {
+						n, a, err := reader.Read(b, a)
+						if err != nil {
+							return n, a, err
+						}
+
+						packet := &rtp.Packet{}
+						if unmarshalErr := packet.Unmarshal(b[:n]); unmarshalErr == nil && len(packet.Payload) >= 2 {
+							if rsid := packet.GetExtension(uint8(atomic.LoadUint32(&rsidID))); rsid != nil {
+								select {
+								case retransmitted <- retransmission{packet.SSRC, string(rsid), binary.BigEndian.Uint16(packet.Payload)}:
+								default:
+								}
+							}
+						}
+						return n, a, err
+					})
+				},
+			}, nil
+		},
+	})
+
+	offererRegistry := &interceptor.Registry{}
+	assert.NoError(t, RegisterDefaultInterceptors(m, offererRegistry))
+
+	offerer, err := NewAPI(WithMediaEngine(m), WithInterceptorRegistry(offererRegistry)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	answerer, err := NewAPI(WithMediaEngine(m), WithInterceptorRegistry(ir)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	var tracks []*TrackLocalStaticRTP
+	for _, rid := range []string{"a", "b"} {
+		track, trackErr := NewTrackLocalStaticRTP(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion", WithRTPStreamID(rid))
+		assert.NoError(t, trackErr)
+		tracks = append(tracks, track)
+	}
+
+	rtpSender, err := offerer.AddTrack(tracks[0])
+	assert.NoError(t, err)
+	assert.NoError(t, rtpSender.AddEncoding(tracks[1]))
+
+	// NACKs are only handled by the responder while RTCP is being read
+	for _, rid := range []string{"a", "b"} {
+		go func(rid string) {
+			for {
+				if _, _, readErr := rtpSender.ReadSimulcastRTCP(rid); readErr != nil {
+					return
+				}
+			}
+		}(rid)
+	}
+
+	parameters := rtpSender.GetParameters()
+	assert.NotEqual(t, SSRC(0), parameters.Encodings[0].RTX.SSRC)
+	assert.NotEqual(t, SSRC(0), parameters.Encodings[1].RTX.SSRC)
+	assert.NotEqual(t, parameters.Encodings[0].RTX.SSRC, parameters.Encodings[1].RTX.SSRC)
+
+	var midID, ridID uint8
+	for _, extension := range parameters.HeaderExtensions {
+		switch extension.URI {
+		case sdp.SDESMidURI:
+			midID = uint8(extension.ID)
+		case sdp.SDESRTPStreamIDURI:
+			ridID = uint8(extension.ID)
+		case sdesRepairRTPStreamIDURI:
+			atomic.StoreUint32(&rsidID, uint32(extension.ID))
+		}
+	}
+
+	offer, err := offerer.CreateOffer(nil)
+	assert.NoError(t, err)
+	for _, encoding := range parameters.Encodings {
+		assert.Contains(t, offer.SDP, fmt.Sprintf("a=ssrc-group:FID %d %d", encoding.SSRC, encoding.RTX.SSRC))
+	}
+
+	answerer.OnTrack(func(trackRemote *TrackRemote, _ *RTPReceiver) {
+		for {
+			if _, _, readErr := trackRemote.ReadRTP(); readErr != nil {
+				return
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(offerer, answerer))
+
+	func() {
+		ticker := time.NewTicker(time.Millisecond * 20)
+		defer ticker.Stop()
+
+		for sequenceNumber := uint16(0); ; sequenceNumber++ {
+			select {
+			case packet := <-retransmitted:
+				assert.Equal(t, retransmission{uint32(parameters.Encodings[1].RTX.SSRC), "b", 0}, packet)
+				return
+			case <-ticker.C:
+				for i, rid := range []string{"a", "b"} {
+					header := &rtp.Header{Version: 2, SequenceNumber: sequenceNumber}
+					assert.NoError(t, header.SetExtension(midID, []byte("0")))
+					assert.NoError(t, header.SetExtension(ridID, []byte(rid)))
+					assert.NoError(t, tracks[i].WriteRTP(&rtp.Packet{Header: *header, Payload: []byte{0x00}}))
+				}
+
+				if sequenceNumber > 0 {
+					assert.NoError(t, answerer.WriteRTCP([]rtcp.Packet{&rtcp.TransportLayerNack{
+						MediaSSRC: uint32(parameters.Encodings[1].SSRC),
+						Nacks:     rtcp.NackPairsFromSequenceNumbers([]uint16{0}),
+					}}))
+				}
+			}
+		}
+	}()
+
+	closePairNow(t, offerer, answerer)
+}
+
+func Test_RTPSender_KeyFrameRequest_Filtering(t *testing.T) {
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+
+	var trackRequests int
+	track.OnKeyFrameRequest(func() { trackRequests++ })
+
+	var requests []KeyFrameRequest
+	settingEngine := SettingEngine{}
+	rtpSender := &RTPSender{api: &API{settingEngine: &settingEngine}}
+	rtpSender.OnKeyFrameRequest(func(request KeyFrameRequest) { requests = append(requests, request) })
+
+	encoding := &trackEncoding{track: track, ssrc: 1234}
+	rtpSender.handleKeyFrameRequests(encoding, []rtcp.Packet{
+		&rtcp.PictureLossIndication{SenderSSRC: 1, MediaSSRC: 1234},
+		&rtcp.PictureLossIndication{SenderSSRC: 1, MediaSSRC: 5678},
+		&rtcp.FullIntraRequest{SenderSSRC: 1, FIR: []rtcp.FIREntry{{SSRC: 1234, SequenceNumber: 7}}},
+		// Retransmission of the previous FIR
+		&rtcp.FullIntraRequest{SenderSSRC: 1, FIR: []rtcp.FIREntry{{SSRC: 1234, SequenceNumber: 7}}},
+		&rtcp.FullIntraRequest{SenderSSRC: 2, FIR: []rtcp.FIREntry{{SSRC: 1234, SequenceNumber: 7}}},
+		&rtcp.FullIntraRequest{SenderSSRC: 1, FIR: []rtcp.FIREntry{{SSRC: 5678, SequenceNumber: 8}, {SSRC: 1234, SequenceNumber: 8}}},
+	})
+	assert.Equal(t, []KeyFrameRequest{
+		{SSRC: 1234},
+		{SSRC: 1234, IsFIR: true},
+		{SSRC: 1234, IsFIR: true},
+		{SSRC: 1234, IsFIR: true},
+	}, requests)
+	assert.Equal(t, 4, trackRequests)
+
+	settingEngine.SetKeyFrameRequestInterval(time.Hour)
+	requests = nil
+	rtpSender.handleKeyFrameRequests(encoding, []rtcp.Packet{
+		&rtcp.PictureLossIndication{SenderSSRC: 1, MediaSSRC: 1234},
+		&rtcp.PictureLossIndication{SenderSSRC: 1, MediaSSRC: 1234},
+	})
+	assert.Empty(t, requests)
+
+	encoding = &trackEncoding{track: track, ssrc: 1234}
+	rtpSender.handleKeyFrameRequests(encoding, []rtcp.Packet{
+		&rtcp.PictureLossIndication{SenderSSRC: 1, MediaSSRC: 1234},
+		&rtcp.PictureLossIndication{SenderSSRC: 1, MediaSSRC: 1234},
+	})
+	assert.Equal(t, []KeyFrameRequest{{SSRC: 1234}}, requests)
+}
+
+func Test_RTPSender_OnKeyFrameRequest(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerer, answerer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+
+	keyFrameRequested := make(chan struct{}, 1)
+	track.OnKeyFrameRequest(func() {
+		select {
+		case keyFrameRequested <- struct{}{}:
+		default:
+		}
+	})
+
+	rtpSender, err := offerer.AddTrack(track)
+	assert.NoError(t, err)
+
+	remoteTrack := make(chan *TrackRemote, 1)
+	answerer.OnTrack(func(trackRemote *TrackRemote, _ *RTPReceiver) {
+		remoteTrack <- trackRemote
+	})
+
+	assert.NoError(t, signalPair(offerer, answerer))
+
+	func() {
+		ticker := time.NewTicker(time.Millisecond * 20)
+		defer ticker.Stop()
+
+		var trackRemote *TrackRemote
+		for {
+			select {
+			case trackRemote = <-remoteTrack:
+			case <-keyFrameRequested:
+				return
+			case <-ticker.C:
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0xAA}, Duration: time.Second}))
+				if trackRemote != nil {
+					assert.NoError(t, trackRemote.RequestKeyFrame())
+				}
+			}
+		}
+	}()
+
+	// The requests are still returned to the application
+	for {
+		pkts, _, readErr := rtpSender.ReadRTCP()
+		assert.NoError(t, readErr)
+		if _, ok := pkts[0].(*rtcp.PictureLossIndication); ok {
+			break
+		}
+	}
+
+	closePairNow(t, offerer, answerer)
+}
+
+func Test_TrackRemote_RequestKeyFrame_NotNegotiated(t *testing.T) {
+	trackRemote := newTrackRemote(RTPCodecTypeVideo, 1234, "", nil)
+	assert.ErrorIs(t, trackRemote.RequestKeyFrame(), errTrackRemoteKeyFrameNotNegotiated)
+}
+
+func Test_TrackRemote_RequestKeyFrame_FIR(t *testing.T) {
+	trackRemote := newTrackRemote(RTPCodecTypeVideo, 1234, "", nil)
+	trackRemote.codec.RTCPFeedback = []RTCPFeedback{{Type: TypeRTCPFBCCM, Parameter: "fir"}}
+
+	// The target SSRC is only in the FIR entry, and the sequence number increases
+	for i := uint8(0); i < 2; i++ {
+		pkt, err := trackRemote.keyFrameRequest()
+		assert.NoError(t, err)
+		assert.Equal(t, &rtcp.FullIntraRequest{FIR: []rtcp.FIREntry{{SSRC: 1234, SequenceNumber: i}}}, pkt)
+	}
+}
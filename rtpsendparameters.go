@@ -4,4 +4,8 @@ package webrtc
 type RTPSendParameters struct {
 	RTPParameters
 	Encodings []RTPEncodingParameters
+
+	// TransactionID identifies the GetParameters call these parameters
+	// were returned from. It is checked by SetParameters.
+	TransactionID string
 }
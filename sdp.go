@@ -26,6 +26,7 @@ type trackDetails struct {
 	id         string
 	ssrcs      []SSRC
 	repairSsrc *SSRC
+	fecSsrc    *SSRC
 	rids       []string
 }
 
@@ -77,6 +78,7 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription) (
 	for _, media := range s.MediaDescriptions {
 		tracksInMediaSection := []trackDetails{}
 		rtxRepairFlows := map[uint64]uint64{}
+		fecRepairFlows := map[uint64]uint64{}
 
 		// Plan B can have multiple tracks in a signle media section
 		streamID := ""
@@ -103,7 +105,8 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription) (
 			switch attr.Key {
 			case sdp.AttrKeySSRCGroup:
 				split := strings.Split(attr.Value, " ")
-				if split[0] == sdp.SemanticTokenFlowIdentification {
+				switch split[0] {
+				case sdp.SemanticTokenFlowIdentification:
 					// Add rtx ssrcs to blacklist, to avoid adding them as tracks
 					// Essentially lines like `a=ssrc-group:FID 2231627014 632943048` are processed by this section
 					// as this declares that the second SSRC (632943048) is a rtx repair flow (RFC4588) for the first
@@ -122,6 +125,23 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription) (
 						rtxRepairFlows[rtxRepairFlow] = baseSsrc
 						tracksInMediaSection = filterTrackWithSSRC(tracksInMediaSection, SSRC(rtxRepairFlow)) // Remove if rtx was added as track before
 					}
+				case sdpSemanticTokenFECFramework:
+					// Lines like `a=ssrc-group:FEC-FR 2231627014 632943048` declare that the second SSRC carries
+					// the FlexFEC packets protecting the first, they are processed like rtx repair flows
+					if len(split) == 3 {
+						baseSsrc, err := strconv.ParseUint(split[1], 10, 32)
+						if err != nil {
+							log.Warnf("Failed to parse SSRC: %v", err)
+							continue
+						}
+						fecRepairFlow, err := strconv.ParseUint(split[2], 10, 32)
+						if err != nil {
+							log.Warnf("Failed to parse SSRC: %v", err)
+							continue
+						}
+						fecRepairFlows[fecRepairFlow] = baseSsrc
+						tracksInMediaSection = filterTrackWithSSRC(tracksInMediaSection, SSRC(fecRepairFlow))
+					}
 				}
 
 			// Handle `a=msid:<stream_id> <track_label>` for Unified plan. The first value is the same as MediaStream.id
@@ -145,6 +165,9 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription) (
 				if _, ok := rtxRepairFlows[ssrc]; ok {
 					continue // This ssrc is a RTX repair flow, ignore
 				}
+				if _, ok := fecRepairFlows[ssrc]; ok {
+					continue // This ssrc is a FEC repair flow, ignore
+				}
 
 				if len(split) == 3 && strings.HasPrefix(split[1], "msid:") {
 					streamID = split[1][len("msid:"):]
@@ -174,6 +197,12 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription) (
 						trackDetails.repairSsrc = &repairSsrc
 					}
 				}
+				for f, baseSsrc := range fecRepairFlows {
+					if baseSsrc == ssrc {
+						fecSsrc := SSRC(f)
+						trackDetails.fecSsrc = &fecSsrc
+					}
+				}
 
 				if isNewTrack {
 					tracksInMediaSection = append(tracksInMediaSection, *trackDetails)
@@ -220,6 +249,10 @@ func trackDetailsToRTPReceiveParameters(t *trackDetails) RTPReceiveParameters {
 		if t.repairSsrc != nil {
 			encodings[i].RTX.SSRC = *t.repairSsrc
 		}
+
+		if t.fecSsrc != nil {
+			encodings[i].FEC.SSRC = *t.fecSsrc
+		}
 	}
 
 	return RTPReceiveParameters{Encodings: encodings}
@@ -356,9 +389,23 @@ func addSenderSDP(
 			continue
 		}
 
-		sendParameters := sender.GetParameters()
+		sendParameters := sender.currentParameters()
 		for _, encoding := range sendParameters.Encodings {
+			if encoding.RTX.SSRC != 0 {
+				media = media.WithValueAttribute(sdp.AttrKeySSRCGroup, fmt.Sprintf("%s %d %d", sdp.SemanticTokenFlowIdentification, encoding.SSRC, encoding.RTX.SSRC))
+			}
+
+			if encoding.FEC.SSRC != 0 {
+				media = media.WithValueAttribute(sdp.AttrKeySSRCGroup, fmt.Sprintf("%s %d %d", sdpSemanticTokenFECFramework, encoding.SSRC, encoding.FEC.SSRC))
+			}
+
 			media = media.WithMediaSource(uint32(encoding.SSRC), track.StreamID() /* cname */, track.StreamID() /* streamLabel */, track.ID())
+			if encoding.RTX.SSRC != 0 {
+				media = media.WithMediaSource(uint32(encoding.RTX.SSRC), track.StreamID() /* cname */, track.StreamID() /* streamLabel */, track.ID())
+			}
+			if encoding.FEC.SSRC != 0 {
+				media = media.WithMediaSource(uint32(encoding.FEC.SSRC), track.StreamID() /* cname */, track.StreamID() /* streamLabel */, track.ID())
+			}
 			if !isPlanB {
 				media = media.WithPropertyAttribute("msid:" + track.StreamID() + " " + track.ID())
 			}
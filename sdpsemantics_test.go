@@ -81,12 +81,21 @@ func getMdNames(sdp *sdp.SessionDescription) []string {
 	return mdNames
 }
 
+// extractSsrcList returns the media SSRCs of a section, RTX repair flows are skipped
 func extractSsrcList(md *sdp.MediaDescription) []string {
 	ssrcMap := map[string]struct{}{}
+	repairFlows := map[string]struct{}{}
 	for _, attr := range md.Attributes {
-		if attr.Key == sdp.AttrKeySSRC {
+		switch attr.Key {
+		case sdp.AttrKeySSRCGroup:
+			if fields := strings.Fields(attr.Value); len(fields) == 3 && fields[0] == sdp.SemanticTokenFlowIdentification {
+				repairFlows[fields[2]] = struct{}{}
+			}
+		case sdp.AttrKeySSRC:
 			ssrc := strings.Fields(attr.Value)[0]
-			ssrcMap[ssrc] = struct{}{}
+			if _, ok := repairFlows[ssrc]; !ok {
+				ssrcMap[ssrc] = struct{}{}
+			}
 		}
 	}
 	ssrcList := make([]string, 0, len(ssrcMap))
@@ -297,20 +306,6 @@ func TestSDPSemantics_UnifiedPlanWithFallback(t *testing.T) {
 
 	assert.ObjectsAreEqual(getMdNames(answer.parsed), []string{"video", "audio", "data"})
 
-	extractSsrcList := func(md *sdp.MediaDescription) []string {
-		ssrcMap := map[string]struct{}{}
-		for _, attr := range md.Attributes {
-			if attr.Key == sdp.AttrKeySSRC {
-				ssrc := strings.Fields(attr.Value)[0]
-				ssrcMap[ssrc] = struct{}{}
-			}
-		}
-		ssrcList := make([]string, 0, len(ssrcMap))
-		for ssrc := range ssrcMap {
-			ssrcList = append(ssrcList, ssrc)
-		}
-		return ssrcList
-	}
 	// Verify that each section has 2 SSRCs (one for each sender)
 	for _, section := range []string{"video", "audio"} {
 		for _, media := range answer.parsed.MediaDescriptions {
@@ -75,6 +75,7 @@ type SettingEngine struct {
 	disableMediaEngineCopy                    bool
 	srtpProtectionProfiles                    []dtls.SRTPProtectionProfile
 	receiveMTU                                uint
+	keyFrameRequestInterval                   time.Duration
 }
 
 // getReceiveMTU returns the configured MTU. If SettingEngine's MTU is configured to 0 it returns the default
@@ -347,3 +348,10 @@ func (e *SettingEngine) SetDTLSRetransmissionInterval(interval time.Duration) {
 func (e *SettingEngine) SetSCTPMaxReceiveBufferSize(maxReceiveBufferSize uint32) {
 	e.sctp.maxReceiveBufferSize = maxReceiveBufferSize
 }
+
+// SetKeyFrameRequestInterval sets the minimum interval between two key frame requests
+// dispatched to the OnKeyFrameRequest handlers of an encoding, the requests received in
+// between are dropped. Leave this 0 to dispatch every request.
+func (e *SettingEngine) SetKeyFrameRequestInterval(interval time.Duration) {
+	e.keyFrameRequestInterval = interval
+}
@@ -221,6 +221,16 @@ type InboundRTPStreamStats struct {
 	// received by the sender and is sent by receiver.
 	NACKCount uint32 `json:"nackCount"`
 
+	// PacketsNACKed is the number of packets of this SSRC requested in at least one NACK
+	PacketsNACKed uint32 `json:"packetsNacked"`
+
+	// PacketsRecovered is the number of NACKed packets of this SSRC that were received
+	PacketsRecovered uint32 `json:"packetsRecovered"`
+
+	// PacketsUnrecovered is the number of missing packets of this SSRC that the NACK
+	// generator gave up on
+	PacketsUnrecovered uint32 `json:"packetsUnrecovered"`
+
 	// SLICount counts the total number of Slice Loss Indication (SLI) packets received
 	// by the sender. This metric is only valid for video and is sent by receiver.
 	SLICount uint32 `json:"sliCount"`
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/pion/ice/v2"
+	"github.com/pion/transport/v2/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -385,3 +386,70 @@ func TestPeerConnection_GetStats_Closed(t *testing.T) {
 
 	pc.GetStats()
 }
+
+func TestPeerConnection_GetStats_RTPStreams(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerPC, answerPC, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	require.NoError(t, err)
+
+	sender, err := offerPC.AddTrack(track)
+	require.NoError(t, err)
+
+	packetsRead := make(chan struct{})
+	answerPC.OnTrack(func(trackRemote *TrackRemote, _ *RTPReceiver) {
+		for i := 0; i < 5; i++ {
+			if _, _, readErr := trackRemote.ReadRTP(); readErr != nil {
+				return
+			}
+		}
+		close(packetsRead)
+	})
+
+	assert.NoError(t, signalPair(offerPC, answerPC))
+
+	done := make(chan struct{})
+	go func() {
+		<-packetsRead
+		close(done)
+	}()
+	sendVideoUntilDone(done, t, []*TrackLocalStaticSample{track})
+
+	ssrc := sender.GetParameters().Encodings[0].SSRC
+
+	// Stats are recorded asynchronously by the interceptor
+	var outbound OutboundRTPStreamStats
+	var inbound InboundRTPStreamStats
+	require.Eventually(t, func() bool {
+		var outboundOK, inboundOK bool
+		outbound, outboundOK = offerPC.GetStats()[fmt.Sprintf("outbound-rtp-%d", ssrc)].(OutboundRTPStreamStats)
+		inbound, inboundOK = answerPC.GetStats()[fmt.Sprintf("inbound-rtp-%d", ssrc)].(InboundRTPStreamStats)
+		return outboundOK && inboundOK && outbound.PacketsSent >= 5 && inbound.PacketsReceived >= 5
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, StatsTypeOutboundRTP, outbound.Type)
+	assert.Equal(t, ssrc, outbound.SSRC)
+	assert.Equal(t, "video", outbound.Kind)
+	assert.GreaterOrEqual(t, outbound.PacketsSent, uint32(5))
+	assert.NotZero(t, outbound.BytesSent)
+	assert.NotEmpty(t, outbound.CodecID)
+
+	assert.Equal(t, StatsTypeInboundRTP, inbound.Type)
+	assert.Equal(t, ssrc, inbound.SSRC)
+	assert.Equal(t, "video", inbound.Kind)
+	assert.GreaterOrEqual(t, inbound.PacketsReceived, uint32(5))
+	assert.NotZero(t, inbound.BytesReceived)
+	assert.NotZero(t, inbound.LastPacketReceivedTimestamp)
+
+	closePairNow(t, offerPC, answerPC)
+
+	_, ok := lookupStats(offerPC.statsID)
+	assert.False(t, ok)
+}
@@ -22,6 +22,8 @@ type TrackLocalContext struct {
 	ssrc            SSRC
 	writeStream     TrackLocalWriter
 	rtcpInterceptor interceptor.RTCPReader
+
+	encodingParameters func() RTPEncodingParameters
 }
 
 // CodecParameters returns the negotiated RTPCodecParameters. These are the codecs supported by both
@@ -58,6 +60,17 @@ func (t *TrackLocalContext) RTCPReader() interceptor.RTCPReader {
 	return t.rtcpInterceptor
 }
 
+// EncodingParameters returns the RTPEncodingParameters currently applied to the encoding this TrackLocal
+// is bound to. They may be changed at any time by RTPSender.SetParameters, so implementations that encode
+// media themselves should query them regularly and adapt their output to the limits.
+func (t *TrackLocalContext) EncodingParameters() RTPEncodingParameters {
+	if t.encodingParameters == nil {
+		return RTPEncodingParameters{Active: true}
+	}
+
+	return t.encodingParameters()
+}
+
 // TrackLocal is an interface that controls how the user can send media
 // The user can provide their own TrackLocal implementations, or use
 // the implementations in pkg/media
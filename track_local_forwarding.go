@@ -0,0 +1,382 @@
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v3/pkg/rtpcodecs"
+)
+
+// How often a key frame is requested from a source that is switched to, until it sends one
+const forwardingKeyFrameRequestInterval = 500 * time.Millisecond
+
+// ForwardingSource is an upstream stream of a TrackLocalForwarding, it is implemented by TrackRemote
+type ForwardingSource interface {
+	SSRC() SSRC
+	ReadRTP() (*rtp.Packet, interceptor.Attributes, error)
+}
+
+// TrackLocalForwarding is a TrackLocal that forwards the packets of one of several upstream
+// tracks, like the simulcast layers of a track or the tracks of different speakers. When the
+// forwarded source changes, the switch happens at a key frame of the new source and the SSRC,
+// sequence numbers, timestamps and VP8 picture IDs of the outgoing stream stay continuous.
+type TrackLocalForwarding struct {
+	rtpTrack *TrackLocalStaticRTP
+
+	mu sync.Mutex
+
+	// current is the source being forwarded, selected the one to switch to
+	hasCurrent, hasSelected bool
+	current, selected       SSRC
+	lastKeyFrameRequest     time.Time
+
+	// The first packet of the current source that was forwarded
+	switchSequenceNumber uint16
+
+	started              bool
+	sequenceNumberOffset uint16
+	timestampOffset      uint32
+	lastSequenceNumber   uint16
+	lastTimestamp        uint32
+	lastWrite            time.Time
+
+	pictureIDOffset, lastPictureID uint16
+	tl0PicIdxOffset, lastTL0PicIdx uint8
+}
+
+// NewTrackLocalForwarding returns a TrackLocalForwarding, its sources must have the codec c
+func NewTrackLocalForwarding(c RTPCodecCapability, id, streamID string, options ...func(*TrackLocalStaticRTP)) (*TrackLocalForwarding, error) {
+	rtpTrack, err := NewTrackLocalStaticRTP(c, id, streamID, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TrackLocalForwarding{rtpTrack: rtpTrack}, nil
+}
+
+// ID is the unique identifier for this Track. This should be unique for the
+// stream, but doesn't have to globally unique. A common example would be 'audio' or 'video'
+// and StreamID would be 'desktop' or 'webcam'
+func (f *TrackLocalForwarding) ID() string { return f.rtpTrack.ID() }
+
+// StreamID is the group this track belongs too. This must be unique
+func (f *TrackLocalForwarding) StreamID() string { return f.rtpTrack.StreamID() }
+
+// RID is the RTP stream identifier.
+func (f *TrackLocalForwarding) RID() string { return f.rtpTrack.RID() }
+
+// Kind controls if this TrackLocal is audio or video
+func (f *TrackLocalForwarding) Kind() RTPCodecType { return f.rtpTrack.Kind() }
+
+// Codec gets the Codec of the track
+func (f *TrackLocalForwarding) Codec() RTPCodecCapability { return f.rtpTrack.Codec() }
+
+// Bind is called by the PeerConnection after negotiation is complete
+// This asserts that the code requested is supported by the remote peer.
+// If so it setups all the state (SSRC and PayloadType) to have a call
+func (f *TrackLocalForwarding) Bind(t TrackLocalContext) (RTPCodecParameters, error) {
+	return f.rtpTrack.Bind(t)
+}
+
+// Unbind implements the teardown logic when the track is no longer needed. This happens
+// because a track has been stopped.
+func (f *TrackLocalForwarding) Unbind(t TrackLocalContext) error {
+	return f.rtpTrack.Unbind(t)
+}
+
+// SwitchTo selects the source that is forwarded. The current source is forwarded until the
+// next key frame of the selected one, which is requested with a PLI.
+func (f *TrackLocalForwarding) SwitchTo(ssrc SSRC) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.hasSelected, f.selected = true, ssrc
+	f.lastKeyFrameRequest = time.Time{}
+}
+
+// Source returns the SSRC of the source that is forwarded, false if there is none yet
+func (f *TrackLocalForwarding) Source() (SSRC, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.current, f.hasCurrent
+}
+
+// Forward reads the packets of a source until it ends, and forwards them while it is selected.
+// If no source is selected, the first one that sends a packet is. writeRTCP sends the key frame
+// requests to the source, it is usually the WriteRTCP method of the PeerConnection that receives
+// it. It returns nil when the source ends, or the first error of the PeerConnections the track
+// is bound to.
+func (f *TrackLocalForwarding) Forward(source ForwardingSource, writeRTCP func([]rtcp.Packet) error) error {
+	defer f.removeSource(source.SSRC())
+
+	for {
+		packet, _, err := source.ReadRTP()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if err := f.forwardRTP(source.SSRC(), packet, writeRTCP); err != nil && !errors.Is(err, io.ErrClosedPipe) {
+			return err
+		}
+	}
+}
+
+func (f *TrackLocalForwarding) removeSource(ssrc SSRC) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.hasCurrent && f.current == ssrc {
+		f.hasCurrent = false
+	}
+	if f.hasSelected && f.selected == ssrc {
+		f.hasSelected = false
+	}
+}
+
+func (f *TrackLocalForwarding) forwardRTP(ssrc SSRC, packet *rtp.Packet, writeRTCP func([]rtcp.Packet) error) error {
+	f.mu.Lock()
+	if !f.hasSelected {
+		f.hasSelected, f.selected = true, ssrc
+	}
+
+	if f.selected == ssrc && (!f.hasCurrent || f.current != ssrc) {
+		if !isKeyFrameStart(f.rtpTrack.codec.MimeType, packet.Payload) {
+			f.requestKeyFrame(ssrc, writeRTCP)
+			f.mu.Unlock()
+			return nil
+		}
+		f.switchSource(ssrc, packet)
+	}
+
+	// Packets of the source before the switch would collide with packets of the previous one
+	if !f.hasCurrent || f.current != ssrc || packet.SequenceNumber-f.switchSequenceNumber >= 0x8000 {
+		f.mu.Unlock()
+		return nil
+	}
+
+	f.rewrite(packet)
+	f.mu.Unlock()
+
+	return f.rtpTrack.WriteRTP(packet)
+}
+
+// requestKeyFrame sends a PLI to the selected source, unless one was sent recently
+func (f *TrackLocalForwarding) requestKeyFrame(ssrc SSRC, writeRTCP func([]rtcp.Packet) error) {
+	if writeRTCP == nil || time.Since(f.lastKeyFrameRequest) < forwardingKeyFrameRequestInterval {
+		return
+	}
+	f.lastKeyFrameRequest = time.Now()
+
+	go func() {
+		_ = writeRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: uint32(ssrc)}})
+	}()
+}
+
+// switchSource computes the offsets that make the first packet of the new source follow the
+// last forwarded packet
+func (f *TrackLocalForwarding) switchSource(ssrc SSRC, packet *rtp.Packet) {
+	f.hasCurrent, f.current = true, ssrc
+	f.switchSequenceNumber = packet.SequenceNumber
+
+	if !f.started {
+		f.started = true
+		return
+	}
+
+	// The timestamps advance by the time elapsed since the last packet
+	elapsed := uint32(time.Since(f.lastWrite).Seconds() * float64(f.rtpTrack.codec.ClockRate))
+	if elapsed == 0 {
+		elapsed = 1
+	}
+	f.sequenceNumberOffset = f.lastSequenceNumber + 1 - packet.SequenceNumber
+	f.timestampOffset = f.lastTimestamp + elapsed - packet.Timestamp
+
+	if descriptor, ok := f.vp8Descriptor(packet.Payload); ok {
+		f.pictureIDOffset = f.lastPictureID + 1 - descriptor.pictureID(packet.Payload)
+		f.tl0PicIdxOffset = f.lastTL0PicIdx + 1 - descriptor.tl0PicIdx(packet.Payload)
+	}
+}
+
+// rewrite applies the offsets of the current source to a packet
+func (f *TrackLocalForwarding) rewrite(packet *rtp.Packet) {
+	packet.SequenceNumber += f.sequenceNumberOffset
+	packet.Timestamp += f.timestampOffset
+
+	if descriptor, ok := f.vp8Descriptor(packet.Payload); ok {
+		if descriptor.pictureIDIndex >= 0 {
+			f.lastPictureID = descriptor.setPictureID(packet.Payload, descriptor.pictureID(packet.Payload)+f.pictureIDOffset)
+		}
+		if descriptor.tl0PicIdxIndex >= 0 {
+			f.lastTL0PicIdx = descriptor.tl0PicIdx(packet.Payload) + f.tl0PicIdxOffset
+			packet.Payload[descriptor.tl0PicIdxIndex] = f.lastTL0PicIdx
+		}
+	}
+
+	f.lastSequenceNumber, f.lastTimestamp, f.lastWrite = packet.SequenceNumber, packet.Timestamp, time.Now()
+}
+
+// vp8Descriptor parses the payload descriptor of a packet of a VP8 track
+func (f *TrackLocalForwarding) vp8Descriptor(payload []byte) (vp8Descriptor, bool) {
+	if !strings.EqualFold(f.rtpTrack.codec.MimeType, MimeTypeVP8) {
+		return vp8Descriptor{}, false
+	}
+
+	return parseVP8Descriptor(payload)
+}
+
+// vp8Descriptor is the position of the fields of a VP8 payload descriptor that are rewritten
+type vp8Descriptor struct {
+	// Indexes of the fields in the payload, -1 if they are absent
+	pictureIDIndex, tl0PicIdxIndex int
+	longPictureID                  bool
+
+	startOfKey bool
+}
+
+// parseVP8Descriptor parses the payload descriptor of a VP8 packet, it returns false if the
+// payload is not a VP8 payload
+func parseVP8Descriptor(payload []byte) (d vp8Descriptor, ok bool) {
+	d.pictureIDIndex, d.tl0PicIdxIndex = -1, -1
+	if len(payload) < 1 {
+		return d, false
+	}
+
+	index := 1
+	if payload[0]&0x80 != 0 {
+		if len(payload) < 2 {
+			return d, false
+		}
+		extension := payload[1]
+		index++
+
+		if extension&0x80 != 0 {
+			if len(payload) <= index {
+				return d, false
+			}
+			d.pictureIDIndex = index
+			d.longPictureID = payload[index]&0x80 != 0
+			if d.longPictureID {
+				index++
+			}
+			index++
+		}
+		if extension&0x40 != 0 {
+			d.tl0PicIdxIndex = index
+			index++
+		}
+		if extension&0x30 != 0 {
+			index++
+		}
+	}
+	if len(payload) <= index {
+		return d, false
+	}
+
+	// Start of partition 0, and the key frame bit of the frame header is 0
+	d.startOfKey = payload[0]&0x1F == 0x10 && payload[index]&0x01 == 0
+
+	return d, true
+}
+
+func (d vp8Descriptor) pictureID(payload []byte) uint16 {
+	switch {
+	case d.pictureIDIndex < 0:
+		return 0
+	case d.longPictureID:
+		return uint16(payload[d.pictureIDIndex]&0x7F)<<8 | uint16(payload[d.pictureIDIndex+1])
+	default:
+		return uint16(payload[d.pictureIDIndex] & 0x7F)
+	}
+}
+
+// setPictureID writes the picture ID with the size of the field, and returns it
+func (d vp8Descriptor) setPictureID(payload []byte, pictureID uint16) uint16 {
+	if d.longPictureID {
+		pictureID &= 0x7FFF
+		payload[d.pictureIDIndex] = 0x80 | byte(pictureID>>8)
+		payload[d.pictureIDIndex+1] = byte(pictureID)
+		return pictureID
+	}
+
+	pictureID &= 0x7F
+	payload[d.pictureIDIndex] = byte(pictureID)
+	return pictureID
+}
+
+func (d vp8Descriptor) tl0PicIdx(payload []byte) uint8 {
+	if d.tl0PicIdxIndex < 0 {
+		return 0
+	}
+
+	return payload[d.tl0PicIdxIndex]
+}
+
+// isKeyFrameStart returns true if the payload is the first packet of a key frame, sources of
+// codecs without key frames can be switched to at any packet
+func isKeyFrameStart(mimeType string, payload []byte) bool {
+	switch {
+	case strings.EqualFold(mimeType, MimeTypeVP8):
+		descriptor, ok := parseVP8Descriptor(payload)
+		return ok && descriptor.startOfKey
+	case strings.EqualFold(mimeType, MimeTypeVP9):
+		vp9 := &codecs.VP9Packet{}
+		if _, err := vp9.Unmarshal(payload); err != nil {
+			return false
+		}
+		return vp9.B && !vp9.P && vp9.SID == 0
+	case strings.EqualFold(mimeType, MimeTypeH264):
+		return h264IsKeyFrameStart(payload)
+	case strings.EqualFold(mimeType, MimeTypeH265):
+		return rtpcodecs.H265IsKeyFrame(payload)
+	case strings.EqualFold(mimeType, MimeTypeAV1):
+		// The first packet of a new coded video sequence
+		return len(payload) > 0 && payload[0]&0x80 == 0 && payload[0]&0x08 != 0
+	default:
+		return !strings.HasPrefix(strings.ToLower(mimeType), "video/")
+	}
+}
+
+// h264IsKeyFrameStart returns true if the payload starts an IDR picture, or the SPS that
+// precedes it
+func h264IsKeyFrameStart(payload []byte) bool {
+	const (
+		naluTypeIDR   = 5
+		naluTypeSPS   = 7
+		naluTypeSTAPA = 24
+		naluTypeFUA   = 28
+	)
+
+	if len(payload) < 1 {
+		return false
+	}
+
+	switch payload[0] & 0x1F {
+	case naluTypeIDR, naluTypeSPS:
+		return true
+	case naluTypeSTAPA:
+		for offset := 1; offset+2 < len(payload); {
+			naluSize := int(payload[offset])<<8 | int(payload[offset+1])
+			if naluType := payload[offset+2] & 0x1F; naluType == naluTypeIDR || naluType == naluTypeSPS {
+				return true
+			}
+			offset += 2 + naluSize
+		}
+	case naluTypeFUA:
+		return len(payload) > 1 && payload[1]&0x80 != 0 && payload[1]&0x1F == naluTypeIDR
+	}
+
+	return false
+}
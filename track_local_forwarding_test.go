@@ -0,0 +1,165 @@
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+type forwardingTestWriter struct {
+	packets []*rtp.Packet
+}
+
+func (w *forwardingTestWriter) WriteRTP(header *rtp.Header, payload []byte) (int, error) {
+	w.packets = append(w.packets, &rtp.Packet{Header: *header, Payload: append([]byte{}, payload...)})
+	return header.MarshalSize() + len(payload), nil
+}
+
+func (w *forwardingTestWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// vp8ForwardingPacket returns a packet with a single VP8 frame, with a 15 bits picture ID and a TL0PICIDX
+func vp8ForwardingPacket(sequenceNumber uint16, timestamp uint32, pictureID uint16, tl0PicIdx uint8, keyFrame bool) *rtp.Packet {
+	header := byte(0x01)
+	if keyFrame {
+		header = 0x00
+	}
+
+	return &rtp.Packet{
+		Header:  rtp.Header{Version: 2, SequenceNumber: sequenceNumber, Timestamp: timestamp, SSRC: 1, PayloadType: 100},
+		Payload: []byte{0x90, 0xC0, 0x80 | byte(pictureID>>8), byte(pictureID), tl0PicIdx, header, 0xAA},
+	}
+}
+
+func Test_TrackLocalForwarding_Switch(t *testing.T) {
+	track, err := NewTrackLocalForwarding(RTPCodecCapability{MimeType: MimeTypeVP8, ClockRate: 90000}, "video", "pion")
+	assert.NoError(t, err)
+
+	writer := &forwardingTestWriter{}
+	_, err = track.Bind(TrackLocalContext{
+		id: "forwarding",
+		params: RTPParameters{Codecs: []RTPCodecParameters{{
+			RTPCodecCapability: RTPCodecCapability{MimeType: MimeTypeVP8, ClockRate: 90000},
+			PayloadType:        96,
+		}}},
+		ssrc:        5000,
+		writeStream: writer,
+	})
+	assert.NoError(t, err)
+
+	keyFrameRequests := make(chan uint32, 10)
+	writeRTCP := func(pkts []rtcp.Packet) error {
+		keyFrameRequests <- pkts[0].(*rtcp.PictureLossIndication).MediaSSRC
+		return nil
+	}
+
+	forward := func(ssrc SSRC, p *rtp.Packet) {
+		assert.NoError(t, track.forwardRTP(ssrc, p, writeRTCP))
+	}
+
+	// The first source starts at a key frame
+	forward(1, vp8ForwardingPacket(99, 1000, 499, 9, false))
+	assert.Equal(t, uint32(1), <-keyFrameRequests)
+	forward(1, vp8ForwardingPacket(100, 4000, 500, 10, true))
+	forward(1, vp8ForwardingPacket(101, 7000, 501, 11, false))
+
+	source, ok := track.Source()
+	assert.True(t, ok)
+	assert.Equal(t, SSRC(1), source)
+
+	// The second source is switched to at its next key frame
+	track.SwitchTo(2)
+	forward(2, vp8ForwardingPacket(30000, 900000, 20, 200, false))
+	assert.Equal(t, uint32(2), <-keyFrameRequests)
+	forward(1, vp8ForwardingPacket(102, 10000, 502, 12, false))
+	time.Sleep(10 * time.Millisecond)
+	forward(2, vp8ForwardingPacket(30001, 903000, 21, 201, true))
+	forward(1, vp8ForwardingPacket(103, 13000, 503, 13, false))
+	forward(2, vp8ForwardingPacket(30002, 906000, 22, 202, false))
+	// Packets sent before the switch are dropped
+	forward(2, vp8ForwardingPacket(29999, 897000, 19, 199, false))
+
+	source, _ = track.Source()
+	assert.Equal(t, SSRC(2), source)
+
+	assert.Equal(t, 5, len(writer.packets))
+	for i, p := range writer.packets {
+		assert.Equal(t, uint32(5000), p.SSRC)
+		assert.Equal(t, uint8(96), p.PayloadType)
+		assert.Equal(t, uint16(100+i), p.SequenceNumber)
+
+		descriptor, ok := parseVP8Descriptor(p.Payload)
+		assert.True(t, ok)
+		assert.Equal(t, uint16(500+i), descriptor.pictureID(p.Payload))
+		assert.Equal(t, uint8(10+i), descriptor.tl0PicIdx(p.Payload))
+	}
+	assert.Equal(t, uint32(10000), writer.packets[2].Timestamp)
+	assert.Greater(t, writer.packets[3].Timestamp, uint32(10000))
+	assert.Equal(t, writer.packets[3].Timestamp+3000, writer.packets[4].Timestamp)
+}
+
+type forwardingTestSource struct {
+	ssrc    SSRC
+	packets []*rtp.Packet
+}
+
+func (s *forwardingTestSource) SSRC() SSRC { return s.ssrc }
+
+func (s *forwardingTestSource) ReadRTP() (*rtp.Packet, interceptor.Attributes, error) {
+	if len(s.packets) == 0 {
+		return nil, nil, io.EOF
+	}
+	p := s.packets[0]
+	s.packets = s.packets[1:]
+	return p, nil, nil
+}
+
+func Test_TrackLocalForwarding_Forward(t *testing.T) {
+	track, err := NewTrackLocalForwarding(RTPCodecCapability{MimeType: MimeTypeOpus, ClockRate: 48000, Channels: 2}, "audio", "pion")
+	assert.NoError(t, err)
+
+	writer := &forwardingTestWriter{}
+	_, err = track.Bind(TrackLocalContext{
+		id: "forwarding",
+		params: RTPParameters{Codecs: []RTPCodecParameters{{
+			RTPCodecCapability: RTPCodecCapability{MimeType: MimeTypeOpus, ClockRate: 48000, Channels: 2},
+			PayloadType:        111,
+		}}},
+		ssrc:        5000,
+		writeStream: writer,
+	})
+	assert.NoError(t, err)
+
+	// Audio sources are switched to immediately, the next source is selected when one ends
+	for _, source := range []*forwardingTestSource{
+		{ssrc: 1, packets: []*rtp.Packet{{Header: rtp.Header{SequenceNumber: 10, Timestamp: 960}, Payload: []byte{0x01}}}},
+		{ssrc: 2, packets: []*rtp.Packet{{Header: rtp.Header{SequenceNumber: 60000, Timestamp: 123}, Payload: []byte{0x02}}}},
+	} {
+		assert.NoError(t, track.Forward(source, nil))
+		_, ok := track.Source()
+		assert.False(t, ok)
+	}
+
+	assert.Equal(t, 2, len(writer.packets))
+	assert.Equal(t, uint16(10), writer.packets[0].SequenceNumber)
+	assert.Equal(t, uint16(11), writer.packets[1].SequenceNumber)
+	assert.Greater(t, writer.packets[1].Timestamp, uint32(960))
+}
+
+func TestH264IsKeyFrameStart(t *testing.T) {
+	assert.True(t, h264IsKeyFrameStart([]byte{0x65, 0x00}))
+	assert.True(t, h264IsKeyFrameStart([]byte{0x78, 0x00, 0x02, 0x67, 0x00, 0x00, 0x02, 0x68, 0x00}))
+	assert.True(t, h264IsKeyFrameStart([]byte{0x7C, 0x85, 0x00}))
+	assert.False(t, h264IsKeyFrameStart([]byte{0x7C, 0x05, 0x00}))
+	assert.False(t, h264IsKeyFrameStart([]byte{0x41, 0x00}))
+	assert.False(t, h264IsKeyFrameStart(nil))
+}
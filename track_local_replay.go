@@ -0,0 +1,334 @@
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/media/pcap"
+	"github.com/pion/webrtc/v3/pkg/media/rtpdump"
+)
+
+// The gap between the last packet of a loop and the first packet of the next one, when the
+// recording is too short to estimate the packet interval
+const replayLoopGap = 20 * time.Millisecond
+
+// ReplaySource is a source of recorded RTP packets for a TrackLocalReplay
+type ReplaySource interface {
+	// NextPacket returns the next packet of the recording and the time since the start of the
+	// recording it was captured at. It returns io.EOF at the end of the recording.
+	NextPacket() (*rtp.Packet, time.Duration, error)
+}
+
+type rtpdumpReplaySource struct {
+	reader *rtpdump.Reader
+}
+
+// NewRTPDumpReplaySource returns a ReplaySource of the RTP packets of a rtpdump recording. RTCP
+// packets and truncated RTP packets are skipped.
+func NewRTPDumpReplaySource(reader *rtpdump.Reader) ReplaySource {
+	return &rtpdumpReplaySource{reader: reader}
+}
+
+func (r *rtpdumpReplaySource) NextPacket() (*rtp.Packet, time.Duration, error) {
+	for {
+		packet, err := r.reader.Next()
+		if err != nil {
+			return nil, 0, err
+		}
+		if packet.IsRTCP {
+			continue
+		}
+
+		rtpPacket := &rtp.Packet{}
+		if err := rtpPacket.Unmarshal(packet.Payload); err != nil {
+			continue
+		}
+		return rtpPacket, packet.Offset, nil
+	}
+}
+
+type pcapReplaySource struct {
+	reader  *pcap.Reader
+	started bool
+	start   time.Time
+}
+
+// NewPCAPReplaySource returns a ReplaySource of the RTP packets of a pcap or pcapng capture. The
+// reader should be filtered to a single stream, with pcap.WithSSRC.
+func NewPCAPReplaySource(reader *pcap.Reader) ReplaySource {
+	return &pcapReplaySource{reader: reader}
+}
+
+func (p *pcapReplaySource) NextPacket() (*rtp.Packet, time.Duration, error) {
+	for {
+		packet, err := p.reader.Next()
+		if err != nil {
+			return nil, 0, err
+		}
+		if packet.IsRTCP {
+			continue
+		}
+
+		rtpPacket, err := packet.RTP()
+		if err != nil {
+			continue
+		}
+		if !p.started {
+			p.started, p.start = true, packet.Timestamp
+		}
+		return rtpPacket, packet.Timestamp.Sub(p.start), nil
+	}
+}
+
+type replayPacket struct {
+	packet *rtp.Packet
+	offset time.Duration
+}
+
+// TrackLocalReplay is a TrackLocal that replays a recorded RTP stream with its original timing.
+// The SSRC and the payload type of the packets are rewritten to the values negotiated by each
+// PeerConnection, the sequence numbers and the timestamps keep increasing across loops.
+type TrackLocalReplay struct {
+	rtpTrack *TrackLocalStaticRTP
+	source   ReplaySource
+
+	speed float64
+	loops int
+
+	mu        sync.Mutex
+	playing   bool
+	exhausted bool
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	// The position of the replay, kept between calls to Play so it can be resumed. pending is
+	// the packet of the source that is due next, and recorded the packets replayed by the
+	// next loops.
+	recorded    []replayPacket
+	pending     *replayPacket
+	loop, count int
+	first, last replayPacket
+	seqOffset   uint16
+	tsOffset    uint32
+	elapsed     time.Duration
+	position    time.Duration
+}
+
+// NewTrackLocalReplay returns a TrackLocalReplay of the packets of the source, they should
+// have the codec c
+func NewTrackLocalReplay(c RTPCodecCapability, id, streamID string, source ReplaySource, options ...func(*TrackLocalReplay)) (*TrackLocalReplay, error) {
+	rtpTrack, err := NewTrackLocalStaticRTP(c, id, streamID)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &TrackLocalReplay{
+		rtpTrack: rtpTrack,
+		source:   source,
+		speed:    1,
+		loops:    1,
+		closed:   make(chan struct{}),
+	}
+	for _, option := range options {
+		option(r)
+	}
+
+	return r, nil
+}
+
+// WithReplaySpeed sets the speed-up of a TrackLocalReplay, 2 replays the recording twice as fast.
+// With a speed of 0, the packets are sent as fast as possible.
+func WithReplaySpeed(speed float64) func(*TrackLocalReplay) {
+	return func(r *TrackLocalReplay) {
+		r.speed = speed
+	}
+}
+
+// WithReplayLoops sets how many times a TrackLocalReplay plays the recording, it is played until
+// Close if count is 0
+func WithReplayLoops(count int) func(*TrackLocalReplay) {
+	return func(r *TrackLocalReplay) {
+		r.loops = count
+	}
+}
+
+// ID is the unique identifier for this Track. This should be unique for the
+// stream, but doesn't have to globally unique. A common example would be 'audio' or 'video'
+// and StreamID would be 'desktop' or 'webcam'
+func (r *TrackLocalReplay) ID() string { return r.rtpTrack.ID() }
+
+// StreamID is the group this track belongs too. This must be unique
+func (r *TrackLocalReplay) StreamID() string { return r.rtpTrack.StreamID() }
+
+// RID is the RTP stream identifier.
+func (r *TrackLocalReplay) RID() string { return r.rtpTrack.RID() }
+
+// Kind controls if this TrackLocal is audio or video
+func (r *TrackLocalReplay) Kind() RTPCodecType { return r.rtpTrack.Kind() }
+
+// Codec gets the Codec of the track
+func (r *TrackLocalReplay) Codec() RTPCodecCapability { return r.rtpTrack.Codec() }
+
+// Bind is called by the PeerConnection after negotiation is complete
+// This asserts that the code requested is supported by the remote peer.
+// If so it setups all the state (SSRC and PayloadType) to have a call
+func (r *TrackLocalReplay) Bind(t TrackLocalContext) (RTPCodecParameters, error) {
+	return r.rtpTrack.Bind(t)
+}
+
+// Unbind implements the teardown logic when the track is no longer needed. This happens
+// because a track has been stopped.
+func (r *TrackLocalReplay) Unbind(t TrackLocalContext) error {
+	return r.rtpTrack.Unbind(t)
+}
+
+// Play replays the recording and returns when it ends, or when the TrackLocalReplay is closed.
+// The packets are only sent to the PeerConnections the track is bound to at the time they are
+// due. An error of the source or of a PeerConnection stops the replay, a later call resumes it
+// from the packet that failed. Once all the loops have been played, the source is exhausted
+// and Play returns errTrackLocalReplaySourceExhausted.
+func (r *TrackLocalReplay) Play() error {
+	r.mu.Lock()
+	if r.playing {
+		r.mu.Unlock()
+		return errTrackLocalReplayPlaying
+	}
+	if r.exhausted {
+		r.mu.Unlock()
+		return errTrackLocalReplaySourceExhausted
+	}
+	r.playing = true
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		r.playing = false
+		r.mu.Unlock()
+	}()
+
+	// A resumed replay continues from the time of the last packet sent
+	start := time.Now().Add(-r.scale(r.position))
+
+	for ; r.loops == 0 || r.loop < r.loops; r.loop++ {
+		for ; ; r.count++ {
+			p, ok, err := r.nextPacket()
+			if err != nil {
+				return err
+			} else if !ok {
+				break
+			}
+
+			if r.count == 0 {
+				r.first = p
+			}
+
+			position := r.elapsed + p.offset - r.first.offset
+			if !r.wait(start.Add(r.scale(position))) {
+				return nil
+			}
+
+			packet := *p.packet
+			packet.SequenceNumber += r.seqOffset
+			packet.Timestamp += r.tsOffset
+			if err := r.rtpTrack.writeRTP(&packet, nil); err != nil {
+				return err
+			}
+			r.pending, r.last, r.position = nil, p, position
+		}
+
+		if r.count == 0 {
+			break
+		}
+
+		// The next loop starts one packet interval after the last packet
+		interval := replayLoopGap
+		if r.count > 1 {
+			interval = (r.last.offset - r.first.offset) / time.Duration(r.count-1)
+		}
+		r.elapsed += r.last.offset - r.first.offset + interval
+		r.seqOffset += r.last.packet.SequenceNumber - r.first.packet.SequenceNumber + 1
+		r.tsOffset += r.last.packet.Timestamp - r.first.packet.Timestamp + uint32(interval.Seconds()*float64(r.rtpTrack.codec.ClockRate))
+		r.count = 0
+	}
+
+	r.mu.Lock()
+	r.exhausted = true
+	r.mu.Unlock()
+
+	return nil
+}
+
+// nextPacket returns the packet at the position of the replay, ok is false at the end of the loop.
+// The first loop reads the packets from the source, the next ones replay the recorded packets.
+func (r *TrackLocalReplay) nextPacket() (p replayPacket, ok bool, err error) {
+	if r.loop > 0 {
+		if r.count == len(r.recorded) {
+			return p, false, nil
+		}
+		return r.recorded[r.count], true, nil
+	}
+
+	if r.pending == nil {
+		packet, offset, err := r.source.NextPacket()
+		if errors.Is(err, io.EOF) {
+			return p, false, nil
+		} else if err != nil {
+			return p, false, err
+		}
+
+		r.pending = &replayPacket{packet, offset}
+		if r.loops != 1 {
+			r.recorded = append(r.recorded, *r.pending)
+		}
+	}
+
+	return *r.pending, true, nil
+}
+
+// Close stops the replay
+func (r *TrackLocalReplay) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.closed)
+	})
+
+	return nil
+}
+
+// scale returns the time to wait at the speed of the replay
+func (r *TrackLocalReplay) scale(d time.Duration) time.Duration {
+	if r.speed <= 0 {
+		return 0
+	}
+
+	return time.Duration(float64(d) / r.speed)
+}
+
+// wait waits until the given time, it returns false if the replay is closed meanwhile
+func (r *TrackLocalReplay) wait(until time.Time) bool {
+	select {
+	case <-r.closed:
+		return false
+	default:
+	}
+
+	d := time.Until(until)
+	if d <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-r.closed:
+		return false
+	}
+}
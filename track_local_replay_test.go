@@ -0,0 +1,179 @@
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/media/rtpdump"
+	"github.com/stretchr/testify/assert"
+)
+
+type replayTestWriter struct {
+	mu      sync.Mutex
+	headers []rtp.Header
+	times   []time.Time
+}
+
+func (w *replayTestWriter) WriteRTP(header *rtp.Header, payload []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.headers = append(w.headers, *header)
+	w.times = append(w.times, time.Now())
+	return header.MarshalSize() + len(payload), nil
+}
+
+func (w *replayTestWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func replayRecording(t *testing.T) *bytes.Buffer {
+	buffer := &bytes.Buffer{}
+	writer, err := rtpdump.NewWriter(buffer, rtpdump.Header{Source: net.IPv4(127, 0, 0, 1), Port: 5000})
+	assert.NoError(t, err)
+
+	for i, offset := range []time.Duration{0, 20, 40, 60} {
+		payload, err := (&rtp.Packet{
+			Header:  rtp.Header{Version: 2, PayloadType: 96, SequenceNumber: 100 + uint16(i), Timestamp: 5000 + uint32(i)*960, SSRC: 1234},
+			Payload: []byte{byte(i)},
+		}).Marshal()
+		assert.NoError(t, err)
+		assert.NoError(t, writer.WritePacket(rtpdump.Packet{Offset: offset * time.Millisecond, Payload: payload}))
+	}
+	assert.NoError(t, writer.WritePacket(rtpdump.Packet{Offset: 70 * time.Millisecond, IsRTCP: true, Payload: []byte{0x80, 0xC9, 0x00, 0x01, 0x00, 0x00, 0x04, 0xD2}}))
+
+	return buffer
+}
+
+func bindReplay(t *testing.T, track *TrackLocalReplay) *replayTestWriter {
+	writer := &replayTestWriter{}
+	codec, err := track.Bind(TrackLocalContext{
+		id: "replay",
+		params: RTPParameters{Codecs: []RTPCodecParameters{{
+			RTPCodecCapability: RTPCodecCapability{MimeType: MimeTypeOpus, ClockRate: 48000, Channels: 2},
+			PayloadType:        111,
+		}}},
+		ssrc:        5678,
+		writeStream: writer,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, PayloadType(111), codec.PayloadType)
+
+	return writer
+}
+
+func Test_TrackLocalReplay(t *testing.T) {
+	reader, _, err := rtpdump.NewReader(replayRecording(t))
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalReplay(RTPCodecCapability{MimeType: MimeTypeOpus, ClockRate: 48000, Channels: 2}, "audio", "pion",
+		NewRTPDumpReplaySource(reader), WithReplaySpeed(2), WithReplayLoops(2))
+	assert.NoError(t, err)
+	assert.Equal(t, RTPCodecTypeAudio, track.Kind())
+
+	writer := bindReplay(t, track)
+
+	start := time.Now()
+	assert.NoError(t, track.Play())
+	assert.Equal(t, errTrackLocalReplaySourceExhausted, track.Play())
+
+	// The second loop starts 80ms after the first one, at twice the speed
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(70*time.Millisecond))
+	assert.Equal(t, 8, len(writer.headers))
+	for i, header := range writer.headers {
+		assert.Equal(t, uint32(5678), header.SSRC)
+		assert.Equal(t, uint8(111), header.PayloadType)
+		assert.Equal(t, 100+uint16(i), header.SequenceNumber)
+		assert.Equal(t, 5000+uint32(i)*960, header.Timestamp)
+	}
+	assert.GreaterOrEqual(t, int64(writer.times[7].Sub(writer.times[0])), int64(70*time.Millisecond))
+}
+
+func Test_TrackLocalReplay_Close(t *testing.T) {
+	reader, _, err := rtpdump.NewReader(replayRecording(t))
+	assert.NoError(t, err)
+
+	track, err := NewTrackLocalReplay(RTPCodecCapability{MimeType: MimeTypeOpus, ClockRate: 48000, Channels: 2}, "audio", "pion",
+		NewRTPDumpReplaySource(reader), WithReplayLoops(0))
+	assert.NoError(t, err)
+
+	writer := bindReplay(t, track)
+
+	done := make(chan error)
+	go func() {
+		done <- track.Play()
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, errTrackLocalReplayPlaying, track.Play())
+	assert.NoError(t, track.Close())
+	assert.NoError(t, <-done)
+
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+	assert.Greater(t, len(writer.headers), 4)
+	for i := 1; i < len(writer.headers); i++ {
+		assert.Equal(t, writer.headers[i-1].SequenceNumber+1, writer.headers[i].SequenceNumber)
+	}
+}
+
+// failingReplaySource returns count packets 20ms apart, and fails once before the packet at failAt
+type failingReplaySource struct {
+	count, failAt int
+	next          int
+	failed        bool
+}
+
+func (f *failingReplaySource) NextPacket() (*rtp.Packet, time.Duration, error) {
+	if f.next == f.failAt && !f.failed {
+		f.failed = true
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	if f.next == f.count {
+		return nil, 0, io.EOF
+	}
+
+	f.next++
+	packet := &rtp.Packet{Header: rtp.Header{Version: 2, SequenceNumber: uint16(f.next), Timestamp: uint32(f.next) * 960}}
+	return packet, time.Duration(f.next) * 20 * time.Millisecond, nil
+}
+
+// Assert that the replay can be resumed after an error of its source
+func Test_TrackLocalReplay_Resume(t *testing.T) {
+	track, err := NewTrackLocalReplay(RTPCodecCapability{MimeType: MimeTypeOpus, ClockRate: 48000, Channels: 2}, "audio", "pion",
+		&failingReplaySource{count: 1})
+	assert.NoError(t, err)
+
+	writer := bindReplay(t, track)
+
+	assert.Equal(t, io.ErrUnexpectedEOF, track.Play())
+	assert.NoError(t, track.Play())
+	assert.Equal(t, 1, len(writer.headers))
+	assert.Equal(t, errTrackLocalReplaySourceExhausted, track.Play())
+
+	// The packets of the first loop read before the error are replayed by the next ones
+	track, err = NewTrackLocalReplay(RTPCodecCapability{MimeType: MimeTypeOpus, ClockRate: 48000, Channels: 2}, "audio", "pion",
+		&failingReplaySource{count: 3, failAt: 2}, WithReplayLoops(2), WithReplaySpeed(4))
+	assert.NoError(t, err)
+
+	writer = bindReplay(t, track)
+
+	assert.Equal(t, io.ErrUnexpectedEOF, track.Play())
+	assert.Equal(t, 2, len(writer.headers))
+	assert.NoError(t, track.Play())
+	assert.Equal(t, errTrackLocalReplaySourceExhausted, track.Play())
+
+	assert.Equal(t, 6, len(writer.headers))
+	for i, header := range writer.headers {
+		assert.Equal(t, 1+uint16(i), header.SequenceNumber)
+		assert.Equal(t, 960+uint32(i)*960, header.Timestamp)
+	}
+}
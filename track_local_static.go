@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/internal/red"
 	"github.com/pion/webrtc/v3/internal/util"
 	"github.com/pion/webrtc/v3/pkg/media"
 )
@@ -20,6 +21,9 @@ type trackBinding struct {
 	ssrc        SSRC
 	payloadType PayloadType
 	writeStream TrackLocalWriter
+
+	// redPayloadType is the payload type of the negotiated RED codec, 0 if RED isn't used
+	redPayloadType PayloadType
 }
 
 // TrackLocalStaticRTP  is a TrackLocal that has a pre-set codec and accepts RTP Packets.
@@ -29,10 +33,24 @@ type TrackLocalStaticRTP struct {
 	bindings          []trackBinding
 	codec             RTPCodecCapability
 	id, rid, streamID string
+	redDistance       int
+
+	onKeyFrameRequestHandler func()
 }
 
 // NewTrackLocalStaticRTP returns a TrackLocalStaticRTP.
 func NewTrackLocalStaticRTP(c RTPCodecCapability, id, streamID string, options ...func(*TrackLocalStaticRTP)) (*TrackLocalStaticRTP, error) {
+	t := newTrackLocalStaticRTP(c, id, streamID, options...)
+
+	// RTP packets are written as they are, only samples can be wrapped in RED
+	if t.redDistance != 0 {
+		return nil, errTrackLocalStaticRTPREDDistance
+	}
+
+	return t, nil
+}
+
+func newTrackLocalStaticRTP(c RTPCodecCapability, id, streamID string, options ...func(*TrackLocalStaticRTP)) *TrackLocalStaticRTP {
 	t := &TrackLocalStaticRTP{
 		codec:    c,
 		bindings: []trackBinding{},
@@ -44,7 +62,7 @@ func NewTrackLocalStaticRTP(c RTPCodecCapability, id, streamID string, options .
 		option(t)
 	}
 
-	return t, nil
+	return t
 }
 
 // WithRTPStreamID sets the RTP stream ID for this TrackLocalStaticRTP.
@@ -54,6 +72,16 @@ func WithRTPStreamID(rid string) func(*TrackLocalStaticRTP) {
 	}
 }
 
+// WithREDDistance enables RED (RFC 2198) for a TrackLocalStaticSample sending Opus. Each packet
+// also carries the payloads of the distance previous packets, so the remote peer can recover
+// from losses without retransmissions. RED is only used if it is configured with ConfigureRED
+// and the remote peer supports it. NewTrackLocalStaticRTP rejects this option.
+func WithREDDistance(distance int) func(*TrackLocalStaticRTP) {
+	return func(t *TrackLocalStaticRTP) {
+		t.redDistance = distance
+	}
+}
+
 // Bind is called by the PeerConnection after negotiation is complete
 // This asserts that the code requested is supported by the remote peer.
 // If so it setups all the state (SSRC and PayloadType) to have a call
@@ -120,6 +148,33 @@ func (s *TrackLocalStaticRTP) Codec() RTPCodecCapability {
 	return s.codec
 }
 
+// OnKeyFrameRequest sets an event handler which is called when a remote peer requests a key
+// frame of the track. It must be set before the track is negotiated, see
+// RTPSender.OnKeyFrameRequest for how the requests are handled.
+func (s *TrackLocalStaticRTP) OnKeyFrameRequest(f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onKeyFrameRequestHandler = f
+}
+
+func (s *TrackLocalStaticRTP) handlesKeyFrameRequests() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.onKeyFrameRequestHandler != nil
+}
+
+func (s *TrackLocalStaticRTP) handleKeyFrameRequest() {
+	s.mu.RLock()
+	handler := s.onKeyFrameRequestHandler
+	s.mu.RUnlock()
+
+	if handler != nil {
+		handler()
+	}
+}
+
 // packetPool is a pool of packets used by WriteRTP and Write below
 // nolint:gochecknoglobals
 var rtpPacketPool = sync.Pool{
@@ -149,24 +204,36 @@ func (s *TrackLocalStaticRTP) WriteRTP(p *rtp.Packet) error {
 
 	*packet = *p
 
-	return s.writeRTP(packet)
+	return s.writeRTP(packet, nil)
 }
 
-// writeRTP is like WriteRTP, except that it may modify the packet p
-func (s *TrackLocalStaticRTP) writeRTP(p *rtp.Packet) error {
+// writeRTP is like WriteRTP, except that it may modify the packet p.
+// If a RED encoder is given, the payload is wrapped in RED for the bindings that negotiated it.
+func (s *TrackLocalStaticRTP) writeRTP(p *rtp.Packet, redEncoder *red.Encoder) error {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	writeErrs := []error{}
 
+	payload := p.Payload
 	for _, b := range s.bindings {
 		p.Header.SSRC = uint32(b.ssrc)
 		p.Header.PayloadType = uint8(b.payloadType)
+		p.Payload = payload
+		if redEncoder != nil && b.redPayloadType != 0 {
+			p.Header.PayloadType = uint8(b.redPayloadType)
+			p.Payload = redEncoder.Encode(uint8(b.payloadType), p.Timestamp, payload)
+		}
+
 		if _, err := b.writeStream.WriteRTP(&p.Header, p.Payload); err != nil {
 			writeErrs = append(writeErrs, err)
 		}
 	}
 
+	if redEncoder != nil {
+		redEncoder.Push(p.Timestamp, payload)
+	}
+
 	return util.FlattenErrs(writeErrs)
 }
 
@@ -183,7 +250,7 @@ func (s *TrackLocalStaticRTP) Write(b []byte) (n int, err error) {
 		return 0, err
 	}
 
-	return len(b), s.writeRTP(packet)
+	return len(b), s.writeRTP(packet, nil)
 }
 
 // TrackLocalStaticSample is a TrackLocal that has a pre-set codec and accepts Samples.
@@ -193,17 +260,13 @@ type TrackLocalStaticSample struct {
 	sequencer  rtp.Sequencer
 	rtpTrack   *TrackLocalStaticRTP
 	clockRate  float64
+	redEncoder *red.Encoder
 }
 
 // NewTrackLocalStaticSample returns a TrackLocalStaticSample
 func NewTrackLocalStaticSample(c RTPCodecCapability, id, streamID string, options ...func(*TrackLocalStaticRTP)) (*TrackLocalStaticSample, error) {
-	rtpTrack, err := NewTrackLocalStaticRTP(c, id, streamID, options...)
-	if err != nil {
-		return nil, err
-	}
-
 	return &TrackLocalStaticSample{
-		rtpTrack: rtpTrack,
+		rtpTrack: newTrackLocalStaticRTP(c, id, streamID, options...),
 	}, nil
 }
 
@@ -238,6 +301,20 @@ func (s *TrackLocalStaticSample) Bind(t TrackLocalContext) (RTPCodecParameters,
 	s.rtpTrack.mu.Lock()
 	defer s.rtpTrack.mu.Unlock()
 
+	if s.rtpTrack.redDistance > 0 && strings.EqualFold(codec.MimeType, MimeTypeOpus) {
+		if redCodec, ok := findREDCodec(codec.PayloadType, t.CodecParameters()); ok {
+			for i := range s.rtpTrack.bindings {
+				if s.rtpTrack.bindings[i].id == t.ID() {
+					s.rtpTrack.bindings[i].redPayloadType = redCodec.PayloadType
+				}
+			}
+
+			if s.redEncoder == nil {
+				s.redEncoder = red.NewEncoder(s.rtpTrack.redDistance)
+			}
+		}
+	}
+
 	// We only need one packetizer
 	if s.packetizer != nil {
 		return codec, nil
@@ -267,6 +344,21 @@ func (s *TrackLocalStaticSample) Unbind(t TrackLocalContext) error {
 	return s.rtpTrack.Unbind(t)
 }
 
+// OnKeyFrameRequest sets an event handler which is called when a remote peer requests a key
+// frame of the track. It must be set before the track is negotiated, see
+// RTPSender.OnKeyFrameRequest for how the requests are handled.
+func (s *TrackLocalStaticSample) OnKeyFrameRequest(f func()) {
+	s.rtpTrack.OnKeyFrameRequest(f)
+}
+
+func (s *TrackLocalStaticSample) handlesKeyFrameRequests() bool {
+	return s.rtpTrack.handlesKeyFrameRequests()
+}
+
+func (s *TrackLocalStaticSample) handleKeyFrameRequest() {
+	s.rtpTrack.handleKeyFrameRequest()
+}
+
 // WriteSample writes a Sample to the TrackLocalStaticSample
 // If one PeerConnection fails the packets will still be sent to
 // all PeerConnections. The error message will contain the ID of the failed
@@ -275,6 +367,7 @@ func (s *TrackLocalStaticSample) WriteSample(sample media.Sample) error {
 	s.rtpTrack.mu.RLock()
 	p := s.packetizer
 	clockRate := s.clockRate
+	redEncoder := s.redEncoder
 	s.rtpTrack.mu.RUnlock()
 
 	if p == nil {
@@ -294,7 +387,7 @@ func (s *TrackLocalStaticSample) WriteSample(sample media.Sample) error {
 
 	writeErrs := []error{}
 	for _, p := range packets {
-		if err := s.rtpTrack.WriteRTP(p); err != nil {
+		if err := s.rtpTrack.writeRTP(p, redEncoder); err != nil {
 			writeErrs = append(writeErrs, err)
 		}
 	}
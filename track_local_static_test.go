@@ -6,11 +6,15 @@ package webrtc
 import (
 	"context"
 	"errors"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/pion/interceptor"
+	mock_interceptor "github.com/pion/interceptor/pkg/mock"
 	"github.com/pion/rtp"
 	"github.com/pion/transport/v2/test"
+	"github.com/pion/webrtc/v3/pkg/media"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -225,6 +229,106 @@ func Test_TrackLocalStatic_Binding_NonBlocking(t *testing.T) {
 	closePairNow(t, pcOffer, pcAnswer)
 }
 
+// Assert that Opus is wrapped in RED when negotiated, and that the receiver restores
+// lost packets from the redundant encodings before returning plain Opus
+func Test_TrackLocalStatic_RED(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	m := &MediaEngine{}
+	assert.NoError(t, m.RegisterDefaultCodecs())
+	assert.NoError(t, ConfigureRED(m))
+
+	var redPacketsSent uint32
+	ir := &interceptor.Registry{}
+	ir.Add(&mock_interceptor.Factory{
+		NewInterceptorFn: func(_ string) (interceptor.Interceptor, error) {
+			return &mock_interceptor.Interceptor{
+				BindLocalStreamFn: func(_ *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+					return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+						if header.PayloadType == 63 {
+							atomic.AddUint32(&redPacketsSent, 1)
+						}
+
+						// Drop some packets, they are restored from the next one
+						if header.SequenceNumber%5 == 2 {
+							return header.MarshalSize() + len(payload), nil
+						}
+						return writer.Write(header, payload, attributes)
+					})
+				},
+			}, nil
+		},
+	})
+
+	pcOffer, err := NewAPI(WithMediaEngine(m), WithInterceptorRegistry(ir)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	answerMediaEngine := &MediaEngine{}
+	assert.NoError(t, answerMediaEngine.RegisterDefaultCodecs())
+	assert.NoError(t, ConfigureRED(answerMediaEngine))
+
+	pcAnswer, err := NewAPI(WithMediaEngine(answerMediaEngine)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = NewTrackLocalStaticRTP(RTPCodecCapability{MimeType: MimeTypeOpus}, "audio", "pion", WithREDDistance(1))
+	assert.ErrorIs(t, err, errTrackLocalStaticRTPREDDistance)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeOpus}, "audio", "pion", WithREDDistance(1))
+	assert.NoError(t, err)
+
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	restored, restoredFn := context.WithCancel(context.Background())
+	pcAnswer.OnTrack(func(trackRemote *TrackRemote, _ *RTPReceiver) {
+		assert.Equal(t, MimeTypeOpus, trackRemote.Codec().MimeType)
+
+		var first *rtp.Packet
+		for {
+			packet, _, readErr := trackRemote.ReadRTP()
+			if readErr != nil {
+				return
+			}
+			assert.Equal(t, uint8(111), packet.PayloadType)
+
+			// Each sample carries its index, check that it matches the sequence number and timestamp
+			if first == nil {
+				first = packet
+			}
+			index := packet.Payload[0] - first.Payload[0]
+			assert.Equal(t, first.SequenceNumber+uint16(index), packet.SequenceNumber)
+			assert.Equal(t, first.Timestamp+uint32(index)*960, packet.Timestamp)
+
+			if packet.SequenceNumber%5 == 2 {
+				restoredFn()
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	func() {
+		ticker := time.NewTicker(time.Millisecond * 20)
+		defer ticker.Stop()
+
+		for index := byte(0); ; index++ {
+			select {
+			case <-restored.Done():
+				return
+			case <-ticker.C:
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{index}, Duration: time.Millisecond * 20}))
+			}
+		}
+	}()
+
+	assert.NotZero(t, atomic.LoadUint32(&redPacketsSent))
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
 func BenchmarkTrackLocalWrite(b *testing.B) {
 	offerPC, answerPC, err := newPair()
 	defer closePairNow(b, offerPC, answerPC)
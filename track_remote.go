@@ -4,11 +4,15 @@
 package webrtc
 
 import (
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/internal/red"
+	"github.com/pion/webrtc/v3/pkg/dtmf"
 )
 
 // TrackRemote represents a single inbound source of media
@@ -28,6 +32,20 @@ type TrackRemote struct {
 	receiver         *RTPReceiver
 	peeked           []byte
 	peekedAttributes interceptor.Attributes
+
+	// RED packets are unwrapped to their primary encoding. Redundant encodings of lost
+	// packets are queued, and returned before the primary encoding that carried them.
+	redPayloadType    PayloadType
+	redPending        [][]byte
+	redSequenceNumber uint16
+	redTimestamp      uint32
+	redReceived       bool
+
+	// firSequenceNumber is the sequence number of the next FIR sent by RequestKeyFrame
+	firSequenceNumber uint8
+
+	onDTMFHandler func(dtmf.Digit)
+	dtmfDecoder   *dtmf.Decoder
 }
 
 func newTrackRemote(kind RTPCodecType, ssrc SSRC, rid string, receiver *RTPReceiver) *TrackRemote {
@@ -100,6 +118,20 @@ func (t *TrackRemote) Codec() RTPCodecParameters {
 
 // Read reads data from the track.
 func (t *TrackRemote) Read(b []byte) (n int, attributes interceptor.Attributes, err error) {
+	for {
+		if n, attributes, err = t.read(b); err != nil {
+			return
+		}
+
+		// telephone-events are consumed by the OnDTMF handler, they don't change the codec of the track
+		if !t.handleDTMF(b[:n]) {
+			err = t.checkAndUpdateTrack(b)
+			return
+		}
+	}
+}
+
+func (t *TrackRemote) read(b []byte) (n int, attributes interceptor.Attributes, err error) {
 	t.mu.RLock()
 	r := t.receiver
 	peeked := t.peeked != nil
@@ -117,20 +149,190 @@ func (t *TrackRemote) Read(b []byte) (n int, attributes interceptor.Attributes,
 		// released the lock.  Deal with it.
 		if data != nil {
 			n = copy(b, data)
-			err = t.checkAndUpdateTrack(b)
 			return
 		}
 	}
 
+	t.mu.Lock()
+	var pending []byte
+	if len(t.redPending) != 0 {
+		pending = t.redPending[0]
+		t.redPending = t.redPending[1:]
+	}
+	t.mu.Unlock()
+	if pending != nil {
+		n = copy(b, pending)
+		return
+	}
+
 	n, attributes, err = r.readRTP(b, t)
 	if err != nil {
 		return
 	}
 
-	err = t.checkAndUpdateTrack(b)
+	n, err = t.unwrapRED(b, n)
 	return
 }
 
+// OnDTMF sets an event handler which is called with the DTMF tones sent by the remote peer
+// as telephone-events. The telephone-event packets are never returned by Read, whether a
+// handler is set or not, so they don't reach the audio decoder.
+func (t *TrackRemote) OnDTMF(f func(dtmf.Digit)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.onDTMFHandler = f
+}
+
+// handleDTMF consumes the packet if it is a telephone-event, and decodes it if an OnDTMF handler
+// is set. It returns true if the packet was consumed.
+func (t *TrackRemote) handleDTMF(b []byte) bool {
+	if len(b) < 2 {
+		return false
+	}
+
+	t.mu.RLock()
+	handler := t.onDTMFHandler
+	payloadType, codec := t.payloadType, t.codec
+	t.mu.RUnlock()
+
+	if packetPayloadType := PayloadType(b[1] & rtpPayloadTypeBitmask); packetPayloadType != payloadType {
+		params, err := t.receiver.api.mediaEngine.getRTPParametersByPayloadType(packetPayloadType)
+		if err != nil {
+			return false
+		}
+		codec = params.Codecs[0]
+	}
+	if !strings.EqualFold(codec.MimeType, MimeTypeTelephoneEvent) {
+		return false
+	} else if handler == nil {
+		return true
+	}
+
+	packet := &rtp.Packet{}
+	if err := packet.Unmarshal(b); err != nil {
+		return true
+	}
+
+	t.mu.Lock()
+	if t.dtmfDecoder == nil {
+		t.dtmfDecoder = dtmf.NewDecoder(codec.ClockRate)
+	}
+	digits, err := t.dtmfDecoder.Decode(packet)
+	t.mu.Unlock()
+	if err != nil {
+		return true
+	}
+
+	for _, digit := range digits {
+		handler(digit)
+	}
+
+	return true
+}
+
+// isREDPayloadType returns true if payloadType is the one of a negotiated RED codec
+func (t *TrackRemote) isREDPayloadType(payloadType PayloadType) bool {
+	t.mu.RLock()
+	redPayloadType, primaryPayloadType := t.redPayloadType, t.payloadType
+	t.mu.RUnlock()
+
+	switch {
+	case redPayloadType != 0 && payloadType == redPayloadType:
+		return true
+	case primaryPayloadType != 0 && payloadType == primaryPayloadType:
+		return false
+	}
+
+	codec, _, err := t.receiver.api.mediaEngine.getCodecByPayload(payloadType)
+	if err != nil || !strings.EqualFold(codec.MimeType, MimeTypeRED) {
+		return false
+	}
+
+	t.mu.Lock()
+	t.redPayloadType = payloadType
+	t.mu.Unlock()
+	return true
+}
+
+// unwrapRED replaces the RED packet in b by its primary encoding, so that consumers
+// of the track only see the codec that has been wrapped
+func (t *TrackRemote) unwrapRED(b []byte, n int) (int, error) {
+	if n < 2 || !t.isREDPayloadType(PayloadType(b[1]&rtpPayloadTypeBitmask)) {
+		return n, nil
+	}
+
+	packet := &rtp.Packet{}
+	if err := packet.Unmarshal(b[:n]); err != nil {
+		return 0, err
+	}
+
+	blocks, err := red.Unmarshal(packet.Payload)
+	if err != nil {
+		return 0, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Only packets that haven't been received are restored from the redundant encodings
+	lost := 0
+	lastTimestamp := t.redTimestamp
+	if diff := packet.SequenceNumber - t.redSequenceNumber; !t.redReceived || diff < 1<<15 {
+		if t.redReceived {
+			lost = int(diff) - 1
+		}
+		t.redSequenceNumber = packet.SequenceNumber
+		t.redTimestamp = packet.Timestamp
+		t.redReceived = true
+	}
+
+	// The redundant encodings are assumed to be the ones of the packets directly preceding
+	// this one. A block is only restored when its timestamp offset agrees with that distance,
+	// given the duration of the lost packets, so that a sender skipping encodings can't make
+	// us restore them with the wrong sequence numbers.
+	var step uint32
+	if span := packet.Timestamp - lastTimestamp; lost > 0 && span%uint32(lost+1) == 0 {
+		step = span / uint32(lost+1)
+	}
+
+	redundant, primary := blocks[:len(blocks)-1], blocks[len(blocks)-1]
+	for i, block := range redundant {
+		distance := len(redundant) - i
+		if distance > lost || uint32(block.TimestampOffset) != uint32(distance)*step {
+			continue
+		}
+
+		header := packet.Header.Clone()
+		header.SequenceNumber = packet.SequenceNumber - uint16(distance)
+		header.Timestamp = packet.Timestamp - uint32(block.TimestampOffset)
+		header.PayloadType = block.PayloadType
+		header.Marker = false
+
+		restored, err := (&rtp.Packet{Header: header, Payload: block.Payload}).Marshal()
+		if err != nil {
+			return 0, err
+		}
+		t.redPending = append(t.redPending, restored)
+	}
+
+	packet.PayloadType = primary.PayloadType
+	packet.Payload = primary.Payload
+	packet.PaddingSize = 0
+	unwrapped, err := packet.Marshal()
+	if err != nil {
+		return 0, err
+	}
+
+	if len(t.redPending) != 0 {
+		t.redPending = append(t.redPending, unwrapped)
+		unwrapped = t.redPending[0]
+		t.redPending = t.redPending[1:]
+	}
+
+	return copy(b, unwrapped), nil
+}
+
 // checkAndUpdateTrack checks payloadType for every incoming packet
 // once a different payloadType is detected the track will be updated
 func (t *TrackRemote) checkAndUpdateTrack(b []byte) error {
@@ -194,3 +396,49 @@ func (t *TrackRemote) peek(b []byte) (n int, a interceptor.Attributes, err error
 func (t *TrackRemote) SetReadDeadline(deadline time.Time) error {
 	return t.receiver.setRTPReadDeadline(deadline, t)
 }
+
+// CaptureTime returns the capture time of a RTP timestamp of the track, on the NTP clock of its
+// sender. It is false unless ConfigureLipSync is used, until a Sender Report of the track is received.
+func (t *TrackRemote) CaptureTime(rtpTimestamp uint32) (time.Time, bool) {
+	t.receiver.mu.RLock()
+	synchronizer := t.receiver.lipSynchronizer
+	t.receiver.mu.RUnlock()
+
+	if synchronizer == nil {
+		return time.Time{}, false
+	}
+
+	return synchronizer.CaptureTime(uint32(t.SSRC()), rtpTimestamp)
+}
+
+// RequestKeyFrame asks the remote peer for a key frame of the track. A Picture Loss Indication
+// is sent if it has been negotiated, otherwise a Full Intra Request.
+func (t *TrackRemote) RequestKeyFrame() error {
+	pkt, err := t.keyFrameRequest()
+	if err != nil {
+		return err
+	}
+
+	_, err = t.receiver.Transport().WriteRTCP([]rtcp.Packet{pkt})
+	return err
+}
+
+func (t *TrackRemote) keyFrameRequest() (rtcp.Packet, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch {
+	case hasRTCPFeedback(t.codec.RTCPFeedback, RTCPFeedback{Type: TypeRTCPFBNACK, Parameter: "pli"}):
+		return &rtcp.PictureLossIndication{MediaSSRC: uint32(t.ssrc)}, nil
+	case hasRTCPFeedback(t.codec.RTCPFeedback, RTCPFeedback{Type: TypeRTCPFBCCM, Parameter: "fir"}):
+		// The media source SSRC of a FIR is unused and must be 0, the target is in its entry
+		// https://tools.ietf.org/html/rfc5104#section-4.3.1.2
+		pkt := &rtcp.FullIntraRequest{
+			FIR: []rtcp.FIREntry{{SSRC: uint32(t.ssrc), SequenceNumber: t.firSequenceNumber}},
+		}
+		t.firSequenceNumber++
+		return pkt, nil
+	default:
+		return nil, errTrackRemoteKeyFrameNotNegotiated
+	}
+}
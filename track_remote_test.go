@@ -0,0 +1,68 @@
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/internal/red"
+	"github.com/stretchr/testify/assert"
+)
+
+// Assert that redundant encodings are only restored when their timestamp offsets
+// match the packets that have been lost
+func TestTrackRemote_UnwrapRED(t *testing.T) {
+	redPacket := func(sequenceNumber uint16, timestamp uint32, redundant ...red.Block) []byte {
+		b, err := (&rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    63,
+				SequenceNumber: sequenceNumber,
+				Timestamp:      timestamp,
+			},
+			Payload: red.Marshal(redundant, red.Block{PayloadType: 111, Payload: []byte{byte(sequenceNumber)}}),
+		}).Marshal()
+		assert.NoError(t, err)
+
+		return append(b, make([]byte, 100)...)
+	}
+
+	read := func(track *TrackRemote, b []byte) *rtp.Packet {
+		n, err := track.unwrapRED(b, len(b)-100)
+		assert.NoError(t, err)
+
+		packet := &rtp.Packet{}
+		assert.NoError(t, packet.Unmarshal(b[:n]))
+		return packet
+	}
+
+	t.Run("Consecutive", func(t *testing.T) {
+		track := &TrackRemote{redPayloadType: 63, payloadType: 111}
+		assert.Equal(t, uint16(1), read(track, redPacket(1, 960)).SequenceNumber)
+
+		// Packets 2 and 3 are lost, both are restored from packet 4
+		packet := read(track, redPacket(4, 3840,
+			red.Block{PayloadType: 111, TimestampOffset: 1920, Payload: []byte{2}},
+			red.Block{PayloadType: 111, TimestampOffset: 960, Payload: []byte{3}},
+		))
+		assert.Equal(t, uint16(2), packet.SequenceNumber)
+		assert.Equal(t, uint32(1920), packet.Timestamp)
+		assert.Equal(t, []byte{2}, packet.Payload)
+		assert.Len(t, track.redPending, 2)
+	})
+
+	t.Run("Skipped encoding", func(t *testing.T) {
+		track := &TrackRemote{redPayloadType: 63, payloadType: 111}
+		assert.Equal(t, uint16(1), read(track, redPacket(1, 960)).SequenceNumber)
+
+		// Packets 2 and 3 are lost, but the sender skipped the encoding of packet 3 and the
+		// nearest block is the one of packet 2, which must not be restored as packet 3
+		packet := read(track, redPacket(4, 3840,
+			red.Block{PayloadType: 111, TimestampOffset: 1920, Payload: []byte{2}},
+		))
+		assert.Equal(t, uint16(4), packet.SequenceNumber)
+		assert.Empty(t, track.redPending)
+	})
+}